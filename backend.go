@@ -0,0 +1,18 @@
+package ngcat
+
+import "context"
+
+// Backend 缓存背后的数据源，用于按键重新加载缓存值（见RefreshAhead）
+type Backend interface {
+	// Load 加载键的最新值
+	Load(ctx context.Context, key string) ([]byte, error)
+}
+
+// reportError 报告后台任务中的错误，设置了WithOnError时调用回调，否则写入日志
+func (ng *NGCache) reportError(err error) {
+	if ng.onError != nil {
+		ng.onError(err)
+		return
+	}
+	ng.logger.Warn("ngcat: 后台任务失败", "error", err)
+}
@@ -0,0 +1,379 @@
+package ngcat
+
+import (
+	"fmt"
+	"os"
+	"sync"
+	"testing"
+	"time"
+
+	"ngcat/internal/benchutil"
+)
+
+// benchKeyCount 基准测试预生成的键数量，取2的幂以便用掩码循环取键
+const benchKeyCount = 1 << 16
+
+var (
+	benchKeys    = benchutil.Keys("key", benchKeyCount)
+	benchStrings = benchutil.Strings(benchKeyCount)
+	benchStructs = benchutil.Structs(1024)
+)
+
+func newBenchCache(b *testing.B, opts ...Option) *NGCache {
+	b.Helper()
+	nc := NewNGCache(256*1024*1024, nil, opts...)
+	b.Cleanup(func() { nc.Close() })
+	return nc
+}
+
+// fillStrings 预先写入所有键
+func fillStrings(nc *NGCache, expireSeconds int) {
+	for i, key := range benchKeys {
+		nc.SetString(key, benchStrings[i], expireSeconds)
+	}
+}
+
+func BenchmarkSetString(b *testing.B) {
+	nc := newBenchCache(b)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		j := i & (benchKeyCount - 1)
+		nc.SetString(benchKeys[j], benchStrings[j], 60)
+	}
+}
+
+func BenchmarkSetStringParallel(b *testing.B) {
+	nc := newBenchCache(b)
+	b.ReportAllocs()
+	b.ResetTimer()
+	b.RunParallel(func(pb *testing.PB) {
+		for i := 0; pb.Next(); i++ {
+			j := i & (benchKeyCount - 1)
+			nc.SetString(benchKeys[j], benchStrings[j], 60)
+		}
+	})
+}
+
+func BenchmarkGetString(b *testing.B) {
+	nc := newBenchCache(b)
+	fillStrings(nc, 0)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		nc.GetString(benchKeys[i&(benchKeyCount-1)])
+	}
+}
+
+func BenchmarkGetStringParallel(b *testing.B) {
+	nc := newBenchCache(b)
+	fillStrings(nc, 0)
+	b.ReportAllocs()
+	b.ResetTimer()
+	b.RunParallel(func(pb *testing.PB) {
+		for i := 0; pb.Next(); i++ {
+			nc.GetString(benchKeys[i&(benchKeyCount-1)])
+		}
+	})
+}
+
+func BenchmarkSetJSON(b *testing.B) {
+	nc := newBenchCache(b)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		nc.SetJSON(benchKeys[i&(benchKeyCount-1)], benchStructs[i&1023], 60)
+	}
+}
+
+func BenchmarkSetJSONParallel(b *testing.B) {
+	nc := newBenchCache(b)
+	b.ReportAllocs()
+	b.ResetTimer()
+	b.RunParallel(func(pb *testing.PB) {
+		for i := 0; pb.Next(); i++ {
+			nc.SetJSON(benchKeys[i&(benchKeyCount-1)], benchStructs[i&1023], 60)
+		}
+	})
+}
+
+func BenchmarkGetAny(b *testing.B) {
+	nc := newBenchCache(b)
+	for i, s := range benchStructs {
+		nc.SetAny(benchKeys[i], s, 0)
+	}
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		var s benchutil.TestStruct
+		nc.GetAny(benchKeys[i&1023], &s)
+	}
+}
+
+func BenchmarkGetAnyParallel(b *testing.B) {
+	nc := newBenchCache(b)
+	for i, s := range benchStructs {
+		nc.SetAny(benchKeys[i], s, 0)
+	}
+	b.ReportAllocs()
+	b.ResetTimer()
+	b.RunParallel(func(pb *testing.PB) {
+		for i := 0; pb.Next(); i++ {
+			var s benchutil.TestStruct
+			nc.GetAny(benchKeys[i&1023], &s)
+		}
+	})
+}
+
+func BenchmarkSetPermanent(b *testing.B) {
+	nc := newBenchCache(b)
+	keys, values := benchByteKeys(), benchByteValues()
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		j := i & (benchKeyCount - 1)
+		nc.SetPermanent(keys[j], values[j])
+	}
+}
+
+func BenchmarkSetPermanentParallel(b *testing.B) {
+	nc := newBenchCache(b)
+	keys, values := benchByteKeys(), benchByteValues()
+	b.ReportAllocs()
+	b.ResetTimer()
+	b.RunParallel(func(pb *testing.PB) {
+		for i := 0; pb.Next(); i++ {
+			j := i & (benchKeyCount - 1)
+			nc.SetPermanent(keys[j], values[j])
+		}
+	})
+}
+
+// newFallbackCache 创建所有永久缓存都已从freecache中移除的缓存，
+// 且读取时不写回，使每次读取都走持久化数据回退路径
+func newFallbackCache(b *testing.B, opts ...Option) *NGCache {
+	nc := newBenchCache(b, append(opts, WithPromotePolicy(PromoteNever, 0))...)
+	fillStrings(nc, 0)
+	nc.clearLoaded(nil)
+	return nc
+}
+
+func BenchmarkGetStringPersistFallback(b *testing.B) {
+	nc := newFallbackCache(b)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		nc.GetString(benchKeys[i&(benchKeyCount-1)])
+	}
+}
+
+func BenchmarkGetStringPersistFallbackParallel(b *testing.B) {
+	nc := newFallbackCache(b)
+	b.ReportAllocs()
+	b.ResetTimer()
+	b.RunParallel(func(pb *testing.PB) {
+		for i := 0; pb.Next(); i++ {
+			nc.GetString(benchKeys[i&(benchKeyCount-1)])
+		}
+	})
+}
+
+// BenchmarkGetStringMissParallel 60%的读取是不存在的键，其余回退到持久化数据，比较是否启用布隆过滤器
+func BenchmarkGetStringMissParallel(b *testing.B) {
+	missKeys := benchutil.Keys("miss", benchKeyCount)
+	for _, bc := range []struct {
+		name string
+		opts []Option
+	}{
+		{"mutex", nil},
+		{"bloom", []Option{WithPersistBloomFilter(time.Minute)}},
+	} {
+		b.Run(bc.name, func(b *testing.B) {
+			nc := newFallbackCache(b, bc.opts...)
+			b.ReportAllocs()
+			b.ResetTimer()
+			b.RunParallel(func(pb *testing.PB) {
+				for i := 0; pb.Next(); i++ {
+					if i%5 < 3 {
+						nc.GetString(missKeys[i&(benchKeyCount-1)])
+					} else {
+						nc.GetString(benchKeys[i&(benchKeyCount-1)])
+					}
+				}
+			})
+			stats := nc.Stats()
+			if lookups := stats.BloomNegatives + stats.BloomFalsePositives; lookups > 0 {
+				b.ReportMetric(float64(stats.BloomFalsePositives)/float64(lookups), "false-positive-rate")
+			}
+		})
+	}
+}
+
+// BenchmarkGetStringPersistPromote 回退读取并写回freecache，写回后立即清除以保持回退路径
+func BenchmarkGetStringPersistPromote(b *testing.B) {
+	nc := newBenchCache(b)
+	fillStrings(nc, 0)
+	nc.clearLoaded(nil)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		key := benchKeys[i&(benchKeyCount-1)]
+		nc.GetString(key)
+		nc.cache.Del([]byte(key))
+	}
+}
+
+// BenchmarkGetPersistPromoteBurst 500KB的永久缓存被淘汰后，并发读取同时回退到持久化数据，
+// 报告每次淘汰后实际写回和被去重的次数
+func BenchmarkGetPersistPromoteBurst(b *testing.B) {
+	nc := NewNGCache(512*1024*1024, nil)
+	b.Cleanup(func() { nc.Close() })
+	nc.SetBytes("big", make([]byte, 500*1024), 0)
+	const readers = 32
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		nc.cache.Del([]byte("big"))
+		var wg sync.WaitGroup
+		for r := 0; r < readers; r++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				nc.GetBytes("big")
+			}()
+		}
+		wg.Wait()
+	}
+	b.StopTimer()
+	stats := nc.Stats()
+	b.ReportMetric(float64(stats.Promotions)/float64(b.N), "promotions/burst")
+	b.ReportMetric(float64(stats.PromotionsDeduplicated)/float64(b.N), "deduped/burst")
+}
+
+func benchByteKeys() [][]byte {
+	keys := make([][]byte, benchKeyCount)
+	for i, key := range benchKeys {
+		keys[i] = []byte(key)
+	}
+	return keys
+}
+
+func benchByteValues() [][]byte {
+	values := make([][]byte, benchKeyCount)
+	for i, value := range benchStrings {
+		values[i] = []byte(value)
+	}
+	return values
+}
+
+// benchBatchSize SetPermanentBatch基准测试每次写入的条目数量
+const benchBatchSize = 10000
+
+// benchContendingReaders 基准测试中持续读取持久化数据的协程数量
+const benchContendingReaders = 4
+
+// newContendedPersistCache 创建启用持久化的缓存，并启动若干持续读取持久化数据的协程，
+// 模拟读取回退到持久化数据时与写入争用persistDataMutex
+func newContendedPersistCache(b *testing.B) *NGCache {
+	b.Helper()
+	config := &PersistConfig{
+		Enabled:  true,
+		FilePath: b.TempDir(),
+		FileName: "bench.bin",
+		Format:   FormatBinary,
+		Interval: time.Hour,
+	}
+	nc := NewNGCache(256*1024*1024, config)
+	stop := make(chan struct{})
+	var readers sync.WaitGroup
+	for r := 0; r < benchContendingReaders; r++ {
+		readers.Add(1)
+		go func() {
+			defer readers.Done()
+			for i := 0; ; i++ {
+				select {
+				case <-stop:
+					return
+				default:
+				}
+				nc.persistDataMutex.RLock()
+				_ = nc.persistData[benchKeys[i&(benchKeyCount-1)]]
+				nc.persistDataMutex.RUnlock()
+			}
+		}()
+	}
+	b.Cleanup(func() {
+		close(stop)
+		readers.Wait()
+		nc.Close()
+	})
+	return nc
+}
+
+func BenchmarkSetPermanent10k(b *testing.B) {
+	nc := newContendedPersistCache(b)
+	keys, values := benchByteKeys(), benchByteValues()
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		for j := 0; j < benchBatchSize; j++ {
+			nc.SetPermanent(keys[j], values[j])
+		}
+	}
+}
+
+func BenchmarkSetPermanentBatch10k(b *testing.B) {
+	nc := newContendedPersistCache(b)
+	entries := make(map[string][]byte, benchBatchSize)
+	for j := 0; j < benchBatchSize; j++ {
+		entries[benchKeys[j]] = []byte(benchStrings[j])
+	}
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		nc.SetPermanentBatch(entries)
+	}
+}
+
+// benchSaveEntries SyncOnWrite基准测试中持久化数据的条目数量
+const benchSaveEntries = 1000
+
+// benchPersistDir 返回基准测试的持久化目录，可通过NGCAT_BENCH_DIR指定，
+// 例如分别指向tmpfs和ext4上的目录来比较SyncOnWrite的开销
+func benchPersistDir(b *testing.B) string {
+	if dir := os.Getenv("NGCAT_BENCH_DIR"); dir != "" {
+		dir, err := os.MkdirTemp(dir, "ngcat-bench")
+		if err != nil {
+			b.Fatal(err)
+		}
+		b.Cleanup(func() { os.RemoveAll(dir) })
+		return dir
+	}
+	return b.TempDir()
+}
+
+func BenchmarkSaveSyncOnWrite(b *testing.B) {
+	for _, sync := range []bool{false, true} {
+		b.Run(fmt.Sprintf("sync=%t", sync), func(b *testing.B) {
+			nc := NewNGCache(64*1024*1024, &PersistConfig{
+				Enabled:     true,
+				FilePath:    benchPersistDir(b),
+				FileName:    "cache.bin",
+				Format:      FormatBinary,
+				Interval:    time.Hour,
+				SyncOnWrite: sync,
+			})
+			b.Cleanup(func() { nc.Close() })
+			for j := 0; j < benchSaveEntries; j++ {
+				nc.SetString(benchKeys[j], benchStrings[j], 0)
+			}
+			b.ReportAllocs()
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				if err := nc.Save(); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,39 @@
+//go:build unix
+
+package ngcat
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"golang.org/x/sys/unix"
+)
+
+// BenchmarkSaveODSync 以O_DSYNC打开文件写出与BenchmarkSaveSyncOnWrite相同的快照，
+// 作为每次写入都同步与保存结束时一次fsync的对照
+func BenchmarkSaveODSync(b *testing.B) {
+	data := &PersistData{Version: BinaryVersion}
+	for j := 0; j < benchSaveEntries; j++ {
+		data.Entries = append(data.Entries, PersistEntry{Key: benchKeys[j], Value: []byte(benchStrings[j])})
+	}
+	var buf bytes.Buffer
+	if err := writePersistData(context.Background(), &buf, FormatBinary, data); err != nil {
+		b.Fatal(err)
+	}
+	path := filepath.Join(benchPersistDir(b), "cache.bin")
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		file, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_TRUNC|unix.O_DSYNC, 0644)
+		if err != nil {
+			b.Fatal(err)
+		}
+		if err := writePersistData(context.Background(), file, FormatBinary, data); err != nil {
+			b.Fatal(err)
+		}
+		file.Close()
+	}
+}
@@ -6,6 +6,7 @@ import (
 	"time"
 
 	"ngcat"
+	"ngcat/internal/benchutil"
 )
 
 // BenchmarkResult 基准测试结果
@@ -65,16 +66,13 @@ func main() {
 
 	// 1. 字符串操作基准测试
 	const stringTestCount = 100000
-	testData := make([]string, stringTestCount)
-	for i := 0; i < stringTestCount; i++ {
-		testData[i] = fmt.Sprintf("test_value_%d", i)
-	}
+	testData := benchutil.Strings(stringTestCount)
+	strKeys := benchutil.Keys("str_key", stringTestCount)
 
 	// 字符串设置测试
 	result := runBenchmark("SetString", stringTestCount, func() {
 		for i := 0; i < stringTestCount; i++ {
-			key := fmt.Sprintf("str_key_%d", i)
-			cache.SetString(key, testData[i])
+			cache.SetString(strKeys[i], testData[i], 0)
 		}
 	})
 	printResult(result)
@@ -82,20 +80,19 @@ func main() {
 	// 字符串获取测试
 	result = runBenchmark("GetString", stringTestCount, func() {
 		for i := 0; i < stringTestCount; i++ {
-			key := fmt.Sprintf("str_key_%d", i)
-			cache.GetString(key)
+			cache.GetString(strKeys[i])
 		}
 	})
 	printResult(result)
 
 	// 2. 整数操作基准测试
 	const intTestCount = 100000
+	intKeys := benchutil.Keys("int_key", intTestCount)
 
 	// Int64设置测试
 	result = runBenchmark("SetInt64", intTestCount, func() {
 		for i := 0; i < intTestCount; i++ {
-			key := fmt.Sprintf("int_key_%d", i)
-			cache.SetInt64(key, int64(i))
+			cache.SetInt64(intKeys[i], int64(i), 0)
 		}
 	})
 	printResult(result)
@@ -103,20 +100,19 @@ func main() {
 	// Int64获取测试
 	result = runBenchmark("GetInt64", intTestCount, func() {
 		for i := 0; i < intTestCount; i++ {
-			key := fmt.Sprintf("int_key_%d", i)
-			cache.GetInt64(key)
+			cache.GetInt64(intKeys[i])
 		}
 	})
 	printResult(result)
 
 	// 3. 浮点数操作基准测试
 	const floatTestCount = 50000
+	floatKeys := benchutil.Keys("float_key", floatTestCount)
 
 	// Float64设置测试
 	result = runBenchmark("SetFloat64", floatTestCount, func() {
 		for i := 0; i < floatTestCount; i++ {
-			key := fmt.Sprintf("float_key_%d", i)
-			cache.SetFloat64(key, float64(i)*3.14159)
+			cache.SetFloat64(floatKeys[i], float64(i)*3.14159, 0)
 		}
 	})
 	printResult(result)
@@ -124,36 +120,21 @@ func main() {
 	// Float64获取测试
 	result = runBenchmark("GetFloat64", floatTestCount, func() {
 		for i := 0; i < floatTestCount; i++ {
-			key := fmt.Sprintf("float_key_%d", i)
-			cache.GetFloat64(key)
+			cache.GetFloat64(floatKeys[i])
 		}
 	})
 	printResult(result)
 
 	// 4. 结构体序列化基准测试
-	type TestStruct struct {
-		ID   int    `json:"id"`
-		Name string `json:"name"`
-		Age  int    `json:"age"`
-		Data []byte `json:"data"`
-	}
-
 	const structTestCount = 10000
-	testStructs := make([]TestStruct, structTestCount)
-	for i := 0; i < structTestCount; i++ {
-		testStructs[i] = TestStruct{
-			ID:   i,
-			Name: fmt.Sprintf("用户_%d", i),
-			Age:  20 + i%50,
-			Data: make([]byte, 100), // 100字节数据
-		}
-	}
+	testStructs := benchutil.Structs(structTestCount)
+	jsonKeys := benchutil.Keys("json_key", structTestCount)
+	gobKeys := benchutil.Keys("gob_key", structTestCount)
 
 	// JSON序列化设置测试
 	result = runBenchmark("SetJSON", structTestCount, func() {
 		for i := 0; i < structTestCount; i++ {
-			key := fmt.Sprintf("json_key_%d", i)
-			cache.SetJSON(key, testStructs[i])
+			cache.SetJSON(jsonKeys[i], testStructs[i], 0)
 		}
 	})
 	printResult(result)
@@ -161,9 +142,8 @@ func main() {
 	// JSON序列化获取测试
 	result = runBenchmark("GetJSON", structTestCount, func() {
 		for i := 0; i < structTestCount; i++ {
-			key := fmt.Sprintf("json_key_%d", i)
-			var s TestStruct
-			cache.GetJSON(key, &s)
+			var s benchutil.TestStruct
+			cache.GetJSON(jsonKeys[i], &s)
 		}
 	})
 	printResult(result)
@@ -171,8 +151,7 @@ func main() {
 	// Gob序列化设置测试
 	result = runBenchmark("SetAny", structTestCount, func() {
 		for i := 0; i < structTestCount; i++ {
-			key := fmt.Sprintf("gob_key_%d", i)
-			cache.SetAny(key, testStructs[i])
+			cache.SetAny(gobKeys[i], testStructs[i], 0)
 		}
 	})
 	printResult(result)
@@ -180,22 +159,21 @@ func main() {
 	// Gob序列化获取测试
 	result = runBenchmark("GetAny", structTestCount, func() {
 		for i := 0; i < structTestCount; i++ {
-			key := fmt.Sprintf("gob_key_%d", i)
-			var s TestStruct
-			cache.GetAny(key, &s)
+			var s benchutil.TestStruct
+			cache.GetAny(gobKeys[i], &s)
 		}
 	})
 	printResult(result)
 
 	// 5. 永久缓存基准测试
 	const permanentTestCount = 50000
+	permKeys := benchutil.Keys("perm_key", permanentTestCount)
+	permValues := benchutil.Keys("permanent_value", permanentTestCount)
 
 	// 永久缓存设置测试
 	result = runBenchmark("SetPermanent", permanentTestCount, func() {
 		for i := 0; i < permanentTestCount; i++ {
-			key := fmt.Sprintf("perm_key_%d", i)
-			value := fmt.Sprintf("permanent_value_%d", i)
-			cache.SetPermanent([]byte(key), []byte(value))
+			cache.SetBytesWithTTL([]byte(permKeys[i]), []byte(permValues[i]), ngcat.TTLPermanent)
 		}
 	})
 	printResult(result)
@@ -203,12 +181,14 @@ func main() {
 	// 永久缓存获取测试
 	result = runBenchmark("GetPermanent", permanentTestCount, func() {
 		for i := 0; i < permanentTestCount; i++ {
-			key := fmt.Sprintf("perm_key_%d", i)
-			cache.GetPermanent([]byte(key))
+			cache.GetBytesByKey([]byte(permKeys[i]))
 		}
 	})
 	printResult(result)
 
+	// 6. 并发读写基准测试
+	runConcurrentComparison()
+
 	fmt.Println("\n=== 内存使用情况 ===")
 	var m runtime.MemStats
 	runtime.ReadMemStats(&m)
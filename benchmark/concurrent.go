@@ -0,0 +1,148 @@
+package main
+
+import (
+	"fmt"
+	"math/rand"
+	"runtime"
+	"sort"
+	"sync"
+	"time"
+
+	"ngcat"
+	"ngcat/internal/benchutil"
+)
+
+const (
+	// concurrentKeyCount 并发基准测试的键空间大小
+	concurrentKeyCount = 10000
+	// concurrentReadPercent 读操作所占的百分比，其余为写操作
+	concurrentReadPercent = 70
+	// concurrentDuration 每个缓存的测试时长
+	concurrentDuration = 5 * time.Second
+	// latencySampleEvery 每隔多少次操作记录一次延迟，避免记录全部延迟占用过多内存
+	latencySampleEvery = 16
+)
+
+// concurrentCache 并发基准测试使用的缓存操作，NGCache和ShardedNGCache都满足
+type concurrentCache interface {
+	SetString(key string, value string, expireSeconds int) error
+	GetString(key string) (string, error)
+}
+
+// ConcurrentResult 并发基准测试结果
+type ConcurrentResult struct {
+	Name       string        // 缓存名称
+	Goroutines int           // 并发协程数
+	Ops        int64         // 总操作次数
+	Duration   time.Duration // 总耗时
+	OpsPerSec  int64         // 每秒操作数
+	P99        time.Duration // 采样延迟的p99
+}
+
+// ConcurrentBenchmark 以runtime.NumCPU()个协程对cache执行70%读、30%写的混合负载，
+// 写入的是永久缓存，持续duration后汇总吞吐量和p99延迟
+func ConcurrentBenchmark(name string, cache concurrentCache, duration time.Duration) ConcurrentResult {
+	keys := benchutil.Keys("concurrent_key", concurrentKeyCount)
+	values := benchutil.Strings(concurrentKeyCount)
+	for i, key := range keys {
+		cache.SetString(key, values[i], 0)
+	}
+
+	runtime.GC()
+	time.Sleep(10 * time.Millisecond)
+
+	goroutines := runtime.NumCPU()
+	var (
+		wg        sync.WaitGroup
+		mu        sync.Mutex
+		totalOps  int64
+		latencies []time.Duration
+	)
+	deadline := time.Now().Add(duration)
+	start := time.Now()
+	for g := 0; g < goroutines; g++ {
+		wg.Add(1)
+		go func(seed int64) {
+			defer wg.Done()
+			rng := rand.New(rand.NewSource(seed))
+			var ops int64
+			var sampled []time.Duration
+			// 每1024次操作检查一次是否到达截止时间
+			for ; ops%1024 != 0 || time.Now().Before(deadline); ops++ {
+				i := rng.Intn(concurrentKeyCount)
+				read := rng.Intn(100) < concurrentReadPercent
+				if ops%latencySampleEvery != 0 {
+					if read {
+						cache.GetString(keys[i])
+					} else {
+						cache.SetString(keys[i], values[i], 0)
+					}
+					continue
+				}
+				opStart := time.Now()
+				if read {
+					cache.GetString(keys[i])
+				} else {
+					cache.SetString(keys[i], values[i], 0)
+				}
+				sampled = append(sampled, time.Since(opStart))
+			}
+			mu.Lock()
+			totalOps += ops
+			latencies = append(latencies, sampled...)
+			mu.Unlock()
+		}(int64(g))
+	}
+	wg.Wait()
+	elapsed := time.Since(start)
+
+	return ConcurrentResult{
+		Name:       name,
+		Goroutines: goroutines,
+		Ops:        totalOps,
+		Duration:   elapsed,
+		OpsPerSec:  int64(float64(totalOps) / elapsed.Seconds()),
+		P99:        percentile(latencies, 0.99),
+	}
+}
+
+// percentile 返回延迟样本的分位数
+func percentile(samples []time.Duration, p float64) time.Duration {
+	if len(samples) == 0 {
+		return 0
+	}
+	sort.Slice(samples, func(i, j int) bool { return samples[i] < samples[j] })
+	return samples[int(float64(len(samples)-1)*p)]
+}
+
+// runConcurrentComparison 对比单实例与16分片缓存的并发性能
+func runConcurrentComparison() {
+	fmt.Printf("\n=== 并发读写基准测试（%d%%读/%d%%写，%d个键，每项%v） ===\n",
+		concurrentReadPercent, 100-concurrentReadPercent, concurrentKeyCount, concurrentDuration)
+	fmt.Printf("%-25s %10s %14s %14s %12s\n", "缓存", "协程数", "总操作数", "ops/sec", "p99延迟")
+	fmt.Println("--------------------------------------------------------------------------------")
+
+	single := ngcat.NewNGCache(100*1024*1024, nil)
+	singleResult := ConcurrentBenchmark("NGCache", single, concurrentDuration)
+	single.Close()
+	printConcurrentResult(singleResult)
+
+	sharded := ngcat.NewShardedNGCache(16, 100*1024*1024/16, nil)
+	shardedResult := ConcurrentBenchmark("ShardedNGCache(16)", sharded, concurrentDuration)
+	sharded.Close()
+	printConcurrentResult(shardedResult)
+
+	if singleResult.OpsPerSec > 0 {
+		fmt.Printf("分片吞吐量为单实例的 %.2f 倍\n", float64(shardedResult.OpsPerSec)/float64(singleResult.OpsPerSec))
+	}
+}
+
+// printConcurrentResult 打印并发测试结果
+func printConcurrentResult(result ConcurrentResult) {
+	fmt.Printf("%-25s %10d %14d %14d %12v\n",
+		result.Name,
+		result.Goroutines,
+		result.Ops,
+		result.OpsPerSec,
+		result.P99)
+}
@@ -0,0 +1,161 @@
+package ngcat
+
+import (
+	"hash/maphash"
+	"sync/atomic"
+)
+
+const (
+	// bloomBitsPerKey 每个键占用的位数，与bloomHashes一起使假阳性率约为1%
+	bloomBitsPerKey = 10
+	// bloomHashes 每个键设置的位数
+	bloomHashes = 7
+	// bloomMinCapacity 过滤器的最小容量
+	bloomMinCapacity = 1024
+)
+
+// bloomFilter 持久化数据键的布隆过滤器，添加和查询都不加锁
+//
+// 过滤器只会把不存在的键误报为可能存在（假阳性），不会把已添加的键报告为不存在。
+// 删除的键仍留在过滤器中，直到下一次重建。
+type bloomFilter struct {
+	bits []atomic.Uint64
+	// mask 位数减一，位数为2的幂
+	mask uint64
+	seed maphash.Seed
+	// capacity 假阳性率保持在设计值时最多容纳的键数量
+	capacity int
+	// count 已添加的次数，超过capacity后需要重建
+	count atomic.Int64
+}
+
+func newBloomFilter(capacity int) *bloomFilter {
+	if capacity < bloomMinCapacity {
+		capacity = bloomMinCapacity
+	}
+	nbits := uint64(64)
+	for nbits < uint64(capacity)*bloomBitsPerKey {
+		nbits <<= 1
+	}
+	return &bloomFilter{
+		bits:     make([]atomic.Uint64, nbits/64),
+		mask:     nbits - 1,
+		seed:     maphash.MakeSeed(),
+		capacity: capacity,
+	}
+}
+
+// positions 使用双重哈希由一次哈希得到bloomHashes个位置
+func (f *bloomFilter) positions(key string) (h1, h2 uint64) {
+	h := maphash.String(f.seed, key)
+	return h, h>>32 | h<<32 | 1
+}
+
+// add 添加键
+func (f *bloomFilter) add(key string) {
+	h1, h2 := f.positions(key)
+	for i := uint64(0); i < bloomHashes; i++ {
+		bit := (h1 + i*h2) & f.mask
+		word, mask := &f.bits[bit/64], uint64(1)<<(bit%64)
+		for {
+			old := word.Load()
+			if old&mask != 0 || word.CompareAndSwap(old, old|mask) {
+				break
+			}
+		}
+	}
+	f.count.Add(1)
+}
+
+// mayContain 键是否可能存在，返回false时一定不存在
+func (f *bloomFilter) mayContain(key string) bool {
+	h1, h2 := f.positions(key)
+	for i := uint64(0); i < bloomHashes; i++ {
+		bit := (h1 + i*h2) & f.mask
+		if f.bits[bit/64].Load()&(uint64(1)<<(bit%64)) == 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// bloomAdd 启用WithPersistBloomFilter时将写入持久化数据的键加入过滤器，调用方需持有persistDataMutex的写锁
+//
+// 添加次数超过容量时立即以两倍的容量重建，重建的开销按写入次数均摊。
+func (ng *NGCache) bloomAdd(key string) {
+	filter := ng.bloom.Load()
+	if filter == nil {
+		return
+	}
+	filter.add(key)
+	if filter.count.Load() > int64(filter.capacity) {
+		ng.rebuildBloomLocked()
+	}
+}
+
+// bloomNoteDelete 记录一次从持久化数据删除键，下一次检查时重建过滤器
+func (ng *NGCache) bloomNoteDelete() {
+	if ng.bloomInterval > 0 {
+		ng.bloomDeletes.Add(1)
+	}
+}
+
+// persistValue 从持久化数据读取键，过滤器确定键不存在时不获取persistDataMutex
+func (ng *NGCache) persistValue(key string) ([]byte, bool) {
+	filter := ng.bloom.Load()
+	if filter != nil && !filter.mayContain(key) {
+		ng.bloomNegatives.Add(1)
+		return nil, false
+	}
+	ng.persistDataMutex.RLock()
+	value, exists := ng.persistData[key]
+	ng.persistDataMutex.RUnlock()
+	if filter != nil && !exists {
+		ng.bloomFalsePositives.Add(1)
+	}
+	return value, exists
+}
+
+// rebuildBloom 上次重建后删除过键时按当前的持久化数据重建过滤器
+func (ng *NGCache) rebuildBloom() {
+	if ng.bloom.Load() == nil || ng.bloomDeletes.Load() == 0 {
+		return
+	}
+	ng.persistDataMutex.RLock()
+	defer ng.persistDataMutex.RUnlock()
+	ng.rebuildBloomLocked()
+}
+
+// rebuildBloomLocked 按当前的持久化数据重建过滤器，调用方需持有persistDataMutex
+//
+// 持有锁期间写入被阻塞，新过滤器包含所有已有的键后才替换旧过滤器，替换前后的查询都不会漏报。
+func (ng *NGCache) rebuildBloomLocked() {
+	// 预留一倍的容量给之后的写入
+	filter := newBloomFilter(2 * len(ng.persistData))
+	for key := range ng.persistData {
+		filter.add(key)
+	}
+	ng.bloomDeletes.Store(0)
+	ng.bloom.Store(filter)
+}
+
+// startBloomRebuilder 启动定期重建过滤器的协程
+func (ng *NGCache) startBloomRebuilder() {
+	if ng.bloomInterval <= 0 {
+		return
+	}
+	ticker := ng.clock.NewTicker(ng.bloomInterval)
+	ng.tasks.Add(1)
+	go func() {
+		defer ng.tasks.Done()
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C():
+				ng.rebuildBloom()
+			case <-ng.ctx.Done():
+				return
+			}
+		}
+	}()
+}
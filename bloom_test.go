@@ -0,0 +1,72 @@
+package ngcat
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestBloomFilterNoFalseNegatives(t *testing.T) {
+	f := newBloomFilter(100)
+	for i := 0; i < 5000; i++ {
+		f.add(fmt.Sprintf("key%d", i))
+	}
+	for i := 0; i < 5000; i++ {
+		if !f.mayContain(fmt.Sprintf("key%d", i)) {
+			t.Fatalf("key%d reported absent", i)
+		}
+	}
+}
+
+func TestPersistBloomFilter(t *testing.T) {
+	clock := newFakeClock()
+	nc := NewNGCache(1024*1024, nil, WithClock(clock), WithPersistBloomFilter(time.Minute),
+		WithPromotePolicy(PromoteNever, 0))
+	defer nc.Close()
+
+	// 超过初始容量的写入触发扩容，所有键仍能从持久化数据读到
+	const n = 3000
+	for i := 0; i < n; i++ {
+		nc.SetString(fmt.Sprintf("key%d", i), "v", 0)
+	}
+	nc.clearLoaded(nil)
+	if f := nc.bloom.Load(); f.capacity < n {
+		t.Fatalf("capacity = %d after %d inserts", f.capacity, n)
+	}
+	for i := 0; i < n; i++ {
+		if _, err := nc.GetString(fmt.Sprintf("key%d", i)); err != nil {
+			t.Fatalf("key%d: %v", i, err)
+		}
+	}
+
+	for i := 0; i < 1000; i++ {
+		nc.GetString(fmt.Sprintf("miss%d", i))
+	}
+	stats := nc.Stats()
+	if stats.BloomNegatives+stats.BloomFalsePositives != 1000 || stats.BloomFalsePositives > 50 {
+		t.Fatalf("negatives = %d, false positives = %d", stats.BloomNegatives, stats.BloomFalsePositives)
+	}
+
+	// 删除后的重建移除已删除的键，保留其余的键
+	for i := 0; i < n/2; i++ {
+		nc.Delete(fmt.Sprintf("key%d", i))
+	}
+	old := nc.bloom.Load()
+	clock.Add(time.Minute)
+	waitFor(t, func() bool { return nc.bloom.Load() != old })
+	nc.ResetStats()
+	for i := 0; i < n/2; i++ {
+		if _, err := nc.GetString(fmt.Sprintf("key%d", i)); !errors.Is(err, ErrKeyNotFound) {
+			t.Fatalf("deleted key%d: %v", i, err)
+		}
+	}
+	if stats := nc.Stats(); stats.BloomFalsePositives > 50 {
+		t.Fatalf("%d false positives for deleted keys after rebuild", stats.BloomFalsePositives)
+	}
+	for i := n / 2; i < n; i++ {
+		if _, err := nc.GetString(fmt.Sprintf("key%d", i)); err != nil {
+			t.Fatalf("key%d after rebuild: %v", i, err)
+		}
+	}
+}
@@ -0,0 +1,181 @@
+package ngcat
+
+import (
+	"strconv"
+)
+
+// BundleKind 指定捆绑写入条目的编码方式
+type BundleKind int
+
+const (
+	// KindAuto 按值的Go类型选择编码方式，无法识别的类型与SetStruct一样选择gob或JSON
+	KindAuto BundleKind = iota
+	// KindInt32 与SetInt32相同的编码
+	KindInt32
+	// KindInt64 与SetInt64相同的编码
+	KindInt64
+	// KindBool 与SetBool相同的编码
+	KindBool
+	// KindFloat32 与SetFloat32相同的编码
+	KindFloat32
+	// KindFloat64 与SetFloat64相同的编码
+	KindFloat64
+	// KindString 与SetString相同的编码
+	KindString
+	// KindBytes 与SetBytes相同的编码
+	KindBytes
+	// KindJSON 与SetJSON相同的编码
+	KindJSON
+	// KindGob 与SetAny相同的编码
+	KindGob
+)
+
+// BundleEntry 捆绑写入的一个条目
+type BundleEntry struct {
+	Key   string
+	Value interface{}
+	// Kind 编码方式，零值为KindAuto
+	Kind BundleKind
+}
+
+// preparedEntry 已编码、待写入的条目
+type preparedEntry struct {
+	key           string
+	value         []byte
+	expireSeconds int
+}
+
+// SetBundle 以相同的过期时间写入一组不同类型的值
+//
+// 所有条目先全部编码并检查长度，任何一个失败时返回错误且不写入任何条目；
+// 写入时按固定顺序持有所有键的分段锁，永久缓存在一次持久化数据加锁内全部写入。
+func (ng *NGCache) SetBundle(entries []BundleEntry, expireSeconds int) error {
+	started, err := ng.beginWrite()
+	if err != nil {
+		return err
+	}
+	if started {
+		defer ng.inflight.Done()
+	}
+	prepared := make([]preparedEntry, 0, len(entries))
+	for _, entry := range entries {
+		data, err := ng.encodeBundleValue(entry)
+		if err != nil {
+			return newError(CodeEncode, "bundle entry "+strconv.Quote(entry.Key), err)
+		}
+		value, expire, err := ng.prepareSet(entry.Key, data, expireSeconds)
+		if err != nil {
+			return newError(CodeStoreFailed, "bundle entry "+strconv.Quote(entry.Key), err)
+		}
+		if len(entry.Key)+len(value) > ng.maxEntrySize {
+			return newError(CodeStoreFailed, "bundle entry "+strconv.Quote(entry.Key),
+				&ValueTooLargeError{Size: len(value), Max: ng.maxEntrySize - len(entry.Key)})
+		}
+		prepared = append(prepared, preparedEntry{key: entry.Key, value: value, expireSeconds: expire})
+	}
+
+	keys := make([]string, len(prepared))
+	for i, p := range prepared {
+		keys[i] = p.key
+	}
+	unlock := ng.lockKeys(keys...)
+	defer unlock()
+
+	ng.persistDataMutex.Lock()
+	for _, p := range prepared {
+		if p.expireSeconds <= 0 {
+			ng.dropCoalesced(p.key)
+			ng.persistData[p.key] = cloneBytes(p.value)
+			ng.bloomAdd(p.key)
+		}
+	}
+	ng.persistDataMutex.Unlock()
+
+	for _, p := range prepared {
+		if p.expireSeconds <= 0 {
+			ng.appendWAL(walOpSet, p.key, p.value)
+			ng.markDirty(p.key)
+		} else {
+			ng.dropPersisted(p.key)
+		}
+		ng.forgetExpiry(p.key)
+		ng.counters.Delete(p.key)
+		err := ng.storeSet(p.key, p.value, p.expireSeconds)
+		if err != nil {
+			return err
+		}
+		ng.noteWrite(p.key, p.value, p.expireSeconds, p.expireSeconds <= 0)
+	}
+	return nil
+}
+
+// encodeBundleValue 按条目的编码方式编码值，值的类型与指定的编码方式不符时返回ErrInvalidType
+func (ng *NGCache) encodeBundleValue(entry BundleEntry) ([]byte, error) {
+	kind := entry.Kind
+	if kind == KindAuto {
+		kind = ng.bundleKindOf(entry.Value)
+	}
+
+	switch kind {
+	case KindInt32:
+		if v, ok := entry.Value.(int32); ok {
+			return encodeInt32(v), nil
+		}
+	case KindInt64:
+		switch v := entry.Value.(type) {
+		case int64:
+			return encodeInt64(v), nil
+		case int:
+			return encodeInt64(int64(v)), nil
+		}
+	case KindBool:
+		if v, ok := entry.Value.(bool); ok {
+			return encodeBool(v), nil
+		}
+	case KindFloat32:
+		if v, ok := entry.Value.(float32); ok {
+			return encodeFloat32(v), nil
+		}
+	case KindFloat64:
+		if v, ok := entry.Value.(float64); ok {
+			return encodeFloat64(v), nil
+		}
+	case KindString:
+		if v, ok := entry.Value.(string); ok {
+			return []byte(v), nil
+		}
+	case KindBytes:
+		if v, ok := entry.Value.([]byte); ok {
+			return v, nil
+		}
+	case KindJSON:
+		return ng.jsonOptions.marshal(entry.Value)
+	case KindGob:
+		return encodeGob(entry.Value)
+	}
+	return nil, ErrInvalidType
+}
+
+// bundleKindOf 按Go类型推断编码方式
+func (ng *NGCache) bundleKindOf(value interface{}) BundleKind {
+	switch value.(type) {
+	case int32:
+		return KindInt32
+	case int64, int:
+		return KindInt64
+	case bool:
+		return KindBool
+	case float32:
+		return KindFloat32
+	case float64:
+		return KindFloat64
+	case string:
+		return KindString
+	case []byte:
+		return KindBytes
+	}
+	if ng.canUseGob(value) {
+		return KindGob
+	}
+	return KindJSON
+}
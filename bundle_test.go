@@ -0,0 +1,72 @@
+package ngcat
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestSetBundle(t *testing.T) {
+	nc := NewNGCache(1024*1024, nil)
+	defer nc.Close()
+
+	err := nc.SetBundle([]BundleEntry{
+		{Key: "user:name", Value: "alice"},
+		{Key: "user:age", Value: int32(30)},
+		{Key: "user:admin", Value: true},
+		{Key: "user:score", Value: 1.5, Kind: KindFloat64},
+		{Key: "user:tags", Value: []string{"a", "b"}, Kind: KindJSON},
+	}, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if v, _ := nc.GetString("user:name"); v != "alice" {
+		t.Fatalf("name = %q", v)
+	}
+	if v, _ := nc.GetInt32("user:age"); v != 30 {
+		t.Fatalf("age = %d", v)
+	}
+	if v, _ := nc.GetBool("user:admin"); !v {
+		t.Fatal("admin = false")
+	}
+	if v, _ := nc.GetFloat64("user:score"); v != 1.5 {
+		t.Fatalf("score = %v", v)
+	}
+	var tags []string
+	if err := nc.GetJSON("user:tags", &tags); err != nil || len(tags) != 2 {
+		t.Fatalf("tags = %v, %v", tags, err)
+	}
+	if n := len(nc.DumpPermanent()); n != 5 {
+		t.Fatalf("persisted %d entries, want 5", n)
+	}
+}
+
+func TestSetBundleWritesNothingOnError(t *testing.T) {
+	nc := NewNGCache(1024*1024, nil)
+	defer nc.Close()
+
+	err := nc.SetBundle([]BundleEntry{
+		{Key: "a", Value: "ok"},
+		{Key: "b", Value: make(chan int)},
+		{Key: "c", Value: int32(1)},
+	}, 0)
+	if err == nil {
+		t.Fatal("expected an encoding error")
+	}
+	err = nc.SetBundle([]BundleEntry{
+		{Key: "a", Value: "ok"},
+		{Key: "d", Value: "not an int", Kind: KindInt32},
+	}, 60)
+	if !errors.Is(err, ErrInvalidType) {
+		t.Fatalf("expected ErrInvalidType, got %v", err)
+	}
+
+	for _, key := range []string{"a", "b", "c", "d"} {
+		if _, err := nc.GetBytes(key); err != ErrKeyNotFound {
+			t.Fatalf("%s was written: %v", key, err)
+		}
+	}
+	if n := len(nc.DumpPermanent()); n != 0 {
+		t.Fatalf("persisted %d entries, want 0", n)
+	}
+}
@@ -0,0 +1,57 @@
+package ngcat
+
+import (
+	"time"
+)
+
+// Clock 时间来源，默认使用系统时间，测试中可通过WithClock替换为可控时钟
+//
+// NGCache自身的时间逻辑（持久化间隔、过期时间计算、新鲜度时间戳等）都通过Clock获取时间，
+// freecache的过期判断也通过适配器使用同一个Clock。
+type Clock interface {
+	// Now 返回当前时间
+	Now() time.Time
+	// NewTicker 创建周期为d的定时器
+	NewTicker(d time.Duration) Ticker
+}
+
+// Ticker 周期定时器
+type Ticker interface {
+	// C 返回定时触发的通道
+	C() <-chan time.Time
+	// Stop 停止定时器
+	Stop()
+}
+
+// realClock 系统时钟
+type realClock struct{}
+
+func (realClock) Now() time.Time {
+	return time.Now()
+}
+
+func (realClock) NewTicker(d time.Duration) Ticker {
+	return realTicker{time.NewTicker(d)}
+}
+
+// realTicker 包装time.Ticker
+type realTicker struct {
+	t *time.Ticker
+}
+
+func (t realTicker) C() <-chan time.Time {
+	return t.t.C
+}
+
+func (t realTicker) Stop() {
+	t.t.Stop()
+}
+
+// freecacheTimer 将Clock适配为freecache的Timer
+type freecacheTimer struct {
+	clock Clock
+}
+
+func (t freecacheTimer) Now() uint32 {
+	return uint32(t.clock.Now().Unix())
+}
@@ -0,0 +1,132 @@
+package ngcat
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeClock 可手动推进的时钟，推进时触发到期的定时器
+type fakeClock struct {
+	mu      sync.Mutex
+	now     time.Time
+	tickers []*fakeTicker
+}
+
+func newFakeClock() *fakeClock {
+	return &fakeClock{now: time.Unix(1700000000, 0)}
+}
+
+func (c *fakeClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+func (c *fakeClock) NewTicker(d time.Duration) Ticker {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	t := &fakeTicker{clock: c, c: make(chan time.Time, 1), period: d, next: c.now.Add(d)}
+	c.tickers = append(c.tickers, t)
+	return t
+}
+
+// Add 推进时钟，与time.Ticker一样在接收方来不及处理时丢弃多余的触发
+func (c *fakeClock) Add(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.now = c.now.Add(d)
+	for _, t := range c.tickers {
+		for !t.stopped && !t.next.After(c.now) {
+			select {
+			case t.c <- t.next:
+			default:
+			}
+			t.next = t.next.Add(t.period)
+		}
+	}
+}
+
+// fakeTicker fakeClock创建的定时器
+type fakeTicker struct {
+	clock   *fakeClock
+	c       chan time.Time
+	period  time.Duration
+	next    time.Time
+	stopped bool
+}
+
+func (t *fakeTicker) C() <-chan time.Time {
+	return t.c
+}
+
+func (t *fakeTicker) Stop() {
+	t.clock.mu.Lock()
+	t.stopped = true
+	t.clock.mu.Unlock()
+}
+
+// waitFor 等待条件成立，用于等待后台协程完成由时钟推进触发的工作
+func waitFor(t testing.TB, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatal("condition not met in time")
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func fileExists(path string) bool {
+	_, err := os.Stat(path)
+	return err == nil
+}
+
+func TestFakeClockDrivesExpiry(t *testing.T) {
+	clock := newFakeClock()
+	nc := NewNGCache(1024*1024, nil, WithClock(clock))
+	defer nc.Close()
+
+	nc.SetString("short", "v", 10)
+	clock.Add(9 * time.Second)
+	if _, err := nc.GetString("short"); err != nil {
+		t.Fatalf("expired too early: %v", err)
+	}
+	clock.Add(time.Second)
+	if _, err := nc.GetString("short"); err != ErrKeyNotFound {
+		t.Fatalf("expected expiry, got %v", err)
+	}
+}
+
+func ExampleWithClock() {
+	dir, _ := os.MkdirTemp("", "ngcat-example")
+	defer os.RemoveAll(dir)
+
+	clock := newFakeClock()
+	nc := NewNGCache(1024*1024, &PersistConfig{
+		Enabled:  true,
+		FilePath: dir,
+		FileName: "cache.json",
+		Format:   FormatJSON,
+		Interval: time.Hour,
+	}, WithClock(clock))
+	defer nc.Close()
+
+	nc.SetString("greeting", "hello", 0)
+	path := filepath.Join(dir, "cache.json")
+	fmt.Println("before interval:", fileExists(path))
+
+	// 推进一个持久化间隔，持久化协程立即执行保存，无需真实等待一小时
+	clock.Add(time.Hour)
+	for !fileExists(path) {
+		time.Sleep(time.Millisecond)
+	}
+	fmt.Println("after interval:", fileExists(path))
+	// Output:
+	// before interval: false
+	// after interval: true
+}
@@ -0,0 +1,117 @@
+package ngcat
+
+import (
+	"sync"
+	"time"
+)
+
+// coalescer 合并高频永久缓存写入对持久化数据的更新
+type coalescer struct {
+	// interval 合并的时间窗口
+	interval time.Duration
+	mu       sync.Mutex
+	// pending 尚未写入持久化数据的最新值，缓冲区在下一次写入同一键时复用
+	pending map[string][]byte
+}
+
+func newCoalescer(interval time.Duration) *coalescer {
+	return &coalescer{interval: interval, pending: make(map[string][]byte)}
+}
+
+// coalesceLocked 记录永久缓存的最新值，调用方需持有键的分段锁
+//
+// 同一窗口内的重复写入复用同一个缓冲区，只有窗口结束时的值会写入持久化数据、WAL和增量记录。
+func (ng *NGCache) coalesceLocked(key string, value []byte) {
+	c := ng.coalescer
+	c.mu.Lock()
+	buf, ok := c.pending[key]
+	if !ok || cap(buf) < len(value) {
+		buf = make([]byte, 0, len(value))
+	}
+	c.pending[key] = append(buf[:0], value...)
+	c.mu.Unlock()
+}
+
+// dropCoalesced 丢弃键尚未写入的合并值，调用方需持有键的分段锁
+//
+// 删除或直接写入持久化数据前调用，避免之后的合并写入覆盖更新的状态。
+func (ng *NGCache) dropCoalesced(key string) {
+	c := ng.coalescer
+	if c == nil {
+		return
+	}
+	c.mu.Lock()
+	delete(c.pending, key)
+	c.mu.Unlock()
+}
+
+// coalescedValue 返回键尚未写入持久化数据的合并值的副本
+func (ng *NGCache) coalescedValue(key string) ([]byte, bool) {
+	c := ng.coalescer
+	if c == nil {
+		return nil, false
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	value, ok := c.pending[key]
+	if !ok {
+		return nil, false
+	}
+	return cloneBytes(value), true
+}
+
+// flushCoalesced 将所有合并值写入持久化数据
+//
+// 每个键在其分段锁内取出并写入，与同一键上的删除和写入保持先后顺序。
+func (ng *NGCache) flushCoalesced() {
+	c := ng.coalescer
+	if c == nil {
+		return
+	}
+	c.mu.Lock()
+	keys := make([]string, 0, len(c.pending))
+	for key := range c.pending {
+		keys = append(keys, key)
+	}
+	c.mu.Unlock()
+
+	for _, key := range keys {
+		mu := ng.keyLock(key)
+		mu.Lock()
+		c.mu.Lock()
+		value, ok := c.pending[key]
+		delete(c.pending, key)
+		c.mu.Unlock()
+		if ok {
+			// 缓冲区的所有权转移给持久化数据，下一次写入会分配新的缓冲区
+			ng.persistDataMutex.Lock()
+			ng.persistData[key] = value
+			ng.bloomAdd(key)
+			ng.persistDataMutex.Unlock()
+			ng.appendWAL(walOpSet, key, value)
+			ng.markDirty(key)
+		}
+		mu.Unlock()
+	}
+}
+
+// startCoalescer 启动定期写入合并值的协程
+func (ng *NGCache) startCoalescer() {
+	if ng.coalescer == nil {
+		return
+	}
+	ticker := ng.clock.NewTicker(ng.coalescer.interval)
+	ng.tasks.Add(1)
+	go func() {
+		defer ng.tasks.Done()
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C():
+				ng.flushCoalesced()
+			case <-ng.ctx.Done():
+				return
+			}
+		}
+	}()
+}
@@ -0,0 +1,105 @@
+package ngcat
+
+import (
+	"fmt"
+	"testing"
+	"time"
+)
+
+// persisted 返回持久化数据中键的值
+func persisted(ng *NGCache, key string) (string, bool) {
+	ng.persistDataMutex.RLock()
+	defer ng.persistDataMutex.RUnlock()
+	value, ok := ng.persistData[key]
+	return string(value), ok
+}
+
+func TestCoalesceWritesFlushOnClose(t *testing.T) {
+	config := &PersistConfig{
+		Enabled:  true,
+		FilePath: t.TempDir(),
+		FileName: "cache.bin",
+		Format:   FormatBinary,
+		Interval: time.Hour,
+	}
+	nc := NewNGCache(1024*1024, config, WithClock(newFakeClock()), WithCoalesceWrites(time.Hour))
+	for i := 0; i < 1000; i++ {
+		nc.SetString("hot", fmt.Sprintf("v%d", i), 0)
+	}
+	if _, ok := persisted(nc, "hot"); ok {
+		t.Fatal("coalesced write should not reach persistData before the window ends")
+	}
+	if v, _ := nc.GetString("hot"); v != "v999" {
+		t.Fatalf("hot = %q", v)
+	}
+	if err := nc.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	reloaded := NewNGCache(1024*1024, config)
+	defer reloaded.Close()
+	if v, _ := reloaded.GetString("hot"); v != "v999" {
+		t.Fatalf("reloaded hot = %q", v)
+	}
+}
+
+func TestCoalesceWrites(t *testing.T) {
+	clock := newFakeClock()
+	config := &PersistConfig{
+		Enabled:  true,
+		FilePath: t.TempDir(),
+		FileName: "cache.bin",
+		Format:   FormatBinary,
+		Interval: time.Hour,
+	}
+	nc := NewNGCache(1024*1024, config, WithClock(clock), WithCoalesceWrites(time.Second))
+	defer nc.Close()
+
+	nc.SetString("a", "1", 0)
+	nc.SetString("a", "2", 0)
+	nc.SetString("b", "1", 0)
+	nc.Delete("b")
+
+	// freecache中的条目被淘汰时仍能读到合并中的值
+	nc.cache.Del([]byte("a"))
+	if v, _ := nc.GetString("a"); v != "2" {
+		t.Fatalf("a = %q", v)
+	}
+
+	clock.Add(time.Second)
+	waitFor(t, func() bool {
+		v, ok := persisted(nc, "a")
+		return ok && v == "2"
+	})
+	if _, ok := persisted(nc, "b"); ok {
+		t.Fatal("deleted key was resurrected by the coalesced write")
+	}
+
+	// SetPermanent直接写入持久化数据，之后的合并写入不能覆盖它
+	nc.SetString("a", "3", 0)
+	nc.SetPermanent([]byte("a"), []byte("4"))
+	nc.flushCoalesced()
+	if v, _ := persisted(nc, "a"); v != "4" {
+		t.Fatalf("persisted a = %q", v)
+	}
+}
+
+func benchmarkHotPermanentKey(b *testing.B, opts ...Option) {
+	nc := NewNGCache(16*1024*1024, nil, opts...)
+	defer nc.Close()
+	value := make([]byte, 256)
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		nc.SetBytes("counters", value, 0)
+	}
+}
+
+func BenchmarkHotPermanentKey(b *testing.B) {
+	benchmarkHotPermanentKey(b)
+}
+
+func BenchmarkHotPermanentKeyCoalesced(b *testing.B) {
+	benchmarkHotPermanentKey(b, WithCoalesceWrites(10*time.Millisecond))
+}
@@ -0,0 +1,67 @@
+package ngcat
+
+import (
+	"bytes"
+	"encoding/gob"
+
+	"github.com/vmihailenco/msgpack/v5"
+)
+
+// Codec SetAny/GetAny使用的序列化方式
+type Codec interface {
+	// Marshal 序列化值
+	Marshal(v interface{}) ([]byte, error)
+	// Unmarshal 将数据反序列化到v
+	Unmarshal(data []byte, v interface{}) error
+}
+
+// GobCodec gob序列化，缓存的默认Codec
+type GobCodec struct{}
+
+// Marshal 使用gob序列化值
+func (GobCodec) Marshal(v interface{}) ([]byte, error) {
+	return encodeGob(v)
+}
+
+// Unmarshal 使用gob反序列化
+func (GobCodec) Unmarshal(data []byte, v interface{}) error {
+	return gob.NewDecoder(bytes.NewReader(data)).Decode(v)
+}
+
+// JSONCodec JSON序列化
+type JSONCodec struct {
+	// Options JSON选项，为nil时与encoding/json的默认行为一致；
+	// 通过WithDefaultCodec设置时为nil则使用缓存的WithJSONOptions
+	Options *JSONOptions
+}
+
+// options 返回使用的JSON选项
+func (c JSONCodec) options() JSONOptions {
+	if c.Options == nil {
+		return defaultJSONOptions
+	}
+	return *c.Options
+}
+
+// Marshal 使用JSON序列化值
+func (c JSONCodec) Marshal(v interface{}) ([]byte, error) {
+	return c.options().marshal(v)
+}
+
+// Unmarshal 使用JSON反序列化
+func (c JSONCodec) Unmarshal(data []byte, v interface{}) error {
+	return c.options().unmarshal(data, v)
+}
+
+// MsgPackCodec MessagePack序列化
+type MsgPackCodec struct{}
+
+// Marshal 使用MessagePack序列化值
+func (MsgPackCodec) Marshal(v interface{}) ([]byte, error) {
+	return msgpack.Marshal(v)
+}
+
+// Unmarshal 使用MessagePack反序列化
+func (MsgPackCodec) Unmarshal(data []byte, v interface{}) error {
+	return msgpack.Unmarshal(data, v)
+}
@@ -0,0 +1,273 @@
+package ngcat
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/vmihailenco/msgpack/v5"
+)
+
+type codecStruct struct {
+	Name  string
+	Score int
+}
+
+func TestWithDefaultCodec(t *testing.T) {
+	for _, tc := range []struct {
+		name  string
+		codec Codec
+	}{
+		{"gob", GobCodec{}},
+		{"json", JSONCodec{}},
+		{"msgpack", MsgPackCodec{}},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			nc := NewNGCache(1024*1024, nil, WithDefaultCodec(tc.codec))
+			defer nc.Close()
+
+			in := codecStruct{Name: "alice", Score: 42}
+			if err := nc.SetAny("k", in, 0); err != nil {
+				t.Fatal(err)
+			}
+			var out codecStruct
+			if err := nc.GetAny("k", &out); err != nil || out != in {
+				t.Fatalf("GetAny = %+v, %v", out, err)
+			}
+			if err := nc.GetStruct("k", &out); err != nil || out != in {
+				t.Fatalf("GetStruct = %+v, %v", out, err)
+			}
+		})
+	}
+}
+
+func TestMsgPackCodecStoresMsgPack(t *testing.T) {
+	nc := NewNGCache(1024*1024, nil, WithDefaultCodec(MsgPackCodec{}))
+	defer nc.Close()
+
+	nc.SetAny("any", codecStruct{Name: "bob", Score: 7}, 0)
+	data, _ := nc.GetBytes("any")
+	var decoded codecStruct
+	if err := msgpack.Unmarshal(data, &decoded); err != nil || decoded.Name != "bob" {
+		t.Fatalf("SetAny should store msgpack: %+v, %v", decoded, err)
+	}
+
+	// SetJSON不受默认Codec影响
+	nc.SetJSON("json", codecStruct{Name: "carol"}, 0)
+	data, _ = nc.GetBytes("json")
+	if !json.Valid(data) {
+		t.Fatalf("SetJSON stored non-JSON data: %q", data)
+	}
+}
+
+// point 同时实现BinaryMarshaler和TextMarshaler的类型，应优先使用二进制格式
+type point struct{ X, Y int8 }
+
+func (p point) MarshalBinary() ([]byte, error) { return []byte{byte(p.X), byte(p.Y)}, nil }
+
+func (p *point) UnmarshalBinary(data []byte) error {
+	if len(data) != 2 {
+		return errors.New("point: invalid length")
+	}
+	p.X, p.Y = int8(data[0]), int8(data[1])
+	return nil
+}
+
+func (p point) MarshalText() ([]byte, error) { return []byte(fmt.Sprintf("%d,%d", p.X, p.Y)), nil }
+
+func (p *point) UnmarshalText(data []byte) error {
+	_, err := fmt.Sscanf(string(data), "%d,%d", &p.X, &p.Y)
+	return err
+}
+
+func TestSetStructMarshalers(t *testing.T) {
+	nc := NewNGCache(1024*1024, nil, WithDefaultCodec(MsgPackCodec{}))
+	defer nc.Close()
+
+	now := time.Date(2024, 5, 6, 7, 8, 9, 10, time.FixedZone("CST", 8*3600))
+	n, _ := new(big.Int).SetString("123456789012345678901234567890", 10)
+	ip := net.ParseIP("2001:db8::1")
+	p := point{X: -3, Y: 4}
+
+	for _, set := range []func(string, interface{}, int) error{nc.SetStruct, nc.SetAuto} {
+		set("time", now, 0)
+		set("big", n, 0)
+		set("ip", ip, 0)
+		set("point", p, 0)
+
+		var gotTime time.Time
+		if err := nc.GetStruct("time", &gotTime); err != nil || !gotTime.Equal(now) {
+			t.Fatalf("time = %v, %v", gotTime, err)
+		}
+		gotBig := new(big.Int)
+		if err := nc.GetAuto("big", gotBig); err != nil || gotBig.Cmp(n) != 0 {
+			t.Fatalf("big = %v, %v", gotBig, err)
+		}
+		var gotIP net.IP
+		if err := nc.GetStruct("ip", &gotIP); err != nil || !gotIP.Equal(ip) {
+			t.Fatalf("ip = %v, %v", gotIP, err)
+		}
+		var gotPoint point
+		if err := nc.GetStruct("point", &gotPoint); err != nil || gotPoint != p {
+			t.Fatalf("point = %v, %v", gotPoint, err)
+		}
+	}
+
+	// 二进制格式优先，存储的是标记、格式和MarshalBinary的结果
+	raw, _ := nc.GetBytes("point")
+	if !bytes.Equal(raw, []byte{marshalerMarker, marshalerBinary, 0xFD, 4}) {
+		t.Fatalf("point stored as %x", raw)
+	}
+	raw, _ = nc.GetBytes("ip")
+	if !bytes.Equal(raw, append([]byte{marshalerMarker, marshalerText}, "2001:db8::1"...)) {
+		t.Fatalf("ip stored as %q", raw)
+	}
+
+	// 目标类型没有实现对应的Unmarshaler
+	var s string
+	if err := nc.GetStruct("time", &s); !errors.Is(err, ErrInvalidType) {
+		t.Fatalf("expected ErrInvalidType, got %v", err)
+	}
+}
+
+func TestGetStructLegacyValues(t *testing.T) {
+	for _, codec := range []Codec{GobCodec{}, MsgPackCodec{}} {
+		nc := NewNGCache(1024*1024, nil, WithDefaultCodec(codec))
+		type user struct{ Name string }
+
+		// 没有标记的旧值按原来的方式解码
+		nc.SetAny("user", user{Name: "a"}, 0)
+		var u user
+		if err := nc.GetStruct("user", &u); err != nil || u.Name != "a" {
+			t.Fatalf("%T user = %+v, %v", codec, u, err)
+		}
+		now := time.Unix(1700000000, 0).UTC()
+		nc.SetJSON("time", now, 0)
+		var got time.Time
+		if err := nc.GetStruct("time", &got); err != nil || !got.Equal(now) {
+			t.Fatalf("%T time = %v, %v", codec, got, err)
+		}
+		if err := nc.GetAuto("user", &u); err != nil || u.Name != "a" {
+			t.Fatalf("%T GetAuto user = %+v, %v", codec, u, err)
+		}
+		nc.Close()
+	}
+}
+
+func TestJSONOptionsUseNumber(t *testing.T) {
+	type record struct {
+		ID   int64
+		Name string
+	}
+	nc := NewNGCache(1024*1024, nil, WithJSONOptions(JSONOptions{UseNumber: true}))
+	defer nc.Close()
+
+	if err := nc.SetJSON("k", record{ID: 9223372036854775807, Name: "<max>"}, 0); err != nil {
+		t.Fatal(err)
+	}
+	var generic map[string]interface{}
+	if err := nc.GetJSON("k", &generic); err != nil {
+		t.Fatal(err)
+	}
+	id, ok := generic["ID"].(json.Number)
+	if !ok || id.String() != "9223372036854775807" {
+		t.Fatalf("ID = %#v, want exact json.Number", generic["ID"])
+	}
+
+	// 通用结构再次写入后数字保持原样
+	if err := nc.SetJSON("copy", generic, 0); err != nil {
+		t.Fatal(err)
+	}
+	var back record
+	if err := nc.GetJSON("copy", &back); err != nil || back.ID != 9223372036854775807 {
+		t.Fatalf("round trip: %+v, %v", back, err)
+	}
+	raw, _ := nc.GetBytes("copy")
+	if !bytes.Contains(raw, []byte("9223372036854775807")) || !bytes.Contains(raw, []byte("<max>")) {
+		t.Fatalf("stored %s: want exact number and unescaped HTML", raw)
+	}
+
+	// 默认选项下经过float64丢失精度
+	plain := NewNGCache(1024*1024, nil)
+	defer plain.Close()
+	plain.SetJSON("k", record{ID: 9223372036854775807}, 0)
+	generic = nil
+	plain.GetJSON("k", &generic)
+	if _, ok := generic["ID"].(float64); !ok {
+		t.Fatalf("default options should decode numbers as float64, got %T", generic["ID"])
+	}
+}
+
+func TestJSONOptionsPaths(t *testing.T) {
+	opts := JSONOptions{EscapeHTML: true, Indent: "  ", UseNumber: true}
+	nc := NewNGCache(1024*1024, nil, WithJSONOptions(opts), WithDefaultCodec(JSONCodec{}))
+	defer nc.Close()
+	if nc.JSONOptions() != opts {
+		t.Fatalf("JSONOptions() = %+v", nc.JSONOptions())
+	}
+
+	nc.SetJSON("indented", map[string]int{"a": 1}, 0)
+	raw, _ := nc.GetBytes("indented")
+	if string(raw) != "{\n  \"a\": 1\n}" {
+		t.Fatalf("indented value = %q", raw)
+	}
+
+	// WithDefaultCodec的JSONCodec和GetJSONMulti同样使用UseNumber
+	nc.SetAny("any", map[string]int64{"n": 1 << 62}, 0)
+	var generic map[string]interface{}
+	if err := nc.GetAny("any", &generic); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := generic["n"].(json.Number); !ok {
+		t.Fatalf("codec: %T", generic["n"])
+	}
+	values, _, err := nc.GetJSONMulti([]string{"any"}, func() interface{} { return new(interface{}) })
+	if err != nil {
+		t.Fatal(err)
+	}
+	if m := (*values["any"].(*interface{})).(map[string]interface{}); fmt.Sprint(m["n"]) != "4611686018427387904" {
+		t.Fatalf("GetJSONMulti: %#v", m["n"])
+	}
+
+	if err := nc.GetJSON("indented", new(int)); err == nil {
+		t.Fatal("decoding into a mismatched type should fail")
+	}
+	if err := opts.unmarshal([]byte(`1 2`), new(interface{})); err == nil {
+		t.Fatal("trailing data should be rejected")
+	}
+}
+
+func TestJSONOptionsPersistKeys(t *testing.T) {
+	config := &PersistConfig{
+		Enabled:  true,
+		FilePath: t.TempDir(),
+		FileName: "cache.json",
+		Format:   FormatJSON,
+		Interval: time.Hour,
+	}
+	nc := NewNGCache(1024*1024, config, WithJSONOptions(JSONOptions{}))
+	nc.SetString("<a&b>", "v", 0)
+	if err := nc.Close(); err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(filepath.Join(config.FilePath, config.FileName))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Contains(data, []byte(`"<a&b>"`)) {
+		t.Fatalf("key should be written without HTML escaping:\n%s", data)
+	}
+
+	nc = NewNGCache(1024*1024, config)
+	defer nc.Close()
+	if v, err := nc.GetString("<a&b>"); err != nil || v != "v" {
+		t.Fatalf("reload: %q, %v", v, err)
+	}
+}
@@ -0,0 +1,107 @@
+package ngcat
+
+import (
+	"context"
+	"math"
+	"os"
+)
+
+// Compact 压缩持久化文件，回收被覆盖和删除的条目占用的空间
+//
+// WAL格式下将存活的永久缓存写为新快照并清空WAL；其他格式每次保存都整体重写文件，Compact等同于SaveContext。
+// 压缩与保存共用持久化锁，不会与定时持久化重叠；回收的字节数记录在日志和Stats的CompactedBytes中。
+func (ng *NGCache) Compact(ctx context.Context) error {
+	if ng.persistConfig == nil || !ng.persistConfig.Enabled {
+		return nil
+	}
+	if ng.persistReadOnly {
+		return ErrReadOnly
+	}
+	if ng.persistConfig.Format != FormatWAL {
+		return ng.saveToPersistContext(ctx)
+	}
+
+	ng.savesInFlight.Add(1)
+	defer ng.savesInFlight.Add(-1)
+	ng.flushCoalesced()
+
+	ng.persistMutex.Lock()
+	defer ng.persistMutex.Unlock()
+	// 先将缓冲的记录写入磁盘，回收的字节数才包含这些记录
+	err := ng.flushWAL()
+	if err != nil {
+		return err
+	}
+	return ng.compactLocked(ctx)
+}
+
+// autoCompact 定时持久化成功后，WAL中已失效的记录比例超过AutoCompactRatio时压缩，只在持久化协程中调用
+func (ng *NGCache) autoCompact() {
+	ratio := ng.persistConfig.AutoCompactRatio
+	if ratio <= 0 || ng.persistConfig.Format != FormatWAL {
+		return
+	}
+
+	ng.persistMutex.Lock()
+	defer ng.persistMutex.Unlock()
+	if ng.walDeadRatio() <= ratio {
+		return
+	}
+	err := ng.compactLocked(context.Background())
+	if err != nil {
+		ng.reportError(err)
+	}
+}
+
+// walDeadRatio 快照和WAL中已失效的记录数与存活条目数之比，没有存活条目而有失效记录时为+Inf
+func (ng *NGCache) walDeadRatio() float64 {
+	ng.persistDataMutex.RLock()
+	live := len(ng.persistData)
+	ng.persistDataMutex.RUnlock()
+
+	ng.walMutex.Lock()
+	var records int
+	if ng.wal != nil {
+		records = ng.wal.records
+	}
+	ng.walMutex.Unlock()
+
+	dead := records - live
+	if dead <= 0 {
+		return 0
+	}
+	if live == 0 {
+		return math.Inf(1)
+	}
+	return float64(dead) / float64(live)
+}
+
+// compactLocked 压缩WAL并记录回收的字节数，调用方需持有persistMutex
+func (ng *NGCache) compactLocked(ctx context.Context) error {
+	before := ng.persistDiskSize()
+	err := ng.compactWAL(ctx)
+	if err != nil {
+		return err
+	}
+	after := ng.persistDiskSize()
+
+	reclaimed := before - after
+	if reclaimed < 0 {
+		reclaimed = 0
+	}
+	ng.compactions.Add(1)
+	ng.compactedBytes.Add(reclaimed)
+	ng.logger.Info("ngcat: 已压缩持久化文件", "reclaimed_bytes", reclaimed, "size", after)
+	return nil
+}
+
+// persistDiskSize 快照文件与WAL文件的字节数之和，不存在的文件计为0
+func (ng *NGCache) persistDiskSize() int64 {
+	var size int64
+	for _, path := range []string{ng.persistFilePath(), ng.walPath()} {
+		if info, err := os.Stat(path); err == nil {
+			size += info.Size()
+		}
+	}
+	return size
+}
@@ -0,0 +1,92 @@
+package ngcat
+
+import (
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io"
+)
+
+// 启用值压缩后每个值的首字节，0xFE和0xFF不会出现在合法的UTF-8文本中
+const (
+	// valueHeaderRaw 值未压缩
+	valueHeaderRaw byte = 0xFE
+	// valueHeaderGzip 值经过gzip压缩
+	valueHeaderGzip byte = 0xFF
+)
+
+// encodeValue 将值编码为存储的形式：按需压缩，启用创建时间记录时在最前面加上写入时间
+func (ng *NGCache) encodeValue(value []byte) ([]byte, error) {
+	data, err := ng.compressValue(value)
+	if err != nil || !ng.trackCreation {
+		return data, err
+	}
+	return ng.stampCreation(data), nil
+}
+
+// decodeValue 将存储的值还原为写入时的值
+func (ng *NGCache) decodeValue(data []byte) ([]byte, error) {
+	if ng.trackCreation {
+		if len(data) < creationPrefixLen {
+			return nil, ErrInvalidType
+		}
+		data = data[creationPrefixLen:]
+	}
+	return ng.decompressValue(data)
+}
+
+// compressValue 启用值压缩时为值加上头部，超过阈值的值进行gzip压缩
+//
+// 压缩后没有变小的值按未压缩存储。未启用时原样返回。
+func (ng *NGCache) compressValue(value []byte) ([]byte, error) {
+	if ng.compressOver <= 0 {
+		return value, nil
+	}
+
+	if len(value) > ng.compressOver {
+		var buf bytes.Buffer
+		buf.WriteByte(valueHeaderGzip)
+		zw := gzip.NewWriter(&buf)
+		_, err := zw.Write(value)
+		if err == nil {
+			err = zw.Close()
+		}
+		if err != nil {
+			return nil, newError(CodeCompress, "", err)
+		}
+		if buf.Len() < len(value)+1 {
+			return buf.Bytes(), nil
+		}
+	}
+
+	data := make([]byte, len(value)+1)
+	data[0] = valueHeaderRaw
+	copy(data[1:], value)
+	return data, nil
+}
+
+// decompressValue 去掉值的头部并在需要时解压
+//
+// 兼容模式：没有头部的值（启用压缩之前写入的值）原样返回。
+func (ng *NGCache) decompressValue(data []byte) ([]byte, error) {
+	if ng.compressOver <= 0 || len(data) == 0 {
+		return data, nil
+	}
+
+	switch data[0] {
+	case valueHeaderRaw:
+		return data[1:], nil
+	case valueHeaderGzip:
+		zr, err := gzip.NewReader(bytes.NewReader(data[1:]))
+		if err != nil {
+			return nil, newError(CodeDecompress, "", err)
+		}
+		value, err := io.ReadAll(zr)
+		if err != nil {
+			return nil, fmt.Errorf("解压缩值失败: %v", err)
+		}
+		return value, nil
+	default:
+		return data, nil
+	}
+}
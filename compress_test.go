@@ -0,0 +1,111 @@
+package ngcat
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestCompressRoundTrip(t *testing.T) {
+	nc := NewNGCache(1024*1024, nil, WithCompressValuesOver(1024))
+	defer nc.Close()
+
+	blob := strings.Repeat(`{"name":"ngcat","tags":["cache","persist"]},`, 5000)
+	if err := nc.SetString("blob", blob, 0); err != nil {
+		t.Fatal(err)
+	}
+	if v, err := nc.GetString("blob"); err != nil || v != blob {
+		t.Fatalf("GetString mismatch: %v", err)
+	}
+
+	stored, _ := nc.cache.Get([]byte("blob"))
+	if stored[0] != valueHeaderGzip || len(stored) > len(blob)/10 {
+		t.Fatalf("blob stored as %d bytes with header 0x%X", len(stored), stored[0])
+	}
+	nc.persistDataMutex.RLock()
+	persisted := nc.persistData["blob"]
+	nc.persistDataMutex.RUnlock()
+	if !bytes.Equal(persisted, stored) {
+		t.Fatal("persistData should hold the compressed form")
+	}
+
+	type doc struct{ Body string }
+	if err := nc.SetJSON("json", doc{Body: blob}, 60); err != nil {
+		t.Fatal(err)
+	}
+	var got doc
+	if err := nc.GetJSON("json", &got); err != nil || got.Body != blob {
+		t.Fatalf("GetJSON mismatch: %v", err)
+	}
+	if err := nc.SetInt64("int", -1, 60); err != nil {
+		t.Fatal(err)
+	}
+	if v, err := nc.GetInt64("int"); err != nil || v != -1 {
+		t.Fatalf("GetInt64 = %d, %v", v, err)
+	}
+	if err := nc.Rename("blob", "renamed"); err != nil {
+		t.Fatal(err)
+	}
+	if dump := nc.DumpPermanent(); string(dump["renamed"]) != blob {
+		t.Fatal("DumpPermanent should return decompressed values")
+	}
+}
+
+func TestCompressThreshold(t *testing.T) {
+	nc := NewNGCache(1024*1024, nil, WithCompressValuesOver(100))
+	defer nc.Close()
+
+	atLimit := strings.Repeat("a", 100)
+	overLimit := strings.Repeat("a", 101)
+	nc.SetString("at", atLimit, 60)
+	nc.SetString("over", overLimit, 60)
+
+	if stored, _ := nc.cache.Get([]byte("at")); stored[0] != valueHeaderRaw || len(stored) != 101 {
+		t.Fatalf("value at threshold stored as %q", stored)
+	}
+	if stored, _ := nc.cache.Get([]byte("over")); stored[0] != valueHeaderGzip {
+		t.Fatalf("value over threshold stored raw: %q", stored)
+	}
+	if v, _ := nc.GetString("at"); v != atLimit {
+		t.Fatalf("at = %q", v)
+	}
+	if v, _ := nc.GetString("over"); v != overLimit {
+		t.Fatalf("over = %q", v)
+	}
+	if err := nc.SetBytes("empty", nil, 60); err != nil {
+		t.Fatal(err)
+	}
+	if v, err := nc.GetBytes("empty"); err != nil || len(v) != 0 {
+		t.Fatalf("empty = %q, %v", v, err)
+	}
+}
+
+func TestCompressReadsLegacyValues(t *testing.T) {
+	dir := t.TempDir()
+	config := &PersistConfig{Enabled: true, FilePath: dir, FileName: "cache.bin", Format: FormatBinary, Interval: time.Hour}
+	legacy := NewNGCache(1024*1024, config)
+	legacy.SetString("old", "written before compression", 0)
+	if err := legacy.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	nc := NewNGCache(1024*1024, config, WithCompressValuesOver(8))
+	if v, err := nc.GetString("old"); err != nil || v != "written before compression" {
+		t.Fatalf("legacy value = %q, %v", v, err)
+	}
+	nc.SetString("new", strings.Repeat("z", 4096), 0)
+	if err := nc.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	reloaded := NewNGCache(1024*1024, config, WithCompressValuesOver(8))
+	defer reloaded.Close()
+	if v, _ := reloaded.GetString("new"); v != strings.Repeat("z", 4096) {
+		t.Fatal("compressed value did not survive reload")
+	}
+	data := reloaded.DumpPermanent()
+	if len(data["new"]) != 4096 {
+		t.Fatalf("dumped value length = %d", len(data["new"]))
+	}
+}
@@ -0,0 +1,38 @@
+package ngcat
+
+import (
+	"context"
+)
+
+// GetOrCompute 获取值，不存在时调用loader计算并以expireSeconds写入缓存
+func (ng *NGCache) GetOrCompute(key string, expireSeconds int, loader func() ([]byte, error)) ([]byte, error) {
+	return ng.GetOrComputeContext(context.Background(), key, expireSeconds, func(context.Context) ([]byte, error) {
+		return loader()
+	})
+}
+
+// GetOrComputeContext 获取值，不存在时以ctx调用loader计算并写入缓存
+//
+// 同一键的并发计算会被合并为一次loader调用，所有等待者共享其结果，
+// 因此loader收到的是第一个发起计算的调用方的ctx。ctx已取消时直接返回ctx.Err()。
+func (ng *NGCache) GetOrComputeContext(ctx context.Context, key string, expireSeconds int, loader func(ctx context.Context) ([]byte, error)) ([]byte, error) {
+	value, err := ng.getWithPersist(key)
+	if err == nil {
+		return value, nil
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	return ng.computeGroup.do(key, func() ([]byte, error) {
+		value, err := loader(ctx)
+		if err != nil {
+			return nil, err
+		}
+		err = ng.setWithPersist(key, value, expireSeconds)
+		if err != nil {
+			return nil, err
+		}
+		return value, nil
+	})
+}
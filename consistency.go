@@ -0,0 +1,149 @@
+package ngcat
+
+import "sort"
+
+// ConsistencyCheck 加载持久化文件后的一致性检查模式
+type ConsistencyCheck int
+
+const (
+	// CheckOff 不检查，默认值
+	CheckOff ConsistencyCheck = iota
+	// CheckVerbose 加载后检查，将不在freecache中的永久缓存写入日志
+	CheckVerbose
+	// CheckStrict 加载后检查并修复，无法写回freecache的永久缓存写入日志
+	CheckStrict
+)
+
+// consistencyLogKeys 日志中最多列出的键数量
+const consistencyLogKeys = 10
+
+// ConsistencyReport 持久化数据与freecache的一致性检查结果
+type ConsistencyReport struct {
+	// Checked 检查的永久缓存数量
+	Checked int
+	// Missing 在持久化数据中、但不在freecache中的键，按字典序排列
+	Missing []string
+	// Repaired RepairConsistency重新写入freecache的键，按字典序排列
+	Repaired []string
+	// Unrepairable RepairConsistency无法写入freecache的键及原因
+	Unrepairable map[string]error
+}
+
+// Consistent 检查时所有永久缓存都在freecache中
+func (r ConsistencyReport) Consistent() bool {
+	return len(r.Missing) == 0
+}
+
+// CheckConsistency 遍历持久化数据，报告不在freecache中的永久缓存
+//
+// 加载时写入freecache失败（值超过条目上限）或之后被淘汰的永久缓存仍可从持久化数据读取，
+// 但每次读取都要回退到持久化数据。检查不修改任何数据。
+func (ng *NGCache) CheckConsistency() ConsistencyReport {
+	ng.persistDataMutex.RLock()
+	keys := make([]string, 0, len(ng.persistData))
+	for key := range ng.persistData {
+		keys = append(keys, key)
+	}
+	ng.persistDataMutex.RUnlock()
+	sort.Strings(keys)
+
+	report := ConsistencyReport{Checked: len(keys)}
+	for _, key := range keys {
+		if !ng.inFreecache(key) {
+			report.Missing = append(report.Missing, key)
+		}
+	}
+	return report
+}
+
+// RepairConsistency 将不在freecache中的永久缓存重新写入freecache，返回修复前的检查结果和修复结果
+//
+// 超过freecache条目上限的值无法写入，记录在Unrepairable中，读取时仍从持久化数据获取。
+// 写入时freecache可能淘汰其他条目，修复后再次检查不保证完全一致。
+func (ng *NGCache) RepairConsistency() ConsistencyReport {
+	report := ng.CheckConsistency()
+	for _, key := range report.Missing {
+		err := ng.repairKey(key)
+		if err == nil {
+			report.Repaired = append(report.Repaired, key)
+			continue
+		}
+		if report.Unrepairable == nil {
+			report.Unrepairable = make(map[string]error)
+		}
+		report.Unrepairable[key] = err
+	}
+	return report
+}
+
+// inFreecache 键是否在freecache中，不复制值也不更新访问统计
+func (ng *NGCache) inFreecache(key string) bool {
+	return ng.storePeekFn(key, func([]byte) error { return nil }) == nil
+}
+
+// repairKey 在键的分段锁内将持久化数据中的值写回freecache，键已被删除或已在freecache中时不做任何事
+func (ng *NGCache) repairKey(key string) error {
+	mu := ng.keyLock(key)
+	mu.Lock()
+	defer mu.Unlock()
+
+	ng.persistDataMutex.RLock()
+	value, ok := ng.persistData[key]
+	ng.persistDataMutex.RUnlock()
+	if !ok || ng.inFreecache(key) {
+		return nil
+	}
+	if len(key)+len(value) > ng.maxEntrySize {
+		return &ValueTooLargeError{Size: len(value), Max: ng.maxEntrySize - len(key)}
+	}
+	return ng.storeSet(key, value, 0)
+}
+
+// checkConsistencyAfterLoad 按WithConsistencyCheck的设置在加载后检查一致性，结果写入日志
+//
+// 预加载策略不是PreloadAll时，未预加载的永久缓存本就不在freecache中，不做检查。
+func (ng *NGCache) checkConsistencyAfterLoad() {
+	if ng.preloadPolicy != PreloadAll {
+		return
+	}
+	var report ConsistencyReport
+	switch ng.consistencyCheck {
+	case CheckVerbose:
+		report = ng.CheckConsistency()
+	case CheckStrict:
+		report = ng.RepairConsistency()
+	default:
+		return
+	}
+
+	if report.Consistent() {
+		ng.logger.Info("ngcat: 持久化数据与freecache一致", "checked", report.Checked)
+		return
+	}
+	if ng.consistencyCheck == CheckVerbose {
+		ng.logger.Warn("ngcat: 部分永久缓存不在freecache中，读取时从持久化数据获取",
+			"checked", report.Checked, "missing", len(report.Missing),
+			"keys", firstKeys(report.Missing))
+		return
+	}
+
+	unrepairable := make([]string, 0, len(report.Unrepairable))
+	for key := range report.Unrepairable {
+		unrepairable = append(unrepairable, key)
+	}
+	sort.Strings(unrepairable)
+	ng.logger.Info("ngcat: 已将不在freecache中的永久缓存写回",
+		"checked", report.Checked, "missing", len(report.Missing), "repaired", len(report.Repaired))
+	if len(unrepairable) > 0 {
+		ng.logger.Warn("ngcat: 部分永久缓存无法写回freecache，读取时从持久化数据获取",
+			"count", len(unrepairable), "keys", firstKeys(unrepairable))
+	}
+}
+
+// firstKeys 返回日志中列出的前几个键
+func firstKeys(keys []string) []string {
+	if len(keys) > consistencyLogKeys {
+		return keys[:consistencyLogKeys]
+	}
+	return keys
+}
@@ -0,0 +1,99 @@
+package ngcat
+
+import (
+	"bytes"
+	"errors"
+	"log/slog"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+// writeDriftFixture 用较大的缓存写入包含大值的持久化文件，较小的缓存加载时大值无法写入freecache
+func writeDriftFixture(t *testing.T) *PersistConfig {
+	config := &PersistConfig{
+		Enabled:  true,
+		FilePath: t.TempDir(),
+		FileName: "cache.bin",
+		Format:   FormatBinary,
+		Interval: time.Hour,
+	}
+	nc := NewNGCache(16*1024*1024, config)
+	nc.SetString("small:1", "a", 0)
+	nc.SetString("small:2", "b", 0)
+	nc.SetBytes("large:1", make([]byte, 1024), 0)
+	nc.SetBytes("large:2", make([]byte, 2048), 0)
+	if err := nc.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return config
+}
+
+func TestCheckAndRepairConsistency(t *testing.T) {
+	config := writeDriftFixture(t)
+	// 512KB的缓存单个条目上限为488字节
+	nc := NewNGCache(512*1024, config)
+	defer nc.Close()
+	// 模拟淘汰
+	nc.cache.Del([]byte("small:2"))
+
+	report := nc.CheckConsistency()
+	if report.Checked != 4 || report.Consistent() {
+		t.Fatalf("report = %+v", report)
+	}
+	if want := []string{"large:1", "large:2", "small:2"}; !reflect.DeepEqual(report.Missing, want) {
+		t.Fatalf("missing = %v, want %v", report.Missing, want)
+	}
+
+	report = nc.RepairConsistency()
+	if !reflect.DeepEqual(report.Repaired, []string{"small:2"}) {
+		t.Fatalf("repaired = %v", report.Repaired)
+	}
+	if len(report.Unrepairable) != 2 {
+		t.Fatalf("unrepairable = %v", report.Unrepairable)
+	}
+	for _, key := range []string{"large:1", "large:2"} {
+		var tooLarge *ValueTooLargeError
+		if err := report.Unrepairable[key]; !errors.As(err, &tooLarge) || !errors.Is(err, ErrValueTooLarge) {
+			t.Fatalf("%s: %v", key, err)
+		}
+	}
+
+	// 修复后只剩无法写入的大值，它们仍可从持久化数据读取
+	report = nc.CheckConsistency()
+	if want := []string{"large:1", "large:2"}; !reflect.DeepEqual(report.Missing, want) {
+		t.Fatalf("missing after repair = %v", report.Missing)
+	}
+	if v, err := nc.GetBytes("large:2"); err != nil || len(v) != 2048 {
+		t.Fatalf("large:2 = %d bytes, %v", len(v), err)
+	}
+}
+
+func TestConsistencyCheckOnLoad(t *testing.T) {
+	config := writeDriftFixture(t)
+
+	var logs bytes.Buffer
+	nc := NewNGCache(512*1024, config, WithConsistencyCheck(CheckVerbose),
+		WithLogger(slog.New(slog.NewTextHandler(&logs, nil))))
+	nc.Close()
+	if !strings.Contains(logs.String(), "missing=2") || !strings.Contains(logs.String(), "large:1") {
+		t.Fatalf("verbose logs = %s", logs.String())
+	}
+
+	logs.Reset()
+	nc = NewNGCache(512*1024, config, WithConsistencyCheck(CheckStrict),
+		WithLogger(slog.New(slog.NewTextHandler(&logs, nil))))
+	nc.Close()
+	if !strings.Contains(logs.String(), "无法写回") || !strings.Contains(logs.String(), "count=2") {
+		t.Fatalf("strict logs = %s", logs.String())
+	}
+
+	logs.Reset()
+	nc = NewNGCache(16*1024*1024, config, WithConsistencyCheck(CheckStrict),
+		WithLogger(slog.New(slog.NewTextHandler(&logs, nil))))
+	nc.Close()
+	if !strings.Contains(logs.String(), "checked=4") || strings.Contains(logs.String(), "WARN") {
+		t.Fatalf("strict logs for a consistent cache = %s", logs.String())
+	}
+}
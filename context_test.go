@@ -0,0 +1,138 @@
+package ngcat
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// cancelAfterCtx 在第n次检查后报告已取消，用于在流式处理中途取消
+type cancelAfterCtx struct {
+	context.Context
+	checks int32
+	n      int32
+}
+
+func (c *cancelAfterCtx) Err() error {
+	if atomic.AddInt32(&c.checks, 1) > c.n {
+		return context.Canceled
+	}
+	return nil
+}
+
+func largeCache(t *testing.T, n int) *NGCache {
+	t.Helper()
+	nc := NewNGCache(64*1024*1024, nil)
+	for i := 0; i < n; i++ {
+		nc.SetString(fmt.Sprintf("key_%d", i), "value", 0)
+	}
+	return nc
+}
+
+func TestExportContextCancelCleansUp(t *testing.T) {
+	nc := largeCache(t, 10*ctxCheckInterval)
+	defer nc.Close()
+
+	dir := t.TempDir()
+	target := filepath.Join(dir, "export.bin")
+	ctx := &cancelAfterCtx{Context: context.Background(), n: 3}
+	err := nc.ExportContext(ctx, target, FormatBinary)
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected context.Canceled, got %v", err)
+	}
+	files, _ := os.ReadDir(dir)
+	if len(files) != 0 {
+		t.Fatalf("partial files left behind: %v", files)
+	}
+
+	if err := nc.Export(target, FormatBinary); err != nil {
+		t.Fatal(err)
+	}
+	imported := NewNGCache(64*1024*1024, nil)
+	defer imported.Close()
+	if err := imported.Import(target, FormatBinary); err != nil {
+		t.Fatal(err)
+	}
+	if v, _ := imported.GetString("key_2559"); v != "value" {
+		t.Fatalf("imported value = %q", v)
+	}
+}
+
+func TestSaveContextCancelKeepsTarget(t *testing.T) {
+	dir := t.TempDir()
+	config := &PersistConfig{Enabled: true, FilePath: dir, FileName: "cache.json", Format: FormatJSON, Interval: time.Hour}
+	nc := NewNGCache(64*1024*1024, config)
+	nc.SetString("first", "1", 0)
+	if err := nc.Save(); err != nil {
+		t.Fatal(err)
+	}
+	before, _ := os.ReadFile(filepath.Join(dir, "cache.json"))
+
+	for i := 0; i < 4*ctxCheckInterval; i++ {
+		nc.SetString(fmt.Sprintf("key_%d", i), "value", 0)
+	}
+	ctx := &cancelAfterCtx{Context: context.Background(), n: 2}
+	if err := nc.SaveContext(ctx); !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected context.Canceled, got %v", err)
+	}
+	after, _ := os.ReadFile(filepath.Join(dir, "cache.json"))
+	if string(before) != string(after) {
+		t.Fatal("cancelled save modified the target file")
+	}
+
+	ctx = &cancelAfterCtx{Context: context.Background(), n: 1}
+	reloaded := NewNGCache(64*1024*1024, nil)
+	if err := reloaded.ImportContext(ctx, filepath.Join(dir, "cache.json"), FormatJSON); err != nil {
+		t.Fatal(err)
+	}
+	reloaded.Close()
+	nc.Close()
+}
+
+func TestGetOrComputeContext(t *testing.T) {
+	nc := NewNGCache(1024*1024, nil)
+	defer nc.Close()
+
+	type ctxKey struct{}
+	ctx := context.WithValue(context.Background(), ctxKey{}, "request-42")
+	var calls int32
+	release := make(chan struct{})
+	loader := func(ctx context.Context) ([]byte, error) {
+		atomic.AddInt32(&calls, 1)
+		<-release
+		return []byte(ctx.Value(ctxKey{}).(string)), nil
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			value, err := nc.GetOrComputeContext(ctx, "k", 60, loader)
+			if err != nil || string(value) != "request-42" {
+				t.Errorf("GetOrComputeContext = %q, %v", value, err)
+			}
+		}()
+	}
+	time.Sleep(10 * time.Millisecond)
+	close(release)
+	wg.Wait()
+	if calls != 1 {
+		t.Fatalf("loader called %d times, want 1", calls)
+	}
+
+	cancelled, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := nc.GetOrComputeContext(cancelled, "other", 60, loader); !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected context.Canceled, got %v", err)
+	}
+	if v, err := nc.GetOrCompute("k", 60, nil); err != nil || string(v) != "request-42" {
+		t.Fatalf("cached value = %q, %v", v, err)
+	}
+}
@@ -0,0 +1,120 @@
+package ngcat
+
+import (
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+)
+
+// ConvertOption 持久化文件转换选项
+type ConvertOption func(*convertOptions)
+
+// convertOptions 转换选项集合
+type convertOptions struct {
+	// force 是否允许覆盖已存在的目标文件
+	force bool
+	// onSkip 跳过损坏条目时的回调
+	onSkip func(index int, err error)
+}
+
+// WithForce 允许覆盖已存在的目标文件
+func WithForce() ConvertOption {
+	return func(o *convertOptions) {
+		o.force = true
+	}
+}
+
+// WithSkipHandler 设置损坏条目回调，index为条目在源文件中的序号
+func WithSkipHandler(fn func(index int, err error)) ConvertOption {
+	return func(o *convertOptions) {
+		o.onSkip = fn
+	}
+}
+
+// ConvertPersistFile 在持久化格式之间转换文件，无需创建缓存实例
+//
+// 条目从源文件流式读取并写入目标文件，目标文件总是使用当前版本的格式，
+// 因此旧版本文件会在转换时自动升级。损坏的条目会被跳过并通过WithSkipHandler报告；
+// 二进制条目损坏后无法定位后续条目，转换在该处停止。
+// 目标文件已存在时返回包装了os.ErrExist的错误，除非指定了WithForce。
+// 写入先落到同目录的临时文件，成功后再重命名，失败时目标文件保持不变。
+func ConvertPersistFile(srcPath string, srcFormat PersistFormat, dstPath string, dstFormat PersistFormat, opts ...ConvertOption) (entries int, err error) {
+	options := convertOptions{}
+	for _, opt := range opts {
+		opt(&options)
+	}
+
+	if !options.force {
+		if _, err := os.Stat(dstPath); err == nil {
+			return 0, newError(CodeFileExists, dstPath, os.ErrExist)
+		}
+	}
+
+	src, err := os.Open(srcPath)
+	if err != nil {
+		return 0, newError(CodeOpenFile, srcPath, err)
+	}
+	defer src.Close()
+
+	pr, err := newPersistReader(src, srcFormat)
+	if err != nil {
+		return 0, err
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(dstPath), filepath.Base(dstPath)+".tmp*")
+	if err != nil {
+		return 0, newError(CodeCreateTemp, filepath.Dir(dstPath), err)
+	}
+	defer func() {
+		if err != nil {
+			tmp.Close()
+			os.Remove(tmp.Name())
+		}
+	}()
+
+	count := pr.count
+	if count < 0 {
+		count = 0
+	}
+	pw, err := newPersistWriter(tmp, dstFormat, pr.timestamp, pr.caps, count, nil)
+	if err != nil {
+		return 0, err
+	}
+
+	for {
+		entry, readErr := pr.next()
+		if readErr == io.EOF {
+			break
+		}
+		var corrupt *corruptEntryError
+		if errors.As(readErr, &corrupt) {
+			if options.onSkip != nil {
+				options.onSkip(corrupt.index, corrupt.err)
+			}
+			continue
+		}
+		if readErr != nil {
+			return 0, readErr
+		}
+
+		err = pw.write(entry)
+		if err != nil {
+			return 0, err
+		}
+	}
+
+	err = pw.finish()
+	if err != nil {
+		return 0, err
+	}
+	err = tmp.Close()
+	if err != nil {
+		return 0, err
+	}
+	err = os.Rename(tmp.Name(), dstPath)
+	if err != nil {
+		return 0, newError(CodeReplaceFile, dstPath, err)
+	}
+	return pw.written, nil
+}
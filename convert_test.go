@@ -0,0 +1,128 @@
+package ngcat
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// writeFixture 通过缓存持久化生成测试用的快照文件
+func writeFixture(t *testing.T, dir, name string, format PersistFormat, data map[string]string) string {
+	t.Helper()
+	nc := NewNGCache(1024*1024, &PersistConfig{
+		Enabled:  true,
+		FilePath: dir,
+		FileName: name,
+		Format:   format,
+		Interval: time.Hour,
+	})
+	for k, v := range data {
+		nc.SetString(k, v, 0)
+	}
+	if err := nc.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return filepath.Join(dir, name)
+}
+
+// loadFixture 从快照文件加载缓存
+func loadFixture(path string, format PersistFormat) *NGCache {
+	return NewNGCache(1024*1024, &PersistConfig{
+		Enabled:  true,
+		FilePath: filepath.Dir(path),
+		FileName: filepath.Base(path),
+		Format:   format,
+		Interval: time.Hour,
+	})
+}
+
+func TestConvertPersistFile(t *testing.T) {
+	data := map[string]string{"a": "1", "b": "二", "empty": ""}
+	formats := map[string]PersistFormat{"json": FormatJSON, "bin": FormatBinary}
+
+	for srcName, srcFormat := range formats {
+		for dstName, dstFormat := range formats {
+			t.Run(srcName+"_to_"+dstName, func(t *testing.T) {
+				dir := t.TempDir()
+				src := writeFixture(t, dir, "src."+srcName, srcFormat, data)
+				dst := filepath.Join(dir, "dst."+dstName)
+
+				n, err := ConvertPersistFile(src, srcFormat, dst, dstFormat)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if n != len(data) {
+					t.Fatalf("converted %d entries, want %d", n, len(data))
+				}
+
+				nc := loadFixture(dst, dstFormat)
+				defer nc.Close()
+				for k, want := range data {
+					got, err := nc.GetString(k)
+					if err != nil || got != want {
+						t.Fatalf("%s: got %q, %v; want %q", k, got, err, want)
+					}
+				}
+			})
+		}
+	}
+}
+
+func TestConvertPersistFileRefusesOverwrite(t *testing.T) {
+	dir := t.TempDir()
+	src := writeFixture(t, dir, "src.json", FormatJSON, map[string]string{"a": "1"})
+	dst := filepath.Join(dir, "dst.bin")
+	if err := os.WriteFile(dst, []byte("keep"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	_, err := ConvertPersistFile(src, FormatJSON, dst, FormatBinary)
+	if !errors.Is(err, os.ErrExist) {
+		t.Fatalf("expected os.ErrExist, got %v", err)
+	}
+	content, _ := os.ReadFile(dst)
+	if string(content) != "keep" {
+		t.Fatal("destination was modified without force")
+	}
+
+	_, err = ConvertPersistFile(src, FormatJSON, dst, FormatBinary, WithForce())
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestConvertPersistFileSkipsCorruptEntries(t *testing.T) {
+	dir := t.TempDir()
+	src := filepath.Join(dir, "src.json")
+	content := `{"version":1,"timestamp":1,"entries":[
+		{"key":"good","value":"MQ=="},
+		{"key":"bad","value":"!!not-base64!!"},
+		{"key":"also-good","value":"Mg=="}
+	]}`
+	if err := os.WriteFile(src, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	var skipped []int
+	dst := filepath.Join(dir, "dst.bin")
+	n, err := ConvertPersistFile(src, FormatJSON, dst, FormatBinary, WithSkipHandler(func(index int, err error) {
+		skipped = append(skipped, index)
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != 2 || len(skipped) != 1 || skipped[0] != 1 {
+		t.Fatalf("converted %d, skipped %v", n, skipped)
+	}
+
+	nc := loadFixture(dst, FormatBinary)
+	defer nc.Close()
+	if v, _ := nc.GetString("also-good"); v != "2" {
+		t.Fatalf("also-good = %q", v)
+	}
+	if _, err := nc.GetString("bad"); err != ErrKeyNotFound {
+		t.Fatalf("bad entry should be skipped, got %v", err)
+	}
+}
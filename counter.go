@@ -0,0 +1,94 @@
+package ngcat
+
+import (
+	"sync"
+	"sync/atomic"
+)
+
+// AtomicInt64Counter 按键保存的无锁int64计数器
+type AtomicInt64Counter struct {
+	counters sync.Map // map[string]*atomic.Int64
+}
+
+// Add 将键的计数器加上delta并返回新值，计数器不存在时以initial创建
+func (c *AtomicInt64Counter) Add(key string, delta int64, initial func() (int64, error)) (int64, error) {
+	if v, ok := c.counters.Load(key); ok {
+		return v.(*atomic.Int64).Add(delta), nil
+	}
+
+	start, err := initial()
+	if err != nil {
+		return 0, err
+	}
+	counter := new(atomic.Int64)
+	counter.Store(start)
+	v, _ := c.counters.LoadOrStore(key, counter)
+	return v.(*atomic.Int64).Add(delta), nil
+}
+
+// Load 读取键的计数器
+func (c *AtomicInt64Counter) Load(key string) (int64, bool) {
+	v, ok := c.counters.Load(key)
+	if !ok {
+		return 0, false
+	}
+	return v.(*atomic.Int64).Load(), true
+}
+
+// Delete 删除键的计数器，返回计数器是否存在
+func (c *AtomicInt64Counter) Delete(key string) bool {
+	_, ok := c.counters.LoadAndDelete(key)
+	return ok
+}
+
+// Range 遍历所有计数器，fn返回false时停止
+func (c *AtomicInt64Counter) Range(fn func(key string, value int64) bool) {
+	c.counters.Range(func(k, v interface{}) bool {
+		return fn(k.(string), v.(*atomic.Int64).Load())
+	})
+}
+
+// SetInt64AtomicAdd 以无锁方式将键的int64值加上delta并返回新值
+//
+// 计数器保存在内存中，首次使用时以缓存中已有的int64值（不存在时为0）为初值，
+// Close时通过SetInt64作为永久缓存写回。对该键的其他写入、Delete、Flush和Rename会丢弃计数器，
+// 之后的SetInt64AtomicAdd以新写入的值为初值重新计数。
+func (ng *NGCache) SetInt64AtomicAdd(key string, delta int64) (int64, error) {
+	started, err := ng.beginWrite()
+	if err != nil {
+		return 0, err
+	}
+	if started {
+		defer ng.inflight.Done()
+	}
+	return ng.counters.Add(key, delta, func() (int64, error) {
+		err := ng.checkKeyLen(len(key))
+		if err != nil {
+			return 0, err
+		}
+		value, err := ng.GetInt64(key)
+		if err == ErrKeyNotFound {
+			return 0, nil
+		}
+		return value, err
+	})
+}
+
+// GetInt64Atomic 读取由SetInt64AtomicAdd维护的计数器，没有计数器时读取缓存中的int64值
+func (ng *NGCache) GetInt64Atomic(key string) (int64, error) {
+	if value, ok := ng.counters.Load(key); ok {
+		return value, nil
+	}
+	return ng.GetInt64(key)
+}
+
+// flushCounters 将所有计数器作为永久缓存写回
+func (ng *NGCache) flushCounters() error {
+	var err error
+	ng.counters.Range(func(key string, value int64) bool {
+		// 不经过SetInt64，Drain之后仍然可以写回
+		err = ng.writeKey(key, ng.tagValue("int64", encodeInt64(value)), 0)
+		return err == nil
+	})
+	return err
+}
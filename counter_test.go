@@ -0,0 +1,137 @@
+package ngcat
+
+import (
+	"errors"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestSetInt64AtomicAdd(t *testing.T) {
+	dir := t.TempDir()
+	config := &PersistConfig{Enabled: true, FilePath: dir, FileName: "cache.bin", Format: FormatBinary, Interval: time.Hour}
+	nc := NewNGCache(1024*1024, config)
+	nc.SetInt64("seeded", 100, 0)
+	nc.SetString("text", "x", 0)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for n := 0; n < 1000; n++ {
+				nc.SetInt64AtomicAdd("hits", 1)
+				nc.SetInt64AtomicAdd("seeded", 1)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if v, err := nc.GetInt64Atomic("hits"); err != nil || v != 10000 {
+		t.Fatalf("hits = %d, %v", v, err)
+	}
+	if v, _ := nc.GetInt64Atomic("seeded"); v != 10100 {
+		t.Fatalf("seeded = %d", v)
+	}
+	if _, err := nc.SetInt64AtomicAdd("text", 1); !errors.Is(err, ErrInvalidType) {
+		t.Fatalf("expected ErrInvalidType, got %v", err)
+	}
+	if err := nc.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	reloaded := loadFixture(filepath.Join(dir, "cache.bin"), FormatBinary)
+	defer reloaded.Close()
+	if v, err := reloaded.GetInt64("hits"); err != nil || v != 10000 {
+		t.Fatalf("flushed hits = %d, %v", v, err)
+	}
+}
+
+func TestAtomicCounterDroppedByWrites(t *testing.T) {
+	dir := t.TempDir()
+	config := &PersistConfig{Enabled: true, FilePath: dir, FileName: "cache.bin", Format: FormatBinary, Interval: time.Hour}
+	nc := NewNGCache(1024*1024, config)
+	nc.SetInt64AtomicAdd("deleted", 5)
+	nc.SetInt64AtomicAdd("overwritten", 5)
+	nc.SetInt64AtomicAdd("old", 7)
+
+	if !nc.Delete("deleted") {
+		t.Fatal("Delete of a counter key should report it existed")
+	}
+	if _, err := nc.GetInt64Atomic("deleted"); err != ErrKeyNotFound {
+		t.Fatalf("deleted counter err = %v", err)
+	}
+	nc.SetInt64("overwritten", 100, 0)
+	if v, _ := nc.SetInt64AtomicAdd("overwritten", 1); v != 101 {
+		t.Fatalf("counter after SetInt64 = %d, want 101", v)
+	}
+	if err := nc.Rename("old", "new"); err != nil {
+		t.Fatal(err)
+	}
+	if v, err := nc.GetInt64Atomic("new"); err != nil || v != 7 {
+		t.Fatalf("renamed counter = %d, %v", v, err)
+	}
+	if err := nc.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	reloaded := loadFixture(filepath.Join(dir, "cache.bin"), FormatBinary)
+	defer reloaded.Close()
+	for _, key := range []string{"deleted", "old"} {
+		if _, err := reloaded.GetInt64(key); err != ErrKeyNotFound {
+			t.Fatalf("%s came back after reopen: %v", key, err)
+		}
+	}
+	if v, _ := reloaded.GetInt64("overwritten"); v != 101 {
+		t.Fatalf("overwritten = %d", v)
+	}
+}
+
+func TestFlushDropsAtomicCounters(t *testing.T) {
+	nc := NewNGCache(1024*1024, nil)
+	defer nc.Close()
+	nc.SetInt64AtomicAdd("hits", 1)
+	nc.SetString("a", "1", 0)
+	if n := nc.Flush(); n != 2 {
+		t.Fatalf("Flush = %d, want 2", n)
+	}
+	if _, err := nc.GetInt64Atomic("hits"); err != ErrKeyNotFound {
+		t.Fatalf("counter survived Flush: %v", err)
+	}
+}
+
+// benchCounter 10个协程并发累加同一个键
+func benchCounter(b *testing.B, add func()) {
+	const workers = 10
+	var wg sync.WaitGroup
+	b.ResetTimer()
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func(n int) {
+			defer wg.Done()
+			for i := 0; i < n; i++ {
+				add()
+			}
+		}(b.N/workers + 1)
+	}
+	wg.Wait()
+}
+
+func BenchmarkCounterMutex(b *testing.B) {
+	nc := newBenchCache(b)
+	var mu sync.Mutex
+	benchCounter(b, func() {
+		mu.Lock()
+		v, _ := nc.GetInt64("counter")
+		nc.SetInt64("counter", v+1, 0)
+		mu.Unlock()
+	})
+}
+
+func BenchmarkCounterAtomic(b *testing.B) {
+	nc := newBenchCache(b)
+	benchCounter(b, func() {
+		nc.SetInt64AtomicAdd("counter", 1)
+	})
+}
@@ -0,0 +1,69 @@
+package ngcat
+
+import (
+	"encoding/binary"
+	"time"
+)
+
+// creationPrefixLen 启用WithCreationTracking时存储的值最前面的写入时间长度（Unix纳秒）
+const creationPrefixLen = 8
+
+// stampCreation 在已编码的值前加上当前时间
+func (ng *NGCache) stampCreation(data []byte) []byte {
+	stamped := make([]byte, creationPrefixLen+len(data))
+	binary.LittleEndian.PutUint64(stamped, uint64(ng.clock.Now().UnixNano()))
+	copy(stamped[creationPrefixLen:], data)
+	return stamped
+}
+
+// SetWithCreationTime 写入值并记录写入时间，可通过GetCreationTime读取
+//
+// 启用WithCreationTracking后所有Set*都会记录写入时间，本方法只是明确表达这一意图；
+// 未启用时返回ErrNotTracked且不写入。覆盖写入时记录的是本次写入的时间。
+func (ng *NGCache) SetWithCreationTime(key string, value []byte, expireSeconds int) error {
+	if !ng.trackCreation {
+		return ErrNotTracked
+	}
+	return ng.setWithPersist(key, value, expireSeconds)
+}
+
+// GetCreationTime 返回键当前的值被写入的时间，只读取时间前缀，不复制值也不计入命中统计
+//
+// 未启用WithCreationTracking时返回ErrNotTracked，键不存在时返回ErrKeyNotFound。
+func (ng *NGCache) GetCreationTime(key string) (time.Time, error) {
+	if !ng.trackCreation {
+		return time.Time{}, ErrNotTracked
+	}
+
+	var nanos uint64
+	readPrefix := func(data []byte) error {
+		if len(data) < creationPrefixLen {
+			return ErrInvalidType
+		}
+		nanos = binary.LittleEndian.Uint64(data)
+		return nil
+	}
+
+	err := ng.storePeekFn(key, readPrefix)
+	if err == nil {
+		return time.Unix(0, int64(nanos)), nil
+	}
+	if err == ErrInvalidType {
+		return time.Time{}, err
+	}
+
+	// freecache中没有时回退到合并中的写入和持久化数据，持久化数据中的值写入后不会被修改
+	data, ok := ng.coalescedValue(key)
+	if !ok {
+		ng.persistDataMutex.RLock()
+		data, ok = ng.persistData[key]
+		ng.persistDataMutex.RUnlock()
+	}
+	if !ok {
+		return time.Time{}, ErrKeyNotFound
+	}
+	if err := readPrefix(data); err != nil {
+		return time.Time{}, err
+	}
+	return time.Unix(0, int64(nanos)), nil
+}
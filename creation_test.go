@@ -0,0 +1,81 @@
+package ngcat
+
+import (
+	"testing"
+	"time"
+)
+
+func TestCreationTracking(t *testing.T) {
+	clock := newFakeClock()
+	config := &PersistConfig{
+		Enabled:  true,
+		FilePath: t.TempDir(),
+		FileName: "cache.bin",
+		Format:   FormatBinary,
+		Interval: time.Hour,
+	}
+	nc := NewNGCache(1024*1024, config, WithClock(clock), WithCreationTracking(), WithCompressValuesOver(16))
+	created := clock.Now()
+
+	if err := nc.SetWithCreationTime("k", []byte("hello"), 0); err != nil {
+		t.Fatal(err)
+	}
+	nc.SetString("big", string(make([]byte, 1024)), 60)
+	nc.SetInt64("n", 42, 0)
+
+	if v, err := nc.GetString("k"); err != nil || v != "hello" {
+		t.Fatalf("k = %q, %v", v, err)
+	}
+	if v, err := nc.GetString("big"); err != nil || len(v) != 1024 {
+		t.Fatalf("big = %d bytes, %v", len(v), err)
+	}
+	if v, err := nc.GetInt64("n"); err != nil || v != 42 {
+		t.Fatalf("n = %d, %v", v, err)
+	}
+	if ct, err := nc.GetCreationTime("k"); err != nil || !ct.Equal(created) {
+		t.Fatalf("creation time = %v, %v; want %v", ct, err, created)
+	}
+
+	// 覆盖写入记录新的写入时间
+	clock.Add(time.Minute)
+	nc.SetString("n", "v2", 0)
+	if ct, _ := nc.GetCreationTime("n"); !ct.Equal(created.Add(time.Minute)) {
+		t.Fatalf("overwritten creation time = %v", ct)
+	}
+	// freecache中被淘汰后从持久化数据读取
+	nc.cache.Del([]byte("k"))
+	if ct, err := nc.GetCreationTime("k"); err != nil || !ct.Equal(created) {
+		t.Fatalf("fallback creation time = %v, %v", ct, err)
+	}
+	if _, err := nc.GetCreationTime("missing"); err != ErrKeyNotFound {
+		t.Fatalf("missing: %v", err)
+	}
+
+	// 写入时间随值一起持久化
+	if err := nc.Close(); err != nil {
+		t.Fatal(err)
+	}
+	reloaded := NewNGCache(1024*1024, config, WithCreationTracking(), WithCompressValuesOver(16))
+	defer reloaded.Close()
+	if ct, err := reloaded.GetCreationTime("k"); err != nil || !ct.Equal(created) {
+		t.Fatalf("reloaded creation time = %v, %v", ct, err)
+	}
+	if v, _ := reloaded.GetString("k"); v != "hello" {
+		t.Fatalf("reloaded k = %q", v)
+	}
+}
+
+func TestCreationTrackingDisabled(t *testing.T) {
+	nc := NewNGCache(1024*1024, nil)
+	defer nc.Close()
+	if err := nc.SetWithCreationTime("k", []byte("v"), 0); err != ErrNotTracked {
+		t.Fatalf("SetWithCreationTime: %v", err)
+	}
+	if _, err := nc.GetString("k"); err != ErrKeyNotFound {
+		t.Fatal("SetWithCreationTime should not write when tracking is disabled")
+	}
+	nc.SetString("k", "v", 0)
+	if _, err := nc.GetCreationTime("k"); err != ErrNotTracked {
+		t.Fatalf("GetCreationTime: %v", err)
+	}
+}
@@ -0,0 +1,126 @@
+package ngcat
+
+import (
+	"context"
+	"io"
+	"os"
+)
+
+// deltaSuffix 增量文件相对快照文件名的后缀
+const deltaSuffix = ".delta"
+
+// deltaPath 增量文件路径
+func (ng *NGCache) deltaPath() string {
+	return ng.persistFilePath() + deltaSuffix
+}
+
+// markDirty 在增量模式下记录被修改的永久缓存键
+func (ng *NGCache) markDirty(key string) {
+	if ng.persistConfig == nil || !ng.persistConfig.Enabled || ng.persistConfig.Format != FormatDelta {
+		return
+	}
+	ng.dirtyMutex.Lock()
+	if ng.dirty == nil {
+		ng.dirty = make(map[string]struct{})
+	}
+	ng.dirty[key] = struct{}{}
+	ng.dirtyMutex.Unlock()
+}
+
+// saveDelta 增量保存：读取上一次的快照，只用脏键修补后写出新快照，
+// 同时将本次写入的条目单独写入增量文件，成功后清空脏键集合
+//
+// 快照不存在时写出完整的持久化数据。保存失败时脏键会放回集合等待下次重试。
+func (ng *NGCache) saveDelta(ctx context.Context) error {
+	ng.dirtyMutex.Lock()
+	dirty := ng.dirty
+	ng.dirty = nil
+	ng.dirtyMutex.Unlock()
+
+	err := ng.writeDelta(ctx, dirty)
+	if err != nil {
+		ng.dirtyMutex.Lock()
+		if ng.dirty == nil {
+			ng.dirty = make(map[string]struct{}, len(dirty))
+		}
+		for key := range dirty {
+			ng.dirty[key] = struct{}{}
+		}
+		ng.dirtyMutex.Unlock()
+	}
+	return err
+}
+
+// writeDelta 按脏键修补快照并写出快照和增量文件
+func (ng *NGCache) writeDelta(ctx context.Context, dirty map[string]struct{}) error {
+	filePath := ng.persistFilePath()
+	if _, err := os.Stat(filePath); os.IsNotExist(err) {
+		data := ng.collectPersistData()
+		ng.applyPersistLimits(data, FormatBinary)
+		return ng.saveToBinary(ctx, filePath, data)
+	}
+	if len(dirty) == 0 {
+		return nil
+	}
+
+	snapshot, err := readSnapshot(ctx, filePath)
+	if err != nil {
+		return err
+	}
+
+	changed := make([]PersistEntry, 0, len(dirty))
+	ng.persistDataMutex.RLock()
+	for key := range dirty {
+		value, exists := ng.persistData[key]
+		if !exists {
+			delete(snapshot, key)
+			continue
+		}
+		snapshot[key] = value
+		changed = append(changed, PersistEntry{Key: key, Value: value})
+	}
+	ng.persistDataMutex.RUnlock()
+
+	timestamp := ng.clock.Now().Unix()
+	entries := make([]PersistEntry, 0, len(snapshot))
+	for key, value := range snapshot {
+		entries = append(entries, PersistEntry{Key: key, Value: value})
+	}
+	data := &PersistData{Version: BinaryVersion, Timestamp: timestamp, Entries: entries, FormatCaps: ng.formatCaps()}
+	ng.applyPersistLimits(data, FormatBinary)
+	err = ng.saveToBinary(ctx, filePath, data)
+	if err != nil {
+		return err
+	}
+	return ng.saveToBinary(ctx, ng.deltaPath(), &PersistData{Version: BinaryVersion, Timestamp: timestamp, Entries: changed, FormatCaps: ng.formatCaps()})
+}
+
+// readSnapshot 读取二进制快照中的全部条目
+func readSnapshot(ctx context.Context, filePath string) (map[string][]byte, error) {
+	file, err := os.Open(filePath)
+	if err != nil {
+		return nil, newError(CodeOpenFile, filePath, err)
+	}
+	defer file.Close()
+
+	pr, err := newPersistReader(file, FormatBinary)
+	if err != nil {
+		return nil, err
+	}
+	entries := make(map[string][]byte)
+	for i := 0; ; i++ {
+		if i%ctxCheckInterval == 0 {
+			if err := ctx.Err(); err != nil {
+				return nil, err
+			}
+		}
+		entry, err := pr.next()
+		if err == io.EOF {
+			return entries, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		entries[entry.Key] = entry.Value
+	}
+}
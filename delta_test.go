@@ -0,0 +1,55 @@
+package ngcat
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestDeltaSavesOnlyDirtyEntries(t *testing.T) {
+	dir := t.TempDir()
+	config := &PersistConfig{Enabled: true, FilePath: dir, FileName: "cache.snap", Format: FormatDelta, Interval: time.Hour}
+	nc := NewNGCache(1024*1024, config)
+	for i := 0; i < 10; i++ {
+		nc.SetString(fmt.Sprintf("k%d", i), "v", 0)
+	}
+	if err := nc.Save(); err != nil {
+		t.Fatal(err)
+	}
+
+	nc.SetString("k3", "changed", 0)
+	nc.SetString("k7", "changed", 0)
+	nc.SetString("ttl", "x", 60)
+	if err := nc.Save(); err != nil {
+		t.Fatal(err)
+	}
+
+	delta, err := readSnapshot(context.Background(), nc.deltaPath())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(delta) != 2 || string(delta["k3"]) != "changed" || string(delta["k7"]) != "changed" {
+		t.Fatalf("delta entries = %v", delta)
+	}
+	snapshot, err := readSnapshot(context.Background(), nc.persistFilePath())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(snapshot) != 10 || string(snapshot["k3"]) != "changed" || string(snapshot["k0"]) != "v" {
+		t.Fatalf("snapshot entries = %v", snapshot)
+	}
+
+	nc.Delete("k0")
+	if err := nc.Close(); err != nil {
+		t.Fatal(err)
+	}
+	reloaded := NewNGCache(1024*1024, config)
+	defer reloaded.Close()
+	if _, err := reloaded.GetString("k0"); err != ErrKeyNotFound {
+		t.Fatalf("deleted key reloaded: %v", err)
+	}
+	if v, _ := reloaded.GetString("k7"); v != "changed" {
+		t.Fatalf("k7 = %q", v)
+	}
+}
@@ -0,0 +1,34 @@
+package ngcat
+
+// Drain 两阶段关闭的第一步：停止接受写入，等待正在进行的读写完成，然后进行最后一次持久化
+//
+// 之后所有写入（Set*、SetInt64AtomicAdd、Rename等）返回ErrDrained，读取和删除不受影响。
+// 返回持久化的错误。之后的Close不再写回计数器和保存，只停止后台协程并释放资源；
+// 因此Drain之后的删除不保证被持久化。重复调用直接返回nil。
+func (ng *NGCache) Drain() error {
+	ng.inflightMutex.Lock()
+	if ng.drained.Load() {
+		ng.inflightMutex.Unlock()
+		return nil
+	}
+	ng.drained.Store(true)
+	ng.inflightMutex.Unlock()
+	// 之后的调用不再计入inflight，Wait不会与Add并发
+	ng.inflight.Wait()
+
+	return ng.persistFinal()
+}
+
+// beginWrite 与beginOp相同，但Drain之后返回ErrDrained
+func (ng *NGCache) beginWrite() (bool, error) {
+	ng.inflightMutex.RLock()
+	defer ng.inflightMutex.RUnlock()
+	if ng.drained.Load() {
+		return false, ErrDrained
+	}
+	if ng.closing {
+		return false, nil
+	}
+	ng.inflight.Add(1)
+	return true, nil
+}
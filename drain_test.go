@@ -0,0 +1,115 @@
+package ngcat
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestDrain(t *testing.T) {
+	dir := t.TempDir()
+	config := &PersistConfig{
+		Enabled:  true,
+		FilePath: dir,
+		FileName: "cache.bin",
+		Format:   FormatBinary,
+		Interval: time.Hour,
+	}
+	path := filepath.Join(dir, "cache.bin")
+	nc := NewNGCache(1024*1024, config)
+	nc.SetString("a", "1", 0)
+	if _, err := nc.SetInt64AtomicAdd("hits", 3); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := nc.Drain(); err != nil {
+		t.Fatal(err)
+	}
+	if v, err := ReadEntry(path, "hits"); err != nil || len(v) != 8 {
+		t.Fatalf("counter not persisted by Drain: %v, %v", v, err)
+	}
+	if err := nc.SetString("b", "2", 0); !errors.Is(err, ErrDrained) {
+		t.Fatalf("SetString after Drain: %v", err)
+	}
+	if _, err := nc.SetInt64AtomicAdd("hits", 1); !errors.Is(err, ErrDrained) {
+		t.Fatalf("SetInt64AtomicAdd after Drain: %v", err)
+	}
+	if err := nc.SetPermanentBatch(map[string][]byte{"c": nil}); !errors.Is(err, ErrDrained) {
+		t.Fatalf("SetPermanentBatch after Drain: %v", err)
+	}
+	if v, err := nc.GetString("a"); err != nil || v != "1" {
+		t.Fatalf("reads should still work: %q, %v", v, err)
+	}
+	if err := nc.Drain(); err != nil {
+		t.Fatalf("second Drain: %v", err)
+	}
+
+	// Close不应再保存
+	if err := os.Remove(path); err != nil {
+		t.Fatal(err)
+	}
+	if err := nc.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if fileExists(path) {
+		t.Fatal("Close after Drain saved again")
+	}
+}
+
+func TestDrainWaitsForWrites(t *testing.T) {
+	dir := t.TempDir()
+	config := &PersistConfig{
+		Enabled:  true,
+		FilePath: dir,
+		FileName: "cache.bin",
+		Format:   FormatBinary,
+		Interval: time.Hour,
+	}
+	nc := NewNGCache(16*1024*1024, config)
+	defer nc.Close()
+
+	var (
+		mu    sync.Mutex
+		acked []string
+		wg    sync.WaitGroup
+	)
+	start := make(chan struct{})
+	for g := 0; g < 8; g++ {
+		wg.Add(1)
+		go func(g int) {
+			defer wg.Done()
+			<-start
+			for i := 0; ; i++ {
+				key := fmt.Sprintf("g%d-%d", g, i)
+				if err := nc.SetString(key, "v", 0); err != nil {
+					if !errors.Is(err, ErrDrained) {
+						t.Error(err)
+					}
+					return
+				}
+				mu.Lock()
+				acked = append(acked, key)
+				mu.Unlock()
+			}
+		}(g)
+	}
+	close(start)
+	time.Sleep(10 * time.Millisecond)
+	if err := nc.Drain(); err != nil {
+		t.Fatal(err)
+	}
+	wg.Wait()
+
+	// 所有成功返回的写入都应包含在Drain保存的文件中
+	summary, err := InspectPersistFile(filepath.Join(dir, "cache.bin"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(acked) == 0 || summary.Entries != len(acked) {
+		t.Fatalf("acked %d writes, file has %d entries", len(acked), summary.Entries)
+	}
+}
@@ -0,0 +1,91 @@
+package ngcat
+
+// Dump 返回所有存活条目的深拷贝，freecache与持久化数据中的同一键只出现一次
+//
+// 遍历按freecache分段逐段加锁，不会在整个过程中持有同一把锁。
+func (ng *NGCache) Dump() map[string][]byte {
+	result := make(map[string][]byte)
+	ng.forEachEntry(func(key string, value []byte, expireAt uint32) bool {
+		result[key] = cloneBytes(value)
+		return true
+	})
+	return result
+}
+
+// DumpPermanent 返回持久化数据（永久缓存）的深拷贝
+func (ng *NGCache) DumpPermanent() map[string][]byte {
+	ng.persistDataMutex.RLock()
+	keys := make([]string, 0, len(ng.persistData))
+	for key := range ng.persistData {
+		keys = append(keys, key)
+	}
+	ng.persistDataMutex.RUnlock()
+
+	result := make(map[string][]byte, len(keys))
+	for _, key := range keys {
+		ng.persistDataMutex.RLock()
+		value, exists := ng.persistData[key]
+		ng.persistDataMutex.RUnlock()
+		if !exists {
+			continue
+		}
+		value, err := ng.decodeValue(value)
+		if err == nil {
+			result[key] = cloneBytes(value)
+		}
+	}
+	return result
+}
+
+// Restore 批量写入条目
+//
+// permanent为true时作为永久缓存写入并参与持久化；否则只写入freecache，
+// 使用键的默认过期时间（见SetTTLPolicy，未设置时不过期但可能被淘汰），键原有的永久缓存从持久化数据中删除。
+// 遇到错误时立即返回。
+func (ng *NGCache) Restore(m map[string][]byte, permanent bool) error {
+	if ng.drained.Load() {
+		return ErrDrained
+	}
+	for key, value := range m {
+		var err error
+		if permanent {
+			err = ng.setWithPersist(key, value, 0)
+		} else {
+			err = ng.setCacheOnly(key, value)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// setCacheOnly 以键的默认过期时间写入存储，不进入持久化数据，持久化数据中该键的旧值被删除
+func (ng *NGCache) setCacheOnly(key string, value []byte) error {
+	started, err := ng.beginWrite()
+	if err != nil {
+		return err
+	}
+	if started {
+		defer ng.inflight.Done()
+	}
+
+	mu := ng.keyLock(key)
+	mu.Lock()
+	encoded, expireSeconds, err := ng.prepareSet(key, value, TTLDefault)
+	if err == nil {
+		err = ng.storeEntryLocked(key, encoded, expireSeconds, true)
+	}
+	mu.Unlock()
+	if err == nil {
+		ng.publishSet(key, value, expireSeconds)
+	}
+	return err
+}
+
+// cloneBytes 复制字节切片
+func cloneBytes(b []byte) []byte {
+	c := make([]byte, len(b))
+	copy(c, b)
+	return c
+}
@@ -0,0 +1,102 @@
+package ngcat
+
+import (
+	"bytes"
+	"testing"
+	"time"
+)
+
+func TestDumpRestoreRoundTrip(t *testing.T) {
+	src := NewNGCache(1024*1024, nil)
+	defer src.Close()
+	src.SetString("perm", "p", 0)
+	src.SetString("ttl", "t", 60)
+	src.SetString("evicted", "e", 0)
+	src.cache.Del([]byte("evicted"))
+
+	dump := src.Dump()
+	if len(dump) != 3 {
+		t.Fatalf("dump has %d entries: %v", len(dump), dump)
+	}
+	permanent := src.DumpPermanent()
+	if len(permanent) != 2 || permanent["ttl"] != nil {
+		t.Fatalf("unexpected permanent dump: %v", permanent)
+	}
+
+	dst := NewNGCache(1024*1024, nil)
+	defer dst.Close()
+	if err := dst.Restore(dump, true); err != nil {
+		t.Fatal(err)
+	}
+	restored := dst.Dump()
+	if len(restored) != len(dump) {
+		t.Fatalf("restored %d entries, want %d", len(restored), len(dump))
+	}
+	for key, value := range dump {
+		if !bytes.Equal(restored[key], value) {
+			t.Fatalf("%s: got %q, want %q", key, restored[key], value)
+		}
+	}
+}
+
+func TestDumpIsDeepCopy(t *testing.T) {
+	nc := NewNGCache(1024*1024, nil)
+	defer nc.Close()
+	nc.SetString("k", "value", 0)
+
+	dump := nc.Dump()
+	dump["k"][0] = 'X'
+	dump["new"] = []byte("x")
+	permanent := nc.DumpPermanent()
+	permanent["k"][0] = 'Y'
+
+	if v, _ := nc.GetString("k"); v != "value" {
+		t.Fatalf("cache mutated through dump: %q", v)
+	}
+	nc.cache.Del([]byte("k"))
+	if v, _ := nc.GetString("k"); v != "value" {
+		t.Fatalf("persistData mutated through dump: %q", v)
+	}
+	if _, err := nc.GetString("new"); err != ErrKeyNotFound {
+		t.Fatal("map insertion leaked into cache")
+	}
+}
+
+func TestRestoreNonPermanent(t *testing.T) {
+	nc := NewNGCache(1024*1024, nil)
+	defer nc.Close()
+	if err := nc.Restore(map[string][]byte{"a": []byte("1")}, false); err != nil {
+		t.Fatal(err)
+	}
+	if v, _ := nc.GetString("a"); v != "1" {
+		t.Fatalf("a = %q", v)
+	}
+	if len(nc.DumpPermanent()) != 0 {
+		t.Fatal("non-permanent restore should not touch persistData")
+	}
+}
+
+func TestRestoreOverPermanent(t *testing.T) {
+	dir := t.TempDir()
+	config := &PersistConfig{Enabled: true, FilePath: dir, FileName: "cache.bin", Format: FormatBinary, Interval: time.Hour}
+	nc := NewNGCache(1024*1024, config)
+	nc.SetString("k", "old", 0)
+	if err := nc.Restore(map[string][]byte{"k": []byte("new")}, false); err != nil {
+		t.Fatal(err)
+	}
+	if v, _ := nc.GetString("k"); v != "new" {
+		t.Fatalf("k = %q", v)
+	}
+	if _, ok := persisted(nc, "k"); ok {
+		t.Fatal("stale permanent value left in persistData")
+	}
+	if err := nc.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	reloaded := NewNGCache(1024*1024, config)
+	defer reloaded.Close()
+	if v, err := reloaded.GetString("k"); err != ErrKeyNotFound {
+		t.Fatalf("k after reopen = %q, %v, want ErrKeyNotFound", v, err)
+	}
+}
@@ -0,0 +1,259 @@
+package ngcat
+
+import (
+	"fmt"
+	"sync/atomic"
+)
+
+// ErrorCode 错误码，机器可读且保持稳定，可通过errors.As取得CacheError后读取
+type ErrorCode string
+
+// 错误码定义，字符串值属于对外接口，不会随版本变化
+const (
+	CodeKeyNotFound        ErrorCode = "key_not_found"
+	CodeInvalidType        ErrorCode = "invalid_type"
+	CodeValueTooLarge      ErrorCode = "value_too_large"
+	CodeKeyTooLong         ErrorCode = "key_too_long"
+	CodeVersionMismatch    ErrorCode = "version_mismatch"
+	CodeCorruptFile        ErrorCode = "corrupt_file"
+	CodeNoBackend          ErrorCode = "no_backend"
+	CodeQuotaExceeded      ErrorCode = "quota_exceeded"
+	CodeQuotaPrefix        ErrorCode = "quota_prefix_mismatch"
+	CodeInvalidPattern     ErrorCode = "invalid_pattern"
+	CodeUnsupportedFormat  ErrorCode = "unsupported_format"
+	CodeUnknownFormat      ErrorCode = "unknown_format"
+	CodeOpenFile           ErrorCode = "open_file"
+	CodeReadFile           ErrorCode = "read_file"
+	CodeStatFile           ErrorCode = "stat_file"
+	CodeCreateDir          ErrorCode = "create_dir"
+	CodeCreateTemp         ErrorCode = "create_temp"
+	CodeWriteFile          ErrorCode = "write_file"
+	CodeReplaceFile        ErrorCode = "replace_file"
+	CodeSyncFile           ErrorCode = "sync_file"
+	CodeFileExists         ErrorCode = "file_exists"
+	CodeMmap               ErrorCode = "mmap"
+	CodeEntryCountMismatch ErrorCode = "entry_count_mismatch"
+	CodeCompress           ErrorCode = "compress"
+	CodeDecompress         ErrorCode = "decompress"
+	CodeEncode             ErrorCode = "encode"
+	CodeDecode             ErrorCode = "decode"
+	CodeLoadFailed         ErrorCode = "load_failed"
+	CodeStoreFailed        ErrorCode = "store_failed"
+	CodeRefreshFailed      ErrorCode = "refresh_failed"
+	CodeNotTracked         ErrorCode = "creation_not_tracked"
+	CodeInvalidTTL         ErrorCode = "invalid_ttl"
+	CodeInvalidConfig      ErrorCode = "invalid_config"
+	CodeClosed             ErrorCode = "cache_closed"
+	CodeShadowPersist      ErrorCode = "shadow_persist"
+	CodeInvalidEncoding    ErrorCode = "invalid_encoding"
+	CodeUnsupportedFeature ErrorCode = "unsupported_feature"
+	CodeDrained            ErrorCode = "cache_drained"
+	CodeReadOnly           ErrorCode = "read_only"
+	CodeTypeMismatch       ErrorCode = "type_mismatch"
+)
+
+// Messages 错误码到错误信息的映射表
+type Messages map[ErrorCode]string
+
+// EnglishMessages 英文错误信息，默认使用
+var EnglishMessages = Messages{
+	CodeKeyNotFound:        "key not found",
+	CodeInvalidType:        "invalid type",
+	CodeValueTooLarge:      "value too large",
+	CodeKeyTooLong:         "key too long",
+	CodeVersionMismatch:    "version mismatch",
+	CodeCorruptFile:        "corrupt persist file",
+	CodeNoBackend:          "no backend configured",
+	CodeQuotaExceeded:      "quota exceeded",
+	CodeQuotaPrefix:        "key outside quota prefix",
+	CodeInvalidPattern:     "invalid key pattern",
+	CodeUnsupportedFormat:  "unsupported persist format",
+	CodeUnknownFormat:      "unrecognized persist file format",
+	CodeOpenFile:           "failed to open file",
+	CodeReadFile:           "failed to read file",
+	CodeStatFile:           "failed to stat file",
+	CodeCreateDir:          "failed to create directory",
+	CodeCreateTemp:         "failed to create temporary file",
+	CodeWriteFile:          "failed to write file",
+	CodeReplaceFile:        "failed to replace file",
+	CodeSyncFile:           "failed to sync file",
+	CodeFileExists:         "destination file already exists",
+	CodeMmap:               "memory mapping failed",
+	CodeEntryCountMismatch: "entry count mismatch",
+	CodeCompress:           "failed to compress value",
+	CodeDecompress:         "failed to decompress value",
+	CodeEncode:             "failed to encode value",
+	CodeDecode:             "failed to decode value",
+	CodeLoadFailed:         "failed to load key",
+	CodeStoreFailed:        "failed to store key",
+	CodeRefreshFailed:      "refresh-ahead failed",
+	CodeNotTracked:         "creation tracking not enabled",
+	CodeInvalidTTL:         "invalid expiration",
+	CodeInvalidConfig:      "invalid configuration",
+	CodeClosed:             "cache closed",
+	CodeShadowPersist:      "shadow persistence failed",
+	CodeInvalidEncoding:    "invalid UTF-8 encoding",
+	CodeUnsupportedFeature: "file uses features not supported by this version, upgrade ngcat to read it",
+	CodeDrained:            "cache drained, writes are rejected",
+	CodeReadOnly:           "read-only cache",
+	CodeTypeMismatch:       "stored type does not match requested type",
+}
+
+// ChineseMessages 中文错误信息，可通过SetMessages启用
+var ChineseMessages = Messages{
+	CodeKeyNotFound:        "键不存在",
+	CodeInvalidType:        "类型不匹配",
+	CodeValueTooLarge:      "值超过长度上限",
+	CodeKeyTooLong:         "键超过长度上限",
+	CodeVersionMismatch:    "版本不一致",
+	CodeCorruptFile:        "持久化文件损坏",
+	CodeNoBackend:          "未设置数据源",
+	CodeQuotaExceeded:      "超出配额",
+	CodeQuotaPrefix:        "键不属于配额前缀",
+	CodeInvalidPattern:     "无效的键匹配模式",
+	CodeUnsupportedFormat:  "不支持的持久化格式",
+	CodeUnknownFormat:      "无法识别的持久化文件格式",
+	CodeOpenFile:           "打开文件失败",
+	CodeReadFile:           "读取文件失败",
+	CodeStatFile:           "读取文件信息失败",
+	CodeCreateDir:          "创建目录失败",
+	CodeCreateTemp:         "创建临时文件失败",
+	CodeWriteFile:          "写入文件失败",
+	CodeReplaceFile:        "替换文件失败",
+	CodeSyncFile:           "同步文件到磁盘失败",
+	CodeFileExists:         "目标文件已存在",
+	CodeMmap:               "内存映射失败",
+	CodeEntryCountMismatch: "条目数量不一致",
+	CodeCompress:           "压缩值失败",
+	CodeDecompress:         "解压缩值失败",
+	CodeEncode:             "编码值失败",
+	CodeDecode:             "解码值失败",
+	CodeLoadFailed:         "加载键失败",
+	CodeStoreFailed:        "写入键失败",
+	CodeRefreshFailed:      "预刷新失败",
+	CodeNotTracked:         "未启用创建时间记录",
+	CodeInvalidTTL:         "过期时间无效",
+	CodeInvalidConfig:      "配置无效",
+	CodeClosed:             "缓存已关闭",
+	CodeShadowPersist:      "影子持久化失败",
+	CodeInvalidEncoding:    "不是有效的UTF-8编码",
+	CodeUnsupportedFeature: "文件使用了当前版本不支持的特性，请升级ngcat后再读取",
+	CodeDrained:            "缓存已停止接受写入",
+	CodeReadOnly:           "只读缓存不允许写入",
+	CodeTypeMismatch:       "存储的值类型与读取的类型不一致",
+}
+
+// messages 当前使用的错误信息表
+var messages atomic.Pointer[Messages]
+
+// SetMessages 替换错误信息表，影响之后所有Error()的输出，表中缺少的错误码回退到英文信息；
+// 传入nil恢复默认的英文信息
+func SetMessages(m Messages) {
+	if m == nil {
+		messages.Store(nil)
+		return
+	}
+	messages.Store(&m)
+}
+
+// messageFor 返回错误码对应的错误信息
+func messageFor(code ErrorCode) string {
+	if m := messages.Load(); m != nil {
+		if msg, ok := (*m)[code]; ok {
+			return msg
+		}
+	}
+	if msg, ok := EnglishMessages[code]; ok {
+		return msg
+	}
+	return string(code)
+}
+
+// CacheError 带错误码的错误，Detail为上下文（如文件路径、键），Err为底层原因
+type CacheError struct {
+	Code   ErrorCode
+	Detail string
+	Err    error
+}
+
+func (e *CacheError) Error() string {
+	msg := messageFor(e.Code)
+	if e.Detail != "" {
+		msg += ": " + e.Detail
+	}
+	if e.Err != nil {
+		msg += ": " + e.Err.Error()
+	}
+	return msg
+}
+
+func (e *CacheError) Unwrap() error {
+	return e.Err
+}
+
+// Is 使带上下文的错误可以通过errors.Is匹配同一错误码的哨兵错误（如ErrCorruptFile）
+func (e *CacheError) Is(target error) bool {
+	t, ok := target.(*CacheError)
+	return ok && t.Detail == "" && t.Err == nil && t.Code == e.Code
+}
+
+// newError 创建带上下文和底层原因的错误
+func newError(code ErrorCode, detail string, err error) error {
+	return &CacheError{Code: code, Detail: detail, Err: err}
+}
+
+// corruptf 创建可匹配ErrCorruptFile的错误
+func corruptf(format string, args ...interface{}) error {
+	return &CacheError{Code: CodeCorruptFile, Detail: fmt.Sprintf(format, args...)}
+}
+
+// 常见错误定义，均为预分配的哨兵错误，热路径返回时不做任何格式化
+var (
+	ErrKeyNotFound   error = &CacheError{Code: CodeKeyNotFound}
+	ErrInvalidType   error = &CacheError{Code: CodeInvalidType}
+	ErrValueTooLarge error = &CacheError{Code: CodeValueTooLarge}
+	ErrKeyTooLong    error = &CacheError{Code: CodeKeyTooLong}
+	// ErrKeyTooLarge 与ErrKeyTooLong相同
+	ErrKeyTooLarge = ErrKeyTooLong
+	// ErrVersionMismatch SetWithVersion的期望版本与存储的版本不一致
+	ErrVersionMismatch error = &CacheError{Code: CodeVersionMismatch}
+	// ErrCorruptFile 持久化文件损坏或格式无效，读取持久化文件的格式错误都可以通过errors.Is匹配
+	ErrCorruptFile error = &CacheError{Code: CodeCorruptFile}
+	// ErrNoBackend 未通过WithBackend设置数据源
+	ErrNoBackend error = &CacheError{Code: CodeNoBackend}
+	// ErrQuotaExceeded 写入后键前缀的用量将超过SetWithQuota的配额
+	ErrQuotaExceeded error = &CacheError{Code: CodeQuotaExceeded}
+	// ErrNotTracked 未通过WithCreationTracking启用创建时间记录
+	ErrNotTracked error = &CacheError{Code: CodeNotTracked}
+	// ErrInvalidTTL 启用WithStrictTTL时expireSeconds为TTLDefault以外的负数
+	ErrInvalidTTL error = &CacheError{Code: CodeInvalidTTL}
+	// ErrClosed 缓存已关闭
+	ErrClosed error = &CacheError{Code: CodeClosed}
+	// ErrInvalidEncoding GetString读取到的值不是有效的UTF-8，通常是由其他类型的Set*写入的
+	ErrInvalidEncoding error = &CacheError{Code: CodeInvalidEncoding}
+	// ErrUnsupportedFeature 二进制持久化文件的FormatCaps中有当前版本不认识的特性位
+	ErrUnsupportedFeature error = &CacheError{Code: CodeUnsupportedFeature}
+	// ErrDrained 已调用Drain，缓存不再接受写入
+	ErrDrained error = &CacheError{Code: CodeDrained}
+	// ErrReadOnly 通过ReadOnlyCache写入，或在WithReadOnlyPersistence的缓存上保存持久化文件
+	ErrReadOnly error = &CacheError{Code: CodeReadOnly}
+	// ErrTypeMismatch 启用WithStrictTypes时值的类型标记与读取的类型不一致
+	ErrTypeMismatch error = &CacheError{Code: CodeTypeMismatch}
+)
+
+// ValueTooLargeError 值超过最大长度的错误，可通过errors.Is匹配ErrValueTooLarge，
+// errors.As取得的CacheError错误码为CodeValueTooLarge
+type ValueTooLargeError struct {
+	// Size 尝试写入的值大小
+	Size int
+	// Max 允许的最大值大小
+	Max int
+}
+
+func (e *ValueTooLargeError) Error() string {
+	return fmt.Sprintf("%s: %d > %d", messageFor(CodeValueTooLarge), e.Size, e.Max)
+}
+
+func (e *ValueTooLargeError) Unwrap() error {
+	return ErrValueTooLarge
+}
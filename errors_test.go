@@ -0,0 +1,89 @@
+package ngcat
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestErrorCodesStable(t *testing.T) {
+	cases := []struct {
+		err  error
+		code string
+	}{
+		{ErrKeyNotFound, "key_not_found"},
+		{ErrInvalidType, "invalid_type"},
+		{ErrValueTooLarge, "value_too_large"},
+		{ErrKeyTooLong, "key_too_long"},
+		{ErrVersionMismatch, "version_mismatch"},
+		{ErrCorruptFile, "corrupt_file"},
+		{ErrNoBackend, "no_backend"},
+		{ErrQuotaExceeded, "quota_exceeded"},
+		{ErrInvalidEncoding, "invalid_encoding"},
+		{&ValueTooLargeError{Size: 2, Max: 1}, "value_too_large"},
+	}
+	for _, c := range cases {
+		var ce *CacheError
+		if !errors.As(c.err, &ce) || string(ce.Code) != c.code {
+			t.Errorf("%v: code = %v, want %s", c.err, ce, c.code)
+		}
+	}
+	for code := range EnglishMessages {
+		if _, ok := ChineseMessages[code]; !ok {
+			t.Errorf("no Chinese message for %s", code)
+		}
+	}
+}
+
+func TestCacheErrorWrapped(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "bad.bin")
+	if err := os.WriteFile(path, []byte("not a cache file"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	nc := NewNGCache(1024*1024, nil)
+	defer nc.Close()
+
+	err := nc.Import(path, FormatBinary)
+	var ce *CacheError
+	if !errors.As(err, &ce) || ce.Code != CodeCorruptFile || !errors.Is(err, ErrCorruptFile) {
+		t.Fatalf("expected corrupt_file, got %v", err)
+	}
+	if errors.Is(err, ErrKeyNotFound) {
+		t.Fatal("different codes should not match")
+	}
+
+	err = nc.Import(filepath.Join(t.TempDir(), "missing"), FormatBinary)
+	if !errors.As(err, &ce) || ce.Code != CodeOpenFile || !errors.Is(err, os.ErrNotExist) {
+		t.Fatalf("expected open_file wrapping ErrNotExist, got %v", err)
+	}
+}
+
+func TestSetMessages(t *testing.T) {
+	defer SetMessages(nil)
+	if ErrKeyNotFound.Error() != "key not found" {
+		t.Fatalf("default message = %q", ErrKeyNotFound.Error())
+	}
+	SetMessages(ChineseMessages)
+	if ErrKeyNotFound.Error() != "键不存在" {
+		t.Fatalf("Chinese message = %q", ErrKeyNotFound.Error())
+	}
+	SetMessages(Messages{CodeKeyNotFound: "missing"})
+	if ErrKeyNotFound.Error() != "missing" || ErrInvalidType.Error() != "invalid type" {
+		t.Fatalf("partial table should fall back to English: %q %q", ErrKeyNotFound, ErrInvalidType)
+	}
+}
+
+func TestGetStringMissNoAlloc(t *testing.T) {
+	nc := NewNGCache(1024*1024, nil)
+	defer nc.Close()
+
+	allocs := testing.AllocsPerRun(100, func() {
+		if _, err := nc.GetString("missing"); err != ErrKeyNotFound {
+			t.Fatal(err)
+		}
+	})
+	if allocs != 0 {
+		t.Fatalf("GetString miss allocated %v times", allocs)
+	}
+}
@@ -0,0 +1,183 @@
+package ngcat
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// DefaultEventBuffer SubscribeChan创建的通道的默认缓冲区大小
+const DefaultEventBuffer = 64
+
+// EventType 缓存事件类型
+type EventType int
+
+const (
+	// EventAny 订阅时使用，接收所有类型的事件
+	EventAny EventType = iota
+	// EventSet 写入
+	EventSet
+	// EventGet 读取，包括未命中
+	EventGet
+	// EventDelete 删除
+	EventDelete
+)
+
+// eventTypes 事件类型的数量
+const eventTypes = 4
+
+// Event 缓存事件
+type Event struct {
+	// Type 事件类型
+	Type EventType
+	// Key 键
+	Key string
+	// Value 写入或读取到的值的副本，删除和未命中的读取为nil
+	Value []byte
+	// TTL 写入的过期时间，0表示永久缓存，其他事件为0
+	TTL time.Duration
+	// Found 读取时键是否存在，写入和删除事件为true
+	Found bool
+	// Timestamp 事件发生的时间
+	Timestamp time.Time
+}
+
+// Bus 缓存事件总线，多个订阅者可以按事件类型接收事件
+//
+// 发布不阻塞：订阅者的通道已满时丢弃该事件，丢弃的数量可通过Dropped获取。
+// 没有订阅者时发布只有一次原子读取的开销。
+type Bus struct {
+	// bufferSize SubscribeChan创建的通道的缓冲区大小
+	bufferSize int
+
+	mu   sync.RWMutex
+	subs [eventTypes][]*subscription
+	// active 各事件类型的订阅者数量，EventAny的订阅者计入所有类型
+	active  [eventTypes]atomic.Int32
+	dropped atomic.Uint64
+}
+
+// subscription 一个订阅
+type subscription struct {
+	ch chan<- Event
+}
+
+func newBus(bufferSize int) *Bus {
+	if bufferSize <= 0 {
+		bufferSize = DefaultEventBuffer
+	}
+	return &Bus{bufferSize: bufferSize}
+}
+
+// Subscribe 将eventType类型的事件发送到ch，eventType为EventAny时接收所有事件，返回取消订阅的函数
+//
+// 取消订阅返回后不会再向ch发送事件，总线不会关闭ch。同一通道可以多次订阅，每次订阅独立取消。
+func (b *Bus) Subscribe(eventType EventType, ch chan<- Event) func() {
+	sub := &subscription{ch: ch}
+	b.mu.Lock()
+	b.subs[eventType] = append(b.subs[eventType], sub)
+	b.adjustActive(eventType, 1)
+	b.mu.Unlock()
+
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			b.mu.Lock()
+			defer b.mu.Unlock()
+			subs := b.subs[eventType]
+			for i, s := range subs {
+				if s == sub {
+					b.subs[eventType] = append(subs[:i:i], subs[i+1:]...)
+					b.adjustActive(eventType, -1)
+					return
+				}
+			}
+		})
+	}
+}
+
+// SubscribeChan 创建缓冲区大小为WithEventBuffer设置值的通道并订阅，返回该通道和取消订阅的函数
+func (b *Bus) SubscribeChan(eventType EventType) (<-chan Event, func()) {
+	ch := make(chan Event, b.bufferSize)
+	return ch, b.Subscribe(eventType, ch)
+}
+
+// Dropped 返回因订阅者通道已满而丢弃的事件数量
+func (b *Bus) Dropped() uint64 {
+	return b.dropped.Load()
+}
+
+// adjustActive 更新订阅者数量，调用方需持有b.mu
+func (b *Bus) adjustActive(eventType EventType, delta int32) {
+	if eventType == EventAny {
+		for i := range b.active {
+			b.active[i].Add(delta)
+		}
+		return
+	}
+	b.active[eventType].Add(delta)
+}
+
+// wants 是否有订阅者接收该类型的事件
+func (b *Bus) wants(eventType EventType) bool {
+	return b.active[eventType].Load() > 0
+}
+
+// publish 向订阅了该类型和EventAny的订阅者发送事件，通道已满时丢弃
+func (b *Bus) publish(e Event) {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	for _, subs := range [2][]*subscription{b.subs[e.Type], b.subs[EventAny]} {
+		for _, s := range subs {
+			select {
+			case s.ch <- e:
+			default:
+				b.dropped.Add(1)
+			}
+		}
+	}
+}
+
+// EventBus 返回缓存的事件总线，第一次调用时创建
+//
+// 写入（setWithPersist覆盖的所有Set*）、读取（Get*）和删除都会发布事件。
+func (ng *NGCache) EventBus() *Bus {
+	if bus := ng.bus.Load(); bus != nil {
+		return bus
+	}
+	ng.bus.CompareAndSwap(nil, newBus(ng.eventBuffer))
+	return ng.bus.Load()
+}
+
+// publishEvent 有订阅者时发布事件，value会被复制
+func (ng *NGCache) publishEvent(eventType EventType, key string, value []byte, ttl time.Duration, found bool) {
+	bus := ng.bus.Load()
+	if bus == nil || !bus.wants(eventType) {
+		return
+	}
+	var copied []byte
+	if value != nil {
+		copied = cloneBytes(value)
+	}
+	bus.publish(Event{
+		Type:      eventType,
+		Key:       key,
+		Value:     copied,
+		TTL:       ttl,
+		Found:     found,
+		Timestamp: ng.clock.Now(),
+	})
+}
+
+// publishSet 有订阅者时发布写入事件，expireSeconds为TTLDefault时解析为实际的过期时间
+func (ng *NGCache) publishSet(key string, value []byte, expireSeconds int) {
+	bus := ng.bus.Load()
+	if bus == nil || !bus.wants(EventSet) {
+		return
+	}
+	ttl := time.Duration(ng.resolveTTL(key, expireSeconds)) * time.Second
+	if ttl < 0 {
+		ttl = 0
+	}
+	ng.publishEvent(EventSet, key, value, ttl, true)
+}
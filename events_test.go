@@ -0,0 +1,107 @@
+package ngcat
+
+import (
+	"testing"
+	"time"
+)
+
+// drain 读取通道中已有的事件
+func drain(ch <-chan Event) []Event {
+	var events []Event
+	for {
+		select {
+		case e := <-ch:
+			events = append(events, e)
+		default:
+			return events
+		}
+	}
+}
+
+func TestEventBus(t *testing.T) {
+	nc := NewNGCache(1024*1024, nil, WithDefaultTTL(time.Minute))
+	defer nc.Close()
+	bus := nc.EventBus()
+	if nc.EventBus() != bus {
+		t.Fatal("EventBus should return the shared bus")
+	}
+
+	all, unsubAll := bus.SubscribeChan(EventAny)
+	sets, unsubSets := bus.SubscribeChan(EventSet)
+	defer unsubSets()
+
+	nc.SetString("a", "1", 30)
+	nc.SetString("b", "2", TTLDefault)
+	nc.GetString("a")
+	nc.GetString("missing")
+	nc.Delete("a")
+	// 删除不存在的键不发布事件
+	nc.Delete("missing")
+
+	events := drain(all)
+	want := []EventType{EventSet, EventSet, EventGet, EventGet, EventDelete}
+	if len(events) != len(want) {
+		t.Fatalf("got %d events, want %d: %+v", len(events), len(want), events)
+	}
+	for i, e := range events {
+		if e.Type != want[i] {
+			t.Fatalf("event %d: type %v, want %v", i, e.Type, want[i])
+		}
+	}
+	if e := events[0]; e.Key != "a" || string(e.Value) != "1" || e.TTL != 30*time.Second {
+		t.Fatalf("set event: %+v", e)
+	}
+	if e := events[1]; e.TTL != time.Minute {
+		t.Fatalf("TTLDefault should resolve to the default TTL, got %v", e.TTL)
+	}
+	if e := events[2]; !e.Found || string(e.Value) != "1" {
+		t.Fatalf("get hit event: %+v", e)
+	}
+	if e := events[3]; e.Found || e.Value != nil {
+		t.Fatalf("get miss event: %+v", e)
+	}
+	if got := drain(sets); len(got) != 2 {
+		t.Fatalf("set subscriber got %d events, want 2", len(got))
+	}
+
+	unsubAll()
+	unsubAll()
+	nc.SetString("c", "3", 0)
+	if got := drain(all); len(got) != 0 {
+		t.Fatalf("unsubscribed channel received %+v", got)
+	}
+}
+
+func TestEventBusDropsWhenFull(t *testing.T) {
+	nc := NewNGCache(1024*1024, nil, WithEventBuffer(2))
+	defer nc.Close()
+	ch, unsub := nc.EventBus().SubscribeChan(EventSet)
+	defer unsub()
+
+	for i := 0; i < 5; i++ {
+		if err := nc.SetString("k", "v", 0); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if got := len(drain(ch)); got != 2 {
+		t.Fatalf("got %d events, want 2", got)
+	}
+	if dropped := nc.EventBus().Dropped(); dropped != 3 {
+		t.Fatalf("Dropped() = %d, want 3", dropped)
+	}
+}
+
+func TestEventValueIsCopied(t *testing.T) {
+	nc := NewNGCache(1024*1024, nil)
+	defer nc.Close()
+	ch := make(chan Event, 1)
+	unsub := nc.EventBus().Subscribe(EventSet, ch)
+	defer unsub()
+
+	value := []byte("abc")
+	nc.SetBytes("k", value, 0)
+	value[0] = 'x'
+	if e := <-ch; string(e.Value) != "abc" {
+		t.Fatalf("event value %q was not copied", e.Value)
+	}
+}
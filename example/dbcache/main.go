@@ -0,0 +1,155 @@
+// 旁路缓存（cache-aside）示例：先查缓存，未命中时查询数据库，结果以5分钟过期写入缓存后返回
+//
+// 运行：go run ./example/dbcache
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"ngcat"
+)
+
+// userTTL 用户数据在缓存中的过期秒数
+const userTTL = 5 * 60
+
+// User 数据库中的用户
+type User struct {
+	ID   int    `json:"id"`
+	Name string `json:"name"`
+}
+
+// errNoRows 数据库中没有这一行，相当于sql.ErrNoRows
+var errNoRows = errors.New("db: no rows")
+
+// fakeDB 模拟的数据库，每次查询耗时50ms并记录查询次数
+type fakeDB struct {
+	users   map[int]User
+	queries atomic.Int64
+	down    atomic.Bool
+}
+
+func (db *fakeDB) QueryUser(ctx context.Context, id int) (User, error) {
+	db.queries.Add(1)
+	select {
+	case <-time.After(50 * time.Millisecond):
+	case <-ctx.Done():
+		return User{}, ctx.Err()
+	}
+	if db.down.Load() {
+		return User{}, errors.New("db: connection refused")
+	}
+	user, ok := db.users[id]
+	if !ok {
+		return User{}, errNoRows
+	}
+	return user, nil
+}
+
+// userService 通过缓存读取用户
+type userService struct {
+	cache *ngcat.NGCache
+	db    *fakeDB
+}
+
+// GetUser 完整的旁路缓存流程
+//
+// GetOrComputeContext先查缓存，命中时直接返回；未命中时调用loader查询数据库，
+// 成功后以userTTL写入缓存再返回。同一键的并发未命中被合并为一次loader调用（singleflight），
+// 其余调用等待并共享这次查询的结果，热点键过期时不会有大量请求同时打到数据库。
+func (s *userService) GetUser(ctx context.Context, id int) (User, error) {
+	key := fmt.Sprintf("user:%d", id)
+	data, err := s.cache.GetOrComputeContext(ctx, key, userTTL, func(ctx context.Context) ([]byte, error) {
+		user, err := s.db.QueryUser(ctx, id)
+		if err != nil {
+			// loader的错误原样返回给所有等待者，且不会写入缓存，下一次请求会重新查询
+			return nil, err
+		}
+		return json.Marshal(user)
+	})
+	if err != nil {
+		return User{}, err
+	}
+	var user User
+	err = json.Unmarshal(data, &user)
+	return user, err
+}
+
+// GetUserManual 不使用GetOrCompute时的等价写法，用于说明ErrKeyNotFound的含义
+//
+// 缓存返回的ErrKeyNotFound只表示未命中，是正常的流程分支而不是错误；数据库返回的错误才需要上报。
+// 这种写法没有singleflight，并发未命中时每个请求都会查询一次数据库。
+func (s *userService) GetUserManual(ctx context.Context, id int) (User, error) {
+	key := fmt.Sprintf("user:%d", id)
+	var user User
+	err := s.cache.GetJSON(key, &user)
+	if err == nil {
+		return user, nil
+	}
+	if !errors.Is(err, ngcat.ErrKeyNotFound) {
+		// 缓存中的值损坏或类型不符，记录后按未命中处理，由数据库的结果覆盖
+		log.Printf("cache read %s: %v", key, err)
+	}
+
+	user, err = s.db.QueryUser(ctx, id)
+	if err != nil {
+		return User{}, err
+	}
+	if err := s.cache.SetJSON(key, user, userTTL); err != nil {
+		// 写缓存失败不影响本次请求的结果
+		log.Printf("cache write %s: %v", key, err)
+	}
+	return user, nil
+}
+
+func main() {
+	cache := ngcat.NewNGCache(10*1024*1024, nil)
+	defer cache.Close()
+	db := &fakeDB{users: map[int]User{1: {ID: 1, Name: "alice"}, 2: {ID: 2, Name: "bob"}}}
+	svc := &userService{cache: cache, db: db}
+	ctx := context.Background()
+
+	fmt.Println("=== 旁路缓存 ===")
+	start := time.Now()
+	user, err := svc.GetUser(ctx, 1)
+	fmt.Printf("第一次读取: %+v, err=%v, 耗时%v（未命中，查询数据库）\n", user, err, time.Since(start).Round(time.Millisecond))
+	start = time.Now()
+	user, err = svc.GetUser(ctx, 1)
+	fmt.Printf("第二次读取: %+v, err=%v, 耗时%v（命中缓存）\n", user, err, time.Since(start).Round(time.Millisecond))
+
+	fmt.Println("\n=== 并发未命中只查询一次数据库 ===")
+	before := db.queries.Load()
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			svc.GetUser(ctx, 2)
+		}()
+	}
+	wg.Wait()
+	fmt.Printf("20个并发请求，数据库查询%d次\n", db.queries.Load()-before)
+
+	fmt.Println("\n=== 区分未命中和数据库错误 ===")
+	_, err = svc.GetUser(ctx, 404)
+	switch {
+	case errors.Is(err, errNoRows):
+		fmt.Println("用户不存在，返回404:", err)
+	case err != nil:
+		fmt.Println("数据库错误，返回500:", err)
+	}
+
+	db.down.Store(true)
+	if _, err := svc.GetUser(ctx, 3); err != nil && !errors.Is(err, errNoRows) {
+		fmt.Println("数据库不可用，返回500:", err)
+	}
+	// 已缓存的用户在数据库不可用时仍可读取
+	user, err = svc.GetUserManual(ctx, 1)
+	fmt.Printf("数据库不可用时读取已缓存的用户: %+v, err=%v\n", user, err)
+}
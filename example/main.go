@@ -81,8 +81,8 @@ func main() {
 	fmt.Println("\n3. 永久缓存:")
 
 	// 设置永久缓存
-	cache.SetPermanent([]byte("permanent_key"), []byte("这是永久缓存的数据"))
-	permanentData, err := cache.GetPermanent([]byte("permanent_key"))
+	cache.SetBytesWithTTL([]byte("permanent_key"), []byte("这是永久缓存的数据"), ngcat.TTLPermanent)
+	permanentData, err := cache.GetBytesByKey([]byte("permanent_key"))
 	if err == nil {
 		fmt.Printf("永久缓存数据: %s\n", string(permanentData))
 	}
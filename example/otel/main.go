@@ -0,0 +1,34 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+
+	"ngcat"
+)
+
+func main() {
+	// 将span以JSON格式输出到标准输出
+	exporter, err := stdouttrace.New(stdouttrace.WithPrettyPrint())
+	if err != nil {
+		log.Fatal(err)
+	}
+	tp := sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter))
+	defer tp.Shutdown(context.Background())
+
+	cache := ngcat.NewNGCache(10*1024*1024, nil, ngcat.WithOTelTracer(tp))
+	defer cache.Close()
+
+	cache.SetString("greeting", "hello", 60)
+	value, _ := cache.GetString("greeting")
+	fmt.Println("greeting:", value)
+
+	// 未命中的Get产生cache.hit=false的span
+	if _, err := cache.GetString("missing"); err != nil {
+		fmt.Println("missing:", err)
+	}
+}
@@ -0,0 +1,76 @@
+package ngcat
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"testing"
+)
+
+// fuzzSeed 通过保存路径生成有效的持久化文件
+func fuzzSeed(t testing.TB, format PersistFormat) []byte {
+	data := &PersistData{Timestamp: 1700000000, Entries: []PersistEntry{
+		{Key: "a", Value: []byte("1")},
+		{Key: "user:1", Value: []byte(`{"name":"ngcat"}`)},
+		{Key: "empty", Value: nil},
+	}}
+	var buf bytes.Buffer
+	if err := writePersistData(context.Background(), &buf, format, data); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+// addSeeds 添加完整文件和若干截断位置作为种子语料
+func addSeeds(f *testing.F, valid []byte) {
+	f.Add(valid)
+	for _, n := range []int{0, 1, 4, 8, 16, 20, 24, len(valid) / 2, len(valid) - 1} {
+		if n <= len(valid) {
+			f.Add(valid[:n])
+		}
+	}
+}
+
+// fuzzLoad 加载任意字节，只允许出现可匹配ErrCorruptFile或ErrUnsupportedFeature的错误
+func fuzzLoad(t *testing.T, data []byte, format PersistFormat) {
+	nc := NewNGCache(512*1024, nil)
+	defer nc.Close()
+	err := nc.loadEntries(context.Background(), bytes.NewReader(data), format)
+	if err != nil && !errors.Is(err, ErrCorruptFile) && !errors.Is(err, ErrUnsupportedFeature) {
+		t.Fatalf("untyped load error: %v", err)
+	}
+}
+
+func FuzzLoadBinary(f *testing.F) {
+	valid := fuzzSeed(f, FormatBinary)
+	addSeeds(f, valid)
+	// 声明超大长度的条目
+	huge := append([]byte(nil), valid[:binaryCountOffset+4]...)
+	huge = append(huge, 0xFF, 0xFF, 0xFF, 0xFF)
+	f.Add(huge)
+	f.Fuzz(func(t *testing.T, data []byte) {
+		fuzzLoad(t, data, FormatBinary)
+	})
+}
+
+func FuzzLoadJSON(f *testing.F) {
+	addSeeds(f, fuzzSeed(f, FormatJSON))
+	f.Add([]byte(`{"entries": {}}`))
+	f.Add([]byte(`{"entries": [{"key": 1, "value": "!!"}]}`))
+	f.Fuzz(func(t *testing.T, data []byte) {
+		fuzzLoad(t, data, FormatJSON)
+	})
+}
+
+func TestLoadRejectsOversizedLengths(t *testing.T) {
+	valid := fuzzSeed(t, FormatBinary)
+	data := append([]byte(nil), valid[:binaryCountOffset+4]...)
+	data = append(data, 1, 0, 0, 0, 'k', 0xFF, 0xFF, 0xFF, 0xFF)
+
+	nc := NewNGCache(512*1024, nil)
+	defer nc.Close()
+	err := nc.loadEntries(context.Background(), bytes.NewReader(data), FormatBinary)
+	if !errors.Is(err, ErrCorruptFile) {
+		t.Fatalf("expected ErrCorruptFile, got %v", err)
+	}
+}
@@ -0,0 +1,311 @@
+package ngcat
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// groupSeparator 持久化文件中分组名与键之间的分隔符，分组名中不能包含该字符
+const groupSeparator = "/"
+
+// GroupOption 缓存组配置选项
+type GroupOption func(*CacheGroup)
+
+// WithGroupPersist 设置缓存组共享的持久化配置
+//
+// 所有子缓存的永久缓存写入同一个文件，每个条目的键以"分组名/"为前缀。
+func WithGroupPersist(config *PersistConfig) GroupOption {
+	return func(g *CacheGroup) {
+		g.persistConfig = config
+	}
+}
+
+// WithGroupLogger 设置缓存组的日志记录器，默认为slog.Default()
+func WithGroupLogger(logger *slog.Logger) GroupOption {
+	return func(g *CacheGroup) {
+		g.logger = logger
+	}
+}
+
+// WithGroupOnError 设置后台任务（定时保存、加载条目）失败时的回调，未设置时写入日志
+func WithGroupOnError(fn func(err error)) GroupOption {
+	return func(g *CacheGroup) {
+		g.onError = fn
+	}
+}
+
+// CacheGroup 管理多个命名子缓存，各子缓存有独立的容量和默认过期时间，共享持久化文件
+type CacheGroup struct {
+	// mu 保护caches和pending
+	mu sync.RWMutex
+	// caches 命名子缓存
+	caches map[string]*NGCache
+	// pending 已从持久化文件加载、尚未创建对应子缓存的条目
+	pending map[string][]PersistEntry
+	// persistConfig 共享的持久化配置
+	persistConfig *PersistConfig
+	// persistMutex 持久化操作互斥锁
+	persistMutex sync.Mutex
+	// stopChan 停止持久化的通道
+	stopChan chan struct{}
+	// loadErr 启动时加载共享持久化文件的错误，不为nil时Save不覆盖该文件，LoadAll成功后清除；由mu保护
+	loadErr error
+	// logger 日志记录器
+	logger *slog.Logger
+	// onError 后台任务失败时的回调
+	onError func(err error)
+}
+
+// NewCacheGroup 创建缓存组，启用持久化时先加载已有的持久化文件
+//
+// 加载失败时通过WithGroupOnError报告（未设置时写入日志），缓存组以空数据启动，
+// 但Save和Close返回加载错误而不覆盖共享的持久化文件，直到LoadAll成功。需要在加载失败时中止启动请使用OpenCacheGroup。
+func NewCacheGroup(opts ...GroupOption) *CacheGroup {
+	g := newCacheGroup(opts)
+	if g.persistEnabled() {
+		if err := g.load(); err != nil {
+			g.loadErr = err
+			g.reportError(err)
+		}
+		g.startPersistRoutine()
+	}
+	return g
+}
+
+// OpenCacheGroup 创建缓存组，共享的持久化文件加载失败时返回nil和加载错误，文件保持原样
+func OpenCacheGroup(opts ...GroupOption) (*CacheGroup, error) {
+	g := newCacheGroup(opts)
+	if g.persistEnabled() {
+		if err := g.load(); err != nil {
+			return nil, err
+		}
+		g.startPersistRoutine()
+	}
+	return g, nil
+}
+
+// newCacheGroup 创建缓存组并应用选项，不加载持久化文件
+func newCacheGroup(opts []GroupOption) *CacheGroup {
+	g := &CacheGroup{
+		caches:   make(map[string]*NGCache),
+		pending:  make(map[string][]PersistEntry),
+		stopChan: make(chan struct{}),
+		logger:   slog.Default(),
+	}
+	for _, opt := range opts {
+		opt(g)
+	}
+	return g
+}
+
+// startPersistRoutine 设置了持久化间隔时启动定时保存的协程
+func (g *CacheGroup) startPersistRoutine() {
+	if g.persistConfig.Interval > 0 {
+		go g.persistRoutine(realClock{}.NewTicker(g.persistConfig.Interval))
+	}
+}
+
+// reportError 报告后台任务的错误，设置了WithGroupOnError时调用回调，否则写入日志
+func (g *CacheGroup) reportError(err error) {
+	if g.onError != nil {
+		g.onError(err)
+		return
+	}
+	g.logger.Warn("ngcat: 缓存组后台任务失败", "error", err)
+}
+
+// Add 添加命名子缓存，同名子缓存已存在时直接返回
+//
+// 持久化文件中属于该分组的条目会加载到新建的子缓存中。分组名不能包含"/"。
+func (g *CacheGroup) Add(name string, sizeBytes int, defaultTTL time.Duration) *NGCache {
+	if strings.Contains(name, groupSeparator) {
+		panic(fmt.Sprintf("ngcat: 分组名不能包含%q: %s", groupSeparator, name))
+	}
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if ng, ok := g.caches[name]; ok {
+		return ng
+	}
+
+	ng := NewNGCache(sizeBytes, nil, WithDefaultTTL(defaultTTL))
+	for _, entry := range g.pending[name] {
+		if err := ng.setWithPersist(entry.Key, entry.Value, 0); err != nil {
+			g.reportError(fmt.Errorf("group %s: load %s: %w", name, entry.Key, err))
+		}
+	}
+	delete(g.pending, name)
+	g.caches[name] = ng
+	return ng
+}
+
+// Get 获取命名子缓存
+func (g *CacheGroup) Get(name string) (*NGCache, bool) {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+	ng, ok := g.caches[name]
+	return ng, ok
+}
+
+// Save 将所有子缓存的永久缓存保存到共享的持久化文件
+//
+// 启动时加载共享文件失败且之后没有成功调用LoadAll时返回加载错误，避免以空数据覆盖文件。
+func (g *CacheGroup) Save() error {
+	if !g.persistEnabled() {
+		return nil
+	}
+	g.mu.RLock()
+	loadErr := g.loadErr
+	g.mu.RUnlock()
+	if loadErr != nil {
+		return loadErr
+	}
+	return g.SaveAll(g.filePath(), g.persistConfig.Format)
+}
+
+// SaveAll 将所有分组的永久缓存写入同一个文件，尚未创建子缓存的已加载分组原样保留
+//
+// JSON格式按分组写入PersistData.GroupEntries；二进制格式的键以"分组名/"为前缀。
+func (g *CacheGroup) SaveAll(path string, format PersistFormat) error {
+	g.persistMutex.Lock()
+	defer g.persistMutex.Unlock()
+
+	data := PersistData{Version: JSONVersion, Timestamp: time.Now().Unix(), GroupEntries: g.collectGroups()}
+	return writePersistFile(context.Background(), path, format, &data, false)
+}
+
+// LoadAll 读取SaveAll写入的文件，将条目作为永久缓存分发到同名子缓存
+//
+// 尚未创建的分组的条目暂存，调用Add创建该分组时加载。也可以读取Save写入的文件。
+// 写入子缓存失败的条目通过WithGroupOnError报告后跳过。完整读取文件后清除启动时的加载错误，Save恢复写入共享文件。
+func (g *CacheGroup) LoadAll(path string, format PersistFormat) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return newError(CodeOpenFile, path, err)
+	}
+	defer file.Close()
+
+	pr, err := newPersistReader(file, format)
+	if err != nil {
+		return err
+	}
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	for {
+		entry, err := pr.next()
+		if err != nil {
+			if err == io.EOF {
+				g.loadErr = nil
+				return nil
+			}
+			return err
+		}
+		name, key, ok := strings.Cut(entry.Key, groupSeparator)
+		if !ok {
+			continue
+		}
+		if ng, ok := g.caches[name]; ok {
+			if err := ng.setWithPersist(key, entry.Value, 0); err != nil {
+				g.reportError(fmt.Errorf("group %s: load %s: %w", name, key, err))
+			}
+			continue
+		}
+		g.pending[name] = append(g.pending[name], PersistEntry{Key: key, Value: entry.Value})
+	}
+}
+
+// collectGroups 按分组名排序收集所有分组的永久缓存
+func (g *CacheGroup) collectGroups() []GroupPersistData {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+
+	groups := make([]GroupPersistData, 0, len(g.caches)+len(g.pending))
+	for name, ng := range g.caches {
+		ng.flushCoalesced()
+		ng.persistDataMutex.RLock()
+		entries := make([]PersistEntry, 0, len(ng.persistData))
+		for key, value := range ng.persistData {
+			entries = append(entries, PersistEntry{Key: key, Value: value})
+		}
+		ng.persistDataMutex.RUnlock()
+		groups = append(groups, GroupPersistData{Name: name, Entries: entries})
+	}
+	for name, entries := range g.pending {
+		groups = append(groups, GroupPersistData{Name: name, Entries: entries})
+	}
+	sort.Slice(groups, func(i, j int) bool { return groups[i].Name < groups[j].Name })
+	return groups
+}
+
+// flattenGroups 将分组条目展开为以"分组名/"为前缀的条目
+func flattenGroups(groups []GroupPersistData) []PersistEntry {
+	var entries []PersistEntry
+	for _, group := range groups {
+		for _, entry := range group.Entries {
+			entries = append(entries, PersistEntry{Key: group.Name + groupSeparator + entry.Key, Value: entry.Value})
+		}
+	}
+	return entries
+}
+
+// Close 停止持久化、保存并关闭所有子缓存
+func (g *CacheGroup) Close() error {
+	var errs []error
+	if g.persistEnabled() {
+		close(g.stopChan)
+		errs = append(errs, g.Save())
+	}
+
+	g.mu.RLock()
+	for _, ng := range g.caches {
+		errs = append(errs, ng.Close())
+	}
+	g.mu.RUnlock()
+	return errors.Join(errs...)
+}
+
+// persistEnabled 是否启用了持久化
+func (g *CacheGroup) persistEnabled() bool {
+	return g.persistConfig != nil && g.persistConfig.Enabled
+}
+
+// filePath 构建持久化文件完整路径
+func (g *CacheGroup) filePath() string {
+	dir := g.persistConfig.FilePath
+	if dir == "" {
+		dir = "."
+	}
+	return filepath.Join(dir, g.persistConfig.FileName)
+}
+
+// load 读取持久化文件，按分组名暂存条目
+func (g *CacheGroup) load() error {
+	if _, err := os.Stat(g.filePath()); os.IsNotExist(err) {
+		return nil
+	}
+	return g.LoadAll(g.filePath(), g.persistConfig.Format)
+}
+
+// persistRoutine 定期保存的协程
+func (g *CacheGroup) persistRoutine(ticker Ticker) {
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C():
+			if err := g.Save(); err != nil {
+				g.reportError(err)
+			}
+		case <-g.stopChan:
+			return
+		}
+	}
+}
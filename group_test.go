@@ -0,0 +1,243 @@
+package ngcat
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestCacheGroupPersistence(t *testing.T) {
+	config := &PersistConfig{
+		Enabled:  true,
+		FilePath: t.TempDir(),
+		FileName: "group.json",
+		Format:   FormatJSON,
+	}
+
+	group := NewCacheGroup(WithGroupPersist(config))
+	auth := group.Add("auth", 1024*1024, time.Minute)
+	product := group.Add("product", 1024*1024, 0)
+	auth.SetString("token", "abc", 0)
+	product.SetString("sku:1", "book", 0)
+	// 同名的键在不同分组中互不影响
+	product.SetString("token", "product-token", 0)
+	if err := group.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	reloaded := NewCacheGroup(WithGroupPersist(config))
+	defer reloaded.Close()
+	auth = reloaded.Add("auth", 1024*1024, time.Minute)
+	product = reloaded.Add("product", 1024*1024, 0)
+
+	if v, _ := auth.GetString("token"); v != "abc" {
+		t.Fatalf("auth token = %q", v)
+	}
+	if v, _ := product.GetString("token"); v != "product-token" {
+		t.Fatalf("product token = %q", v)
+	}
+	if v, _ := product.GetString("sku:1"); v != "book" {
+		t.Fatalf("product sku = %q", v)
+	}
+	if got, ok := reloaded.Get("auth"); !ok || got != auth {
+		t.Fatal("Get should return the added cache")
+	}
+	if _, ok := reloaded.Get("missing"); ok {
+		t.Fatal("Get should report missing groups")
+	}
+}
+
+func TestCacheGroupDefaultTTL(t *testing.T) {
+	group := NewCacheGroup()
+	defer group.Close()
+	session := group.Add("session", 1024*1024, 30*time.Minute)
+
+	session.SetString("s1", "v", TTLDefault)
+	if ttl, err := session.cache.TTL([]byte("s1")); err != nil || ttl == 0 || ttl > 1800 {
+		t.Fatalf("default TTL not applied: %d, %v", ttl, err)
+	}
+	if _, exists := session.persistData["s1"]; exists {
+		t.Fatal("key written with default TTL should not be permanent")
+	}
+}
+
+func TestCacheGroupSaveAllLoadAll(t *testing.T) {
+	for _, format := range []PersistFormat{FormatJSON, FormatBinary} {
+		path := filepath.Join(t.TempDir(), "groups.dat")
+
+		group := NewCacheGroup()
+		auth := group.Add("auth", 1024*1024, 0)
+		product := group.Add("product", 1024*1024, 0)
+		auth.SetString("token", "abc", 0)
+		auth.SetBytes("empty", []byte{}, 0)
+		product.SetString("token", "product-token", 0)
+		for i := 0; i < 100; i++ {
+			product.SetString(fmt.Sprintf("sku:%d", i), fmt.Sprintf("item-%d", i), 0)
+		}
+		// 非永久缓存不写入文件
+		product.SetString("temp", "x", 60)
+		if err := group.SaveAll(path, format); err != nil {
+			t.Fatal(err)
+		}
+		group.Close()
+
+		reloaded := NewCacheGroup()
+		// 已创建的分组直接加载，未创建的分组在Add时加载
+		auth = reloaded.Add("auth", 1024*1024, 0)
+		if err := reloaded.LoadAll(path, format); err != nil {
+			t.Fatal(err)
+		}
+		product = reloaded.Add("product", 1024*1024, 0)
+
+		if v, _ := auth.GetString("token"); v != "abc" {
+			t.Fatalf("format %d: auth token = %q", format, v)
+		}
+		if v, err := auth.GetBytes("empty"); err != nil || len(v) != 0 {
+			t.Fatalf("format %d: auth empty = %q, %v", format, v, err)
+		}
+		if v, _ := product.GetString("token"); v != "product-token" {
+			t.Fatalf("format %d: product token = %q", format, v)
+		}
+		for i := 0; i < 100; i++ {
+			if v, _ := product.GetString(fmt.Sprintf("sku:%d", i)); v != fmt.Sprintf("item-%d", i) {
+				t.Fatalf("format %d: sku:%d = %q", format, i, v)
+			}
+		}
+		if _, err := product.GetString("temp"); err != ErrKeyNotFound {
+			t.Fatalf("format %d: temp should not be saved: %v", format, err)
+		}
+		reloaded.Close()
+	}
+}
+
+func TestCacheGroupSaveAllKeepsPendingGroups(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "groups.json")
+
+	group := NewCacheGroup()
+	group.Add("a", 1024*1024, 0).SetString("k", "1", 0)
+	group.Add("b", 1024*1024, 0).SetString("k", "2", 0)
+	if err := group.SaveAll(path, FormatJSON); err != nil {
+		t.Fatal(err)
+	}
+	group.Close()
+
+	// 只加载不创建分组b，再次保存时b的条目原样保留
+	partial := NewCacheGroup()
+	if err := partial.LoadAll(path, FormatJSON); err != nil {
+		t.Fatal(err)
+	}
+	partial.Add("a", 1024*1024, 0)
+	copyPath := filepath.Join(dir, "copy.json")
+	if err := partial.SaveAll(copyPath, FormatJSON); err != nil {
+		t.Fatal(err)
+	}
+	partial.Close()
+
+	raw, err := os.ReadFile(copyPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var data PersistData
+	if err := json.Unmarshal(raw, &data); err != nil {
+		t.Fatal(err)
+	}
+	if len(data.GroupEntries) != 2 || data.GroupEntries[0].Name != "a" || data.GroupEntries[1].Name != "b" {
+		t.Fatalf("groups = %+v", data.GroupEntries)
+	}
+	if got := data.GroupEntries[1].Entries; len(got) != 1 || got[0].Key != "k" || string(got[0].Value) != "2" {
+		t.Fatalf("group b entries = %+v", got)
+	}
+
+	if err := NewCacheGroup().LoadAll(filepath.Join(dir, "missing.json"), FormatJSON); !errors.Is(err, os.ErrNotExist) {
+		t.Fatalf("missing file: %v", err)
+	}
+}
+
+func TestCacheGroupCorruptFileNotOverwritten(t *testing.T) {
+	config := &PersistConfig{
+		Enabled:  true,
+		FilePath: t.TempDir(),
+		FileName: "group.bin",
+		Format:   FormatBinary,
+	}
+	path := filepath.Join(config.FilePath, config.FileName)
+	garbage := []byte("this is not a group snapshot!!!")
+	if err := os.WriteFile(path, garbage, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	if g, err := OpenCacheGroup(WithGroupPersist(config)); err == nil || g != nil {
+		t.Fatalf("OpenCacheGroup = %v, %v, want load error", g, err)
+	}
+
+	var reported []error
+	group := NewCacheGroup(WithGroupPersist(config), WithGroupOnError(func(err error) {
+		reported = append(reported, err)
+	}))
+	if len(reported) != 1 {
+		t.Fatalf("reported %v, want the load error", reported)
+	}
+	group.Add("auth", 1024*1024, 0).SetString("token", "abc", 0)
+	if err := group.Save(); err == nil {
+		t.Fatal("Save should refuse to overwrite a file that failed to load")
+	}
+	if err := group.Close(); err == nil {
+		t.Fatal("Close should report the load error")
+	}
+	if data, _ := os.ReadFile(path); string(data) != string(garbage) {
+		t.Fatalf("group file was overwritten: %q", data)
+	}
+}
+
+func TestCacheGroupLoadAllClearsLoadError(t *testing.T) {
+	dir := t.TempDir()
+	config := &PersistConfig{Enabled: true, FilePath: dir, FileName: "group.json", Format: FormatJSON}
+	if err := os.WriteFile(filepath.Join(dir, "group.json"), []byte("{"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	group := NewCacheGroup(WithGroupPersist(config), WithGroupOnError(func(error) {}))
+	defer group.Close()
+
+	source := NewCacheGroup()
+	source.Add("auth", 1024*1024, 0).SetString("token", "abc", 0)
+	backup := filepath.Join(dir, "backup.json")
+	if err := source.SaveAll(backup, FormatJSON); err != nil {
+		t.Fatal(err)
+	}
+	source.Close()
+
+	if err := group.LoadAll(backup, FormatJSON); err != nil {
+		t.Fatal(err)
+	}
+	if err := group.Save(); err != nil {
+		t.Fatalf("Save after a successful LoadAll: %v", err)
+	}
+}
+
+func TestCacheGroupReportsRejectedEntries(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "group.json")
+	source := NewCacheGroup()
+	source.Add("small", 64*1024*1024, 0).SetString("big", strings.Repeat("x", 4096), 0)
+	if err := source.SaveAll(path, FormatJSON); err != nil {
+		t.Fatal(err)
+	}
+	source.Close()
+
+	var reported []error
+	group := NewCacheGroup(WithGroupOnError(func(err error) { reported = append(reported, err) }))
+	defer group.Close()
+	if err := group.LoadAll(path, FormatJSON); err != nil {
+		t.Fatal(err)
+	}
+	// 512KB的freecache不能存放超过其1/1024的条目
+	group.Add("small", 512*1024, 0)
+	if len(reported) != 1 || !strings.Contains(reported[0].Error(), "big") {
+		t.Fatalf("reported %v, want the rejected entry", reported)
+	}
+}
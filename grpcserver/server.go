@@ -0,0 +1,146 @@
+// Package grpcserver 通过gRPC暴露NGCache，供sidecar和其他语言的客户端访问
+//
+// 服务定义见ngcatpb/ngcat.proto，客户端使用生成的ngcatpb.NewCacheClient。
+package grpcserver
+
+//go:generate protoc --go_out=ngcatpb --go_opt=paths=source_relative --go-grpc_out=ngcatpb --go-grpc_opt=paths=source_relative -I ngcatpb ngcat.proto
+
+import (
+	"context"
+	"errors"
+	"net"
+	"regexp"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"ngcat"
+	"ngcat/grpcserver/ngcatpb"
+)
+
+// keysBatchSize Keys流式响应中每条消息包含的键数量
+const keysBatchSize = 512
+
+// Serve 在lis上启动gRPC服务，阻塞直到lis关闭或服务出错
+func Serve(ng *ngcat.NGCache, lis net.Listener, opts ...grpc.ServerOption) error {
+	s := grpc.NewServer(opts...)
+	ngcatpb.RegisterCacheServer(s, NewServer(ng))
+	return s.Serve(lis)
+}
+
+// NewServer 返回ngcatpb.CacheServer实现，用于注册到已有的grpc.Server
+func NewServer(ng *ngcat.NGCache) ngcatpb.CacheServer {
+	return &server{ng: ng}
+}
+
+// server ngcatpb.CacheServer实现
+type server struct {
+	ngcatpb.UnimplementedCacheServer
+	ng *ngcat.NGCache
+}
+
+// Get 获取值
+func (s *server) Get(ctx context.Context, req *ngcatpb.GetRequest) (*ngcatpb.GetResponse, error) {
+	value, err := s.ng.GetBytes(req.GetKey())
+	if err != nil {
+		return nil, toStatus(err)
+	}
+	return &ngcatpb.GetResponse{Value: value}, nil
+}
+
+// Set 写入值
+func (s *server) Set(ctx context.Context, req *ngcatpb.SetRequest) (*ngcatpb.SetResponse, error) {
+	expireSeconds := ngcat.TTLDefault
+	if req.GetPermanent() {
+		expireSeconds = 0
+	} else if req.GetTtlSeconds() > 0 {
+		expireSeconds = int(req.GetTtlSeconds())
+	}
+	err := s.ng.SetBytes(req.GetKey(), req.GetValue(), expireSeconds)
+	if err != nil {
+		return nil, toStatus(err)
+	}
+	return &ngcatpb.SetResponse{}, nil
+}
+
+// Delete 删除键
+func (s *server) Delete(ctx context.Context, req *ngcatpb.DeleteRequest) (*ngcatpb.DeleteResponse, error) {
+	return &ngcatpb.DeleteResponse{Deleted: s.ng.Delete(req.GetKey())}, nil
+}
+
+// MGet 批量获取值
+func (s *server) MGet(ctx context.Context, req *ngcatpb.MGetRequest) (*ngcatpb.MGetResponse, error) {
+	entries := make([]*ngcatpb.MGetEntry, len(req.GetKeys()))
+	for i, key := range req.GetKeys() {
+		value, err := s.ng.GetBytes(key)
+		if err != nil && !errors.Is(err, ngcat.ErrKeyNotFound) {
+			return nil, toStatus(err)
+		}
+		entries[i] = &ngcatpb.MGetEntry{Key: key, Value: value, Found: err == nil}
+	}
+	return &ngcatpb.MGetResponse{Entries: entries}, nil
+}
+
+// Stats 获取缓存统计信息
+func (s *server) Stats(ctx context.Context, req *ngcatpb.StatsRequest) (*ngcatpb.StatsResponse, error) {
+	stats := s.ng.Stats()
+	return &ngcatpb.StatsResponse{
+		HitCount:       stats.HitCount,
+		MissCount:      stats.MissCount,
+		EntryCount:     stats.EntryCount,
+		EvacuateCount:  stats.EvacuateCount,
+		ExpiredCount:   stats.ExpiredCount,
+		PersistEntries: stats.PersistEntries,
+		Promotions:     stats.Promotions,
+	}, nil
+}
+
+// Keys 分批流式返回匹配的键，客户端取消时停止遍历
+func (s *server) Keys(req *ngcatpb.KeysRequest, stream ngcatpb.Cache_KeysServer) error {
+	var re *regexp.Regexp
+	if req.GetPattern() != "" {
+		var err error
+		re, err = regexp.Compile(req.GetPattern())
+		if err != nil {
+			return status.Errorf(codes.InvalidArgument, "无效的键匹配模式: %v", err)
+		}
+	}
+
+	var sendErr error
+	batch := make([]string, 0, keysBatchSize)
+	s.ng.ForEachWithExpire(func(key string, value []byte, remaining time.Duration) bool {
+		if re != nil && !re.MatchString(key) {
+			return true
+		}
+		batch = append(batch, key)
+		if len(batch) < keysBatchSize {
+			return true
+		}
+		sendErr = stream.Send(&ngcatpb.KeysResponse{Keys: batch})
+		batch = make([]string, 0, keysBatchSize)
+		return sendErr == nil
+	})
+	if sendErr != nil {
+		return sendErr
+	}
+	if len(batch) > 0 {
+		return stream.Send(&ngcatpb.KeysResponse{Keys: batch})
+	}
+	return nil
+}
+
+// toStatus 将缓存错误映射为gRPC状态码
+func toStatus(err error) error {
+	switch {
+	case errors.Is(err, ngcat.ErrKeyNotFound):
+		return status.Error(codes.NotFound, err.Error())
+	case errors.Is(err, ngcat.ErrValueTooLarge), errors.Is(err, ngcat.ErrQuotaExceeded):
+		return status.Error(codes.ResourceExhausted, err.Error())
+	case errors.Is(err, ngcat.ErrKeyTooLong):
+		return status.Error(codes.InvalidArgument, err.Error())
+	default:
+		return status.Error(codes.Internal, err.Error())
+	}
+}
@@ -0,0 +1,144 @@
+package grpcserver
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"sort"
+	"testing"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+
+	"ngcat"
+	"ngcat/grpcserver/ngcatpb"
+)
+
+// newTestClient 通过bufconn启动服务并返回客户端
+func newTestClient(t *testing.T, ng *ngcat.NGCache) ngcatpb.CacheClient {
+	t.Helper()
+	lis := bufconn.Listen(1024 * 1024)
+	done := make(chan error, 1)
+	go func() { done <- Serve(ng, lis) }()
+
+	conn, err := grpc.NewClient("passthrough:///bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			return lis.DialContext(ctx)
+		}),
+		grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		conn.Close()
+		lis.Close()
+		<-done
+	})
+	return ngcatpb.NewCacheClient(conn)
+}
+
+func TestGetSetDelete(t *testing.T) {
+	ng := ngcat.NewNGCache(1024*1024, nil, ngcat.WithMaxValueSize(16))
+	defer ng.Close()
+	client := newTestClient(t, ng)
+	ctx := context.Background()
+
+	if _, err := client.Set(ctx, &ngcatpb.SetRequest{Key: "perm", Value: []byte("p"), Permanent: true}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := client.Set(ctx, &ngcatpb.SetRequest{Key: "ttl", Value: []byte("t"), TtlSeconds: 60}); err != nil {
+		t.Fatal(err)
+	}
+	resp, err := client.Get(ctx, &ngcatpb.GetRequest{Key: "perm"})
+	if err != nil || string(resp.GetValue()) != "p" {
+		t.Fatalf("Get(perm) = %v, %v", resp, err)
+	}
+	if stats, _ := client.Stats(ctx, &ngcatpb.StatsRequest{}); stats.GetPersistEntries() != 1 {
+		t.Fatalf("permanent flag not honoured: %v", stats)
+	}
+	if ttl, _ := ng.GetBytes("ttl"); string(ttl) != "t" {
+		t.Fatalf("ttl = %q", ttl)
+	}
+
+	_, err = client.Get(ctx, &ngcatpb.GetRequest{Key: "missing"})
+	if status.Code(err) != codes.NotFound {
+		t.Fatalf("expected NotFound, got %v", err)
+	}
+	_, err = client.Set(ctx, &ngcatpb.SetRequest{Key: "big", Value: make([]byte, 17)})
+	if status.Code(err) != codes.ResourceExhausted {
+		t.Fatalf("expected ResourceExhausted, got %v", err)
+	}
+
+	del, err := client.Delete(ctx, &ngcatpb.DeleteRequest{Key: "perm"})
+	if err != nil || !del.GetDeleted() {
+		t.Fatalf("Delete = %v, %v", del, err)
+	}
+	del, _ = client.Delete(ctx, &ngcatpb.DeleteRequest{Key: "perm"})
+	if del.GetDeleted() {
+		t.Fatal("second delete should report missing key")
+	}
+}
+
+func TestMGet(t *testing.T) {
+	ng := ngcat.NewNGCache(1024*1024, nil)
+	defer ng.Close()
+	client := newTestClient(t, ng)
+
+	ng.SetString("a", "1", 0)
+	ng.SetString("c", "3", 60)
+	resp, err := client.MGet(context.Background(), &ngcatpb.MGetRequest{Keys: []string{"a", "b", "c"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := resp.GetEntries()
+	if len(got) != 3 || !got[0].GetFound() || got[1].GetFound() || string(got[2].GetValue()) != "3" {
+		t.Fatalf("MGet = %v", got)
+	}
+}
+
+func TestKeysStreaming(t *testing.T) {
+	ng := ngcat.NewNGCache(4*1024*1024, nil)
+	defer ng.Close()
+	client := newTestClient(t, ng)
+
+	const users = keysBatchSize*2 + 10
+	for i := 0; i < users; i++ {
+		ng.SetString(fmt.Sprintf("user:%d", i), "u", 0)
+	}
+	ng.SetString("product:1", "p", 0)
+
+	stream, err := client.Keys(context.Background(), &ngcatpb.KeysRequest{Pattern: "^user:"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var keys []string
+	messages := 0
+	for {
+		resp, err := stream.Recv()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		messages++
+		keys = append(keys, resp.GetKeys()...)
+	}
+	if len(keys) != users || messages != 3 {
+		t.Fatalf("got %d keys in %d messages", len(keys), messages)
+	}
+	sort.Strings(keys)
+	if keys[0] != "user:0" {
+		t.Fatalf("unexpected key %q", keys[0])
+	}
+
+	stream, _ = client.Keys(context.Background(), &ngcatpb.KeysRequest{Pattern: "("})
+	if _, err := stream.Recv(); status.Code(err) != codes.InvalidArgument {
+		t.Fatalf("expected InvalidArgument, got %v", err)
+	}
+}
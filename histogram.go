@@ -0,0 +1,189 @@
+package ngcat
+
+import (
+	"sync/atomic"
+	"time"
+)
+
+// valueSizeBounds 值大小直方图各桶的上限（字节）
+var valueSizeBounds = []int64{64, 256, 1 << 10, 4 << 10, 16 << 10, 64 << 10, 256 << 10, 1 << 20}
+
+// getLatencyBounds Get延迟直方图各桶的上限
+var getLatencyBounds = []int64{
+	int64(time.Microsecond),
+	int64(5 * time.Microsecond),
+	int64(25 * time.Microsecond),
+	int64(100 * time.Microsecond),
+	int64(500 * time.Microsecond),
+	int64(2500 * time.Microsecond),
+	int64(10 * time.Millisecond),
+}
+
+// getOutcome 一次读取的结果
+type getOutcome int
+
+const (
+	// getHit freecache命中
+	getHit getOutcome = iota
+	// getFallback 从尚未合并的写入或持久化数据读取
+	getFallback
+	// getMiss 键不存在
+	getMiss
+)
+
+// Histogram 直方图快照，桶的划分与Prometheus直方图一致
+type Histogram struct {
+	// Bounds 各桶的上限（含），值大小以字节为单位，延迟以秒为单位
+	Bounds []float64
+	// Counts 各桶的计数（非累计），比Bounds多一个元素，最后一个是超过最大上限的计数
+	Counts []uint64
+	// Count 记录的总次数
+	Count uint64
+	// Sum 记录值的总和，单位与Bounds相同
+	Sum float64
+}
+
+// Cumulative 返回Prometheus格式的累计桶计数（上限到不超过该上限的计数），
+// 可与Count、Sum一起传给prometheus.MustNewConstHistogram
+func (h Histogram) Cumulative() map[float64]uint64 {
+	buckets := make(map[float64]uint64, len(h.Bounds))
+	var total uint64
+	for i, bound := range h.Bounds {
+		total += h.Counts[i]
+		buckets[bound] = total
+	}
+	return buckets
+}
+
+// histogram 固定桶上限的直方图，各桶以原子计数器记录，不加锁
+type histogram struct {
+	bounds []int64
+	counts []atomic.Uint64
+	sum    atomic.Int64
+}
+
+func newHistogram(bounds []int64) *histogram {
+	return &histogram{bounds: bounds, counts: make([]atomic.Uint64, len(bounds)+1)}
+}
+
+// observe 记录一个值
+func (h *histogram) observe(v int64) {
+	i := 0
+	for i < len(h.bounds) && v > h.bounds[i] {
+		i++
+	}
+	h.counts[i].Add(1)
+	h.sum.Add(v)
+}
+
+// snapshot 返回直方图快照，unit为一个导出单位折合的内部单位数量
+func (h *histogram) snapshot(unit float64) Histogram {
+	s := Histogram{
+		Bounds: make([]float64, len(h.bounds)),
+		Counts: make([]uint64, len(h.counts)),
+		Sum:    float64(h.sum.Load()) / unit,
+	}
+	for i, bound := range h.bounds {
+		s.Bounds[i] = float64(bound) / unit
+	}
+	for i := range h.counts {
+		s.Counts[i] = h.counts[i].Load()
+		s.Count += s.Counts[i]
+	}
+	return s
+}
+
+// reset 清零所有计数
+func (h *histogram) reset() {
+	for i := range h.counts {
+		h.counts[i].Store(0)
+	}
+	h.sum.Store(0)
+}
+
+// histograms 写入值大小和读取延迟的直方图
+type histograms struct {
+	sampleRate uint64
+	setCalls   atomic.Uint64
+	getCalls   atomic.Uint64
+	valueSize  *histogram
+	// getLatency 按getOutcome区分的读取延迟
+	getLatency [3]*histogram
+}
+
+func newHistograms(sampleRate int) *histograms {
+	if sampleRate < 1 {
+		sampleRate = 1
+	}
+	h := &histograms{sampleRate: uint64(sampleRate), valueSize: newHistogram(valueSizeBounds)}
+	for i := range h.getLatency {
+		h.getLatency[i] = newHistogram(getLatencyBounds)
+	}
+	return h
+}
+
+// sample 按采样率决定是否记录本次调用
+func (h *histograms) sample(calls *atomic.Uint64) bool {
+	return h.sampleRate <= 1 || calls.Add(1)%h.sampleRate == 0
+}
+
+// observeValueSize 按采样率记录一次写入的值大小
+func (ng *NGCache) observeValueSize(size int) {
+	if h := ng.histograms; h != nil && h.sample(&h.setCalls) {
+		h.valueSize.observe(int64(size))
+	}
+}
+
+// timeGet 按采样率决定是否记录本次读取的延迟，需要记录时返回开始时间
+func (ng *NGCache) timeGet() (time.Time, bool) {
+	if h := ng.histograms; h != nil && h.sample(&h.getCalls) {
+		return ng.clock.Now(), true
+	}
+	return time.Time{}, false
+}
+
+// observeGet 记录一次读取的延迟
+func (ng *NGCache) observeGet(start time.Time, outcome getOutcome) {
+	ng.histograms.getLatency[outcome].observe(int64(ng.clock.Now().Sub(start)))
+}
+
+// add 将另一个直方图的计数加到h上，h为零值时复制o的桶上限，两者的桶上限必须相同
+func (h *Histogram) add(o Histogram) {
+	if o.Count == 0 && o.Counts == nil {
+		return
+	}
+	if h.Counts == nil {
+		h.Bounds = o.Bounds
+		h.Counts = make([]uint64, len(o.Counts))
+	}
+	for i, c := range o.Counts {
+		h.Counts[i] += c
+	}
+	h.Count += o.Count
+	h.Sum += o.Sum
+}
+
+// histogramStats 填充Stats中的直方图，未启用时保持零值
+func (ng *NGCache) histogramStats(stats *CacheStats) {
+	h := ng.histograms
+	if h == nil {
+		return
+	}
+	seconds := float64(time.Second)
+	stats.ValueSizes = h.valueSize.snapshot(1)
+	stats.GetLatencyHit = h.getLatency[getHit].snapshot(seconds)
+	stats.GetLatencyFallback = h.getLatency[getFallback].snapshot(seconds)
+	stats.GetLatencyMiss = h.getLatency[getMiss].snapshot(seconds)
+}
+
+// resetHistograms 清零所有直方图
+func (ng *NGCache) resetHistograms() {
+	h := ng.histograms
+	if h == nil {
+		return
+	}
+	h.valueSize.reset()
+	for _, l := range h.getLatency {
+		l.reset()
+	}
+}
@@ -0,0 +1,95 @@
+package ngcat
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestHistogramObserve(t *testing.T) {
+	h := newHistogram([]int64{10, 100})
+	for _, v := range []int64{0, 10, 11, 100, 101, 5000} {
+		h.observe(v)
+	}
+	s := h.snapshot(1)
+	if !reflect.DeepEqual(s.Counts, []uint64{2, 2, 2}) || s.Count != 6 || s.Sum != 5222 {
+		t.Fatalf("snapshot = %+v", s)
+	}
+	if got := s.Cumulative(); !reflect.DeepEqual(got, map[float64]uint64{10: 2, 100: 4}) {
+		t.Fatalf("cumulative = %v", got)
+	}
+}
+
+func TestHistogramStats(t *testing.T) {
+	config := &PersistConfig{
+		Enabled:  true,
+		FilePath: t.TempDir(),
+		FileName: "cache.bin",
+		Format:   FormatBinary,
+		Interval: time.Hour,
+	}
+	nc := NewNGCache(1024*1024, config, WithClock(newFakeClock()), WithHistograms(1),
+		WithPromotePolicy(PromoteNever, 0))
+	defer nc.Close()
+
+	if stats := nc.Stats(); stats.ValueSizes.Count != 0 || len(stats.ValueSizes.Counts) != len(valueSizeBounds)+1 {
+		t.Fatalf("initial value sizes = %+v", stats.ValueSizes)
+	}
+
+	nc.SetBytes("a", make([]byte, 10), 60)
+	nc.SetBytes("b", make([]byte, 64), 60)
+	nc.SetBytes("c", make([]byte, 100), 60)
+	nc.SetBytes("p", make([]byte, 900), 0)
+
+	for i := 0; i < 3; i++ {
+		nc.GetBytes("a")
+	}
+	nc.GetBytes("missing")
+	nc.GetPermanent([]byte("missing"))
+	nc.cache.Del([]byte("p"))
+	nc.GetBytes("p")
+
+	stats := nc.Stats()
+	wantSizes := make([]uint64, len(valueSizeBounds)+1)
+	wantSizes[0], wantSizes[1], wantSizes[2] = 2, 1, 1
+	if !reflect.DeepEqual(stats.ValueSizes.Counts, wantSizes) || stats.ValueSizes.Sum != 1074 {
+		t.Fatalf("value sizes = %+v", stats.ValueSizes)
+	}
+	if stats.ValueSizes.Bounds[2] != 1024 {
+		t.Fatalf("value size bounds = %v", stats.ValueSizes.Bounds)
+	}
+	// 假时钟不前进，所有延迟都落在第一个桶
+	for name, c := range map[string]struct {
+		h    Histogram
+		want uint64
+	}{
+		"hit":      {stats.GetLatencyHit, 3},
+		"fallback": {stats.GetLatencyFallback, 1},
+		"miss":     {stats.GetLatencyMiss, 2},
+	} {
+		if c.h.Count != c.want || c.h.Counts[0] != c.want {
+			t.Errorf("%s latency = %+v, want %d", name, c.h, c.want)
+		}
+	}
+	if stats.GetLatencyHit.Bounds[0] != 1e-6 {
+		t.Fatalf("latency bounds = %v", stats.GetLatencyHit.Bounds)
+	}
+
+	nc.ResetStats()
+	if stats := nc.Stats(); stats.ValueSizes.Count != 0 || stats.GetLatencyHit.Count != 0 {
+		t.Fatalf("after reset: %+v", stats)
+	}
+}
+
+func TestHistogramSampling(t *testing.T) {
+	nc := NewNGCache(1024*1024, nil, WithHistograms(4))
+	defer nc.Close()
+	for i := 0; i < 10; i++ {
+		nc.SetString("k", "v", 0)
+		nc.GetString("k")
+	}
+	stats := nc.Stats()
+	if stats.ValueSizes.Count != 2 || stats.GetLatencyHit.Count != 2 {
+		t.Fatalf("sampled counts = %d, %d", stats.ValueSizes.Count, stats.GetLatencyHit.Count)
+	}
+}
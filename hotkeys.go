@@ -0,0 +1,130 @@
+package ngcat
+
+import (
+	"sort"
+	"sync"
+	"sync/atomic"
+)
+
+// hotKeyStripes 热点键统计的分片数量，分片之间互不加锁
+const hotKeyStripes = 16
+
+// KeyFreq 热点键及其近似访问次数
+type KeyFreq struct {
+	Key   string
+	Count uint64
+}
+
+// hotKeyTracker 基于采样和衰减的热点键统计
+//
+// 键按哈希分散到各个分片，每个分片最多保存perStripe个键。分片满时所有计数减半
+// 并丢弃归零的键，冷键很快被淘汰而热点键的相对顺序保持不变，内存占用与键空间大小无关。
+type hotKeyTracker struct {
+	sampleRate uint64
+	perStripe  int
+	calls      atomic.Uint64
+	stripes    [hotKeyStripes]hotKeyStripe
+}
+
+// hotKeyStripe 热点键统计分片
+type hotKeyStripe struct {
+	mu     sync.Mutex
+	counts map[string]uint64
+}
+
+// newHotKeyTracker 创建热点键统计，capacity为最多跟踪的键数量
+func newHotKeyTracker(capacity, sampleRate int) *hotKeyTracker {
+	if sampleRate < 1 {
+		sampleRate = 1
+	}
+	perStripe := capacity / hotKeyStripes
+	if perStripe < 1 {
+		perStripe = 1
+	}
+	return &hotKeyTracker{sampleRate: uint64(sampleRate), perStripe: perStripe}
+}
+
+// record 按采样率记录一次访问
+func (t *hotKeyTracker) record(key string) {
+	if t.sampleRate > 1 && t.calls.Add(1)%t.sampleRate != 0 {
+		return
+	}
+
+	s := &t.stripes[stringHash(key)%hotKeyStripes]
+	s.mu.Lock()
+	if s.counts == nil {
+		s.counts = make(map[string]uint64, t.perStripe+1)
+	}
+	s.counts[key]++
+	for len(s.counts) > t.perStripe {
+		for k, c := range s.counts {
+			if c /= 2; c == 0 {
+				delete(s.counts, k)
+			} else {
+				s.counts[k] = c
+			}
+		}
+	}
+	s.mu.Unlock()
+}
+
+// top 返回计数最高的n个键，计数按采样率放大
+func (t *hotKeyTracker) top(n int) []KeyFreq {
+	var all []KeyFreq
+	for i := range t.stripes {
+		s := &t.stripes[i]
+		s.mu.Lock()
+		for k, c := range s.counts {
+			all = append(all, KeyFreq{Key: k, Count: c * t.sampleRate})
+		}
+		s.mu.Unlock()
+	}
+
+	sort.Slice(all, func(i, j int) bool {
+		if all[i].Count != all[j].Count {
+			return all[i].Count > all[j].Count
+		}
+		return all[i].Key < all[j].Key
+	})
+	if n >= 0 && len(all) > n {
+		all = all[:n]
+	}
+	return all
+}
+
+// reset 清空统计
+func (t *hotKeyTracker) reset() {
+	for i := range t.stripes {
+		s := &t.stripes[i]
+		s.mu.Lock()
+		s.counts = nil
+		s.mu.Unlock()
+	}
+}
+
+// stringHash FNV-1a哈希，避免为计算哈希分配内存
+func stringHash(s string) uint32 {
+	h := uint32(2166136261)
+	for i := 0; i < len(s); i++ {
+		h ^= uint32(s[i])
+		h *= 16777619
+	}
+	return h
+}
+
+// HotKeys 返回近似的访问次数最高的n个键，未通过WithHotKeys启用时返回nil
+//
+// 计数为自上次ResetHotKeys以来的采样计数乘以采样率，分片满时会衰减，只适合比较相对热度。
+func (ng *NGCache) HotKeys(n int) []KeyFreq {
+	if ng.hotKeys == nil {
+		return nil
+	}
+	return ng.hotKeys.top(n)
+}
+
+// ResetHotKeys 清空热点键统计
+func (ng *NGCache) ResetHotKeys() {
+	if ng.hotKeys != nil {
+		ng.hotKeys.reset()
+	}
+}
@@ -0,0 +1,59 @@
+package ngcat
+
+import (
+	"fmt"
+	"math/rand"
+	"testing"
+)
+
+func TestHotKeysZipf(t *testing.T) {
+	nc := NewNGCache(1024*1024, nil, WithHotKeys(64, 1))
+	defer nc.Close()
+
+	zipf := rand.NewZipf(rand.New(rand.NewSource(1)), 1.2, 1, 9999)
+	for i := 0; i < 100000; i++ {
+		nc.GetString(fmt.Sprintf("key_%d", zipf.Uint64()))
+	}
+
+	top := nc.HotKeys(5)
+	if len(top) != 5 {
+		t.Fatalf("HotKeys(5) returned %d keys", len(top))
+	}
+	found := make(map[string]bool)
+	for _, kf := range top {
+		found[kf.Key] = true
+	}
+	for _, heavy := range []string{"key_0", "key_1", "key_2"} {
+		if !found[heavy] {
+			t.Fatalf("heavy hitter %s missing from %v", heavy, top)
+		}
+	}
+	if top[0].Key != "key_0" {
+		t.Fatalf("hottest key = %v", top[0])
+	}
+
+	nc.ResetHotKeys()
+	if top := nc.HotKeys(5); len(top) != 0 {
+		t.Fatalf("HotKeys after reset = %v", top)
+	}
+}
+
+func TestHotKeysSampled(t *testing.T) {
+	nc := NewNGCache(1024*1024, nil, WithHotKeys(16, 10))
+	defer nc.Close()
+
+	for i := 0; i < 1000; i++ {
+		nc.GetString("hot")
+		if i%100 == 0 {
+			nc.GetString(fmt.Sprintf("cold_%d", i))
+		}
+	}
+	top := nc.HotKeys(1)
+	if len(top) != 1 || top[0].Key != "hot" || top[0].Count < 500 {
+		t.Fatalf("HotKeys(1) = %v", top)
+	}
+
+	if NewNGCache(1024*1024, nil).HotKeys(1) != nil {
+		t.Fatal("HotKeys should be nil when tracking is disabled")
+	}
+}
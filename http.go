@@ -0,0 +1,131 @@
+package ngcat
+
+import (
+	"encoding/json"
+	"net/http"
+	"sort"
+	"strings"
+)
+
+// Flush 删除所有条目（包括持久化数据中的永久缓存），返回删除的键数量
+//
+// 逐个键删除，WAL、增量持久化、配额和条目元数据与调用Delete时保持一致。
+func (ng *NGCache) Flush() int {
+	removed := 0
+	for _, key := range ng.allKeys() {
+		if ng.deleteWithPersist(key) {
+			removed++
+		}
+	}
+	// 尚未写回的计数器不在freecache中
+	ng.counters.Range(func(key string, _ int64) bool {
+		if ng.deleteWithPersist(key) {
+			removed++
+		}
+		return true
+	})
+	return removed
+}
+
+// allKeys 返回所有存活的键，已按字典序排序
+func (ng *NGCache) allKeys() []string {
+	var keys []string
+	ng.scanKeys("", func(key string, expireAt uint32) bool {
+		keys = append(keys, key)
+		return true
+	})
+	sort.Strings(keys)
+	return keys
+}
+
+// ServeHTTP 提供用于查看缓存内容的简单REST接口，可通过http.Handle("/cache/", cache)挂载：
+//
+//	GET    .../keys     所有键的JSON数组
+//	GET    .../key/{k}  键的原始值（application/octet-stream），Accept包含application/json时返回{"key","value"}
+//	DELETE .../key/{k}  删除键
+//	GET    .../stats    JSON格式的CacheStats
+//	POST   .../flush    删除所有条目，返回{"flushed": n}
+//
+// 路由按路径的最后部分匹配，与挂载前缀无关；键中可以包含"/"。接口不做任何鉴权，不应暴露到公网。
+func (ng *NGCache) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	path := r.URL.Path
+	if i := strings.Index(path, "/key/"); i >= 0 {
+		ng.serveKey(w, r, path[i+len("/key/"):])
+		return
+	}
+
+	switch path[strings.LastIndex(path, "/")+1:] {
+	case "keys":
+		if !allowMethod(w, r, http.MethodGet) {
+			return
+		}
+		keys := ng.allKeys()
+		if keys == nil {
+			keys = []string{}
+		}
+		writeJSON(w, keys)
+	case "stats":
+		if !allowMethod(w, r, http.MethodGet) {
+			return
+		}
+		writeJSON(w, ng.Stats())
+	case "flush":
+		if !allowMethod(w, r, http.MethodPost) {
+			return
+		}
+		writeJSON(w, map[string]int{"flushed": ng.Flush()})
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+// serveKey 处理单个键的读取和删除
+func (ng *NGCache) serveKey(w http.ResponseWriter, r *http.Request, key string) {
+	switch r.Method {
+	case http.MethodGet, http.MethodHead:
+		value, err := ng.GetBytes(key)
+		if err == ErrKeyNotFound {
+			http.NotFound(w, r)
+			return
+		}
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		if strings.Contains(r.Header.Get("Accept"), "application/json") {
+			writeJSON(w, map[string]string{"key": key, "value": string(value)})
+			return
+		}
+		w.Header().Set("Content-Type", "application/octet-stream")
+		w.Write(value)
+	case http.MethodDelete:
+		if !ng.Delete(key) {
+			http.NotFound(w, r)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		w.Header().Set("Allow", "GET, HEAD, DELETE")
+		http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+	}
+}
+
+// allowMethod 检查请求方法，不匹配时返回405
+func allowMethod(w http.ResponseWriter, r *http.Request, method string) bool {
+	if r.Method == method || (method == http.MethodGet && r.Method == http.MethodHead) {
+		return true
+	}
+	allow := method
+	if method == http.MethodGet {
+		allow += ", " + http.MethodHead
+	}
+	w.Header().Set("Allow", allow)
+	http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+	return false
+}
+
+// writeJSON 以application/json输出v
+func writeJSON(w http.ResponseWriter, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(v)
+}
@@ -0,0 +1,77 @@
+package ngcat
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func serve(h http.Handler, method, url, accept string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, url, nil)
+	if accept != "" {
+		req.Header.Set("Accept", accept)
+	}
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	return rec
+}
+
+func TestServeHTTP(t *testing.T) {
+	nc := NewNGCache(1024*1024, nil)
+	defer nc.Close()
+	nc.SetString("b", "2", 60)
+	nc.SetString("a/nested", "1", 0)
+
+	mux := http.NewServeMux()
+	mux.Handle("/cache/", nc)
+
+	rec := serve(mux, http.MethodGet, "/cache/keys", "")
+	var keys []string
+	if err := json.Unmarshal(rec.Body.Bytes(), &keys); err != nil || len(keys) != 2 || keys[0] != "a/nested" {
+		t.Fatalf("keys = %s, %v", rec.Body.String(), err)
+	}
+
+	rec = serve(mux, http.MethodGet, "/cache/key/a/nested", "")
+	if rec.Code != http.StatusOK || rec.Body.String() != "1" || rec.Header().Get("Content-Type") != "application/octet-stream" {
+		t.Fatalf("raw value: %d %q %v", rec.Code, rec.Body.String(), rec.Header())
+	}
+	rec = serve(mux, http.MethodGet, "/cache/key/b", "application/json")
+	var entry map[string]string
+	if err := json.Unmarshal(rec.Body.Bytes(), &entry); err != nil || entry["key"] != "b" || entry["value"] != "2" {
+		t.Fatalf("JSON value = %s, %v", rec.Body.String(), err)
+	}
+	if rec := serve(mux, http.MethodGet, "/cache/key/missing", ""); rec.Code != http.StatusNotFound {
+		t.Fatalf("missing key: %d", rec.Code)
+	}
+
+	rec = serve(mux, http.MethodGet, "/cache/stats", "")
+	var stats CacheStats
+	if err := json.Unmarshal(rec.Body.Bytes(), &stats); err != nil || stats.PersistEntries != 1 {
+		t.Fatalf("stats = %s, %v", rec.Body.String(), err)
+	}
+
+	if rec := serve(mux, http.MethodDelete, "/cache/key/b", ""); rec.Code != http.StatusNoContent {
+		t.Fatalf("delete: %d", rec.Code)
+	}
+	if _, err := nc.GetString("b"); err != ErrKeyNotFound {
+		t.Fatalf("b not deleted: %v", err)
+	}
+	if rec := serve(mux, http.MethodDelete, "/cache/key/b", ""); rec.Code != http.StatusNotFound {
+		t.Fatalf("second delete: %d", rec.Code)
+	}
+
+	if rec := serve(mux, http.MethodGet, "/cache/flush", ""); rec.Code != http.StatusMethodNotAllowed {
+		t.Fatalf("GET flush: %d", rec.Code)
+	}
+	rec = serve(mux, http.MethodPost, "/cache/flush", "")
+	if rec.Code != http.StatusOK || rec.Body.String() != "{\"flushed\":1}\n" {
+		t.Fatalf("flush: %d %s", rec.Code, rec.Body.String())
+	}
+	if rec := serve(mux, http.MethodGet, "/cache/keys", ""); rec.Body.String() != "[]\n" {
+		t.Fatalf("keys after flush = %s", rec.Body.String())
+	}
+	if rec := serve(mux, http.MethodGet, "/cache/unknown", ""); rec.Code != http.StatusNotFound {
+		t.Fatalf("unknown route: %d", rec.Code)
+	}
+}
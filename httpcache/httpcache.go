@@ -0,0 +1,294 @@
+// Package httpcache 提供基于NGCache的net/http响应缓存中间件
+package httpcache
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"ngcat"
+)
+
+// HeaderName 标识缓存命中情况的响应头
+const HeaderName = "X-NGCache"
+
+// 默认配置
+const (
+	// DefaultTTL 默认缓存时间
+	DefaultTTL = time.Minute
+	// DefaultMaxBodySize 默认可缓存的最大响应体大小
+	DefaultMaxBodySize = 1024 * 1024
+)
+
+// errCorruptResponse 缓存的响应数据损坏
+var errCorruptResponse = errors.New("httpcache: corrupt cached response")
+
+// Option 中间件配置选项
+type Option func(*config)
+
+// config 中间件配置
+type config struct {
+	ttl            time.Duration
+	maxBodySize    int
+	varyHeaders    []string
+	cacheSetCookie bool
+	statusCodes    map[int]bool
+}
+
+// WithTTL 设置响应缓存时间，不足一秒按一秒计算
+func WithTTL(ttl time.Duration) Option {
+	return func(c *config) {
+		c.ttl = ttl
+	}
+}
+
+// WithMaxBodySize 设置可缓存的最大响应体字节数
+func WithMaxBodySize(n int) Option {
+	return func(c *config) {
+		c.maxBodySize = n
+	}
+}
+
+// WithVaryHeaders 设置参与缓存键计算的请求头
+func WithVaryHeaders(headers ...string) Option {
+	return func(c *config) {
+		for _, h := range headers {
+			c.varyHeaders = append(c.varyHeaders, http.CanonicalHeaderKey(h))
+		}
+	}
+}
+
+// WithCacheSetCookie 允许缓存带有Set-Cookie头的响应
+func WithCacheSetCookie() Option {
+	return func(c *config) {
+		c.cacheSetCookie = true
+	}
+}
+
+// WithStatusCodes 设置可缓存的响应状态码，默认只缓存200
+func WithStatusCodes(codes ...int) Option {
+	return func(c *config) {
+		c.statusCodes = make(map[int]bool, len(codes))
+		for _, code := range codes {
+			c.statusCodes[code] = true
+		}
+	}
+}
+
+// Middleware 返回缓存GET响应的中间件
+//
+// 缓存键由请求方法、URL以及可选的vary请求头组成，响应的状态码、响应头和响应体
+// 以紧凑的二进制格式存入缓存。默认不缓存带Set-Cookie或非200状态的响应。
+func Middleware(ng *ngcat.NGCache, opts ...Option) func(http.Handler) http.Handler {
+	cfg := &config{
+		ttl:         DefaultTTL,
+		maxBodySize: DefaultMaxBodySize,
+		statusCodes: map[int]bool{http.StatusOK: true},
+	}
+	for _, opt := range opts {
+		opt(cfg)
+	}
+
+	expireSeconds := int((cfg.ttl + time.Second - 1) / time.Second)
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.Method != http.MethodGet {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			key := cfg.cacheKey(r)
+			if data, err := ng.GetBytes(key); err == nil {
+				if resp, err := decodeResponse(data); err == nil {
+					resp.writeTo(w)
+					return
+				}
+			}
+
+			w.Header().Set(HeaderName, "MISS")
+			rec := &recorder{ResponseWriter: w, status: http.StatusOK, limit: cfg.maxBodySize}
+			next.ServeHTTP(rec, r)
+
+			if !cfg.cacheable(rec) {
+				return
+			}
+			resp := &cachedResponse{status: rec.status, header: w.Header().Clone(), body: rec.body.Bytes()}
+			resp.header.Del(HeaderName)
+			ng.SetBytes(key, resp.encode(), expireSeconds)
+		})
+	}
+}
+
+// cacheKey 计算请求的缓存键
+func (c *config) cacheKey(r *http.Request) string {
+	var b strings.Builder
+	b.WriteString("httpcache:")
+	b.WriteString(r.Method)
+	b.WriteByte(' ')
+	b.WriteString(r.URL.String())
+	for _, h := range c.varyHeaders {
+		b.WriteByte('\n')
+		b.WriteString(h)
+		b.WriteByte(':')
+		b.WriteString(strings.Join(r.Header.Values(h), ","))
+	}
+	return b.String()
+}
+
+// cacheable 判断响应是否可以缓存
+func (c *config) cacheable(rec *recorder) bool {
+	if rec.overflow || !c.statusCodes[rec.status] {
+		return false
+	}
+	if !c.cacheSetCookie && rec.Header().Get("Set-Cookie") != "" {
+		return false
+	}
+	return true
+}
+
+// recorder 在写出响应的同时记录状态码和响应体
+type recorder struct {
+	http.ResponseWriter
+	status      int
+	wroteHeader bool
+	body        bytes.Buffer
+	limit       int
+	overflow    bool
+}
+
+func (r *recorder) WriteHeader(status int) {
+	if !r.wroteHeader {
+		r.status = status
+		r.wroteHeader = true
+	}
+	r.ResponseWriter.WriteHeader(status)
+}
+
+func (r *recorder) Write(p []byte) (int, error) {
+	r.wroteHeader = true
+	if !r.overflow {
+		if r.body.Len()+len(p) > r.limit {
+			// 超过上限后不再缓存，释放已记录的内容
+			r.overflow = true
+			r.body = bytes.Buffer{}
+		} else {
+			r.body.Write(p)
+		}
+	}
+	return r.ResponseWriter.Write(p)
+}
+
+// cachedResponse 缓存的响应
+type cachedResponse struct {
+	status int
+	header http.Header
+	body   []byte
+}
+
+// writeTo 将缓存的响应写给客户端
+func (c *cachedResponse) writeTo(w http.ResponseWriter) {
+	h := w.Header()
+	for name, values := range c.header {
+		h[name] = values
+	}
+	h.Set(HeaderName, "HIT")
+	w.WriteHeader(c.status)
+	w.Write(c.body)
+}
+
+// encode 编码响应
+//
+// 格式: [状态码:uvarint][响应头数量:uvarint]
+// 每个响应头 [名称长度][名称][值数量]{[值长度][值]}，随后是剩余的响应体
+func (c *cachedResponse) encode() []byte {
+	names := make([]string, 0, len(c.header))
+	for name := range c.header {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	buf := make([]byte, 0, 64+len(c.body))
+	buf = binary.AppendUvarint(buf, uint64(c.status))
+	buf = binary.AppendUvarint(buf, uint64(len(names)))
+	for _, name := range names {
+		buf = appendString(buf, name)
+		values := c.header[name]
+		buf = binary.AppendUvarint(buf, uint64(len(values)))
+		for _, v := range values {
+			buf = appendString(buf, v)
+		}
+	}
+	return append(buf, c.body...)
+}
+
+// appendString 追加带长度前缀的字符串
+func appendString(buf []byte, s string) []byte {
+	buf = binary.AppendUvarint(buf, uint64(len(s)))
+	return append(buf, s...)
+}
+
+// decodeResponse 解码缓存的响应
+func decodeResponse(data []byte) (*cachedResponse, error) {
+	d := decoder{data: data}
+	resp := &cachedResponse{status: int(d.uvarint())}
+	headerCount := d.uvarint()
+	if d.err != nil || headerCount > uint64(len(data)) {
+		return nil, errCorruptResponse
+	}
+	resp.header = make(http.Header, headerCount)
+	for i := uint64(0); i < headerCount && d.err == nil; i++ {
+		name := d.string()
+		valueCount := d.uvarint()
+		if valueCount > uint64(len(data)) {
+			return nil, errCorruptResponse
+		}
+		values := make([]string, 0, valueCount)
+		for j := uint64(0); j < valueCount && d.err == nil; j++ {
+			values = append(values, d.string())
+		}
+		resp.header[name] = values
+	}
+	if d.err != nil {
+		return nil, d.err
+	}
+	resp.body = d.data
+	return resp, nil
+}
+
+// decoder 顺序读取编码数据，出错后后续读取均无效
+type decoder struct {
+	data []byte
+	err  error
+}
+
+func (d *decoder) uvarint() uint64 {
+	if d.err != nil {
+		return 0
+	}
+	v, n := binary.Uvarint(d.data)
+	if n <= 0 {
+		d.err = errCorruptResponse
+		return 0
+	}
+	d.data = d.data[n:]
+	return v
+}
+
+func (d *decoder) string() string {
+	n := d.uvarint()
+	if d.err != nil {
+		return ""
+	}
+	if n > uint64(len(d.data)) {
+		d.err = errCorruptResponse
+		return ""
+	}
+	s := string(d.data[:n])
+	d.data = d.data[n:]
+	return s
+}
@@ -0,0 +1,150 @@
+package httpcache
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"ngcat"
+)
+
+// countingHandler 返回固定响应并统计调用次数
+func countingHandler(calls *int32, status int, body string) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(calls, 1)
+		w.Header().Set("Content-Type", "text/plain")
+		w.WriteHeader(status)
+		w.Write([]byte(body))
+	})
+}
+
+func get(h http.Handler, url string) *httptest.ResponseRecorder {
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, url, nil))
+	return rec
+}
+
+func TestMiddlewareHitAndMiss(t *testing.T) {
+	ng := ngcat.NewNGCache(1024*1024, nil)
+	defer ng.Close()
+
+	var calls int32
+	h := Middleware(ng)(countingHandler(&calls, http.StatusOK, "hello"))
+
+	first := get(h, "/a")
+	if first.Header().Get(HeaderName) != "MISS" || first.Body.String() != "hello" {
+		t.Fatalf("first request: %v %q", first.Header(), first.Body.String())
+	}
+	second := get(h, "/a")
+	if second.Header().Get(HeaderName) != "HIT" || second.Body.String() != "hello" {
+		t.Fatalf("second request: %v %q", second.Header(), second.Body.String())
+	}
+	if second.Code != http.StatusOK || second.Header().Get("Content-Type") != "text/plain" {
+		t.Fatalf("cached status/header not restored: %d %v", second.Code, second.Header())
+	}
+	if get(h, "/b").Header().Get(HeaderName) != "MISS" {
+		t.Fatal("different URL should miss")
+	}
+	if calls != 2 {
+		t.Fatalf("handler called %d times, want 2", calls)
+	}
+}
+
+// manualClock 手动推进的时钟，用于控制缓存过期
+type manualClock struct {
+	mu  sync.Mutex
+	now time.Time
+}
+
+func (c *manualClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+func (c *manualClock) NewTicker(d time.Duration) ngcat.Ticker {
+	panic("not used")
+}
+
+func (c *manualClock) Add(d time.Duration) {
+	c.mu.Lock()
+	c.now = c.now.Add(d)
+	c.mu.Unlock()
+}
+
+func TestMiddlewareTTLExpiry(t *testing.T) {
+	clock := &manualClock{now: time.Unix(1700000000, 0)}
+	ng := ngcat.NewNGCache(1024*1024, nil, ngcat.WithClock(clock))
+	defer ng.Close()
+
+	var calls int32
+	h := Middleware(ng, WithTTL(time.Second))(countingHandler(&calls, http.StatusOK, "x"))
+	get(h, "/ttl")
+	if get(h, "/ttl").Header().Get(HeaderName) != "HIT" {
+		t.Fatal("expected hit before expiry")
+	}
+	clock.Add(time.Second)
+	if get(h, "/ttl").Header().Get(HeaderName) != "MISS" {
+		t.Fatal("expected miss after expiry")
+	}
+}
+
+func TestMiddlewareSizeLimit(t *testing.T) {
+	ng := ngcat.NewNGCache(1024*1024, nil)
+	defer ng.Close()
+
+	var calls int32
+	h := Middleware(ng, WithMaxBodySize(10))(countingHandler(&calls, http.StatusOK, strings.Repeat("x", 11)))
+	get(h, "/big")
+	rec := get(h, "/big")
+	if rec.Header().Get(HeaderName) != "MISS" || rec.Body.Len() != 11 {
+		t.Fatalf("oversized body should not be cached: %v %d", rec.Header(), rec.Body.Len())
+	}
+}
+
+func TestMiddlewareSkipsUncacheable(t *testing.T) {
+	ng := ngcat.NewNGCache(1024*1024, nil)
+	defer ng.Close()
+
+	var calls int32
+	h := Middleware(ng)(countingHandler(&calls, http.StatusNotFound, "nope"))
+	get(h, "/404")
+	if get(h, "/404").Header().Get(HeaderName) != "MISS" {
+		t.Fatal("non-200 response should not be cached")
+	}
+
+	cookie := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Set-Cookie", "session=1")
+		w.Write([]byte("private"))
+	})
+	h = Middleware(ng)(cookie)
+	get(h, "/cookie")
+	if get(h, "/cookie").Header().Get(HeaderName) != "MISS" {
+		t.Fatal("Set-Cookie response should not be cached")
+	}
+	h = Middleware(ng, WithCacheSetCookie())(cookie)
+	get(h, "/cookie2")
+	if get(h, "/cookie2").Header().Get(HeaderName) != "HIT" {
+		t.Fatal("WithCacheSetCookie should allow caching")
+	}
+}
+
+func TestMiddlewareVaryHeaders(t *testing.T) {
+	ng := ngcat.NewNGCache(1024*1024, nil)
+	defer ng.Close()
+
+	var calls int32
+	h := Middleware(ng, WithVaryHeaders("accept-language"))(countingHandler(&calls, http.StatusOK, "x"))
+	for _, lang := range []string{"en", "zh", "en"} {
+		req := httptest.NewRequest(http.MethodGet, "/vary", nil)
+		req.Header.Set("Accept-Language", lang)
+		h.ServeHTTP(httptest.NewRecorder(), req)
+	}
+	if calls != 2 {
+		t.Fatalf("handler called %d times, want 2", calls)
+	}
+}
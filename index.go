@@ -0,0 +1,193 @@
+package ngcat
+
+import (
+	"bytes"
+	"encoding/binary"
+	"hash/fnv"
+	"io"
+	"sort"
+)
+
+// 二进制格式的索引写在所有条目之后，保存时仍只需顺序写一遍：
+//
+//	[键哈希:8字节][条目偏移:8字节]...  按键哈希升序，哈希相同时保持条目在文件中的顺序
+//	[索引偏移:8字节][索引条目数:4字节][索引魔数:4字节]
+//
+// 文件头的FormatCaps带有CapIndex时才有索引。顺序读取只读到条目数量为止，不受索引影响。
+const (
+	// binaryIndexMagic 索引尾部的魔数
+	binaryIndexMagic = 0x4E474349 // "NGCI"
+	// binaryIndexSlotSize 索引中每个条目的字节数
+	binaryIndexSlotSize = 8 + 8
+	// binaryIndexTrailerSize 索引尾部的字节数
+	binaryIndexTrailerSize = 8 + 4 + 4
+)
+
+// indexSlot 索引中的一个条目
+type indexSlot struct {
+	hash   uint64
+	offset int64
+}
+
+// indexKeyHash 索引使用的键哈希，需要跨进程稳定，因此使用FNV-1a而不是maphash
+func indexKeyHash(key string) uint64 {
+	h := fnv.New64a()
+	h.Write([]byte(key))
+	return h.Sum64()
+}
+
+// binaryIndexSize 包含n个条目的索引（含尾部）的字节数
+func binaryIndexSize(n int) int {
+	return n*binaryIndexSlotSize + binaryIndexTrailerSize
+}
+
+// putBinaryIndex 将索引按哈希排序后编码到buf，buf长度必须为binaryIndexSize(len(slots))
+func putBinaryIndex(buf []byte, slots []indexSlot, indexOffset int64) {
+	sort.SliceStable(slots, func(i, j int) bool { return slots[i].hash < slots[j].hash })
+	off := 0
+	for _, slot := range slots {
+		binary.LittleEndian.PutUint64(buf[off:], slot.hash)
+		binary.LittleEndian.PutUint64(buf[off+8:], uint64(slot.offset))
+		off += binaryIndexSlotSize
+	}
+	binary.LittleEndian.PutUint64(buf[off:], uint64(indexOffset))
+	binary.LittleEndian.PutUint32(buf[off+8:], uint32(len(slots)))
+	binary.LittleEndian.PutUint32(buf[off+12:], binaryIndexMagic)
+}
+
+// readIndexedEntry 通过索引读取一个键的值，只读取文件头、索引尾部、二分查找经过的索引条目和匹配的条目
+//
+// 文件没有索引（JSON格式、旧版本或未设置CapIndex）时indexed为false，调用方应改为顺序读取。
+// 与顺序读取一致，同一键出现多次时返回最后一次的值。
+func readIndexedEntry(r io.ReaderAt, size int64, key string) (value []byte, found, indexed bool, err error) {
+	idx, err := openBinaryIndex(r, size)
+	if err != nil {
+		return nil, false, true, err
+	}
+	if idx == nil {
+		return nil, false, false, nil
+	}
+	value, found, err = idx.lookup(key)
+	return value, found, true, err
+}
+
+// binaryIndex 已校验过文件头和尾部的索引，lookup可以并发调用
+type binaryIndex struct {
+	r io.ReaderAt
+	// indexOffset 索引的起始偏移，也是条目部分的结束位置
+	indexOffset int64
+	// n 索引条目数
+	n int64
+}
+
+// openBinaryIndex 校验文件头和索引尾部，文件没有索引时返回nil和nil
+func openBinaryIndex(r io.ReaderAt, size int64) (*binaryIndex, error) {
+	var header [binaryCountOffset + 4]byte
+	if _, err := r.ReadAt(header[:], 0); err != nil {
+		return nil, nil
+	}
+	if binary.LittleEndian.Uint32(header[0:]) != BinaryMagic ||
+		binary.LittleEndian.Uint32(header[4:]) != BinaryVersion {
+		return nil, nil
+	}
+	caps := binary.LittleEndian.Uint32(header[8:])
+	if caps&^knownFormatCaps != 0 || caps&CapIndex == 0 {
+		return nil, nil
+	}
+
+	var trailer [binaryIndexTrailerSize]byte
+	if size < int64(len(header)+len(trailer)) {
+		return nil, corruptf("binary index: file too short")
+	}
+	if _, err := r.ReadAt(trailer[:], size-binaryIndexTrailerSize); err != nil {
+		return nil, newError(CodeCorruptFile, "read index trailer", err)
+	}
+	indexOffset := int64(binary.LittleEndian.Uint64(trailer[0:]))
+	n := int64(binary.LittleEndian.Uint32(trailer[8:]))
+	if binary.LittleEndian.Uint32(trailer[12:]) != binaryIndexMagic ||
+		indexOffset < int64(len(header)) || indexOffset+n*binaryIndexSlotSize+binaryIndexTrailerSize != size {
+		return nil, corruptf("binary index: invalid trailer")
+	}
+	return &binaryIndex{r: r, indexOffset: indexOffset, n: n}, nil
+}
+
+// lookup 二分查找键的哈希并逐个比较哈希相同的条目，同一键出现多次时返回最后一次的值
+func (idx *binaryIndex) lookup(key string) ([]byte, bool, error) {
+	var slot [binaryIndexSlotSize]byte
+	readSlot := func(i int64) (uint64, int64, error) {
+		if _, err := idx.r.ReadAt(slot[:], idx.indexOffset+i*binaryIndexSlotSize); err != nil {
+			return 0, 0, newError(CodeCorruptFile, "read index slot", err)
+		}
+		return binary.LittleEndian.Uint64(slot[0:]), int64(binary.LittleEndian.Uint64(slot[8:])), nil
+	}
+
+	hash := indexKeyHash(key)
+	var searchErr error
+	first := int64(sort.Search(int(idx.n), func(i int) bool {
+		h, _, err := readSlot(int64(i))
+		if err != nil {
+			searchErr = err
+			return true
+		}
+		return h >= hash
+	}))
+	if searchErr != nil {
+		return nil, false, searchErr
+	}
+
+	// 哈希相同的条目按文件中的顺序排列，逐个比较键
+	var value []byte
+	found := false
+	for i := first; i < idx.n; i++ {
+		h, offset, err := readSlot(i)
+		if err != nil {
+			return nil, false, err
+		}
+		if h != hash {
+			break
+		}
+		v, ok, err := readEntryAt(idx.r, offset, idx.indexOffset, key)
+		if err != nil {
+			return nil, false, err
+		}
+		if ok {
+			value, found = v, true
+		}
+	}
+	return value, found, nil
+}
+
+// readEntryAt 读取offset处的条目，键等于key时返回值，条目不能越过limit
+func readEntryAt(r io.ReaderAt, offset, limit int64, key string) ([]byte, bool, error) {
+	var lenBuf [4]byte
+	if _, err := r.ReadAt(lenBuf[:], offset); err != nil {
+		return nil, false, newError(CodeCorruptFile, "read indexed key length", err)
+	}
+	keyLen := int64(binary.LittleEndian.Uint32(lenBuf[:]))
+	if keyLen != int64(len(key)) {
+		return nil, false, nil
+	}
+	if offset+4+keyLen+4 > limit {
+		return nil, false, corruptf("binary index: entry at %d exceeds entries section", offset)
+	}
+	keyBuf := make([]byte, keyLen)
+	if _, err := r.ReadAt(keyBuf, offset+4); err != nil {
+		return nil, false, newError(CodeCorruptFile, "read indexed key", err)
+	}
+	if !bytes.Equal(keyBuf, []byte(key)) {
+		return nil, false, nil
+	}
+
+	if _, err := r.ReadAt(lenBuf[:], offset+4+keyLen); err != nil {
+		return nil, false, newError(CodeCorruptFile, "read indexed value length", err)
+	}
+	valueLen := int64(binary.LittleEndian.Uint32(lenBuf[:]))
+	if valueLen > MaxPersistValueSize || offset+4+keyLen+4+valueLen > limit {
+		return nil, false, corruptf("binary index: entry at %d exceeds entries section", offset)
+	}
+	value := make([]byte, valueLen)
+	if _, err := r.ReadAt(value, offset+4+keyLen+4); err != nil {
+		return nil, false, newError(CodeCorruptFile, "read indexed value", err)
+	}
+	return value, true, nil
+}
@@ -0,0 +1,106 @@
+package ngcat
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+)
+
+// countingReaderAt 统计通过ReadAt读取的字节数
+type countingReaderAt struct {
+	r    io.ReaderAt
+	read atomic.Int64
+}
+
+func (c *countingReaderAt) ReadAt(p []byte, off int64) (int, error) {
+	n, err := c.r.ReadAt(p, off)
+	c.read.Add(int64(n))
+	return n, err
+}
+
+func TestIndexedReadEntry(t *testing.T) {
+	const n = 100000
+	entries := make([]PersistEntry, 0, n+1)
+	for i := 0; i < n; i++ {
+		entries = append(entries, PersistEntry{Key: fmt.Sprintf("key%06d", i), Value: []byte(fmt.Sprintf("value%06d", i))})
+	}
+	// 同一键出现多次时取最后一次
+	entries = append(entries, PersistEntry{Key: "key000042", Value: []byte("latest")})
+	path := filepath.Join(t.TempDir(), "cache.bin")
+	data := &PersistData{Timestamp: 1700000000, Entries: entries}
+	if err := writePersistFile(context.Background(), path, FormatBinary, data, false); err != nil {
+		t.Fatal(err)
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+	info, _ := file.Stat()
+	counter := &countingReaderAt{r: file}
+	value, found, indexed, err := readIndexedEntry(counter, info.Size(), "key077777")
+	if err != nil || !found || !indexed || string(value) != "value077777" {
+		t.Fatalf("readIndexedEntry = %q, %v, %v, %v", value, found, indexed, err)
+	}
+	// 文件头、索引尾部、约17次二分查找和一个条目
+	if read := counter.read.Load(); read > 1024 || info.Size() < 1024*1024 {
+		t.Fatalf("read %d of %d bytes", read, info.Size())
+	}
+
+	if v, err := ReadEntry(path, "key000042"); err != nil || string(v) != "latest" {
+		t.Fatalf("duplicate key = %q, %v", v, err)
+	}
+	if _, err := ReadEntry(path, "missing"); err != ErrKeyNotFound {
+		t.Fatalf("missing key err = %v", err)
+	}
+	summary, err := InspectPersistFile(path)
+	if err != nil || summary.FormatCaps&CapIndex == 0 || summary.Entries != n+1 {
+		t.Fatalf("summary = %+v, %v", summary, err)
+	}
+}
+
+func TestReadEntryWithoutIndex(t *testing.T) {
+	path := writeV1File(t, []PersistEntry{{Key: "a", Value: []byte("1")}, {Key: "b", Value: []byte("2")}})
+	file, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	info, _ := file.Stat()
+	_, _, indexed, err := readIndexedEntry(file, info.Size(), "b")
+	file.Close()
+	if indexed || err != nil {
+		t.Fatalf("v1 file should not be indexed: %v, %v", indexed, err)
+	}
+	if v, err := ReadEntry(path, "b"); err != nil || string(v) != "2" {
+		t.Fatalf("b = %q, %v", v, err)
+	}
+}
+
+func TestIndexedMMapFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "cache.mmap")
+	nc := NewNGCache(1024*1024, nil)
+	defer nc.Close()
+	for i := 0; i < 100; i++ {
+		nc.SetString(fmt.Sprintf("k%d", i), fmt.Sprintf("v%d", i), 0)
+	}
+	if err := writeMMapFile(nc.ctx, path, nc.collectPersistData()); err != nil {
+		t.Fatal(err)
+	}
+	if v, err := ReadEntry(path, "k57"); err != nil || string(v) != "v57" {
+		t.Fatalf("k57 = %q, %v", v, err)
+	}
+
+	other := NewNGCache(1024*1024, nil)
+	defer other.Close()
+	if err := other.Import(path, FormatBinary); err != nil {
+		t.Fatal(err)
+	}
+	if v, _ := other.GetString("k99"); v != "v99" {
+		t.Fatalf("k99 = %q", v)
+	}
+}
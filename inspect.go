@@ -0,0 +1,250 @@
+package ngcat
+
+import (
+	"bufio"
+	"container/heap"
+	"encoding/binary"
+	"errors"
+	"io"
+	"os"
+	"sort"
+	"strings"
+)
+
+// 文件摘要的统计规模
+const (
+	// inspectTopEntries 摘要中列出的最大条目数量
+	inspectTopEntries = 10
+	// inspectTopPrefixes 摘要中列出的键前缀数量
+	inspectTopPrefixes = 10
+	// inspectMaxPrefixes 统计的不同前缀数量上限，超过后新前缀计入OtherPrefixes
+	inspectMaxPrefixes = 1024
+)
+
+// EntrySize 条目的键和值长度
+type EntrySize struct {
+	Key  string
+	Size int
+}
+
+// PrefixCount 键前缀及其条目数量
+type PrefixCount struct {
+	Prefix string
+	Count  int
+}
+
+// FileSummary 持久化文件摘要
+type FileSummary struct {
+	// Format 检测到的文件格式
+	Format PersistFormat
+	// Version 文件格式版本
+	Version int
+	// FormatCaps 二进制格式文件头中的特性位，JSON格式为0
+	FormatCaps uint32
+	// Timestamp 文件写入时间戳
+	Timestamp int64
+	// Entries 条目数量
+	Entries int
+	// Corrupt 跳过的损坏条目数量
+	Corrupt int
+	// TotalBytes 文件大小
+	TotalBytes int64
+	// Largest 值最大的若干条目，按值长度降序
+	Largest []EntrySize
+	// Prefixes 条目最多的若干键前缀（第一个':'或'/'之前的部分，没有分隔符的键计入空前缀）
+	Prefixes []PrefixCount
+	// OtherPrefixes 因不同前缀过多而未单独统计的条目数量
+	OtherPrefixes int
+}
+
+// sizeHeap 按值长度排序的最小堆，用于保留最大的若干条目
+type sizeHeap []EntrySize
+
+func (h sizeHeap) Len() int            { return len(h) }
+func (h sizeHeap) Less(i, j int) bool  { return h[i].Size < h[j].Size }
+func (h sizeHeap) Swap(i, j int)       { h[i], h[j] = h[j], h[i] }
+func (h *sizeHeap) Push(x interface{}) { *h = append(*h, x.(EntrySize)) }
+func (h *sizeHeap) Pop() interface{} {
+	old := *h
+	x := old[len(old)-1]
+	*h = old[:len(old)-1]
+	return x
+}
+
+// InspectPersistFile 流式读取持久化文件并返回摘要，自动识别JSON和二进制格式
+//
+// 内存占用与条目数量无关，只与单个最大条目和前缀统计上限有关。
+func InspectPersistFile(path string) (*FileSummary, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, newError(CodeOpenFile, path, err)
+	}
+	defer file.Close()
+
+	info, err := file.Stat()
+	if err != nil {
+		return nil, newError(CodeStatFile, path, err)
+	}
+	pr, err := openPersistFile(file)
+	if err != nil {
+		return nil, err
+	}
+
+	summary := &FileSummary{
+		Format:     pr.format,
+		Version:    pr.version,
+		FormatCaps: pr.caps,
+		Timestamp:  pr.timestamp,
+		TotalBytes: info.Size(),
+	}
+	var largest sizeHeap
+	prefixes := make(map[string]int)
+	err = eachPersistEntry(pr, func(entry PersistEntry) {
+		summary.Entries++
+
+		if len(largest) < inspectTopEntries {
+			heap.Push(&largest, EntrySize{Key: entry.Key, Size: len(entry.Value)})
+		} else if len(entry.Value) > largest[0].Size {
+			largest[0] = EntrySize{Key: entry.Key, Size: len(entry.Value)}
+			heap.Fix(&largest, 0)
+		}
+
+		prefix := keyPrefix(entry.Key)
+		if _, ok := prefixes[prefix]; ok || len(prefixes) < inspectMaxPrefixes {
+			prefixes[prefix]++
+		} else {
+			summary.OtherPrefixes++
+		}
+	}, &summary.Corrupt)
+	if err != nil {
+		return nil, err
+	}
+
+	sort.Slice(largest, func(i, j int) bool { return largest[i].Size > largest[j].Size })
+	summary.Largest = largest
+	for prefix, count := range prefixes {
+		summary.Prefixes = append(summary.Prefixes, PrefixCount{Prefix: prefix, Count: count})
+	}
+	sort.Slice(summary.Prefixes, func(i, j int) bool {
+		a, b := summary.Prefixes[i], summary.Prefixes[j]
+		if a.Count != b.Count {
+			return a.Count > b.Count
+		}
+		return a.Prefix < b.Prefix
+	})
+	if len(summary.Prefixes) > inspectTopPrefixes {
+		summary.Prefixes = summary.Prefixes[:inspectTopPrefixes]
+	}
+	return summary, nil
+}
+
+// ReadEntry 直接从持久化文件中读取一个键的值，自动识别格式，键不存在时返回ErrKeyNotFound
+//
+// 带索引的二进制文件只读取索引中查找经过的部分和匹配的条目，其他文件顺序读取。
+// 与加载时的行为一致，同一键出现多次时返回最后一次的值。
+func ReadEntry(path, key string) ([]byte, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, newError(CodeOpenFile, path, err)
+	}
+	defer file.Close()
+
+	info, err := file.Stat()
+	if err != nil {
+		return nil, newError(CodeStatFile, path, err)
+	}
+	if value, found, indexed, err := readIndexedEntry(file, info.Size(), key); indexed {
+		if err != nil {
+			return nil, err
+		}
+		if !found {
+			return nil, ErrKeyNotFound
+		}
+		return value, nil
+	}
+
+	pr, err := openPersistFile(file)
+	if err != nil {
+		return nil, err
+	}
+	var value []byte
+	found := false
+	err = eachPersistEntry(pr, func(entry PersistEntry) {
+		if entry.Key == key {
+			value, found = entry.Value, true
+		}
+	}, nil)
+	if err != nil {
+		return nil, err
+	}
+	if !found {
+		return nil, ErrKeyNotFound
+	}
+	return value, nil
+}
+
+// openPersistFile 根据文件开头识别格式并创建读取器
+func openPersistFile(r io.Reader) (*persistReader, error) {
+	br := bufio.NewReader(r)
+	format, err := detectPersistFormat(br)
+	if err != nil {
+		return nil, err
+	}
+	return newPersistReader(br, format)
+}
+
+// detectPersistFormat 识别持久化文件格式：以二进制魔数开头为二进制格式，以'{'开头为JSON格式
+func detectPersistFormat(br *bufio.Reader) (PersistFormat, error) {
+	head, err := br.Peek(4)
+	if len(head) == 4 && binary.LittleEndian.Uint32(head) == BinaryMagic {
+		return FormatBinary, nil
+	}
+	for {
+		b, err := br.Peek(1)
+		if err != nil {
+			break
+		}
+		switch b[0] {
+		case ' ', '\t', '\r', '\n':
+			br.ReadByte()
+			continue
+		case '{':
+			return FormatJSON, nil
+		}
+		break
+	}
+	if err != nil && err != io.EOF {
+		return 0, newError(CodeReadFile, "", err)
+	}
+	return 0, newError(CodeUnknownFormat, "", nil)
+}
+
+// eachPersistEntry 逐条读取条目，跳过可恢复的损坏条目并计入corrupt
+func eachPersistEntry(pr *persistReader, fn func(entry PersistEntry), corrupt *int) error {
+	for {
+		entry, err := pr.next()
+		if err == io.EOF {
+			return nil
+		}
+		var ce *corruptEntryError
+		if errors.As(err, &ce) && !ce.fatal {
+			if corrupt != nil {
+				*corrupt++
+			}
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		fn(entry)
+	}
+}
+
+// keyPrefix 键在第一个':'或'/'之前的部分，没有分隔符时为空
+func keyPrefix(key string) string {
+	i := strings.IndexAny(key, ":/")
+	if i < 0 {
+		return ""
+	}
+	return key[:i]
+}
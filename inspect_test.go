@@ -0,0 +1,90 @@
+package ngcat
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// inspectFixture 写出包含多个前缀和一个数MB大条目的快照
+func inspectFixture(t *testing.T, format PersistFormat) (string, []byte) {
+	t.Helper()
+	big := bytes.Repeat([]byte{0xAB}, 3*1024*1024)
+	data := &PersistData{Timestamp: 1700000000}
+	for i := 0; i < 30; i++ {
+		data.Entries = append(data.Entries, PersistEntry{Key: fmt.Sprintf("user:%d", i), Value: []byte("u")})
+	}
+	for i := 0; i < 5; i++ {
+		data.Entries = append(data.Entries, PersistEntry{Key: fmt.Sprintf("session/%d", i), Value: make([]byte, 100*(i+1))})
+	}
+	data.Entries = append(data.Entries,
+		PersistEntry{Key: "blob:big", Value: big},
+		PersistEntry{Key: "plain", Value: []byte("p")},
+	)
+
+	path := filepath.Join(t.TempDir(), "snapshot")
+	if err := writePersistFile(context.Background(), path, format, data, false); err != nil {
+		t.Fatal(err)
+	}
+	return path, big
+}
+
+func TestInspectPersistFile(t *testing.T) {
+	for name, format := range map[string]PersistFormat{"json": FormatJSON, "binary": FormatBinary} {
+		t.Run(name, func(t *testing.T) {
+			version := JSONVersion
+			if format == FormatBinary {
+				version = BinaryVersion
+			}
+			path, _ := inspectFixture(t, format)
+			summary, err := InspectPersistFile(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			info, _ := os.Stat(path)
+			if summary.Format != format || summary.Version != version || summary.Timestamp != 1700000000 {
+				t.Fatalf("header = %+v", summary)
+			}
+			if summary.Entries != 37 || summary.TotalBytes != info.Size() {
+				t.Fatalf("entries = %d, bytes = %d", summary.Entries, summary.TotalBytes)
+			}
+			if summary.Largest[0].Key != "blob:big" || summary.Largest[0].Size != 3*1024*1024 {
+				t.Fatalf("largest = %v", summary.Largest[0])
+			}
+			if summary.Largest[1].Key != "session/4" || len(summary.Largest) != inspectTopEntries {
+				t.Fatalf("largest = %v", summary.Largest)
+			}
+			want := []PrefixCount{{"user", 30}, {"session", 5}, {"", 1}, {"blob", 1}}
+			if fmt.Sprint(summary.Prefixes) != fmt.Sprint(want) {
+				t.Fatalf("prefixes = %v", summary.Prefixes)
+			}
+		})
+	}
+}
+
+func TestReadEntry(t *testing.T) {
+	for name, format := range map[string]PersistFormat{"json": FormatJSON, "binary": FormatBinary} {
+		t.Run(name, func(t *testing.T) {
+			path, big := inspectFixture(t, format)
+			value, err := ReadEntry(path, "blob:big")
+			if err != nil || !bytes.Equal(value, big) {
+				t.Fatalf("ReadEntry(blob:big) = %d bytes, %v", len(value), err)
+			}
+			if value, _ := ReadEntry(path, "user:7"); string(value) != "u" {
+				t.Fatalf("ReadEntry(user:7) = %q", value)
+			}
+			if _, err := ReadEntry(path, "missing"); err != ErrKeyNotFound {
+				t.Fatalf("expected ErrKeyNotFound, got %v", err)
+			}
+		})
+	}
+
+	garbage := filepath.Join(t.TempDir(), "garbage")
+	os.WriteFile(garbage, []byte("not a snapshot"), 0644)
+	if _, err := InspectPersistFile(garbage); err == nil {
+		t.Fatal("expected an unknown format error")
+	}
+}
@@ -0,0 +1,46 @@
+// Package benchutil 提供基准测试共用的预生成数据，避免在计时循环中生成键和值
+package benchutil
+
+import (
+	"fmt"
+)
+
+// TestStruct 序列化基准测试使用的结构体
+type TestStruct struct {
+	ID   int    `json:"id"`
+	Name string `json:"name"`
+	Age  int    `json:"age"`
+	Data []byte `json:"data"`
+}
+
+// Keys 生成n个形如prefix_i的键
+func Keys(prefix string, n int) []string {
+	keys := make([]string, n)
+	for i := range keys {
+		keys[i] = fmt.Sprintf("%s_%d", prefix, i)
+	}
+	return keys
+}
+
+// Strings 生成n个字符串值
+func Strings(n int) []string {
+	values := make([]string, n)
+	for i := range values {
+		values[i] = fmt.Sprintf("test_value_%d", i)
+	}
+	return values
+}
+
+// Structs 生成n个带100字节数据的结构体
+func Structs(n int) []TestStruct {
+	values := make([]TestStruct, n)
+	for i := range values {
+		values[i] = TestStruct{
+			ID:   i,
+			Name: fmt.Sprintf("用户_%d", i),
+			Age:  20 + i%50,
+			Data: make([]byte, 100),
+		}
+	}
+	return values
+}
@@ -0,0 +1,166 @@
+package ngcat
+
+import (
+	"bytes"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// ForEachWithExpire 遍历所有存活条目及其剩余过期时间，永久缓存的剩余时间为0
+//
+// 同一键只访问一次，fn返回false时停止遍历。遍历期间写入的条目可能被访问也可能不被访问。
+func (ng *NGCache) ForEachWithExpire(fn func(key string, value []byte, remainingTTL time.Duration) bool) error {
+	now := ng.clock.Now().Unix()
+	ng.forEachEntry(func(key string, value []byte, expireAt uint32) bool {
+		var remaining time.Duration
+		if expireAt != 0 {
+			if int64(expireAt) <= now {
+				return true
+			}
+			remaining = time.Duration(int64(expireAt)-now) * time.Second
+		}
+		return fn(key, value, remaining)
+	})
+	return nil
+}
+
+// forEachEntry 遍历freecache与持久化数据中的所有存活条目
+//
+// value为解压后的值，无法解压的条目会被跳过；expireAt为过期时间的Unix秒数，
+// 0表示永久缓存；fn返回false时停止遍历。
+// freecache按分段加锁遍历，随后只补充已被淘汰、仅存在于持久化数据中的永久缓存，
+// 遍历期间不会长时间持有任何一把锁。
+func (ng *NGCache) forEachEntry(fn func(key string, value []byte, expireAt uint32) bool) {
+	ng.scanEntries("", fn)
+}
+
+// scanEntries 与forEachEntry相同，但只访问以prefix开头的键，其他键不转换、不解压
+func (ng *NGCache) scanEntries(prefix string, fn func(key string, value []byte, expireAt uint32) bool) {
+	prefixBytes := []byte(prefix)
+	stopped := false
+	ng.storeRange(func(key, stored []byte, expireAt uint32) bool {
+		if !bytes.HasPrefix(key, prefixBytes) {
+			return true
+		}
+		value, err := ng.decodeValue(stored)
+		if err != nil {
+			return true
+		}
+		stopped = !fn(string(key), value, expireAt)
+		return !stopped
+	})
+	if stopped {
+		return
+	}
+
+	for _, key := range ng.persistKeys(prefix) {
+		if _, err := ng.storePeek(key); err == nil {
+			continue // 已在freecache遍历中访问过
+		}
+		ng.persistDataMutex.RLock()
+		value, exists := ng.persistData[key]
+		ng.persistDataMutex.RUnlock()
+		if !exists {
+			continue
+		}
+		value, err := ng.decodeValue(value)
+		if err == nil && !fn(key, value, 0) {
+			return
+		}
+	}
+}
+
+// scanKeys 与scanEntries相同，但只访问键和过期时间，值不会被解码或解压，无法解压的条目也会被访问
+func (ng *NGCache) scanKeys(prefix string, fn func(key string, expireAt uint32) bool) {
+	prefixBytes := []byte(prefix)
+	stopped := false
+	ng.storeRange(func(key, _ []byte, expireAt uint32) bool {
+		if !bytes.HasPrefix(key, prefixBytes) {
+			return true
+		}
+		stopped = !fn(string(key), expireAt)
+		return !stopped
+	})
+	if stopped {
+		return
+	}
+
+	for _, key := range ng.persistKeys(prefix) {
+		if _, err := ng.cache.TTL([]byte(key)); err == nil {
+			continue // 已在freecache遍历中访问过
+		}
+		ng.persistDataMutex.RLock()
+		_, exists := ng.persistData[key]
+		ng.persistDataMutex.RUnlock()
+		if exists && !fn(key, 0) {
+			return
+		}
+	}
+}
+
+// persistKeys 返回持久化数据中以prefix开头的键
+func (ng *NGCache) persistKeys(prefix string) []string {
+	ng.persistDataMutex.RLock()
+	defer ng.persistDataMutex.RUnlock()
+	keys := make([]string, 0, len(ng.persistData))
+	for key := range ng.persistData {
+		if strings.HasPrefix(key, prefix) {
+			keys = append(keys, key)
+		}
+	}
+	return keys
+}
+
+// ScanPrefix 遍历所有以prefix开头的存活条目（包括只存在于持久化数据中的永久缓存），fn返回false时停止
+//
+// 不匹配的键在遍历中直接跳过，不会复制或解压其值。
+func (ng *NGCache) ScanPrefix(prefix string, fn func(key string, value []byte) bool) error {
+	ng.scanEntries(prefix, func(key string, value []byte, expireAt uint32) bool {
+		return fn(key, value)
+	})
+	return nil
+}
+
+// Filter 遍历所有存活条目（包括只存在于持久化数据中的永久缓存），返回predicate为true的键
+func (ng *NGCache) Filter(predicate func(key string, value []byte) bool) ([]string, error) {
+	var keys []string
+	ng.forEachEntry(func(key string, value []byte, expireAt uint32) bool {
+		if predicate(key, value) {
+			keys = append(keys, key)
+		}
+		return true
+	})
+	return keys, nil
+}
+
+// KeysMatching 返回freecache与持久化数据中匹配正则表达式pattern的所有键
+//
+// 编译后的正则表达式按pattern缓存，重复调用不会重新编译。只匹配键，不会解压任何值。pattern无效时返回编译错误。
+func (ng *NGCache) KeysMatching(pattern string) ([]string, error) {
+	re, err := ng.compilePattern(pattern)
+	if err != nil {
+		return nil, err
+	}
+	var keys []string
+	ng.scanKeys("", func(key string, expireAt uint32) bool {
+		if re.MatchString(key) {
+			keys = append(keys, key)
+		}
+		return true
+	})
+	return keys, nil
+}
+
+// compilePattern 编译并缓存正则表达式
+func (ng *NGCache) compilePattern(pattern string) (*regexp.Regexp, error) {
+	if re, ok := ng.patterns.Load(pattern); ok {
+		return re.(*regexp.Regexp), nil
+	}
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil, newError(CodeInvalidPattern, pattern, err)
+	}
+	ng.patterns.Store(pattern, re)
+	return re, nil
+}
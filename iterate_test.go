@@ -0,0 +1,187 @@
+package ngcat
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp/syntax"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestForEachWithExpire(t *testing.T) {
+	clock := newFakeClock()
+	nc := NewNGCache(1024*1024, nil, WithClock(clock))
+	defer nc.Close()
+
+	nc.SetString("permanent", "p", 0)
+	nc.SetString("ttl", "t", 100)
+	nc.SetString("expired", "e", 5)
+	// 被淘汰、只剩持久化数据的永久缓存
+	nc.SetString("evicted", "x", 0)
+	nc.cache.Del([]byte("evicted"))
+	clock.Add(10 * time.Second)
+
+	got := make(map[string]time.Duration)
+	err := nc.ForEachWithExpire(func(key string, value []byte, remainingTTL time.Duration) bool {
+		got[key] = remainingTTL
+		return true
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]time.Duration{"permanent": 0, "ttl": 90 * time.Second, "evicted": 0}
+	if len(got) != len(want) {
+		t.Fatalf("visited %v", got)
+	}
+	for key, ttl := range want {
+		if got[key] != ttl {
+			t.Fatalf("%s: remaining %v, want %v", key, got[key], ttl)
+		}
+	}
+
+	visited := 0
+	nc.ForEachWithExpire(func(string, []byte, time.Duration) bool {
+		visited++
+		return false
+	})
+	if visited != 1 {
+		t.Fatalf("iteration did not stop: %d", visited)
+	}
+}
+
+func TestFilter(t *testing.T) {
+	nc := NewNGCache(1024*1024, nil)
+	defer nc.Close()
+
+	type account struct {
+		Status string `json:"status"`
+	}
+	nc.SetJSON("user:1", account{Status: "active"}, 0)
+	nc.SetJSON("user:2", account{Status: "disabled"}, 0)
+	nc.SetJSON("user:3", account{Status: "active"}, 60)
+	nc.SetString("raw", "not json", 0)
+	nc.SetJSON("user:4", account{Status: "active"}, 0)
+	nc.cache.Del([]byte("user:4"))
+
+	keys, err := nc.Filter(func(key string, value []byte) bool {
+		var a account
+		return json.Unmarshal(value, &a) == nil && a.Status == "active"
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	sort.Strings(keys)
+	if fmt.Sprint(keys) != "[user:1 user:3 user:4]" {
+		t.Fatalf("Filter = %v", keys)
+	}
+}
+
+func TestKeysMatching(t *testing.T) {
+	nc := NewNGCache(1024*1024, nil)
+	defer nc.Close()
+
+	nc.SetString("user:1:session", "a", 60)
+	nc.SetString("user:2:session", "b", 0)
+	nc.SetString("user:2:profile", "c", 0)
+	nc.SetString("user:3:session", "d", 0)
+	nc.cache.Del([]byte("user:3:session"))
+
+	for i := 0; i < 2; i++ {
+		keys, err := nc.KeysMatching("^user:.*:session$")
+		if err != nil {
+			t.Fatal(err)
+		}
+		sort.Strings(keys)
+		if fmt.Sprint(keys) != "[user:1:session user:2:session user:3:session]" {
+			t.Fatalf("KeysMatching = %v", keys)
+		}
+	}
+
+	_, err := nc.KeysMatching("user:(")
+	var syntaxErr *syntax.Error
+	if !errors.As(err, &syntaxErr) {
+		t.Fatalf("expected a wrapped regexp error, got %v", err)
+	}
+}
+
+func TestKeysMatchingSkipsValues(t *testing.T) {
+	nc := NewNGCache(1024*1024, nil, WithCompressValuesOver(16))
+	defer nc.Close()
+	nc.SetString("user:1", strings.Repeat("x", 1024), 0)
+	// gzip头部之后的数据已损坏，解压会失败；只匹配键时不应读取它
+	nc.cache.Set([]byte("user:2"), []byte{valueHeaderGzip, 1, 2, 3}, 0)
+
+	keys, err := nc.KeysMatching("^user:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	sort.Strings(keys)
+	if fmt.Sprint(keys) != "[user:1 user:2]" {
+		t.Fatalf("KeysMatching = %v", keys)
+	}
+	if keys := nc.allKeys(); fmt.Sprint(keys) != "[user:1 user:2]" {
+		t.Fatalf("allKeys = %v", keys)
+	}
+}
+
+func TestScanPrefix(t *testing.T) {
+	nc := NewNGCache(1024*1024, nil)
+	defer nc.Close()
+
+	for i := 0; i < 10; i++ {
+		nc.SetString(fmt.Sprintf("session:%d", i), "s", 60)
+		nc.SetString(fmt.Sprintf("user:%d", i), "u", 0)
+	}
+	nc.SetString("session:perm", "p", 0)
+	nc.cache.Del([]byte("session:perm"))
+
+	var keys []string
+	err := nc.ScanPrefix("session:", func(key string, value []byte) bool {
+		keys = append(keys, key+"="+string(value))
+		return true
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(keys) != 11 {
+		t.Fatalf("ScanPrefix visited %d keys: %v", len(keys), keys)
+	}
+	for _, kv := range keys {
+		if kv[:8] != "session:" {
+			t.Fatalf("non-matching key visited: %s", kv)
+		}
+	}
+
+	visited := 0
+	nc.ScanPrefix("user:", func(key string, value []byte) bool {
+		visited++
+		return visited < 3
+	})
+	if visited != 3 {
+		t.Fatalf("ScanPrefix should stop when fn returns false, visited %d", visited)
+	}
+}
+
+func BenchmarkScanPrefix(b *testing.B) {
+	nc := NewNGCache(64*1024*1024, nil)
+	defer nc.Close()
+	for i := 0; i < 100000; i++ {
+		nc.SetString(fmt.Sprintf("other:%d", i), "value", 0)
+	}
+	for i := 0; i < 100; i++ {
+		nc.SetString(fmt.Sprintf("session:%d", i), "value", 60)
+	}
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		n := 0
+		nc.ScanPrefix("session:", func(key string, value []byte) bool {
+			n++
+			return true
+		})
+	}
+}
@@ -0,0 +1,175 @@
+package ngcat
+
+import (
+	"container/heap"
+	"sync"
+	"time"
+)
+
+// DefaultJanitorInterval 过期检查协程的默认检查间隔
+const DefaultJanitorInterval = time.Second
+
+// expiryItem 一个被跟踪的过期时间
+type expiryItem struct {
+	key      string
+	deadline int64
+	index    int
+}
+
+// expiryHeap 按过期时间排序的最小堆
+type expiryHeap []*expiryItem
+
+func (h expiryHeap) Len() int           { return len(h) }
+func (h expiryHeap) Less(i, j int) bool { return h[i].deadline < h[j].deadline }
+func (h expiryHeap) Swap(i, j int) {
+	h[i], h[j] = h[j], h[i]
+	h[i].index = i
+	h[j].index = j
+}
+
+func (h *expiryHeap) Push(x interface{}) {
+	item := x.(*expiryItem)
+	item.index = len(*h)
+	*h = append(*h, item)
+}
+
+func (h *expiryHeap) Pop() interface{} {
+	old := *h
+	item := old[len(old)-1]
+	old[len(old)-1] = nil
+	*h = old[:len(old)-1]
+	return item
+}
+
+// janitor 跟踪登记的键的过期时间，到期后调用OnExpire回调
+type janitor struct {
+	mu       sync.Mutex
+	heap     expiryHeap
+	items    map[string]*expiryItem
+	onExpire func(key string)
+	interval time.Duration
+	stop     chan struct{}
+	done     chan struct{}
+}
+
+// newJanitor 创建过期检查器
+func newJanitor(onExpire func(key string), interval time.Duration) *janitor {
+	if interval <= 0 {
+		interval = DefaultJanitorInterval
+	}
+	return &janitor{
+		items:    make(map[string]*expiryItem),
+		onExpire: onExpire,
+		interval: interval,
+		stop:     make(chan struct{}),
+		done:     make(chan struct{}),
+	}
+}
+
+// track 登记或更新键的过期时间
+func (j *janitor) track(key string, deadline int64) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	if item, ok := j.items[key]; ok {
+		item.deadline = deadline
+		heap.Fix(&j.heap, item.index)
+		return
+	}
+	item := &expiryItem{key: key, deadline: deadline}
+	heap.Push(&j.heap, item)
+	j.items[key] = item
+}
+
+// extend 更新已登记的键的过期时间，未登记的键不做任何事
+func (j *janitor) extend(key string, deadline int64) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	if item, ok := j.items[key]; ok {
+		item.deadline = deadline
+		heap.Fix(&j.heap, item.index)
+	}
+}
+
+// forget 取消键的跟踪
+func (j *janitor) forget(key string) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	if item, ok := j.items[key]; ok {
+		heap.Remove(&j.heap, item.index)
+		delete(j.items, key)
+	}
+}
+
+// expired 取出所有到期的键
+func (j *janitor) expired(now int64) []string {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	var keys []string
+	for len(j.heap) > 0 && j.heap[0].deadline <= now {
+		item := heap.Pop(&j.heap).(*expiryItem)
+		delete(j.items, item.key)
+		keys = append(keys, item.key)
+	}
+	return keys
+}
+
+// run 按检查间隔触发到期回调，回调在锁外调用
+func (j *janitor) run(clock Clock, ticker Ticker) {
+	defer close(j.done)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C():
+			for _, key := range j.expired(clock.Now().Unix()) {
+				j.onExpire(key)
+			}
+		case <-j.stop:
+			return
+		}
+	}
+}
+
+// startJanitor 启动过期检查协程，定时器在调用方协程中创建
+func (ng *NGCache) startJanitor() {
+	if ng.janitor == nil {
+		return
+	}
+	go ng.janitor.run(ng.clock, ng.clock.NewTicker(ng.janitor.interval))
+}
+
+// stopJanitor 停止过期检查协程并等待其退出
+func (ng *NGCache) stopJanitor() {
+	if ng.janitor == nil {
+		return
+	}
+	close(ng.janitor.stop)
+	<-ng.janitor.done
+}
+
+// NotifyOnExpire 登记键，在其过期时调用WithOnExpire设置的回调
+//
+// 回调在过期后的下一次检查时触发，精度取决于检查间隔。键被删除或重新写入后登记失效，
+// 需要再次调用NotifyOnExpire。永久缓存不会过期，登记后不会触发回调。
+// 键不存在时返回ErrKeyNotFound，未设置回调时不做任何事。
+func (ng *NGCache) NotifyOnExpire(key string) error {
+	if ng.janitor == nil {
+		return nil
+	}
+	ttl, err := ng.cache.TTL([]byte(key))
+	if err != nil {
+		return ErrKeyNotFound
+	}
+	if ttl == 0 {
+		return nil
+	}
+	ng.janitor.track(key, ng.clock.Now().Unix()+int64(ttl))
+	return nil
+}
+
+// forgetExpiry 键被删除或覆盖时取消过期跟踪
+func (ng *NGCache) forgetExpiry(key string) {
+	if ng.janitor != nil {
+		ng.janitor.forget(key)
+	}
+}
@@ -0,0 +1,95 @@
+package ngcat
+
+import (
+	"sync"
+	"testing"
+	"time"
+)
+
+// expireRecorder 记录过期回调
+type expireRecorder struct {
+	mu   sync.Mutex
+	keys []string
+}
+
+func (r *expireRecorder) onExpire(key string) {
+	r.mu.Lock()
+	r.keys = append(r.keys, key)
+	r.mu.Unlock()
+}
+
+func (r *expireRecorder) fired() []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]string(nil), r.keys...)
+}
+
+func TestNotifyOnExpire(t *testing.T) {
+	clock := newFakeClock()
+	rec := &expireRecorder{}
+	nc := NewNGCache(1024*1024, nil, WithClock(clock), WithOnExpire(rec.onExpire, time.Second))
+	defer nc.Close()
+
+	nc.SetString("short", "v", 5)
+	nc.SetString("long", "v", 20)
+	nc.SetString("permanent", "v", 0)
+	for _, key := range []string{"short", "long", "permanent"} {
+		if err := nc.NotifyOnExpire(key); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := nc.NotifyOnExpire("missing"); err != ErrKeyNotFound {
+		t.Fatalf("expected ErrKeyNotFound, got %v", err)
+	}
+
+	clock.Add(4 * time.Second)
+	time.Sleep(10 * time.Millisecond)
+	if keys := rec.fired(); len(keys) != 0 {
+		t.Fatalf("fired before expiry: %v", keys)
+	}
+	clock.Add(time.Second)
+	waitFor(t, func() bool { return len(rec.fired()) == 1 })
+	if keys := rec.fired(); keys[0] != "short" {
+		t.Fatalf("fired %v", keys)
+	}
+
+	clock.Add(20 * time.Second)
+	waitFor(t, func() bool { return len(rec.fired()) == 2 })
+	time.Sleep(10 * time.Millisecond)
+	if keys := rec.fired(); len(keys) != 2 || keys[1] != "long" {
+		t.Fatalf("fired %v", keys)
+	}
+}
+
+func TestNotifyOnExpireForgottenOnDeleteAndOverwrite(t *testing.T) {
+	clock := newFakeClock()
+	rec := &expireRecorder{}
+	nc := NewNGCache(1024*1024, nil, WithClock(clock), WithOnExpire(rec.onExpire, time.Second))
+
+	nc.SetString("deleted", "v", 5)
+	nc.SetString("overwritten", "v", 5)
+	nc.SetString("marker", "v", 10)
+	nc.NotifyOnExpire("deleted")
+	nc.NotifyOnExpire("overwritten")
+	nc.NotifyOnExpire("marker")
+	nc.Delete("deleted")
+	nc.SetString("overwritten", "v2", 5)
+
+	clock.Add(10 * time.Second)
+	waitFor(t, func() bool { return len(rec.fired()) > 0 })
+	if keys := rec.fired(); len(keys) != 1 || keys[0] != "marker" {
+		t.Fatalf("fired %v", keys)
+	}
+
+	// Close后协程退出，推进时钟不再触发回调
+	nc.SetString("after", "v", 1)
+	nc.NotifyOnExpire("after")
+	if err := nc.Close(); err != nil {
+		t.Fatal(err)
+	}
+	clock.Add(5 * time.Second)
+	time.Sleep(10 * time.Millisecond)
+	if keys := rec.fired(); len(keys) != 1 {
+		t.Fatalf("fired after Close: %v", keys)
+	}
+}
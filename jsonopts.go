@@ -0,0 +1,77 @@
+package ngcat
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+)
+
+// JSONOptions JSON序列化选项（见WithJSONOptions）
+//
+// 作用于SetJSON/GetJSON、SetStruct/GetStruct的JSON回退、GetJSONMulti、SetBundle的KindJSON条目、
+// 缓存使用的JSONCodec，以及JSON格式持久化文件中键的转义。
+type JSONOptions struct {
+	// EscapeHTML 将字符串中的<、>、&转义为<等，encoding/json默认转义
+	EscapeHTML bool
+	// Indent 非空时每一层以Indent缩进输出，不影响持久化文件的布局
+	Indent string
+	// UseNumber 解码到interface{}时数字保存为json.Number而不是float64，
+	// 超过2^53的整数不会丢失精度，再次编码时原样输出，不会变为科学计数法
+	UseNumber bool
+}
+
+// defaultJSONOptions 未设置WithJSONOptions时使用的选项，与encoding/json的默认行为一致
+var defaultJSONOptions = JSONOptions{EscapeHTML: true}
+
+// marshal 按选项序列化值
+func (o JSONOptions) marshal(v interface{}) ([]byte, error) {
+	if o.EscapeHTML && o.Indent == "" {
+		return json.Marshal(v)
+	}
+	return o.encode(v, "", o.Indent)
+}
+
+// marshalIndent 按选项的EscapeHTML序列化值，使用给定的缩进
+func (o JSONOptions) marshalIndent(v interface{}, prefix, indent string) ([]byte, error) {
+	if o.EscapeHTML {
+		return json.MarshalIndent(v, prefix, indent)
+	}
+	return o.encode(v, prefix, indent)
+}
+
+// encode 使用json.Encoder序列化值，去掉末尾的换行
+func (o JSONOptions) encode(v interface{}, prefix, indent string) ([]byte, error) {
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(o.EscapeHTML)
+	if prefix != "" || indent != "" {
+		enc.SetIndent(prefix, indent)
+	}
+	err := enc.Encode(v)
+	if err != nil {
+		return nil, err
+	}
+	return bytes.TrimSuffix(buf.Bytes(), []byte("\n")), nil
+}
+
+// unmarshal 按选项反序列化，与json.Unmarshal一样拒绝值之后多余的数据
+func (o JSONOptions) unmarshal(data []byte, v interface{}) error {
+	if !o.UseNumber {
+		return json.Unmarshal(data, v)
+	}
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	err := dec.Decode(v)
+	if err != nil {
+		return err
+	}
+	if _, err := dec.Token(); err != io.EOF {
+		return newError(CodeDecode, "unexpected data after top-level JSON value", nil)
+	}
+	return nil
+}
+
+// JSONOptions 返回缓存使用的JSON序列化选项
+func (ng *NGCache) JSONOptions() JSONOptions {
+	return ng.jsonOptions
+}
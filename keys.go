@@ -0,0 +1,120 @@
+package ngcat
+
+import (
+	"sync"
+)
+
+// keyLockStripes 按键分段的写入锁数量
+const keyLockStripes = 64
+
+// keyLock 返回键所在分段的锁
+func (ng *NGCache) keyLock(key string) *sync.Mutex {
+	return &ng.keyLocks[stringHash(key)%keyLockStripes]
+}
+
+// lockKeys 按分段序号升序锁定多个键所在的分段，同一分段只锁一次，返回解锁函数
+func (ng *NGCache) lockKeys(keys ...string) func() {
+	var seen [keyLockStripes]bool
+	for _, key := range keys {
+		seen[stringHash(key)%keyLockStripes] = true
+	}
+	stripes := make([]int, 0, min(len(keys), keyLockStripes))
+	for i, ok := range seen {
+		if ok {
+			stripes = append(stripes, i)
+		}
+	}
+	for _, i := range stripes {
+		ng.keyLocks[i].Lock()
+	}
+	return func() {
+		for j := len(stripes) - 1; j >= 0; j-- {
+			ng.keyLocks[stripes[j]].Unlock()
+		}
+	}
+}
+
+// Delete 删除键，同时从freecache和持久化数据中删除，返回键是否存在
+func (ng *NGCache) Delete(key string) bool {
+	return ng.deleteWithPersist(key)
+}
+
+// Rename 将键重命名，保留原有的值和剩余过期时间
+//
+// 永久缓存重命名后仍为永久缓存，旧键会同时从freecache和持久化数据中删除，newKey已存在时被覆盖。
+// 操作期间持有两个键的写入锁，不会与这两个键上的其他写入交错。oldKey不存在时返回ErrKeyNotFound。
+func (ng *NGCache) Rename(oldKey, newKey string) error {
+	started, err := ng.beginWrite()
+	if err != nil {
+		return err
+	}
+	if started {
+		defer ng.inflight.Done()
+	}
+	unlock := ng.lockKeys(oldKey, newKey)
+	defer unlock()
+
+	value, expireSeconds, err := ng.getWithTTL(oldKey)
+	if err != nil {
+		return err
+	}
+	if oldKey == newKey {
+		return nil
+	}
+
+	err = ng.setLocked(newKey, value, expireSeconds)
+	if err != nil {
+		return err
+	}
+	ng.deleteLocked(oldKey)
+	return nil
+}
+
+// CopyKey 将src的值复制到dst，dst以expireSeconds写入（0为永久缓存），已存在时被覆盖
+//
+// 操作期间持有两个键的写入锁。src不存在时返回ErrKeyNotFound。
+func (ng *NGCache) CopyKey(src, dst string, expireSeconds int) error {
+	started, err := ng.beginWrite()
+	if err != nil {
+		return err
+	}
+	if started {
+		defer ng.inflight.Done()
+	}
+	unlock := ng.lockKeys(src, dst)
+	defer unlock()
+
+	value, _, err := ng.getWithTTL(src)
+	if err != nil {
+		return err
+	}
+	return ng.setLocked(dst, value, expireSeconds)
+}
+
+// getWithTTL 获取值及剩余过期秒数，永久缓存返回0
+func (ng *NGCache) getWithTTL(key string) ([]byte, int, error) {
+	// SetInt64AtomicAdd维护的计数器尚未写回，以计数器的当前值作为永久缓存
+	if counter, ok := ng.counters.Load(key); ok {
+		return ng.tagValue("int64", encodeInt64(counter)), 0, nil
+	}
+	value, expireAt, err := ng.storeGetWithExpiration(key)
+	if err == nil {
+		if expireAt == 0 {
+			value, err = ng.decodeValue(value)
+			return value, 0, err
+		}
+		remaining := int64(expireAt) - ng.clock.Now().Unix()
+		if remaining > 0 {
+			value, err = ng.decodeValue(value)
+			return value, int(remaining), err
+		}
+	}
+
+	// freecache中没有时只可能存在于持久化数据（永久缓存）
+	persistValue, exists := ng.persistValue(key)
+	if !exists {
+		return nil, 0, ErrKeyNotFound
+	}
+	persistValue, err = ng.decodeValue(persistValue)
+	return persistValue, 0, err
+}
@@ -0,0 +1,128 @@
+package ngcat
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+)
+
+func TestRename(t *testing.T) {
+	nc := NewNGCache(1024*1024, nil)
+	defer nc.Close()
+
+	nc.SetString("v1:user:1", "alice", 0)
+	nc.SetString("v1:user:2", "bob", 60)
+
+	if err := nc.Rename("v1:user:1", "v2:user:1"); err != nil {
+		t.Fatal(err)
+	}
+	if err := nc.Rename("v1:user:2", "v2:user:2"); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := nc.GetString("v1:user:1"); err != ErrKeyNotFound {
+		t.Fatalf("old permanent key still present: %v", err)
+	}
+	if _, exists := nc.persistData["v1:user:1"]; exists {
+		t.Fatal("old key still in persistData")
+	}
+	if v, _ := nc.GetString("v2:user:1"); v != "alice" {
+		t.Fatalf("v2:user:1 = %q", v)
+	}
+	if _, exists := nc.persistData["v2:user:1"]; !exists {
+		t.Fatal("renamed permanent key should stay permanent")
+	}
+
+	if _, err := nc.GetString("v1:user:2"); err != ErrKeyNotFound {
+		t.Fatalf("old expiring key still present: %v", err)
+	}
+	if v, _ := nc.GetString("v2:user:2"); v != "bob" {
+		t.Fatalf("v2:user:2 = %q", v)
+	}
+	if ttl, _ := nc.cache.TTL([]byte("v2:user:2")); ttl == 0 || ttl > 60 {
+		t.Fatalf("TTL not preserved: %d", ttl)
+	}
+
+	if err := nc.Rename("missing", "other"); err != ErrKeyNotFound {
+		t.Fatalf("expected ErrKeyNotFound, got %v", err)
+	}
+}
+
+func TestCopyKey(t *testing.T) {
+	nc := NewNGCache(1024*1024, nil)
+	defer nc.Close()
+
+	nc.SetString("src", "value", 60)
+	nc.SetString("dst", "old", 0)
+	if err := nc.CopyKey("src", "dst", 0); err != nil {
+		t.Fatal(err)
+	}
+	if v, _ := nc.GetString("src"); v != "value" {
+		t.Fatalf("src = %q", v)
+	}
+	if v, _ := nc.GetString("dst"); v != "value" {
+		t.Fatalf("dst = %q", v)
+	}
+	if _, exists := nc.persistData["dst"]; !exists {
+		t.Fatal("dst copied with expireSeconds 0 should be permanent")
+	}
+
+	if err := nc.CopyKey("dst", "ttl", 30); err != nil {
+		t.Fatal(err)
+	}
+	if _, exists := nc.persistData["ttl"]; exists {
+		t.Fatal("expiring copy should not be in persistData")
+	}
+	if ttl, _ := nc.cache.TTL([]byte("ttl")); ttl == 0 || ttl > 30 {
+		t.Fatalf("copy TTL = %d", ttl)
+	}
+
+	if err := nc.CopyKey("missing", "dst", 0); err != ErrKeyNotFound {
+		t.Fatalf("expected ErrKeyNotFound, got %v", err)
+	}
+}
+
+func TestRenameConcurrentWrite(t *testing.T) {
+	nc := NewNGCache(1024*1024, nil)
+	defer nc.Close()
+
+	for i := 0; i < 200; i++ {
+		src := fmt.Sprintf("src%d", i)
+		dst := fmt.Sprintf("dst%d", i)
+		nc.SetString(src, "before", 0)
+
+		var wg sync.WaitGroup
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			nc.Rename(src, dst)
+		}()
+		go func() {
+			defer wg.Done()
+			nc.SetString(src, "after", 0)
+		}()
+		wg.Wait()
+
+		// 写入要么在重命名之前（值随重命名移动到dst），要么在之后（src重新出现）
+		srcValue, srcErr := nc.GetString(src)
+		dstValue, _ := nc.GetString(dst)
+		switch {
+		case srcErr == ErrKeyNotFound:
+			if dstValue != "after" {
+				t.Fatalf("write before rename lost: dst = %q", dstValue)
+			}
+		case srcValue == "after":
+			if dstValue != "before" {
+				t.Fatalf("rename after write: dst = %q", dstValue)
+			}
+		default:
+			t.Fatalf("inconsistent state: src = %q (%v), dst = %q", srcValue, srcErr, dstValue)
+		}
+		nc.persistDataMutex.RLock()
+		_, srcPersisted := nc.persistData[src]
+		nc.persistDataMutex.RUnlock()
+		if srcPersisted != (srcErr == nil) {
+			t.Fatalf("freecache and persistData disagree on %s", src)
+		}
+	}
+}
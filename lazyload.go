@@ -0,0 +1,109 @@
+package ngcat
+
+import (
+	"context"
+	"io"
+	"os"
+)
+
+// wrapLazyLoadFile 包装后台加载顺序读取的Reader，测试中用于控制加载的进度
+var wrapLazyLoadFile = func(r io.Reader) io.Reader { return r }
+
+// startLazyLoad 启用WithLazyLoad且持久化文件带有索引时开始后台加载，返回是否已开始
+//
+// 热点键在返回前通过索引读取，其余条目由finishLazyLoad顺序加载。persistDataMutex从这里一直持有到后台加载完成，
+// 因此访问持久化数据的操作都会等待，不会看到只加载了一部分的数据，也不会被后台加载覆盖。
+// 文件不存在、没有索引或索引损坏时返回false，由调用方顺序加载并报告错误。
+func (ng *NGCache) startLazyLoad() bool {
+	if !ng.lazyLoad || ng.persistConfig == nil || !ng.persistConfig.Enabled {
+		return false
+	}
+	if format := ng.persistConfig.Format; format != FormatBinary && format != FormatDelta {
+		return false
+	}
+	file, err := os.Open(ng.persistFilePath())
+	if err != nil {
+		return false
+	}
+	info, err := file.Stat()
+	var idx *binaryIndex
+	if err == nil {
+		idx, err = openBinaryIndex(file, info.Size())
+	}
+	if err != nil || idx == nil {
+		file.Close()
+		return false
+	}
+
+	ng.preparePreload()
+	stats := ng.newLoadStats()
+	ng.persistDataMutex.Lock()
+	indexed := make(map[string]struct{}, len(ng.preloadHot))
+	for key := range ng.preloadHot {
+		// 读取失败的键留给顺序加载
+		value, found, err := idx.lookup(key)
+		if err == nil && found {
+			ng.loadEntry(key, value, &stats)
+			indexed[key] = struct{}{}
+		}
+	}
+	ng.lazyIndex = idx
+	ng.lazyLoading.Store(true)
+
+	ng.tasks.Add(1)
+	go ng.finishLazyLoad(file, indexed, stats)
+	return true
+}
+
+// finishLazyLoad 顺序加载startLazyLoad之外的条目，完成后停止通过索引读取并释放persistDataMutex
+//
+// 缓存创建后已无法中止启动，加载失败时FailStartup按StartEmpty处理。
+func (ng *NGCache) finishLazyLoad(file *os.File, indexed map[string]struct{}, stats loadStats) {
+	defer ng.tasks.Done()
+	defer file.Close()
+
+	pr, err := newPersistReader(wrapLazyLoadFile(file), FormatBinary)
+	if err == nil {
+		err = ng.loadEntriesLocked(context.Background(), pr, &stats, indexed)
+	}
+	if err != nil {
+		policy := ng.loadFailurePolicy
+		if policy == FailStartup {
+			policy = StartEmpty
+		}
+		ng.recoverLoadLocked(err, policy)
+	}
+
+	ng.lazyMutex.Lock()
+	ng.lazyIndex = nil
+	ng.lazyLoading.Store(false)
+	ng.lazyMutex.Unlock()
+	ng.persistDataMutex.Unlock()
+
+	stats.report(ng)
+	if err != nil {
+		ng.reportError(err)
+		return
+	}
+	ng.checkConsistencyAfterLoad()
+}
+
+// lazyLookup 后台加载期间通过索引从持久化文件读取键，loading为false时调用方应读取持久化数据
+//
+// 加载期间持久化数据不会被修改，文件中的值与加载完成后持久化数据中的值相同。
+// 索引读取失败时返回loading为false，调用方等待加载完成后从持久化数据读取。
+func (ng *NGCache) lazyLookup(key string) (value []byte, found, loading bool) {
+	if !ng.lazyLoading.Load() {
+		return nil, false, false
+	}
+	ng.lazyMutex.RLock()
+	defer ng.lazyMutex.RUnlock()
+	if ng.lazyIndex == nil {
+		return nil, false, false
+	}
+	value, found, err := ng.lazyIndex.lookup(key)
+	if err != nil {
+		return nil, false, false
+	}
+	return value, found, true
+}
@@ -0,0 +1,134 @@
+package ngcat
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"testing"
+)
+
+// gatedReader 每次Read之前等待release被关闭
+type gatedReader struct {
+	r       io.Reader
+	release chan struct{}
+}
+
+func (g *gatedReader) Read(p []byte) (int, error) {
+	<-g.release
+	return g.r.Read(p)
+}
+
+// gateLazyLoad 使后台加载在release被关闭之前无法读取文件
+func gateLazyLoad(t *testing.T) chan struct{} {
+	release := make(chan struct{})
+	orig := wrapLazyLoadFile
+	wrapLazyLoadFile = func(r io.Reader) io.Reader { return &gatedReader{r: r, release: release} }
+	t.Cleanup(func() { wrapLazyLoadFile = orig })
+	return release
+}
+
+func TestLazyLoad(t *testing.T) {
+	config := preloadConfig(t)
+	var keys []string
+	for i := 0; i < 1000; i++ {
+		keys = append(keys, fmt.Sprintf("key%d", i))
+	}
+	seedPersistFile(t, config, keys)
+
+	release := gateLazyLoad(t)
+	nc := NewNGCache(1024*1024, config, WithLazyLoad(), WithPreload(PreloadNone))
+	if !nc.lazyLoading.Load() {
+		t.Fatal("indexed file should be loaded in the background")
+	}
+
+	// 加载完成前通过索引读取
+	if v, err := nc.GetString("key500"); err != nil || v != "v-key500" {
+		t.Fatalf("GetString during lazy load = %q, %v", v, err)
+	}
+	if _, err := nc.GetString("missing"); !errors.Is(err, ErrKeyNotFound) {
+		t.Fatalf("missing key during lazy load: %v", err)
+	}
+
+	// 写入等待加载完成，不会被后台加载覆盖
+	written := make(chan error)
+	go func() { written <- nc.SetString("key1", "new", 0) }()
+	deleted := make(chan bool)
+	go func() { deleted <- nc.Delete("key2") }()
+	select {
+	case <-written:
+		t.Fatal("write finished before the background load")
+	case <-deleted:
+		t.Fatal("delete finished before the background load")
+	default:
+	}
+	close(release)
+	if err := <-written; err != nil {
+		t.Fatal(err)
+	}
+	if !<-deleted {
+		t.Fatal("key2 should have been deleted")
+	}
+	if n := nc.Stats().PersistEntries; n != 999 {
+		t.Fatalf("PersistEntries = %d, want 999", n)
+	}
+	if nc.lazyLoading.Load() {
+		t.Fatal("lazy load should be finished")
+	}
+	if err := nc.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	reloaded := NewNGCache(1024*1024, config)
+	defer reloaded.Close()
+	if v, _ := reloaded.GetString("key1"); v != "new" {
+		t.Fatalf("key1 = %q", v)
+	}
+	if _, err := reloaded.GetString("key2"); !errors.Is(err, ErrKeyNotFound) {
+		t.Fatalf("key2 after reopen: %v", err)
+	}
+	if v, _ := reloaded.GetString("key999"); v != "v-key999" {
+		t.Fatalf("key999 = %q", v)
+	}
+}
+
+func TestLazyLoadPreloadsHotKeysByIndex(t *testing.T) {
+	config := preloadConfig(t)
+	nc := NewNGCache(1024*1024, config, WithHotKeys(64, 1))
+	for _, key := range []string{"a", "b", "c"} {
+		nc.SetString(key, "v-"+key, 0)
+	}
+	nc.GetString("c")
+	if err := nc.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	// 后台加载被阻塞时热点键已通过索引写入freecache
+	release := gateLazyLoad(t)
+	nc = NewNGCache(1024*1024, config, WithLazyLoad(), WithHotKeys(64, 1), WithPreloadTopN(1))
+	defer nc.Close()
+	if !nc.inFreecache("c") || nc.inFreecache("a") {
+		t.Fatal("only the hot key should be preloaded before the background load")
+	}
+	close(release)
+	if v, err := nc.GetString("a"); err != nil || v != "v-a" {
+		t.Fatalf("GetString = %q, %v", v, err)
+	}
+	if n := nc.Stats().PersistEntries; n != 3 {
+		t.Fatalf("PersistEntries = %d, want 3", n)
+	}
+}
+
+func TestLazyLoadWithoutIndex(t *testing.T) {
+	config := preloadConfig(t)
+	config.Format = FormatJSON
+	seedPersistFile(t, config, []string{"a", "b"})
+
+	nc := NewNGCache(1024*1024, config, WithLazyLoad())
+	defer nc.Close()
+	if nc.lazyLoading.Load() {
+		t.Fatal("files without an index should be loaded before NewNGCache returns")
+	}
+	if n := nc.Stats().PersistEntries; n != 2 {
+		t.Fatalf("PersistEntries = %d, want 2", n)
+	}
+}
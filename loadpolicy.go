@@ -0,0 +1,62 @@
+package ngcat
+
+// LoadFailurePolicy 启动时持久化文件加载失败的处理策略
+type LoadFailurePolicy int
+
+const (
+	// FailStartup Open返回加载错误，不创建缓存
+	FailStartup LoadFailurePolicy = iota
+	// StartEmpty 记录日志并丢弃已加载的条目，以空缓存启动
+	StartEmpty
+	// RecoverPartial 保留损坏位置之前已读取的所有条目，恢复数量写入日志和Stats().RecoveredEntries
+	RecoverPartial
+)
+
+// handleLoadError 按加载失败策略处理加载错误，返回需要中止启动的错误
+func (ng *NGCache) handleLoadError(err error, canFail bool) error {
+	if err == nil {
+		return nil
+	}
+
+	policy := ng.loadFailurePolicy
+	if policy == FailStartup && !canFail {
+		policy = StartEmpty
+	}
+
+	if policy == FailStartup {
+		return err
+	}
+	ng.persistDataMutex.Lock()
+	ng.recoverLoadLocked(err, policy)
+	ng.persistDataMutex.Unlock()
+	return nil
+}
+
+// recoverLoadLocked 按RecoverPartial保留已加载的条目，或按StartEmpty丢弃，调用方需持有persistDataMutex
+func (ng *NGCache) recoverLoadLocked(err error, policy LoadFailurePolicy) {
+	if policy == RecoverPartial {
+		ng.recoveredEntries = int64(len(ng.persistData))
+		ng.logger.Warn("ngcat: 持久化文件加载失败，已恢复损坏位置之前的条目",
+			"path", ng.persistFilePath(), "recovered", len(ng.persistData), "error", err)
+		return
+	}
+	loaded := ng.persistData
+	ng.persistData = make(map[string][]byte)
+	ng.bloomNoteDelete()
+	ng.clearLoaded(loaded)
+	ng.logger.Warn("ngcat: 持久化文件加载失败，以空缓存启动",
+		"path", ng.persistFilePath(), "error", err)
+}
+
+// clearLoaded 清除加载失败前写入存储的条目
+//
+// NGCache创建的freecache直接清空；传入的存储可能与应用的其他部分共享，只删除从持久化文件加载的键。
+func (ng *NGCache) clearLoaded(loaded map[string][]byte) {
+	if c, ok := ng.ownFreecache(); ok {
+		c.Clear()
+		return
+	}
+	for key := range loaded {
+		ng.storeDel(key)
+	}
+}
@@ -0,0 +1,103 @@
+package ngcat
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+// truncatedBinaryFixture 写出包含100个条目的二进制持久化文件并截掉最后一个条目的一部分
+func truncatedBinaryFixture(t *testing.T) *PersistConfig {
+	t.Helper()
+	config := &PersistConfig{
+		Enabled:  true,
+		FilePath: t.TempDir(),
+		FileName: "cache.bin",
+		Format:   FormatBinary,
+		Interval: time.Hour,
+	}
+	nc := NewNGCache(1024*1024, config)
+	for i := 0; i < 100; i++ {
+		nc.SetString(fmt.Sprintf("key%03d", i), "value", 0)
+	}
+	if err := nc.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	path := nc.persistFilePath()
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 条目之后是索引
+	if err := os.Truncate(path, info.Size()-int64(binaryIndexSize(100))-3); err != nil {
+		t.Fatal(err)
+	}
+	return config
+}
+
+func TestLoadFailurePolicyFailStartup(t *testing.T) {
+	config := truncatedBinaryFixture(t)
+	path := (&NGCache{persistConfig: config}).persistFilePath()
+	before, _ := os.ReadFile(path)
+
+	nc, err := Open(1024*1024, config)
+	if nc != nil || !errors.Is(err, ErrCorruptFile) {
+		t.Fatalf("Open = %v, %v; want nil and ErrCorruptFile", nc, err)
+	}
+	if after, _ := os.ReadFile(path); !bytes.Equal(before, after) {
+		t.Fatal("failed startup should leave the persist file untouched")
+	}
+
+	// NewNGCache无法返回错误，按StartEmpty处理
+	nc = NewNGCache(1024*1024, config, WithLogger(slog.New(slog.NewTextHandler(&bytes.Buffer{}, nil))))
+	defer nc.Close()
+	if n := nc.Stats().PersistEntries; n != 0 {
+		t.Fatalf("NewNGCache should start empty, got %d entries", n)
+	}
+}
+
+func TestLoadFailurePolicyStartEmpty(t *testing.T) {
+	config := truncatedBinaryFixture(t)
+	var logs bytes.Buffer
+	nc, err := Open(1024*1024, config, WithLoadFailurePolicy(StartEmpty),
+		WithLogger(slog.New(slog.NewTextHandler(&logs, nil))))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer nc.Close()
+
+	if _, err := nc.GetString("key000"); err != ErrKeyNotFound {
+		t.Fatalf("StartEmpty kept entries: %v", err)
+	}
+	if nc.cache.EntryCount() != 0 {
+		t.Fatal("freecache should be empty")
+	}
+	if !strings.Contains(logs.String(), "以空缓存启动") {
+		t.Fatalf("missing log: %s", logs.String())
+	}
+}
+
+func TestLoadFailurePolicyRecoverPartial(t *testing.T) {
+	config := truncatedBinaryFixture(t)
+	var logs bytes.Buffer
+	nc, err := Open(1024*1024, config, WithLoadFailurePolicy(RecoverPartial),
+		WithLogger(slog.New(slog.NewTextHandler(&logs, nil))))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer nc.Close()
+
+	stats := nc.Stats()
+	if stats.RecoveredEntries != 99 || stats.PersistEntries != 99 {
+		t.Fatalf("recovered %d entries (%d persisted), want 99", stats.RecoveredEntries, stats.PersistEntries)
+	}
+	if !strings.Contains(logs.String(), "recovered=99") {
+		t.Fatalf("missing log: %s", logs.String())
+	}
+}
@@ -0,0 +1,98 @@
+package ngcat
+
+// MergeStrategy 合并时的冲突处理策略
+type MergeStrategy int
+
+const (
+	// MergeKeepExisting 保留已存在的键
+	MergeKeepExisting MergeStrategy = iota
+	// MergeOverwriteAll 总是使用来源缓存的值
+	MergeOverwriteAll
+	// MergeKeepNewer 保留较新的值
+	//
+	// 缓存不记录写入时间，较新由剩余过期时间判断：剩余时间更长的视为更新写入，
+	// 永久缓存视为最新；双方都是永久缓存时保留已存在的值。
+	MergeKeepNewer
+)
+
+// MergeFrom 将other中的所有条目（包括持久化数据）合并到当前缓存
+//
+// 合并保留条目的剩余过期时间，永久缓存合并后仍为永久缓存。遇到写入错误时停止并返回该错误。
+func (ng *NGCache) MergeFrom(other *NGCache, strategy MergeStrategy) error {
+	_, err := ng.mergeFrom(other, strategy)
+	return err
+}
+
+// CopyTo 将当前缓存的所有条目（包括持久化数据）复制到dst，返回实际写入dst的键数量
+//
+// overwrite为false时跳过dst中已存在的键。与dst.MergeFrom相同，复制保留条目的剩余过期时间，
+// 永久缓存复制后仍为永久缓存；遇到写入错误时停止，返回已复制的数量和该错误。
+func (ng *NGCache) CopyTo(dst *NGCache, overwrite bool) (int, error) {
+	strategy := MergeKeepExisting
+	if overwrite {
+		strategy = MergeOverwriteAll
+	}
+	return dst.mergeFrom(ng, strategy)
+}
+
+// mergeFrom 按strategy将other的条目写入当前缓存，返回写入的键数量
+func (ng *NGCache) mergeFrom(other *NGCache, strategy MergeStrategy) (int, error) {
+	var mergeErr error
+	merged := 0
+	now := ng.clock.Now().Unix()
+
+	other.forEachEntry(func(key string, value []byte, expireAt uint32) bool {
+		expireSeconds := 0
+		if expireAt != 0 {
+			expireSeconds = int(int64(expireAt) - now)
+			if expireSeconds <= 0 {
+				return true
+			}
+		}
+
+		if strategy != MergeOverwriteAll {
+			existingTTL, exists := ng.remainingTTL(key)
+			if exists {
+				if strategy == MergeKeepExisting {
+					return true
+				}
+				if !newerTTL(expireSeconds, existingTTL) {
+					return true
+				}
+			}
+		}
+
+		mergeErr = ng.setWithPersist(key, value, expireSeconds)
+		if mergeErr != nil {
+			return false
+		}
+		merged++
+		return true
+	})
+
+	return merged, mergeErr
+}
+
+// remainingTTL 获取键的剩余过期秒数，0表示永久缓存
+func (ng *NGCache) remainingTTL(key string) (int, bool) {
+	ttl, err := ng.cache.TTL([]byte(key))
+	if err == nil {
+		return int(ttl), true
+	}
+	ng.persistDataMutex.RLock()
+	_, exists := ng.persistData[key]
+	ng.persistDataMutex.RUnlock()
+	return 0, exists
+}
+
+// newerTTL 按剩余过期时间判断候选值是否比已存在的值更新，0表示永久
+func newerTTL(candidate, existing int) bool {
+	switch {
+	case candidate == 0:
+		return existing != 0
+	case existing == 0:
+		return false
+	default:
+		return candidate > existing
+	}
+}
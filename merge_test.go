@@ -0,0 +1,99 @@
+package ngcat
+
+import (
+	"testing"
+)
+
+// mergeFixture 构造带冲突键的主缓存和备用缓存
+func mergeFixture() (primary, standby *NGCache) {
+	primary = NewNGCache(1024*1024, nil)
+	standby = NewNGCache(1024*1024, nil)
+
+	primary.SetString("only_primary", "p", 0)
+	standby.SetString("only_standby", "s", 0)
+
+	// 冲突：备用缓存的剩余过期时间更长
+	primary.SetString("ttl_conflict", "p", 60)
+	standby.SetString("ttl_conflict", "s", 600)
+	// 冲突：主缓存为永久缓存
+	primary.SetString("perm_conflict", "p", 0)
+	standby.SetString("perm_conflict", "s", 600)
+	return primary, standby
+}
+
+func TestMergeFrom(t *testing.T) {
+	cases := []struct {
+		strategy MergeStrategy
+		want     map[string]string
+	}{
+		{MergeKeepExisting, map[string]string{"ttl_conflict": "p", "perm_conflict": "p"}},
+		{MergeOverwriteAll, map[string]string{"ttl_conflict": "s", "perm_conflict": "s"}},
+		{MergeKeepNewer, map[string]string{"ttl_conflict": "s", "perm_conflict": "p"}},
+	}
+
+	for _, c := range cases {
+		primary, standby := mergeFixture()
+		if err := primary.MergeFrom(standby, c.strategy); err != nil {
+			t.Fatal(err)
+		}
+
+		c.want["only_primary"] = "p"
+		c.want["only_standby"] = "s"
+		for key, want := range c.want {
+			if got, _ := primary.GetString(key); got != want {
+				t.Errorf("strategy %d: %s = %q, want %q", c.strategy, key, got, want)
+			}
+		}
+		if _, exists := primary.persistData["only_standby"]; !exists {
+			t.Errorf("strategy %d: merged permanent key not persisted", c.strategy)
+		}
+		primary.Close()
+		standby.Close()
+	}
+}
+
+func TestMergeFromPersistOnlyEntries(t *testing.T) {
+	primary := NewNGCache(1024*1024, nil)
+	standby := NewNGCache(1024*1024, nil)
+	defer primary.Close()
+	defer standby.Close()
+
+	// 模拟已被freecache淘汰、只存在于持久化数据中的永久缓存
+	standby.SetString("evicted", "v", 0)
+	standby.cache.Del([]byte("evicted"))
+
+	if err := primary.MergeFrom(standby, MergeKeepExisting); err != nil {
+		t.Fatal(err)
+	}
+	if v, _ := primary.GetString("evicted"); v != "v" {
+		t.Fatalf("persistData entry not merged: %q", v)
+	}
+}
+
+func TestCopyTo(t *testing.T) {
+	for _, overwrite := range []bool{false, true} {
+		// standby为来源，primary为预先写入了冲突键的目标
+		primary, standby := mergeFixture()
+		copied, err := standby.CopyTo(primary, overwrite)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		want := map[string]string{"only_primary": "p", "only_standby": "s", "ttl_conflict": "p", "perm_conflict": "p"}
+		wantCopied := 1
+		if overwrite {
+			want["ttl_conflict"], want["perm_conflict"] = "s", "s"
+			wantCopied = 3
+		}
+		if copied != wantCopied {
+			t.Fatalf("overwrite=%v: copied = %d, want %d", overwrite, copied, wantCopied)
+		}
+		for key, v := range want {
+			if got, err := primary.GetString(key); err != nil || got != v {
+				t.Fatalf("overwrite=%v: %s = %q, %v; want %q", overwrite, key, got, err, v)
+			}
+		}
+		primary.Close()
+		standby.Close()
+	}
+}
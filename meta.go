@@ -0,0 +1,174 @@
+package ngcat
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// metaStripes 条目元数据的分片数量
+const metaStripes = 64
+
+// metaSweepMin 分片中的元数据少于该数量时不清理已过期的条目
+const metaSweepMin = 1024
+
+// EntryMeta 条目的元数据
+type EntryMeta struct {
+	// LastWrite 最后一次写入的时间，从持久化文件加载而之后未写入的键为零值
+	LastWrite time.Time
+	// LastAccess 最后一次读取的时间（秒级精度），未通过WithAccessTracking启用或从未读取时为零值
+	LastAccess time.Time
+	// ExpireAt 过期时间，永久缓存为零值
+	ExpireAt time.Time
+}
+
+// entryMeta 单个键的元数据，时间均为Unix秒
+type entryMeta struct {
+	lastWrite  int64
+	lastAccess atomic.Int64
+	expireAt   int64
+}
+
+// metaTracker 按键记录最后写入和读取时间
+//
+// 删除键时同步删除其元数据；过期或被淘汰的键的元数据在分片增长到上次清理时的两倍后，
+// 按记录的过期时间批量清理。
+type metaTracker struct {
+	stripes [metaStripes]metaStripe
+}
+
+// metaStripe 元数据分片
+type metaStripe struct {
+	mu      sync.RWMutex
+	entries map[string]*entryMeta
+	sweepAt int
+}
+
+// written 记录一次写入，expireSeconds不大于0表示永久缓存
+func (t *metaTracker) written(key string, now int64, expireSeconds int) {
+	m := &entryMeta{lastWrite: now}
+	if expireSeconds > 0 {
+		m.expireAt = now + int64(expireSeconds)
+	}
+
+	s := &t.stripes[stringHash(key)%metaStripes]
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.entries == nil {
+		s.entries = make(map[string]*entryMeta)
+		s.sweepAt = metaSweepMin
+	}
+	if old, ok := s.entries[key]; ok {
+		m.lastAccess.Store(old.lastAccess.Load())
+	}
+	s.entries[key] = m
+	if len(s.entries) >= s.sweepAt {
+		for k, e := range s.entries {
+			if e.expireAt != 0 && e.expireAt <= now {
+				delete(s.entries, k)
+			}
+		}
+		s.sweepAt = 2 * len(s.entries)
+		if s.sweepAt < metaSweepMin {
+			s.sweepAt = metaSweepMin
+		}
+	}
+}
+
+// accessed 记录一次读取，同一键在同一秒内只更新一次
+func (t *metaTracker) accessed(key string, now int64) {
+	s := &t.stripes[stringHash(key)%metaStripes]
+	s.mu.RLock()
+	m, ok := s.entries[key]
+	s.mu.RUnlock()
+	if ok && m.lastAccess.Load() != now {
+		m.lastAccess.Store(now)
+	}
+}
+
+// extended 键的过期时间被延长后更新记录的过期时间，未记录的键不做任何事
+func (t *metaTracker) extended(key string, expireAt int64) {
+	s := &t.stripes[stringHash(key)%metaStripes]
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	old, ok := s.entries[key]
+	if !ok {
+		return
+	}
+	// 读取方在锁外读取expireAt，替换为新的条目而不是原地修改
+	m := &entryMeta{lastWrite: old.lastWrite, expireAt: expireAt}
+	m.lastAccess.Store(old.lastAccess.Load())
+	s.entries[key] = m
+}
+
+// forget 删除键的元数据
+func (t *metaTracker) forget(key string) {
+	s := &t.stripes[stringHash(key)%metaStripes]
+	s.mu.Lock()
+	delete(s.entries, key)
+	s.mu.Unlock()
+}
+
+// get 返回键的元数据
+func (t *metaTracker) get(key string) (EntryMeta, bool) {
+	s := &t.stripes[stringHash(key)%metaStripes]
+	s.mu.RLock()
+	m, ok := s.entries[key]
+	s.mu.RUnlock()
+	if !ok {
+		return EntryMeta{}, false
+	}
+	return EntryMeta{
+		LastWrite:  unixOrZero(m.lastWrite),
+		LastAccess: unixOrZero(m.lastAccess.Load()),
+		ExpireAt:   unixOrZero(m.expireAt),
+	}, true
+}
+
+// unixOrZero 将Unix秒转换为时间，0转换为零值
+func unixOrZero(sec int64) time.Time {
+	if sec == 0 {
+		return time.Time{}
+	}
+	return time.Unix(sec, 0)
+}
+
+// GetMeta 返回条目的元数据，不返回值、不计入命中统计也不写回freecache
+//
+// 键不存在时返回ErrKeyNotFound。
+func (ng *NGCache) GetMeta(key string) (EntryMeta, error) {
+	ttl, exists := ng.remainingTTL(key)
+	if !exists {
+		return EntryMeta{}, ErrKeyNotFound
+	}
+	meta, ok := ng.meta.get(key)
+	if !ok && ttl > 0 {
+		meta.ExpireAt = ng.clock.Now().Add(time.Duration(ttl) * time.Second)
+	}
+	return meta, nil
+}
+
+// noteWrite 写入成功后更新配额用量和元数据，persisted表示值同时保存在持久化数据中
+func (ng *NGCache) noteWrite(key string, value []byte, expireSeconds int, persisted bool) {
+	ng.noteWriteAt(key, value, expireSeconds, persisted, ng.clock.Now().Unix())
+}
+
+// noteWriteAt 与noteWrite相同，写入时间由调用方给出，批量写入时所有条目共用一次时钟读取
+func (ng *NGCache) noteWriteAt(key string, value []byte, expireSeconds int, persisted bool, now int64) {
+	ng.accountQuota(key, entryFootprint(key, value, persisted))
+	ng.meta.written(key, now, expireSeconds)
+	ng.observeValueSize(len(value))
+}
+
+// noteDelete 删除键后更新配额用量和元数据
+func (ng *NGCache) noteDelete(key string) {
+	ng.accountQuota(key, 0)
+	ng.meta.forget(key)
+}
+
+// noteAccess 启用WithAccessTracking时记录一次读取
+func (ng *NGCache) noteAccess(key string) {
+	if ng.trackAccess {
+		ng.meta.accessed(key, ng.clock.Now().Unix())
+	}
+}
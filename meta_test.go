@@ -0,0 +1,92 @@
+package ngcat
+
+import (
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestGetMeta(t *testing.T) {
+	clock := newFakeClock()
+	start := clock.Now()
+	nc := NewNGCache(1024*1024, nil, WithClock(clock), WithAccessTracking())
+	defer nc.Close()
+
+	nc.SetString("k", "v1", 0)
+	meta, err := nc.GetMeta("k")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !meta.LastWrite.Equal(start) || !meta.LastAccess.IsZero() || !meta.ExpireAt.IsZero() {
+		t.Fatalf("after write: %+v", meta)
+	}
+
+	clock.Add(10 * time.Second)
+	nc.GetString("k")
+	meta, _ = nc.GetMeta("k")
+	if !meta.LastAccess.Equal(start.Add(10*time.Second)) || !meta.LastWrite.Equal(start) {
+		t.Fatalf("after read: %+v", meta)
+	}
+
+	clock.Add(5 * time.Second)
+	nc.SetString("k", "v2", 60)
+	meta, _ = nc.GetMeta("k")
+	if !meta.LastWrite.Equal(start.Add(15*time.Second)) || !meta.LastAccess.Equal(start.Add(10*time.Second)) {
+		t.Fatalf("after overwrite: %+v", meta)
+	}
+	if !meta.ExpireAt.Equal(start.Add(75 * time.Second)) {
+		t.Fatalf("ExpireAt = %v", meta.ExpireAt)
+	}
+
+	// GetMeta本身不计入读取和命中
+	hits := nc.Stats().HitCount
+	clock.Add(time.Second)
+	nc.GetMeta("k")
+	if meta, _ = nc.GetMeta("k"); !meta.LastAccess.Equal(start.Add(10 * time.Second)) {
+		t.Fatalf("GetMeta counted as access: %+v", meta)
+	}
+	if nc.Stats().HitCount != hits {
+		t.Fatal("GetMeta counted as a hit")
+	}
+
+	nc.Delete("k")
+	if _, err := nc.GetMeta("k"); err != ErrKeyNotFound {
+		t.Fatalf("expected ErrKeyNotFound, got %v", err)
+	}
+}
+
+func TestGetMetaWithoutAccessTracking(t *testing.T) {
+	clock := newFakeClock()
+	nc := NewNGCache(1024*1024, nil, WithClock(clock))
+	defer nc.Close()
+
+	nc.SetString("k", "v", 0)
+	clock.Add(time.Second)
+	nc.GetString("k")
+	meta, _ := nc.GetMeta("k")
+	if !meta.LastAccess.IsZero() || meta.LastWrite.IsZero() {
+		t.Fatalf("LastAccess should be opt-in: %+v", meta)
+	}
+}
+
+func TestMetaSweepsExpiredEntries(t *testing.T) {
+	clock := newFakeClock()
+	nc := NewNGCache(4*1024*1024, nil, WithClock(clock))
+	defer nc.Close()
+
+	for i := 0; i < metaStripes*metaSweepMin; i++ {
+		nc.SetString(fmt.Sprintf("old%d", i), "v", 1)
+	}
+	clock.Add(2 * time.Second)
+	for i := 0; i < metaStripes*metaSweepMin; i++ {
+		nc.SetString(fmt.Sprintf("new%d", i), "v", 0)
+	}
+
+	total := 0
+	for i := range nc.meta.stripes {
+		total += len(nc.meta.stripes[i].entries)
+	}
+	if total >= 2*metaStripes*metaSweepMin {
+		t.Fatalf("expired metadata not swept: %d entries", total)
+	}
+}
@@ -0,0 +1,103 @@
+package ngcat
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"sync"
+)
+
+// MigrationFunc 将旧版本的完整二进制持久化文件内容转换为新版本
+//
+// 返回的数据必须以新版本的文件头开始（魔数和版本号）。
+type MigrationFunc func(oldData []byte) (newData []byte, err error)
+
+// migration 一个已注册的版本迁移
+type migration struct {
+	to uint32
+	fn MigrationFunc
+}
+
+var (
+	migrationsMu sync.RWMutex
+	migrations   = map[uint32]migration{
+		1: {to: 2, fn: migrateV1},
+		2: {to: 3, fn: migrateV2},
+	}
+)
+
+// migrateV1 在版本1的文件头中版本号之后插入值为0的FormatCaps
+func migrateV1(old []byte) ([]byte, error) {
+	if len(old) < 8 {
+		return nil, io.ErrUnexpectedEOF
+	}
+	data := make([]byte, 0, len(old)+4)
+	data = binary.LittleEndian.AppendUint32(data, BinaryMagic)
+	data = binary.LittleEndian.AppendUint32(data, 2)
+	data = binary.LittleEndian.AppendUint32(data, 0)
+	return append(data, old[8:]...), nil
+}
+
+// migrateV2 将版本2的文件标记为版本3，版本2没有索引，FormatCaps中不设置CapIndex
+func migrateV2(old []byte) ([]byte, error) {
+	if len(old) < 8 {
+		return nil, io.ErrUnexpectedEOF
+	}
+	data := append([]byte(nil), old...)
+	binary.LittleEndian.PutUint32(data[4:], 3)
+	return data, nil
+}
+
+// RegisterMigration 注册从fromVersion到toVersion的二进制格式迁移
+//
+// 加载旧版本文件时会从文件的版本开始依次应用迁移，直到得到BinaryVersion。
+// 同一fromVersion重复注册时以最后一次为准，toVersion不大于fromVersion时panic。
+func RegisterMigration(fromVersion, toVersion uint32, fn MigrationFunc) {
+	if toVersion <= fromVersion {
+		panic(fmt.Sprintf("ngcat: 无效的迁移版本: %d -> %d", fromVersion, toVersion))
+	}
+	migrationsMu.Lock()
+	defer migrationsMu.Unlock()
+	migrations[fromVersion] = migration{to: toVersion, fn: fn}
+}
+
+// migrateBinary 检查二进制文件头，版本低于BinaryVersion时读入全部数据并依次应用迁移
+//
+// 不需要迁移时返回的读取器与原始数据一致，头部校验留给读取器完成。
+func migrateBinary(r io.Reader) (io.Reader, error) {
+	br := bufio.NewReader(r)
+	header, err := br.Peek(8)
+	if err != nil || binary.LittleEndian.Uint32(header) != BinaryMagic {
+		return br, nil
+	}
+	version := binary.LittleEndian.Uint32(header[4:])
+	if version >= BinaryVersion {
+		return br, nil
+	}
+
+	data, err := io.ReadAll(br)
+	if err != nil {
+		return nil, newError(CodeReadFile, "", err)
+	}
+	for version < BinaryVersion {
+		migrationsMu.RLock()
+		m, ok := migrations[version]
+		migrationsMu.RUnlock()
+		if !ok {
+			return nil, corruptf("no migration registered for binary version %d", version)
+		}
+
+		data, err = m.fn(data)
+		if err != nil {
+			return nil, newError(CodeCorruptFile, fmt.Sprintf("migrate binary version %d to %d", version, m.to), err)
+		}
+		if len(data) < 8 || binary.LittleEndian.Uint32(data) != BinaryMagic ||
+			binary.LittleEndian.Uint32(data[4:]) != m.to {
+			return nil, corruptf("invalid header after migrating binary version %d to %d", version, m.to)
+		}
+		version = m.to
+	}
+	return bytes.NewReader(data), nil
+}
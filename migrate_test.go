@@ -0,0 +1,132 @@
+package ngcat
+
+import (
+	"encoding/binary"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// withMigrations 在测试期间替换已注册的迁移
+func withMigrations(t *testing.T, m map[uint32]migration) {
+	t.Helper()
+	migrationsMu.Lock()
+	saved := migrations
+	migrations = m
+	migrationsMu.Unlock()
+	t.Cleanup(func() {
+		migrationsMu.Lock()
+		migrations = saved
+		migrationsMu.Unlock()
+	})
+}
+
+// writeV0File 写出假想的版本0文件：没有时间戳字段
+func writeV0File(t *testing.T, entries map[string]string) string {
+	t.Helper()
+	buf := binary.LittleEndian.AppendUint32(nil, BinaryMagic)
+	buf = binary.LittleEndian.AppendUint32(buf, 0)
+	buf = binary.LittleEndian.AppendUint32(buf, uint32(len(entries)))
+	for k, v := range entries {
+		buf = binary.LittleEndian.AppendUint32(buf, uint32(len(k)))
+		buf = append(buf, k...)
+		buf = binary.LittleEndian.AppendUint32(buf, uint32(len(v)))
+		buf = append(buf, v...)
+	}
+	path := filepath.Join(t.TempDir(), "v0.bin")
+	if err := os.WriteFile(path, buf, 0644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+// migrateV0 在版本0的文件头中插入时间戳
+func migrateV0(old []byte) ([]byte, error) {
+	data := binary.LittleEndian.AppendUint32(nil, BinaryMagic)
+	data = binary.LittleEndian.AppendUint32(data, 1)
+	data = binary.LittleEndian.AppendUint64(data, 0)
+	return append(data, old[8:]...), nil
+}
+
+func TestRegisterMigration(t *testing.T) {
+	withMigrations(t, map[uint32]migration{1: {to: 2, fn: migrateV1}, 2: {to: 3, fn: migrateV2}})
+	path := writeV0File(t, map[string]string{"a": "1", "b": "2"})
+
+	nc := NewNGCache(1024*1024, nil)
+	defer nc.Close()
+	err := nc.Import(path, FormatBinary)
+	if err == nil || !strings.Contains(err.Error(), "no migration") {
+		t.Fatalf("expected a missing migration error, got %v", err)
+	}
+
+	RegisterMigration(0, 1, migrateV0)
+	if err := nc.Import(path, FormatBinary); err != nil {
+		t.Fatal(err)
+	}
+	if v, _ := nc.GetString("b"); v != "2" {
+		t.Fatalf("b = %q", v)
+	}
+}
+
+func TestMigrationErrors(t *testing.T) {
+	withMigrations(t, map[uint32]migration{1: {to: 2, fn: migrateV1}, 2: {to: 3, fn: migrateV2}})
+	path := writeV0File(t, map[string]string{"a": "1"})
+	nc := NewNGCache(1024*1024, nil)
+	defer nc.Close()
+
+	RegisterMigration(0, 1, func(old []byte) ([]byte, error) { return nil, errors.New("boom") })
+	if err := nc.Import(path, FormatBinary); err == nil || !strings.Contains(err.Error(), "boom") {
+		t.Fatalf("expected migration error, got %v", err)
+	}
+	RegisterMigration(0, 1, func(old []byte) ([]byte, error) { return old, nil })
+	if err := nc.Import(path, FormatBinary); err == nil {
+		t.Fatal("migration returning the old header should fail")
+	}
+
+	defer func() {
+		if recover() == nil {
+			t.Fatal("RegisterMigration should panic when toVersion <= fromVersion")
+		}
+	}()
+	RegisterMigration(2, 1, migrateV0)
+}
+
+// writeV1File 写出版本1的文件：文件头没有FormatCaps，条目之后没有索引
+func writeV1File(t *testing.T, entries []PersistEntry) string {
+	t.Helper()
+	buf := binary.LittleEndian.AppendUint32(nil, BinaryMagic)
+	buf = binary.LittleEndian.AppendUint32(buf, 1)
+	buf = binary.LittleEndian.AppendUint64(buf, 1700000000)
+	buf = binary.LittleEndian.AppendUint32(buf, uint32(len(entries)))
+	for _, entry := range entries {
+		buf = binary.LittleEndian.AppendUint32(buf, uint32(len(entry.Key)))
+		buf = append(buf, entry.Key...)
+		buf = binary.LittleEndian.AppendUint32(buf, uint32(len(entry.Value)))
+		buf = append(buf, entry.Value...)
+	}
+	path := filepath.Join(t.TempDir(), "v1.bin")
+	if err := os.WriteFile(path, buf, 0644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestMigrateV1(t *testing.T) {
+	path := writeV1File(t, []PersistEntry{{Key: "a", Value: []byte("1")}})
+
+	summary, err := InspectPersistFile(path)
+	if err != nil || summary.Version != BinaryVersion || summary.FormatCaps != 0 ||
+		summary.Timestamp != 1700000000 || summary.Entries != 1 {
+		t.Fatalf("summary = %+v, %v", summary, err)
+	}
+	nc := NewNGCache(1024*1024, nil)
+	defer nc.Close()
+	if err := nc.Import(path, FormatBinary); err != nil {
+		t.Fatal(err)
+	}
+	if v, _ := nc.GetString("a"); v != "1" {
+		t.Fatalf("a = %q", v)
+	}
+}
@@ -0,0 +1,17 @@
+//go:build !unix
+
+package ngcat
+
+import (
+	"context"
+)
+
+// writeMMapFile 不支持内存映射的平台上退化为普通的二进制格式写入
+func writeMMapFile(ctx context.Context, filePath string, data *PersistData) error {
+	return writePersistFile(ctx, filePath, FormatBinary, data, false)
+}
+
+// loadFromMMap 不支持内存映射的平台上退化为普通的二进制格式读取
+func (ng *NGCache) loadFromMMap(ctx context.Context, filePath string) error {
+	return ng.loadFromBinary(ctx, filePath)
+}
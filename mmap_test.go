@@ -0,0 +1,75 @@
+package ngcat
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestMMapRoundTrip(t *testing.T) {
+	dir := t.TempDir()
+	config := &PersistConfig{Enabled: true, FilePath: dir, FileName: "cache.mmap", Format: FormatMMap, Interval: time.Hour}
+	nc := NewNGCache(1024*1024, config)
+	for i := 0; i < 100; i++ {
+		nc.SetString(fmt.Sprintf("k%d", i), fmt.Sprintf("v%d", i), 0)
+	}
+	nc.SetBytes("empty", nil, 0)
+	if err := nc.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	// 映射写出的文件与普通二进制格式兼容
+	binary := NewNGCache(1024*1024, nil)
+	defer binary.Close()
+	if err := binary.Import(filepath.Join(dir, "cache.mmap"), FormatBinary); err != nil {
+		t.Fatal(err)
+	}
+	if v, _ := binary.GetString("k42"); v != "v42" {
+		t.Fatalf("k42 = %q", v)
+	}
+
+	reloaded := NewNGCache(1024*1024, config)
+	defer reloaded.Close()
+	if v, _ := reloaded.GetString("k99"); v != "v99" {
+		t.Fatalf("k99 = %q", v)
+	}
+	if v, err := reloaded.GetBytes("empty"); err != nil || len(v) != 0 {
+		t.Fatalf("empty = %q, %v", v, err)
+	}
+}
+
+// benchData 100MB的持久化数据，每个条目1MB
+func benchData() *PersistData {
+	value := make([]byte, 1024*1024)
+	data := &PersistData{Timestamp: time.Now().Unix()}
+	for i := 0; i < 100; i++ {
+		data.Entries = append(data.Entries, PersistEntry{Key: fmt.Sprintf("key_%d", i), Value: value})
+	}
+	return data
+}
+
+func BenchmarkWriteMMap100MB(b *testing.B) {
+	data := benchData()
+	path := filepath.Join(b.TempDir(), "cache.mmap")
+	b.SetBytes(100 * 1024 * 1024)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if err := writeMMapFile(context.Background(), path, data); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkWriteFile100MB(b *testing.B) {
+	data := benchData()
+	path := filepath.Join(b.TempDir(), "cache.bin")
+	b.SetBytes(100 * 1024 * 1024)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if err := writePersistFile(context.Background(), path, FormatBinary, data, false); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
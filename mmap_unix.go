@@ -0,0 +1,129 @@
+//go:build unix
+
+package ngcat
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"os"
+	"path/filepath"
+
+	"golang.org/x/sys/unix"
+)
+
+// writeMMapFile 通过内存映射写出二进制格式的持久化文件
+//
+// 先按条目大小计算文件长度并映射临时文件，条目直接编码到映射区域，
+// msync后再重命名为目标文件。任何错误都会删除临时文件，目标文件保持原样。
+func writeMMapFile(ctx context.Context, filePath string, data *PersistData) (err error) {
+	size := binaryCountOffset + 4
+	for _, entry := range data.Entries {
+		size += 4 + len(entry.Key) + 4 + len(entry.Value)
+	}
+	size += binaryIndexSize(len(data.Entries))
+
+	dir := filepath.Dir(filePath)
+	err = os.MkdirAll(dir, 0755)
+	if err != nil {
+		return newError(CodeCreateDir, dir, err)
+	}
+	file, err := os.CreateTemp(dir, filepath.Base(filePath)+".tmp*")
+	if err != nil {
+		return newError(CodeCreateTemp, dir, err)
+	}
+	defer func() {
+		if err != nil {
+			file.Close()
+			os.Remove(file.Name())
+		}
+	}()
+
+	err = file.Truncate(int64(size))
+	if err != nil {
+		return newError(CodeWriteFile, file.Name(), err)
+	}
+	mapped, err := unix.Mmap(int(file.Fd()), 0, size, unix.PROT_READ|unix.PROT_WRITE, unix.MAP_SHARED)
+	if err != nil {
+		return newError(CodeMmap, filePath, err)
+	}
+
+	err = encodeBinaryTo(ctx, mapped, data)
+	if err == nil {
+		err = unix.Msync(mapped, unix.MS_SYNC)
+		if err != nil {
+			err = newError(CodeMmap, "msync", err)
+		}
+	}
+	if uerr := unix.Munmap(mapped); err == nil && uerr != nil {
+		err = newError(CodeMmap, "munmap", uerr)
+	}
+	if err != nil {
+		return err
+	}
+
+	err = file.Close()
+	if err != nil {
+		return newError(CodeWriteFile, file.Name(), err)
+	}
+	err = os.Rename(file.Name(), filePath)
+	if err != nil {
+		return newError(CodeReplaceFile, filePath, err)
+	}
+	return nil
+}
+
+// encodeBinaryTo 将持久化数据按二进制格式编码到buf，buf长度必须恰好容纳全部数据
+func encodeBinaryTo(ctx context.Context, buf []byte, data *PersistData) error {
+	binary.LittleEndian.PutUint32(buf[0:], BinaryMagic)
+	binary.LittleEndian.PutUint32(buf[4:], BinaryVersion)
+	binary.LittleEndian.PutUint32(buf[8:], data.FormatCaps|CapIndex)
+	binary.LittleEndian.PutUint64(buf[12:], uint64(data.Timestamp))
+	binary.LittleEndian.PutUint32(buf[binaryCountOffset:], uint32(len(data.Entries)))
+	off := binaryCountOffset + 4
+	index := make([]indexSlot, 0, len(data.Entries))
+
+	for i, entry := range data.Entries {
+		if i%ctxCheckInterval == 0 {
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+		}
+		index = append(index, indexSlot{hash: indexKeyHash(entry.Key), offset: int64(off)})
+		binary.LittleEndian.PutUint32(buf[off:], uint32(len(entry.Key)))
+		off += 4
+		off += copy(buf[off:], entry.Key)
+		binary.LittleEndian.PutUint32(buf[off:], uint32(len(entry.Value)))
+		off += 4
+		off += copy(buf[off:], entry.Value)
+	}
+	putBinaryIndex(buf[off:], index, int64(off))
+	return nil
+}
+
+// loadFromMMap 以只读方式映射二进制持久化文件并加载
+func (ng *NGCache) loadFromMMap(ctx context.Context, filePath string) error {
+	file, err := os.Open(filePath)
+	if err != nil {
+		return newError(CodeOpenFile, filePath, err)
+	}
+	defer file.Close()
+
+	info, err := file.Stat()
+	if err != nil {
+		return newError(CodeStatFile, filePath, err)
+	}
+	if info.Size() == 0 {
+		// 空文件无法映射，交给读取器报告文件头错误
+		return ng.loadEntries(ctx, file, FormatBinary)
+	}
+
+	mapped, err := unix.Mmap(int(file.Fd()), 0, int(info.Size()), unix.PROT_READ, unix.MAP_SHARED)
+	if err != nil {
+		return newError(CodeMmap, filePath, err)
+	}
+	defer unix.Munmap(mapped)
+
+	// 读取器会复制键和值，解除映射后加载的数据仍然有效
+	return ng.loadEntries(ctx, bytes.NewReader(mapped), FormatBinary)
+}
@@ -0,0 +1,129 @@
+package ngcat
+
+import (
+	"runtime"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// multiDecodeParallel 批量读取的键数量超过该值时并行解码
+const multiDecodeParallel = 64
+
+// MultiDecodeError GetJSONMulti/GetAnyMulti中解码失败的键及其错误，
+// 返回的error为错误码CodeDecode的CacheError，可通过errors.As取得
+type MultiDecodeError map[string]error
+
+func (e MultiDecodeError) Error() string {
+	keys := make([]string, 0, len(e))
+	for key := range e {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	var b strings.Builder
+	for i, key := range keys {
+		if i > 0 {
+			b.WriteString("; ")
+		}
+		b.WriteString(key)
+		b.WriteString(": ")
+		b.WriteString(e[key].Error())
+	}
+	return b.String()
+}
+
+// GetJSONMulti 批量读取并以JSON解码多个键，newValue为每个键创建解码目标（如func() interface{} { return new(User) }）
+//
+// 返回解码成功的值和不存在的键；部分键解码失败时其余键照常返回，
+// error中的MultiDecodeError列出失败的键。键数量较多时并行解码。
+func (ng *NGCache) GetJSONMulti(keys []string, newValue func() interface{}) (map[string]interface{}, []string, error) {
+	return ng.getDecodedMulti("json", keys, newValue, ng.jsonOptions.unmarshal)
+}
+
+// GetAnyMulti 与GetJSONMulti相同，但以GetAny使用的序列化方式（默认为gob，见WithDefaultCodec）解码
+func (ng *NGCache) GetAnyMulti(keys []string, newValue func() interface{}) (map[string]interface{}, []string, error) {
+	return ng.getDecodedMulti("any", keys, newValue, ng.codec.Unmarshal)
+}
+
+// multiEntry 批量读取中单个键的原始值和解码结果
+type multiEntry struct {
+	key   string
+	data  []byte
+	value interface{}
+	err   error
+}
+
+// getMulti 批量读取多个键的原始值，返回存在的条目、不存在的键和读取失败的键
+func (ng *NGCache) getMulti(keys []string) ([]multiEntry, []string, MultiDecodeError) {
+	entries := make([]multiEntry, 0, len(keys))
+	var missing []string
+	var errs MultiDecodeError
+	for _, key := range keys {
+		data, err := ng.getWithPersist(key)
+		switch {
+		case err == ErrKeyNotFound:
+			missing = append(missing, key)
+		case err != nil:
+			if errs == nil {
+				errs = make(MultiDecodeError)
+			}
+			errs[key] = err
+		default:
+			entries = append(entries, multiEntry{key: key, data: data})
+		}
+	}
+	return entries, missing, errs
+}
+
+// getDecodedMulti 先读取所有原始值，再逐个或并行检查类型标记并解码
+func (ng *NGCache) getDecodedMulti(typ string, keys []string, newValue func() interface{}, unmarshal func([]byte, interface{}) error) (map[string]interface{}, []string, error) {
+	results, missing, errs := ng.getMulti(keys)
+	decode := func(d *multiEntry) {
+		data, err := ng.untagValue(typ, d.data)
+		if err != nil {
+			d.err = err
+			return
+		}
+		d.value = newValue()
+		d.err = unmarshal(data, d.value)
+	}
+
+	if len(results) > multiDecodeParallel {
+		workers := runtime.GOMAXPROCS(0)
+		if workers > len(results) {
+			workers = len(results)
+		}
+		var wg sync.WaitGroup
+		for w := 0; w < workers; w++ {
+			wg.Add(1)
+			go func(w int) {
+				defer wg.Done()
+				for i := w; i < len(results); i += workers {
+					decode(&results[i])
+				}
+			}(w)
+		}
+		wg.Wait()
+	} else {
+		for i := range results {
+			decode(&results[i])
+		}
+	}
+
+	values := make(map[string]interface{}, len(results))
+	for _, d := range results {
+		if d.err != nil {
+			if errs == nil {
+				errs = make(MultiDecodeError)
+			}
+			errs[d.key] = d.err
+			continue
+		}
+		values[d.key] = d.value
+	}
+	if errs != nil {
+		return values, missing, &CacheError{Code: CodeDecode, Detail: strconv.Itoa(len(errs)) + " keys", Err: errs}
+	}
+	return values, missing, nil
+}
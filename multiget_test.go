@@ -0,0 +1,120 @@
+package ngcat
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+)
+
+type multiGetItem struct {
+	ID   int
+	Name string
+}
+
+func newMultiGetItem() interface{} { return new(multiGetItem) }
+
+func TestGetJSONMulti(t *testing.T) {
+	nc := NewNGCache(1024*1024, nil)
+	defer nc.Close()
+
+	var keys []string
+	for i := 0; i < 40; i++ {
+		key := fmt.Sprintf("item:%d", i)
+		keys = append(keys, key)
+		nc.SetJSON(key, multiGetItem{ID: i, Name: key}, 0)
+	}
+	nc.SetString("item:bad", "{not json", 0)
+	keys = append(keys, "item:bad", "item:missing", "item:0")
+
+	values, missing, err := nc.GetJSONMulti(keys, newMultiGetItem)
+	if len(values) != 40 || values["item:7"].(*multiGetItem).ID != 7 {
+		t.Fatalf("values = %d, item:7 = %+v", len(values), values["item:7"])
+	}
+	if len(missing) != 1 || missing[0] != "item:missing" {
+		t.Fatalf("missing = %v", missing)
+	}
+	var ce *CacheError
+	var decodeErrs MultiDecodeError
+	if !errors.As(err, &ce) || ce.Code != CodeDecode || !errors.As(err, &decodeErrs) {
+		t.Fatalf("expected decode error, got %v", err)
+	}
+	if len(decodeErrs) != 1 || decodeErrs["item:bad"] == nil {
+		t.Fatalf("decode errors = %v", decodeErrs)
+	}
+
+	values, _, err = nc.GetJSONMulti(keys[:3], newMultiGetItem)
+	if err != nil || len(values) != 3 {
+		t.Fatalf("sequential path: %v, %d", err, len(values))
+	}
+}
+
+func TestGetAnyMulti(t *testing.T) {
+	nc := NewNGCache(1024*1024, nil)
+	defer nc.Close()
+	nc.SetAny("a", multiGetItem{ID: 1}, 0)
+	nc.SetAny("b", multiGetItem{ID: 2}, 60)
+
+	values, missing, err := nc.GetAnyMulti([]string{"a", "b", "c"}, newMultiGetItem)
+	if err != nil || len(missing) != 1 || values["b"].(*multiGetItem).ID != 2 {
+		t.Fatalf("values = %v, missing = %v, err = %v", values, missing, err)
+	}
+}
+
+func benchmarkMultiGetCache(b *testing.B, n int) (*NGCache, []string) {
+	nc := NewNGCache(16*1024*1024, nil)
+	keys := make([]string, n)
+	for i := range keys {
+		keys[i] = fmt.Sprintf("item:%d", i)
+		nc.SetJSON(keys[i], multiGetItem{ID: i, Name: keys[i]}, 0)
+	}
+	b.Cleanup(func() { nc.Close() })
+	return nc, keys
+}
+
+func BenchmarkGetJSONLoop30(b *testing.B) {
+	nc, keys := benchmarkMultiGetCache(b, 30)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		values := make(map[string]interface{}, len(keys))
+		for _, key := range keys {
+			item := new(multiGetItem)
+			if err := nc.GetJSON(key, item); err == nil {
+				values[key] = item
+			}
+		}
+	}
+}
+
+func BenchmarkGetJSONMulti30(b *testing.B) {
+	nc, keys := benchmarkMultiGetCache(b, 30)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		nc.GetJSONMulti(keys, newMultiGetItem)
+	}
+}
+
+func BenchmarkGetJSONLoop500(b *testing.B) {
+	nc, keys := benchmarkMultiGetCache(b, 500)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		values := make(map[string]interface{}, len(keys))
+		for _, key := range keys {
+			item := new(multiGetItem)
+			if err := nc.GetJSON(key, item); err == nil {
+				values[key] = item
+			}
+		}
+	}
+}
+
+func BenchmarkGetJSONMulti500(b *testing.B) {
+	nc, keys := benchmarkMultiGetCache(b, 500)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		nc.GetJSONMulti(keys, newMultiGetItem)
+	}
+}
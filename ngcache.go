@@ -1,11 +1,16 @@
 package ngcat
 
 import (
-	"errors"
+	"context"
+	"log/slog"
+	"math"
+	"strconv"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/coocood/freecache"
+	"go.opentelemetry.io/otel/trace"
 )
 
 // PersistFormat 持久化格式类型
@@ -16,6 +21,15 @@ const (
 	FormatJSON PersistFormat = iota
 	// FormatBinary 自定义二进制格式持久化
 	FormatBinary
+	// FormatWAL 预写日志持久化，每次永久缓存的写入和删除追加一条记录，
+	// 启动时加载二进制快照并重放日志，关闭时将日志压缩为新的快照
+	FormatWAL
+	// FormatDelta 增量持久化，记录被修改的永久缓存键，保存时只用这些键修补
+	// 二进制快照，并将本次修改的条目另外写入"<文件名>.delta"
+	FormatDelta
+	// FormatMMap 通过内存映射读写二进制格式文件，适合很大的缓存；
+	// 不支持内存映射的平台（如Windows）上等同于FormatBinary
+	FormatMMap
 )
 
 // PersistConfig 持久化配置
@@ -30,96 +44,527 @@ type PersistConfig struct {
 	Format PersistFormat
 	// Interval 持久化间隔时间
 	Interval time.Duration
+	// MaxPersistEntries 持久化文件中的最大条目数量，超过时按键排序丢弃排在最后的条目，0表示不限制
+	MaxPersistEntries int
+	// MaxFileSizeBytes 持久化文件的最大字节数，超过时从值最大的条目开始丢弃，0表示不限制
+	MaxFileSizeBytes int64
+	// MaxRetries 写出持久化文件遇到暂时性错误（如网络文件系统返回的EAGAIN、EINTR）时的最大重试次数，0表示不重试
+	MaxRetries int
+	// RetryBackoff 第一次重试前的等待时间，之后每次翻倍并加入随机抖动，0表示使用DefaultRetryBackoff
+	RetryBackoff time.Duration
+	// SyncOnWrite 每次保存持久化文件后、重命名之前调用fsync，避免系统崩溃时丢失已确认的快照，
+	// 会增加保存的耗时
+	SyncOnWrite bool
+	// AutoCompactRatio WAL格式下，定时持久化后已失效的记录数（被覆盖或删除）与存活条目数之比超过该值时
+	// 在持久化协程中自动调用Compact，0表示不自动压缩
+	AutoCompactRatio float64
 }
 
 // NGCache 扩展缓存库
 type NGCache struct {
-	// cache freecache实例
-	cache *freecache.Cache
+	// cache 底层存储，默认为NGCache创建的freecache实例
+	cache ByteStore
+	// sharedStore 底层存储由NewNGCacheWithStore传入，可能与应用的其他部分共享
+	sharedStore bool
+	// ownedKeys 共享存储中由本实例写入的键，只在sharedStore时使用，由ownedMutex保护
+	ownedKeys  map[string]struct{}
+	ownedMutex sync.Mutex
 	// persistConfig 持久化配置
 	persistConfig *PersistConfig
 	// persistMutex 持久化操作互斥锁
 	persistMutex sync.RWMutex
 	// stopChan 停止持久化的通道
 	stopChan chan struct{}
+	// persistRoutineDone 持久化协程退出后关闭
+	persistRoutineDone chan struct{}
+	// persistRoutineMutex 保护stopChan和persistRoutineDone的替换与关闭
+	persistRoutineMutex sync.Mutex
 	// persistData 永久缓存数据（Expire=0的数据）
 	persistData map[string][]byte
 	// persistDataMutex 永久数据互斥锁
 	persistDataMutex sync.RWMutex
+	// bloom 持久化数据键的布隆过滤器，未启用WithPersistBloomFilter时为nil
+	bloom atomic.Pointer[bloomFilter]
+	// bloomInterval 检查是否需要重建布隆过滤器的间隔
+	bloomInterval time.Duration
+	// bloomDeletes 上次重建后从持久化数据删除键的次数
+	bloomDeletes atomic.Int64
+	// bloomNegatives 布隆过滤器确定不存在、跳过persistDataMutex的查询次数
+	bloomNegatives atomic.Int64
+	// bloomFalsePositives 布隆过滤器报告可能存在、实际不存在的查询次数
+	bloomFalsePositives atomic.Int64
+	// maxValueSize 单个值的最大字节数，0表示不限制
+	maxValueSize int
+	// maxKeyLen 键的最大字节数
+	maxKeyLen int
+	// clock 时间来源
+	clock Clock
+	// wal WAL模式下的日志文件
+	wal *walLog
+	// walMutex WAL互斥锁
+	walMutex sync.Mutex
+	// defaultTTL 以TTLDefault写入时使用的过期时间，0表示永久
+	defaultTTL time.Duration
+	// ttlPolicies 键前缀的默认过期时间，按前缀长度降序排列
+	ttlPolicies []TTLPolicy
+	// ttlPoliciesMutex 前缀过期时间互斥锁
+	ttlPoliciesMutex sync.RWMutex
+	// slidingDefault 没有匹配的前缀时读取带过期时间的键后延长的过期时间，0表示不延长
+	slidingDefault time.Duration
+	// slidingPolicies 键前缀的滑动过期时间，按前缀长度降序排列
+	slidingPolicies []TTLPolicy
+	// slidingMutex 滑动过期时间互斥锁
+	slidingMutex sync.RWMutex
+	// slidingActive 是否设置了滑动过期时间，读取时据此跳过查找
+	slidingActive atomic.Bool
+	// refreshGroup 合并GetOrRefresh的并发加载
+	refreshGroup flightGroup
+	// computeGroup 合并GetOrCompute的并发计算
+	computeGroup flightGroup
+	// dirty 增量模式下自上次保存以来被修改的永久缓存键
+	dirty map[string]struct{}
+	// dirtyMutex 脏键集合互斥锁
+	dirtyMutex sync.Mutex
+	// hotKeys 热点键统计，未启用时为nil
+	hotKeys *hotKeyTracker
+	// compressOver 超过该字节数的值压缩存储，0表示不启用值压缩
+	compressOver int
+	// keyLocks 按键分段的写入锁，保证同一键上的复合操作不与其他写入交错
+	keyLocks [keyLockStripes]sync.Mutex
+	// promotePolicy 读取时写回freecache的策略
+	promotePolicy PromotePolicy
+	// promoteProbability PromoteProbabilistic策略的写回概率
+	promoteProbability float64
+	// promotions 写回freecache的次数
+	promotions atomic.Int64
+	// promoteTracker 合并同一键的并发写回
+	promoteTracker promoteTracker
+	// promotionsDeduped 因同一键的写回进行中或刚完成而跳过的写回次数
+	promotionsDeduped atomic.Int64
+	// maxEntrySize freecache可接受的键和值的总长度上限
+	maxEntrySize int
+	// capacity freecache的实际容量（不小于freecache的最小容量），传入的存储容量未知时为0
+	capacity int
+	// logger 日志输出
+	logger *slog.Logger
+	// janitor 过期回调检查器，未设置WithOnExpire时为nil
+	janitor *janitor
+	// patterns KeysMatching编译后的正则表达式缓存
+	patterns sync.Map
+	// counters SetInt64AtomicAdd维护的计数器
+	counters AtomicInt64Counter
+	// backend 缓存背后的数据源，未设置时为nil
+	backend Backend
+	// onError 后台任务出错时的回调
+	onError func(err error)
+	// ctx 在Close时取消，用于停止后台任务
+	ctx context.Context
+	// cancel 取消ctx
+	cancel context.CancelFunc
+	// tasks 正在运行的后台任务
+	tasks sync.WaitGroup
+	// inflight 正在进行的读写调用，Close在最后一次持久化之前等待
+	inflight sync.WaitGroup
+	// inflightMutex 保证Close开始等待后不再有调用计入inflight
+	inflightMutex sync.RWMutex
+	// closing Close已开始等待正在进行的读写调用
+	closing bool
+	// drained 已调用Drain，写入返回ErrDrained，持有inflightMutex的写锁时修改
+	drained atomic.Bool
+	// quotas 通过RegisterQuota登记的前缀配额
+	quotas []*Quota
+	// quotasMutex 配额列表互斥锁
+	quotasMutex sync.RWMutex
+	// codec SetAny/GetAny使用的序列化方式
+	codec Codec
+	// meta 条目的最后写入和读取时间
+	meta metaTracker
+	// trackAccess 是否记录最后读取时间
+	trackAccess bool
+	// trackCreation 是否在存储的值前记录写入时间
+	trackCreation bool
+	// loadFailurePolicy 持久化文件加载失败时的处理策略
+	loadFailurePolicy LoadFailurePolicy
+	// consistencyCheck 加载后的一致性检查模式
+	consistencyCheck ConsistencyCheck
+	// recoveredEntries RecoverPartial策略下从损坏的持久化文件中恢复的条目数量，由persistDataMutex保护
+	recoveredEntries int64
+	// persistFailures 定时持久化连续失败的次数
+	persistFailures int
+	// persistBackoff 断路器当前的暂停时长，为0时断路器处于关闭状态
+	persistBackoff time.Duration
+	// persistRetryAt 断路器打开时，下一次尝试定时持久化的时间
+	persistRetryAt time.Time
+	// persistNextAt 早于该时间的定时持久化tick被跳过，只在持久化协程中访问
+	persistNextAt time.Time
+	// adaptivePersist 保存耗时超过间隔的一半时拉长定时持久化的间隔
+	adaptivePersist bool
+	// savesInFlight 正在进行的保存数量
+	savesInFlight atomic.Int32
+	// persistSkipped 因保存正在进行或间隔被拉长而跳过的定时持久化次数
+	persistSkipped atomic.Int64
+	// compactions 压缩持久化文件的次数
+	compactions atomic.Int64
+	// compactedBytes 压缩持久化文件回收的字节数
+	compactedBytes atomic.Int64
+	// coalescer 永久缓存写入合并，未设置WithCoalesceWrites时为nil
+	coalescer *coalescer
+	// tracer 为类型化Set/Get创建span，未设置WithOTelTracer时为nil
+	tracer trace.Tracer
+	// histograms 值大小和读取延迟的直方图，未设置WithHistograms时为nil
+	histograms *histograms
+	// bus 事件总线，第一次调用EventBus时创建
+	bus atomic.Pointer[Bus]
+	// eventBuffer SubscribeChan创建的通道的缓冲区大小
+	eventBuffer int
+	// persistReadOnly 只加载持久化文件，不写入
+	persistReadOnly bool
+	// preloadPolicy 加载持久化数据时写入freecache的策略
+	preloadPolicy PreloadPolicy
+	// preloadTopN PreloadTopN策略预加载的键数量
+	preloadTopN int
+	// preloadPrefixes PreloadPrefixes策略预加载的键前缀
+	preloadPrefixes []string
+	// preloadHot PreloadTopN策略从热点键列表读取的键，由persistDataMutex保护
+	preloadHot map[string]struct{}
+	// lazyLoad 创建缓存时是否在后台加载带索引的持久化文件
+	lazyLoad bool
+	// lazyIndex 后台加载期间用于读取持久化文件的索引，加载完成后为nil，由lazyMutex保护
+	lazyIndex *binaryIndex
+	lazyMutex sync.RWMutex
+	// lazyLoading 后台加载是否正在进行，未进行时读取不获取lazyMutex
+	lazyLoading atomic.Bool
+	// shadow 影子持久化的目标文件，未设置WithShadowPersist时为nil
+	shadow *shadowTarget
+	// jsonOptions JSON序列化选项
+	jsonOptions JSONOptions
+	// strictTTL TTLDefault以外的负数过期时间返回ErrInvalidTTL
+	strictTTL bool
+	// strictTypes 类型化的写入在值前加上类型标记，读取时检查
+	strictTypes bool
+	// acceptUntagged 严格类型模式下接受没有类型标记的旧值
+	acceptUntagged bool
+	// negativeTTLWarning 未启用strictTTL时只警告一次负数过期时间
+	negativeTTLWarning sync.Once
 }
 
+// DefaultMaxKeyLen 默认的键最大长度，与freecache的内部限制一致，也是WithMaxKeyLen允许的最大值
+//
+// 所有写入入口都检查键长度，加载持久化文件和WAL时超长的键被跳过并通过WithOnError报告。
+const DefaultMaxKeyLen = 65535
+
+// TTLPermanent 作为expireSeconds传入时写入永久缓存，不会过期并保存到持久化数据中
+const TTLPermanent = 0
+
+// TTLDefault 作为expireSeconds传入时使用键前缀的默认过期时间（见SetTTLPolicy），
+// 没有匹配的前缀时使用缓存的默认过期时间（见WithDefaultTTL），都未设置时为永久缓存
+const TTLDefault = -1
+
 // NewNGCache 创建新的扩展缓存实例
-func NewNGCache(size int, config *PersistConfig) *NGCache {
+//
+// NewNGCache无法返回错误：持久化文件加载失败时，FailStartup策略（默认）按StartEmpty处理，
+// 即记录日志并以空缓存启动。需要在加载失败时中止启动请使用Open。
+func NewNGCache(size int, config *PersistConfig, opts ...Option) *NGCache {
+	ng, _ := newNGCache(size, nil, config, false, opts)
+	return ng
+}
+
+// NewNGCacheWithStore 以已有的存储创建扩展缓存实例，持久化和类型化读写都建立在store之上
+//
+// store可以是已调优或与应用其他部分共享的*freecache.Cache，也可以是其他存储的适配器（如MapStore）。
+// NGCache记录自己写入的键，Flush、Keys、ScanPrefix等遍历只访问这些键，存储中的其他条目不受影响；
+// 存储的命中、淘汰等统计无法按键区分，因此不报告，ResetStats也不会清零。
+// 存储的容量未知，因此不检查单个条目的大小上限（超出时由存储的Set返回错误），LoadFactor返回0。
+// 持久化文件加载失败以空缓存启动时，只删除从文件加载的键。
+func NewNGCacheWithStore(store ByteStore, config *PersistConfig, opts ...Option) *NGCache {
+	ng, _ := newNGCache(0, store, config, false, opts)
+	return ng
+}
+
+// Open 创建新的扩展缓存实例，按WithLoadFailurePolicy设置的策略处理持久化文件的加载错误
+//
+// FailStartup策略（默认）下加载失败时返回nil和加载错误，持久化文件保持原样。
+func Open(size int, config *PersistConfig, opts ...Option) (*NGCache, error) {
+	return newNGCache(size, nil, config, true, opts)
+}
+
+// newNGCache 创建缓存实例，store为nil时创建size字节的freecache，canFail为false时FailStartup按StartEmpty处理
+func newNGCache(size int, store ByteStore, config *PersistConfig, canFail bool, opts []Option) (*NGCache, error) {
+	// 复制调用方的配置，ReloadConfig修改的是缓存自己的副本
+	if config != nil {
+		c := *config
+		config = &c
+	}
 	ng := &NGCache{
-		cache:         freecache.NewCache(size),
 		persistConfig: config,
 		stopChan:      make(chan struct{}),
 		persistData:   make(map[string][]byte),
+		maxKeyLen:     DefaultMaxKeyLen,
+		clock:         realClock{},
+		logger:        slog.Default(),
+		codec:         GobCodec{},
+		jsonOptions:   defaultJSONOptions,
+	}
+	for _, opt := range opts {
+		opt(ng)
+	}
+	// 未单独设置选项的JSONCodec使用缓存的JSON选项
+	if c, ok := ng.codec.(JSONCodec); ok && c.Options == nil {
+		ng.codec = JSONCodec{Options: &ng.jsonOptions}
+	}
+	if ng.maxKeyLen <= 0 || ng.maxKeyLen > DefaultMaxKeyLen {
+		ng.maxKeyLen = DefaultMaxKeyLen
+	}
+	ng.ctx, ng.cancel = context.WithCancel(context.Background())
+	if store != nil {
+		ng.cache = store
+		ng.sharedStore = true
+		ng.ownedKeys = make(map[string]struct{})
+		ng.maxEntrySize = math.MaxInt
+	} else {
+		ng.cache = freecache.NewCacheCustomTimer(size, freecacheTimer{ng.clock})
+		ng.maxEntrySize = maxEntrySize(size)
+		ng.capacity = size
+		if ng.capacity < freecacheMinSize {
+			ng.capacity = freecacheMinSize
+		}
 	}
 
 	// 如果启用持久化，先加载数据，然后启动持久化协程
 	if config != nil && config.Enabled {
 		// 加载持久化数据
-		ng.loadFromPersist()
+		err := ng.handleLoadError(ng.loadFromPersist(), canFail)
+		if err != nil {
+			ng.cancel()
+			ng.closeWAL()
+			return nil, err
+		}
+		// 后台加载完成后再检查
+		if !ng.lazyLoading.Load() {
+			ng.checkConsistencyAfterLoad()
+		}
 		// 启动持久化协程
-		go ng.persistRoutine()
+		if !ng.persistReadOnly {
+			ng.startPersistRoutine()
+		}
 	}
+	ng.startJanitor()
+	ng.startCoalescer()
+	ng.startBloomRebuilder()
 
-	return ng
+	return ng, nil
 }
 
 // Close 关闭缓存并执行最后一次持久化
+//
+// 最后一次持久化之前等待正在进行的读写调用完成，这些调用的写入都会包含在保存的文件中；
+// Close开始之后发起的调用不会被等待。
 func (ng *NGCache) Close() error {
+	ng.cancel()
+	ng.tasks.Wait()
+	ng.waitInflight()
+	ng.stopJanitor()
 	if ng.persistConfig != nil && ng.persistConfig.Enabled {
+		ng.persistRoutineMutex.Lock()
 		close(ng.stopChan)
-		return ng.saveToPersist()
+		ng.persistRoutineMutex.Unlock()
 	}
-	return nil
+	// Drain已经完成了最后一次持久化
+	var err error
+	if !ng.drained.Load() {
+		err = ng.persistFinal()
+	}
+	if ng.persistConfig != nil && ng.persistConfig.Enabled && ng.persistConfig.Format == FormatWAL {
+		if cerr := ng.closeWAL(); err == nil {
+			err = cerr
+		}
+	}
+	return err
 }
 
-// SetPermanent 设置永久缓存（expire=0）
+// persistFinal 写回计数器和合并中的写入，然后进行最后一次持久化，WAL模式下压缩日志
+func (ng *NGCache) persistFinal() error {
+	// 计数器先写回，随后的持久化才能包含最终值
+	err := ng.flushCounters()
+	ng.flushCoalesced()
+	if ng.persistConfig == nil || !ng.persistConfig.Enabled || ng.persistReadOnly {
+		return err
+	}
+	if ng.persistConfig.Format == FormatWAL {
+		if cerr := ng.compactWAL(context.Background()); err == nil {
+			err = cerr
+		}
+		return err
+	}
+	if serr := ng.saveToPersist(); err == nil {
+		err = serr
+	}
+	return err
+}
+
+// beginOp 将一次读写调用计入inflight，返回true时调用方需在结束时调用inflight.Done
+//
+// Close或Drain开始等待之后不再计入，避免WaitGroup.Add与Wait并发。Drain之后不再有写入，读取无需等待。
+func (ng *NGCache) beginOp() bool {
+	ng.inflightMutex.RLock()
+	defer ng.inflightMutex.RUnlock()
+	if ng.closing || ng.drained.Load() {
+		return false
+	}
+	ng.inflight.Add(1)
+	return true
+}
+
+// waitInflight 停止计入新的读写调用并等待正在进行的调用完成
+func (ng *NGCache) waitInflight() {
+	ng.inflightMutex.Lock()
+	ng.closing = true
+	ng.inflightMutex.Unlock()
+	ng.inflight.Wait()
+}
+
+// SetBytesWithTTL 以字节数组作为键设置值，与SetBytes使用同一写入路径
+//
+// expireSeconds为TTLPermanent时写入永久缓存，无论是否启用持久化都会保存到持久化数据中，
+// 未启用持久化时这些数据只在内存中作为freecache淘汰后的回退。
+func (ng *NGCache) SetBytesWithTTL(key []byte, value []byte, expireSeconds int) error {
+	return ng.setTyped("bytes", string(key), value, expireSeconds)
+}
+
+// GetBytesByKey 以字节数组作为键读取值，与GetBytes使用同一读取路径，键不存在时返回ErrKeyNotFound
+func (ng *NGCache) GetBytesByKey(key []byte) ([]byte, error) {
+	return ng.getTyped("bytes", string(key))
+}
+
+// SetPermanent 设置永久缓存，等同于SetBytesWithTTL(key, value, TTLPermanent)
+//
+// Deprecated: 使用SetBytesWithTTL。早期版本在未启用持久化时不写入持久化数据，与其他Set*不一致，现已统一。
 func (ng *NGCache) SetPermanent(key []byte, value []byte) error {
-	// 设置到freecache（永久缓存）
-	err := ng.cache.Set(key, value, 0)
+	return ng.SetBytesWithTTL(key, value, TTLPermanent)
+}
+
+// SetPermanentBatch 批量设置永久缓存，适合启动预热等一次写入大量永久缓存的场景
+//
+// 所有条目先全部检查并编码，任何一个失败时返回错误且不写入任何条目；写入时持有所有键的分段锁，
+// 持久化数据只加锁一次，freecache在持久化数据锁之外写入。
+func (ng *NGCache) SetPermanentBatch(entries map[string][]byte) error {
+	started, err := ng.beginWrite()
 	if err != nil {
 		return err
 	}
+	if started {
+		defer ng.inflight.Done()
+	}
+	prepared := make([]preparedEntry, 0, len(entries))
+	keys := make([]string, 0, len(entries))
+	for key, value := range entries {
+		err := ng.checkKeyLen(len(key))
+		if err == nil {
+			err = ng.checkValueSize(len(value))
+		}
+		if err == nil {
+			value, err = ng.encodeValue(value)
+		}
+		if err == nil && len(key)+len(value) > ng.maxEntrySize {
+			err = &ValueTooLargeError{Size: len(value), Max: ng.maxEntrySize - len(key)}
+		}
+		if err != nil {
+			return newError(CodeStoreFailed, "batch entry "+strconv.Quote(key), err)
+		}
+		prepared = append(prepared, preparedEntry{key: key, value: value})
+		keys = append(keys, key)
+	}
 
-	// 如果启用持久化，同时保存到持久化数据
-	if ng.persistConfig != nil && ng.persistConfig.Enabled {
-		ng.persistDataMutex.Lock()
-		ng.persistData[string(key)] = value
-		ng.persistDataMutex.Unlock()
+	unlock := ng.lockKeys(keys...)
+	defer unlock()
+
+	// 与其他永久缓存的写入一样，无论是否启用持久化都保存到持久化数据中
+	ng.persistDataMutex.Lock()
+	for _, p := range prepared {
+		ng.dropCoalesced(p.key)
+		ng.persistData[p.key] = cloneBytes(p.value)
+		ng.bloomAdd(p.key)
 	}
+	ng.persistDataMutex.Unlock()
 
+	now := ng.clock.Now().Unix()
+	for _, p := range prepared {
+		ng.appendWAL(walOpSet, p.key, p.value)
+		ng.markDirty(p.key)
+		ng.forgetExpiry(p.key)
+		ng.counters.Delete(p.key)
+		err := ng.storeSet(p.key, p.value, 0)
+		if err != nil {
+			return err
+		}
+		ng.noteWriteAt(p.key, p.value, 0, true, now)
+	}
 	return nil
 }
 
-// GetPermanent 获取永久缓存
+// GetPermanent 读取键，等同于GetBytesByKey
+//
+// Deprecated: 使用GetBytesByKey。早期版本在未启用持久化时不从持久化数据读取，现与其他Get*一致。
 func (ng *NGCache) GetPermanent(key []byte) ([]byte, error) {
-	// 首先尝试从freecache获取
-	value, err := ng.cache.Get(key)
-	if err == nil {
-		return value, nil
+	return ng.GetBytesByKey(key)
+}
+
+// DeletePermanent 删除永久缓存，同时从freecache和持久化数据中删除
+//
+// 键不存在时返回nil，可以重复调用。
+func (ng *NGCache) DeletePermanent(key []byte) error {
+	err := ng.checkKeyLen(len(key))
+	if err != nil {
+		return err
 	}
+	ng.deleteWithPersist(string(key))
+	return nil
+}
 
-	// 如果freecache中没有，尝试从持久化数据获取
-	if ng.persistConfig != nil && ng.persistConfig.Enabled {
-		ng.persistDataMutex.RLock()
-		value, exists := ng.persistData[string(key)]
-		ng.persistDataMutex.RUnlock()
-		if exists {
-			// 重新加载到freecache
-			ng.cache.Set(key, value, 0)
-			return value, nil
-		}
+// DefaultTTL 返回缓存的默认过期时间
+func (ng *NGCache) DefaultTTL() time.Duration {
+	return ng.defaultTTL
+}
+
+// resolveTTL 将TTLDefault替换为键的默认过期时间（见SetTTLPolicy），其他负数按TTLPermanent处理
+func (ng *NGCache) resolveTTL(key string, expireSeconds int) int {
+	if expireSeconds == TTLDefault {
+		return int(ng.defaultTTLFor(key) / time.Second)
+	}
+	if expireSeconds < 0 {
+		return TTLPermanent
 	}
+	return expireSeconds
+}
 
-	return nil, err
+// checkTTL 检查expireSeconds，TTLDefault以外的负数在启用WithStrictTTL时返回ErrInvalidTTL，
+// 否则按TTLPermanent写入并记录一次警告
+func (ng *NGCache) checkTTL(expireSeconds int) error {
+	if expireSeconds >= 0 || expireSeconds == TTLDefault {
+		return nil
+	}
+	if ng.strictTTL {
+		return ErrInvalidTTL
+	}
+	ng.negativeTTLWarning.Do(func() {
+		ng.logger.Warn("ngcat: expireSeconds为TTLDefault以外的负数，按永久缓存写入；下一版本起将返回ErrInvalidTTL，可通过WithStrictTTL提前启用",
+			"expireSeconds", expireSeconds)
+	})
+	return nil
 }
 
-// 常见错误定义
-var (
-	ErrKeyNotFound = errors.New("key not found")
-	ErrInvalidType = errors.New("invalid type")
-)
+// checkKeyLen 检查键长度是否超过上限
+func (ng *NGCache) checkKeyLen(size int) error {
+	if size > ng.maxKeyLen {
+		return ErrKeyTooLong
+	}
+	return nil
+}
+
+// checkValueSize 检查值大小是否超过上限
+func (ng *NGCache) checkValueSize(size int) error {
+	if ng.maxValueSize > 0 && size > ng.maxValueSize {
+		return &ValueTooLargeError{Size: size, Max: ng.maxValueSize}
+	}
+	return nil
+}
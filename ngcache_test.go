@@ -0,0 +1,517 @@
+package ngcat
+
+import (
+	"bytes"
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"math"
+	"math/big"
+	"net"
+	"strings"
+	"testing"
+	"time"
+	"unicode/utf8"
+)
+
+func TestMaxValueSize(t *testing.T) {
+	nc := NewNGCache(1024*1024, nil, WithMaxValueSize(64))
+	defer nc.Close()
+
+	err := nc.SetBytes("big", make([]byte, 100), 0)
+	if !errors.Is(err, ErrValueTooLarge) {
+		t.Fatalf("expected ErrValueTooLarge, got %v", err)
+	}
+	var sizeErr *ValueTooLargeError
+	if !errors.As(err, &sizeErr) || sizeErr.Size != 100 || sizeErr.Max != 64 {
+		t.Fatalf("unexpected error detail: %#v", err)
+	}
+	if _, err := nc.GetBytes("big"); err != ErrKeyNotFound {
+		t.Fatalf("rejected value was stored: %v", err)
+	}
+
+	if err := nc.SetJSON("json", strings.Repeat("x", 65), 0); !errors.Is(err, ErrValueTooLarge) {
+		t.Fatalf("SetJSON: expected ErrValueTooLarge, got %v", err)
+	}
+	if err := nc.SetAny("gob", strings.Repeat("x", 65), 0); !errors.Is(err, ErrValueTooLarge) {
+		t.Fatalf("SetAny: expected ErrValueTooLarge, got %v", err)
+	}
+	if err := nc.SetString("small", "ok", 0); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestTypedGetMismatch(t *testing.T) {
+	nc := NewNGCache(1024*1024, nil)
+	defer nc.Close()
+
+	nc.SetBytes("byte", []byte{2}, 0)
+	if _, err := nc.GetBool("byte"); !errors.Is(err, ErrInvalidType) {
+		t.Fatalf("GetBool of arbitrary byte err = %v", err)
+	}
+	nc.SetBool("bool", true, 0)
+	if v, err := nc.GetBool("bool"); err != nil || !v {
+		t.Fatalf("GetBool = %v, %v", v, err)
+	}
+
+	nc.SetFloat64("float", 1.5, 0)
+	if _, err := nc.GetString("float"); !errors.Is(err, ErrInvalidEncoding) {
+		t.Fatalf("GetString of float64 err = %v", err)
+	}
+	if _, err := nc.GetStringZeroCopy("float"); !errors.Is(err, ErrInvalidEncoding) {
+		t.Fatalf("GetStringZeroCopy of float64 err = %v", err)
+	}
+	if _, err := nc.GetInt32("float"); !errors.Is(err, ErrInvalidType) {
+		t.Fatalf("GetInt32 of float64 err = %v", err)
+	}
+	nc.SetString("utf8", "张三", 0)
+	if v, err := nc.GetString("utf8"); err != nil || v != "张三" {
+		t.Fatalf("GetString = %q, %v", v, err)
+	}
+}
+
+func TestIntCrossWidth(t *testing.T) {
+	for _, fallback := range []bool{false, true} {
+		t.Run(fmt.Sprintf("fallback=%v", fallback), func(t *testing.T) {
+			nc := NewNGCache(1024*1024, nil, WithPromotePolicy(PromoteNever, 0))
+			defer nc.Close()
+			nc.SetInt32("i32", -7, 0)
+			nc.SetInt64("i64", math.MinInt64, 0)
+			nc.SetInt("int", -42, 0)
+			if fallback {
+				// 从持久化数据读取
+				nc.clearLoaded(nil)
+			}
+
+			if v, err := nc.GetInt("int"); err != nil || v != -42 {
+				t.Fatalf("GetInt = %d, %v", v, err)
+			}
+			if v, err := nc.GetInt64("int"); err != nil || v != -42 {
+				t.Fatalf("GetInt64 of SetInt = %d, %v", v, err)
+			}
+			for key, want := range map[string]int64{"i32": -7, "i64": math.MinInt64, "int": -42} {
+				if v, err := nc.GetNumberAsInt64(key); err != nil || v != want {
+					t.Fatalf("GetNumberAsInt64(%s) = %d, %v", key, v, err)
+				}
+			}
+
+			_, err := nc.GetInt64("i32")
+			var ce *CacheError
+			if !errors.Is(err, ErrInvalidType) || !errors.As(err, &ce) ||
+				!strings.Contains(ce.Detail, "expected 8 bytes, got 4") || !strings.Contains(ce.Detail, "SetInt32") {
+				t.Fatalf("GetInt64 of int32 err = %v", err)
+			}
+			if _, err := nc.GetInt32("i64"); err == nil || !strings.Contains(err.Error(), "expected 4 bytes, got 8") {
+				t.Fatalf("GetInt32 of int64 err = %v", err)
+			}
+			if _, err := nc.GetInt("i32"); !errors.Is(err, ErrInvalidType) {
+				t.Fatalf("GetInt of int32 err = %v", err)
+			}
+			nc.SetBool("bool", true, 0)
+			if _, err := nc.GetNumberAsInt64("bool"); !errors.Is(err, ErrInvalidType) {
+				t.Fatalf("GetNumberAsInt64 of bool err = %v", err)
+			}
+		})
+	}
+}
+
+func TestRune(t *testing.T) {
+	nc := NewNGCache(1024*1024, nil)
+	defer nc.Close()
+
+	for _, r := range []rune{0, 'a', '张', 0xD7FF, 0xE000, utf8.MaxRune} {
+		nc.SetRune("r", r, 0)
+		if got, err := nc.GetRune("r"); err != nil || got != r {
+			t.Fatalf("GetRune(%U) = %U, %v", r, got, err)
+		}
+		if got, err := nc.GetInt32("r"); err != nil || got != r {
+			t.Fatalf("GetInt32 of rune %U = %d, %v", r, got, err)
+		}
+	}
+	for _, r := range []rune{0xD800, 0xDBFF, 0xDC00, 0xDFFF, utf8.MaxRune + 1, -1} {
+		nc.SetInt32("r", r, 0)
+		if _, err := nc.GetRune("r"); !errors.Is(err, ErrInvalidType) {
+			t.Fatalf("GetRune(%#x) err = %v", r, err)
+		}
+	}
+	nc.SetInt64("wide", 'a', 0)
+	if _, err := nc.GetRune("wide"); !errors.Is(err, ErrInvalidType) {
+		t.Fatalf("GetRune of int64 err = %v", err)
+	}
+}
+
+func TestBigFloat(t *testing.T) {
+	nc := NewNGCache(1024*1024, nil)
+	defer nc.Close()
+
+	third := new(big.Float).SetPrec(256).Quo(big.NewFloat(1).SetPrec(256), big.NewFloat(3))
+	pi, _, err := big.ParseFloat("3.14159265358979323846264338327950288419716939937510582097494459230781640628620899", 10, 256, big.ToNearestEven)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, v := range []*big.Float{
+		new(big.Float),
+		new(big.Float).Neg(new(big.Float)),
+		new(big.Float).SetInf(false),
+		new(big.Float).SetInf(true),
+		third,
+		pi,
+		new(big.Float).SetMode(big.ToZero).SetFloat64(-1.5),
+	} {
+		if err := nc.SetBigFloat("f", v, 0); err != nil {
+			t.Fatal(err)
+		}
+		got, err := nc.GetBigFloat("f")
+		if err != nil {
+			t.Fatalf("GetBigFloat(%v): %v", v, err)
+		}
+		// 逐位相等：值、符号、精度和舍入模式都保持不变
+		want, _ := v.GobEncode()
+		have, _ := got.GobEncode()
+		if !bytes.Equal(want, have) || got.Cmp(v) != 0 || got.Signbit() != v.Signbit() {
+			t.Fatalf("round trip of %v (prec %d) = %v (prec %d)", v, v.Prec(), got, got.Prec())
+		}
+	}
+	if got, _ := nc.GetBigFloat("f"); got.Mode() != big.ToZero {
+		t.Fatalf("mode = %v", got.Mode())
+	}
+
+	// big.Float不能表示NaN，产生NaN的运算会panic而不是得到可写入的值
+	func() {
+		defer func() {
+			if _, ok := recover().(big.ErrNaN); !ok {
+				t.Fatal("expected big.ErrNaN")
+			}
+		}()
+		inf := new(big.Float).SetInf(false)
+		new(big.Float).Sub(inf, inf)
+	}()
+
+	if err := nc.SetBigFloat("nil", nil, 0); err == nil {
+		t.Fatal("SetBigFloat(nil) succeeded")
+	}
+	nc.SetString("text", "not a float", 0)
+	if _, err := nc.GetBigFloat("text"); !errors.Is(err, ErrInvalidType) {
+		t.Fatalf("GetBigFloat of string err = %v", err)
+	}
+	nc.SetBytes("empty", nil, 0)
+	if _, err := nc.GetBigFloat("empty"); !errors.Is(err, ErrInvalidType) {
+		t.Fatalf("GetBigFloat of empty value err = %v", err)
+	}
+}
+
+func TestUUID(t *testing.T) {
+	nc := NewNGCache(1024*1024, nil)
+	defer nc.Close()
+
+	// 随机生成的v4 UUID：第6字节高4位为版本4，第8字节高2位为变体10
+	var id [16]byte
+	if _, err := rand.Read(id[:]); err != nil {
+		t.Fatal(err)
+	}
+	id[6] = id[6]&0x0f | 0x40
+	id[8] = id[8]&0x3f | 0x80
+	if err := nc.SetUUID("device:1", id, 0); err != nil {
+		t.Fatal(err)
+	}
+	if got, err := nc.GetUUID("device:1"); err != nil || got != id {
+		t.Fatalf("GetUUID = %x, %v, want %x", got, err, id)
+	}
+	if v, _ := persisted(nc, "device:1"); len(v) != 16 {
+		t.Fatalf("stored %d bytes, want 16", len(v))
+	}
+
+	nc.SetString("short", "abc", 0)
+	if _, err := nc.GetUUID("short"); !errors.Is(err, ErrInvalidType) {
+		t.Fatalf("GetUUID of 3 bytes err = %v", err)
+	}
+}
+
+func TestParseAndSetUUID(t *testing.T) {
+	nc := NewNGCache(1024*1024, nil)
+	defer nc.Close()
+
+	want := [16]byte{0x12, 0x3e, 0x45, 0x67, 0xe8, 0x9b, 0x12, 0xd3, 0xa4, 0x56, 0x42, 0x66, 0x14, 0x17, 0x40, 0x00}
+	if err := nc.ParseAndSetUUID("order:1", "123E4567-e89b-12d3-a456-426614174000", 0); err != nil {
+		t.Fatal(err)
+	}
+	if got, err := nc.GetUUID("order:1"); err != nil || got != want {
+		t.Fatalf("GetUUID = %x, %v, want %x", got, err, want)
+	}
+
+	for _, s := range []string{
+		"",
+		"123e4567e89b12d3a456426614174000",
+		"123e4567-e89b-12d3-a456-42661417400",
+		"123e4567-e89b-12d3-a456_426614174000",
+		"123e4567-e89b-12d3-a456-42661417400g",
+		"{123e4567-e89b-12d3-a456-426614174000}",
+	} {
+		var cerr *CacheError
+		if err := nc.ParseAndSetUUID("order:2", s, 0); !errors.As(err, &cerr) || cerr.Code != CodeEncode {
+			t.Fatalf("ParseAndSetUUID(%q) err = %v, want CodeEncode", s, err)
+		}
+	}
+	if _, err := nc.GetUUID("order:2"); err != ErrKeyNotFound {
+		t.Fatalf("invalid UUID was stored: %v", err)
+	}
+}
+
+func TestIP(t *testing.T) {
+	nc := NewNGCache(1024*1024, nil)
+	defer nc.Close()
+
+	for _, tc := range []struct {
+		ip   net.IP
+		size int
+	}{
+		{net.ParseIP("192.168.1.10"), 5}, // ParseIP返回16字节的IPv4映射地址
+		{net.IPv4(10, 0, 0, 1).To4(), 5},
+		{net.ParseIP("2001:db8::1"), 17},
+		{net.IPv6loopback, 17},
+		{nil, 1},
+	} {
+		if err := nc.SetIP("ip", tc.ip, 0); err != nil {
+			t.Fatal(err)
+		}
+		got, err := nc.GetIP("ip")
+		if err != nil {
+			t.Fatalf("GetIP(%v): %v", tc.ip, err)
+		}
+		if !got.Equal(tc.ip) || (tc.ip == nil) != (got == nil) {
+			t.Fatalf("round trip of %v = %v", tc.ip, got)
+		}
+		if v, _ := persisted(nc, "ip"); len(v) != tc.size {
+			t.Fatalf("%v stored in %d bytes, want %d", tc.ip, len(v), tc.size)
+		}
+	}
+	if got, _ := nc.GetIP("ip"); got != nil {
+		t.Fatalf("nil IP read back as %v", got)
+	}
+	nc.SetIP("v4", net.ParseIP("127.0.0.1"), 0)
+	if got, _ := nc.GetIP("v4"); len(got) != net.IPv4len {
+		t.Fatalf("IPv4 read back with %d bytes", len(got))
+	}
+
+	if err := nc.SetIP("bad", net.IP{1, 2, 3}, 0); err == nil {
+		t.Fatal("SetIP with 3 bytes succeeded")
+	}
+	for name, value := range map[string][]byte{
+		"empty":     {},
+		"v4 short":  {ipFamilyV4, 1, 2, 3},
+		"v6 as v4":  append([]byte{ipFamilyV6}, 1, 2, 3, 4),
+		"family":    {9, 1, 2, 3, 4},
+		"nil extra": {ipFamilyNil, 1},
+	} {
+		nc.SetBytes("raw", value, 0)
+		if _, err := nc.GetIP("raw"); !errors.Is(err, ErrInvalidType) {
+			t.Fatalf("GetIP of %s err = %v", name, err)
+		}
+	}
+}
+
+func TestComplexValues(t *testing.T) {
+	nc := NewNGCache(1024*1024, nil)
+	defer nc.Close()
+
+	nan, inf := math.NaN(), math.Inf(1)
+	// 逐位比较，NaN和负零也能区分
+	same := func(a, b float64) bool { return math.Float64bits(a) == math.Float64bits(b) }
+	for _, v := range []complex128{
+		0,
+		complex(math.Copysign(0, -1), math.Copysign(0, -1)),
+		complex(1.5, -2.25),
+		complex(nan, 1),
+		complex(inf, -inf),
+		complex(math.MaxFloat32, math.SmallestNonzeroFloat32),
+	} {
+		nc.SetComplex128("c128", v, 0)
+		got, err := nc.GetComplex128("c128")
+		if err != nil || !same(real(got), real(v)) || !same(imag(got), imag(v)) {
+			t.Fatalf("GetComplex128(%v) = %v, %v", v, got, err)
+		}
+
+		v64 := complex64(v)
+		nc.SetComplex64("c64", v64, 0)
+		got64, err := nc.GetComplex64("c64")
+		if err != nil || !same(float64(real(got64)), float64(real(v64))) || !same(float64(imag(got64)), float64(imag(v64))) {
+			t.Fatalf("GetComplex64(%v) = %v, %v", v64, got64, err)
+		}
+	}
+
+	if _, err := nc.GetComplex128("c64"); !errors.Is(err, ErrInvalidType) {
+		t.Fatalf("GetComplex128 of complex64 err = %v", err)
+	}
+	if _, err := nc.GetComplex64("c128"); !errors.Is(err, ErrInvalidType) {
+		t.Fatalf("GetComplex64 of complex128 err = %v", err)
+	}
+}
+
+func TestMaxKeyLen(t *testing.T) {
+	nc := NewNGCache(1024*1024, nil)
+	defer nc.Close()
+
+	// 默认上限与freecache一致，但返回的是本包的哨兵错误
+	if err := nc.SetString(strings.Repeat("k", DefaultMaxKeyLen+1), "v", 0); err != ErrKeyTooLong {
+		t.Fatalf("expected ErrKeyTooLong, got %v", err)
+	}
+	if err := nc.SetPermanent(make([]byte, DefaultMaxKeyLen+1), []byte("v")); err != ErrKeyTooLong {
+		t.Fatalf("SetPermanent: expected ErrKeyTooLong, got %v", err)
+	}
+
+	if err := nc.SetBytes(strings.Repeat("k", 70*1024), []byte("v"), 60); !errors.Is(err, ErrKeyTooLarge) {
+		t.Fatalf("70KB key: expected ErrKeyTooLarge, got %v", err)
+	}
+	if err := nc.SetBundle([]BundleEntry{{Key: strings.Repeat("k", 70*1024), Value: "v"}}, 0); !errors.Is(err, ErrKeyTooLarge) {
+		t.Fatalf("SetBundle: expected ErrKeyTooLarge, got %v", err)
+	}
+
+	short := NewNGCache(1024*1024, nil, WithMaxKeyLen(8))
+	defer short.Close()
+	if err := short.SetString("123456789", "v", 0); err != ErrKeyTooLong {
+		t.Fatalf("expected ErrKeyTooLong, got %v", err)
+	}
+	if err := short.SetString("12345678", "v", 0); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestSetPermanentBatch(t *testing.T) {
+	config := &PersistConfig{
+		Enabled:  true,
+		FilePath: t.TempDir(),
+		FileName: "cache.bin",
+		Format:   FormatBinary,
+		Interval: time.Hour,
+	}
+	nc := NewNGCache(1024*1024, config, WithMaxValueSize(64))
+	entries := map[string][]byte{"a": []byte("1"), "b": []byte("2"), "c": {}}
+	if err := nc.SetPermanentBatch(entries); err != nil {
+		t.Fatal(err)
+	}
+	for key, want := range entries {
+		if v, err := nc.GetPermanent([]byte(key)); err != nil || string(v) != string(want) {
+			t.Fatalf("%s = %q, %v", key, v, err)
+		}
+		if v, ok := persisted(nc, key); !ok || v != string(want) {
+			t.Fatalf("persisted %s = %q, %v", key, v, ok)
+		}
+	}
+
+	// 任何一个条目无效时不写入任何条目
+	err := nc.SetPermanentBatch(map[string][]byte{"d": []byte("4"), "big": make([]byte, 100)})
+	if !errors.Is(err, ErrValueTooLarge) || !strings.Contains(err.Error(), `"big"`) {
+		t.Fatalf("expected ErrValueTooLarge for big, got %v", err)
+	}
+	if _, err := nc.GetString("d"); err != ErrKeyNotFound {
+		t.Fatalf("d should not be written: %v", err)
+	}
+
+	if err := nc.Close(); err != nil {
+		t.Fatal(err)
+	}
+	reloaded := NewNGCache(1024*1024, config)
+	defer reloaded.Close()
+	if v, _ := reloaded.GetString("b"); v != "2" {
+		t.Fatalf("reloaded b = %q", v)
+	}
+}
+
+func TestBytesKeyAPIsSymmetric(t *testing.T) {
+	// 未启用持久化时两种入口的行为也应一致
+	nc := NewNGCache(1024*1024, nil)
+	defer nc.Close()
+
+	type api struct {
+		set func(key string, value []byte, expireSeconds int) error
+		get func(key string) ([]byte, error)
+	}
+	apis := map[string]api{
+		"string": {nc.SetBytes, nc.GetBytes},
+		"bytes": {
+			func(key string, value []byte, expireSeconds int) error {
+				return nc.SetBytesWithTTL([]byte(key), value, expireSeconds)
+			},
+			func(key string) ([]byte, error) { return nc.GetBytesByKey([]byte(key)) },
+		},
+		"permanent": {
+			func(key string, value []byte, expireSeconds int) error {
+				if expireSeconds != TTLPermanent {
+					return nc.SetBytesWithTTL([]byte(key), value, expireSeconds)
+				}
+				return nc.SetPermanent([]byte(key), value)
+			},
+			func(key string) ([]byte, error) { return nc.GetPermanent([]byte(key)) },
+		},
+	}
+	for name, a := range apis {
+		perm, ttl := name+":perm", name+":ttl"
+		if err := a.set(perm, []byte("value"), TTLPermanent); err != nil {
+			t.Fatal(err)
+		}
+		if err := a.set(ttl, []byte("value"), 60); err != nil {
+			t.Fatal(err)
+		}
+		if v, ok := persisted(nc, perm); !ok || v != "value" {
+			t.Fatalf("%s: permanent key not in persistData: %q, %v", name, v, ok)
+		}
+		if _, ok := persisted(nc, ttl); ok {
+			t.Fatalf("%s: TTL key should not be in persistData", name)
+		}
+
+		// 修改返回值不影响缓存，命中freecache和回退到持久化数据时都是如此
+		for _, path := range []string{"hit", "fallback"} {
+			if path == "fallback" {
+				nc.cache.Del([]byte(perm))
+			}
+			v, err := a.get(perm)
+			if err != nil || string(v) != "value" {
+				t.Fatalf("%s %s: %q, %v", name, path, v, err)
+			}
+			v[0] = 'X'
+			if v, _ := a.get(perm); string(v) != "value" {
+				t.Fatalf("%s %s: value modified through returned slice: %q", name, path, v)
+			}
+		}
+
+		if _, err := a.get(name + ":missing"); err != ErrKeyNotFound {
+			t.Fatalf("%s: miss err = %v", name, err)
+		}
+	}
+}
+
+func TestDeletePermanent(t *testing.T) {
+	config := &PersistConfig{
+		Enabled:  true,
+		FilePath: t.TempDir(),
+		FileName: "cache.bin",
+		Format:   FormatBinary,
+		Interval: time.Hour,
+	}
+	nc := NewNGCache(1024*1024, config)
+	if err := nc.SetPermanent([]byte("session:1"), []byte("token")); err != nil {
+		t.Fatal(err)
+	}
+	if err := nc.DeletePermanent([]byte("session:1")); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := nc.GetPermanent([]byte("session:1")); !errors.Is(err, ErrKeyNotFound) {
+		t.Fatalf("expected ErrKeyNotFound, got %v", err)
+	}
+	if _, ok := persisted(nc, "session:1"); ok {
+		t.Fatal("key should be removed from persistData")
+	}
+	// 重复删除不返回错误
+	if err := nc.DeletePermanent([]byte("session:1")); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := nc.Close(); err != nil {
+		t.Fatal(err)
+	}
+	reloaded := NewNGCache(1024*1024, config)
+	defer reloaded.Close()
+	if _, err := reloaded.GetPermanent([]byte("session:1")); !errors.Is(err, ErrKeyNotFound) {
+		t.Fatalf("deleted key was reloaded: %v", err)
+	}
+}
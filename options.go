@@ -0,0 +1,313 @@
+package ngcat
+
+import (
+	"log/slog"
+	"time"
+
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Option 缓存配置选项
+type Option func(*NGCache)
+
+// WithMaxValueSize 设置单个值的最大字节数，超过时写入返回ErrValueTooLarge
+func WithMaxValueSize(maxBytes int) Option {
+	return func(ng *NGCache) {
+		ng.maxValueSize = maxBytes
+	}
+}
+
+// WithMaxKeyLen 设置键的最大字节数，超过时写入返回ErrKeyTooLong，加载时跳过，默认为DefaultMaxKeyLen；
+// freecache无法存储更长的键，不大于0或超过DefaultMaxKeyLen时按DefaultMaxKeyLen处理
+func WithMaxKeyLen(n int) Option {
+	return func(ng *NGCache) {
+		ng.maxKeyLen = n
+	}
+}
+
+// WithClock 设置时间来源，主要用于测试中控制过期时间和持久化间隔
+func WithClock(clock Clock) Option {
+	return func(ng *NGCache) {
+		ng.clock = clock
+	}
+}
+
+// WithDefaultTTL 设置以TTLDefault写入时使用的默认过期时间
+func WithDefaultTTL(ttl time.Duration) Option {
+	return func(ng *NGCache) {
+		ng.defaultTTL = ttl
+	}
+}
+
+// WithSlidingTTL 设置滑动过期时间的默认值：通过Get*读取带过期时间的键时，将其过期时间延长为当前时间加slide
+//
+// 适合会话等需要在活跃期间保持的键。键前缀可通过SetSlidingTTL单独设置，永久缓存不受影响。
+func WithSlidingTTL(slide time.Duration) Option {
+	return func(ng *NGCache) {
+		ng.slidingDefault = slide
+		ng.updateSlidingActive()
+	}
+}
+
+// WithStrictTTL expireSeconds为TTLPermanent、TTLDefault以外的负数时返回ErrInvalidTTL
+//
+// 未启用时这些值按TTLPermanent写入并记录一次警告，下一版本起将默认启用。
+func WithStrictTTL() Option {
+	return func(ng *NGCache) {
+		ng.strictTTL = true
+	}
+}
+
+// WithStrictTypes 启用严格类型：类型化的Set*在值前加上一个字节的类型标记，Get*检查标记，
+// 类型不一致时返回ErrTypeMismatch（如"stored string, requested int64"）
+//
+// acceptUntagged为true时，没有类型标记的值（启用之前写入的值，或SetBundle、SetPermanentBatch等
+// 原始字节写入的值）按原样返回，否则同样返回ErrTypeMismatch；acceptUntagged只影响读取，新写入的值总是带有标记。
+// 兼容模式下定长类型的旧值按长度与带标记的值区分，变长类型的旧值以0x80-0x8D开头时按带标记的值处理（见hasTag）。
+// 标记是值的一部分，持久化文件原样保存；ViewBytes、ScanPrefix等直接访问字节的方法看到的值包含标记。
+func WithStrictTypes(acceptUntagged bool) Option {
+	return func(ng *NGCache) {
+		ng.strictTypes = true
+		ng.acceptUntagged = acceptUntagged
+	}
+}
+
+// WithReadOnlyPersistence 只读取持久化文件而不写入，用于从主实例的持久化文件加载数据的只读副本
+//
+// 启动时照常加载，但不启动持久化协程、Close时不保存，WAL格式不打开日志用于追加；
+// Save和ReloadConfig返回ErrReadOnly。写入只影响内存中的数据，通常配合ReadOnly使用。
+func WithReadOnlyPersistence() Option {
+	return func(ng *NGCache) {
+		ng.persistReadOnly = true
+	}
+}
+
+// WithPreload 设置加载持久化数据时哪些永久缓存同时写入freecache，默认为PreloadAll
+//
+// PreloadTopN和PreloadPrefixes应通过WithPreloadTopN和WithPreloadPrefixes设置。
+// 策略同样作用于Load和Import；不是PreloadAll时WithConsistencyCheck的加载后检查被跳过。
+func WithPreload(policy PreloadPolicy) Option {
+	return func(ng *NGCache) {
+		ng.preloadPolicy = policy
+	}
+}
+
+// WithPreloadTopN 加载时只将上次运行中访问次数最多的n个键写入freecache（PreloadTopN）
+//
+// 需要同时通过WithHotKeys启用热点键统计，capacity应不小于n；没有热点键列表时不预加载任何键。
+func WithPreloadTopN(n int) Option {
+	return func(ng *NGCache) {
+		ng.preloadPolicy = PreloadTopN
+		ng.preloadTopN = n
+	}
+}
+
+// WithPreloadPrefixes 加载时只将以prefixes之一开头的键写入freecache（PreloadPrefixes）
+func WithPreloadPrefixes(prefixes ...string) Option {
+	return func(ng *NGCache) {
+		ng.preloadPolicy = PreloadPrefixes
+		ng.preloadPrefixes = prefixes
+	}
+}
+
+// WithLazyLoad 持久化文件是带索引的二进制文件时，创建缓存后在后台加载条目
+//
+// 创建时只校验文件头和索引，并通过索引直接读取PreloadTopN的热点键，其余条目由后台协程顺序加载。
+// 加载完成前Get*在freecache中未命中时通过索引从文件读取；写入、删除、遍历、保存等访问持久化数据的操作
+// 等待加载完成，Close也会等待。后台加载失败时通过WithOnError报告，FailStartup按StartEmpty处理。
+// 文件没有索引或索引损坏时与未启用一样在创建时顺序加载；FormatWAL和FormatMMap不受影响。
+func WithLazyLoad() Option {
+	return func(ng *NGCache) {
+		ng.lazyLoad = true
+	}
+}
+
+// WithJSONOptions 设置JSON序列化选项，默认与encoding/json的默认行为一致（转义HTML、不缩进、数字解码为float64）
+//
+// 需要通过map[string]interface{}等通用结构读取大整数时应启用UseNumber。
+func WithJSONOptions(opts JSONOptions) Option {
+	return func(ng *NGCache) {
+		ng.jsonOptions = opts
+	}
+}
+
+// WithShadowPersist 每次保存持久化文件时以format将相同的快照额外写入path，用于切换格式前比较两种格式的输出
+//
+// 支持FormatJSON、FormatBinary和FormatMMap。影子文件的写入错误包装为CodeShadowPersist错误
+// 通过WithOnError报告，不影响主持久化文件和Save的返回值；启动时只从主持久化文件加载。
+// 可通过ComparePersistFiles比较两个文件。
+func WithShadowPersist(path string, format PersistFormat) Option {
+	return func(ng *NGCache) {
+		ng.shadow = &shadowTarget{path: path, format: format}
+	}
+}
+
+// WithHotKeys 启用热点键统计（见HotKeys），capacity为最多跟踪的键数量，
+// 每sampleRate次Get采样一次，sampleRate不大于1时记录每次Get
+func WithHotKeys(capacity, sampleRate int) Option {
+	return func(ng *NGCache) {
+		ng.hotKeys = newHotKeyTracker(capacity, sampleRate)
+	}
+}
+
+// WithHistograms 启用写入值大小和读取延迟的直方图（见Stats），每sampleRate次调用记录一次，
+// sampleRate不大于1时记录每次调用
+//
+// 值大小按实际存储的字节数（启用值压缩时为压缩后）记录；读取延迟按freecache命中、
+// 从持久化数据回退和未命中分别记录，延迟通过WithClock设置的时钟测量。
+func WithHistograms(sampleRate int) Option {
+	return func(ng *NGCache) {
+		ng.histograms = newHistograms(sampleRate)
+	}
+}
+
+// WithEventBuffer 设置事件总线SubscribeChan创建的通道的缓冲区大小，默认为DefaultEventBuffer
+//
+// 发布事件不会阻塞缓存操作，通道已满时事件被丢弃，消费较慢的订阅者应设置更大的缓冲区。
+func WithEventBuffer(size int) Option {
+	return func(ng *NGCache) {
+		ng.eventBuffer = size
+	}
+}
+
+// WithCompressValuesOver 启用值压缩，超过threshold字节的值以gzip压缩后存储
+//
+// 启用后所有新写入的值都带有一个字节的头部，持久化文件中保存的也是压缩后的形式。
+// 启用之前写入的、首字节不是0xFE或0xFF的值仍按原样读取。
+func WithCompressValuesOver(threshold int) Option {
+	return func(ng *NGCache) {
+		ng.compressOver = threshold
+	}
+}
+
+// WithPromotePolicy 设置读取时将永久缓存从持久化数据写回freecache的策略，默认为PromoteAlways，
+// probability只在PromoteProbabilistic时使用，表示每次读取写回的概率
+func WithPromotePolicy(policy PromotePolicy, probability float64) Option {
+	return func(ng *NGCache) {
+		ng.promotePolicy = policy
+		ng.promoteProbability = probability
+	}
+}
+
+// WithDefaultCodec 设置SetAny/GetAny（以及SetStruct/GetStruct）使用的序列化方式，默认为GobCodec；
+// SetJSON/GetJSON始终使用JSON
+func WithDefaultCodec(c Codec) Option {
+	return func(ng *NGCache) {
+		ng.codec = c
+	}
+}
+
+// WithAccessTracking 启用最后读取时间的记录（见GetMeta），以秒为精度，同一键每秒最多更新一次
+func WithAccessTracking() Option {
+	return func(ng *NGCache) {
+		ng.trackAccess = true
+	}
+}
+
+// WithCreationTracking 在每个存储的值前加上8字节的写入时间（Unix纳秒），可通过GetCreationTime读取，
+// 读取值时自动去掉该前缀
+//
+// 时间随值一起写入持久化文件，重启后仍然可用。启用前写入的值（包括持久化文件中的值）没有该前缀，
+// 无法正确读取，因此只应在新的缓存上启用，启用后也不应再关闭。
+func WithCreationTracking() Option {
+	return func(ng *NGCache) {
+		ng.trackCreation = true
+	}
+}
+
+// WithCoalesceWrites 启用永久缓存写入合并：写入立即更新freecache，持久化数据（以及WAL和增量记录）
+// 最多延迟interval后以最后一次写入的值更新，适合高频覆盖的永久缓存
+//
+// 同一键在窗口内的重复写入不再复制到持久化数据，显著减少内存分配和锁竞争。Save、Export、
+// SnapshotExport和Close会先写入所有合并中的值；进程崩溃时最多丢失最近interval内的永久缓存写入。
+func WithCoalesceWrites(interval time.Duration) Option {
+	return func(ng *NGCache) {
+		if interval > 0 {
+			ng.coalescer = newCoalescer(interval)
+		}
+	}
+}
+
+// WithPersistBloomFilter 在持久化数据的键前维护布隆过滤器，freecache未命中后过滤器确定不存在的键
+// 直接返回未命中，不再获取persistDataMutex
+//
+// 过滤器在写入持久化数据时更新，键的数量超过容量时自动扩容；删除的键在每隔interval的检查中通过重建移除，
+// 重建期间阻塞永久缓存的写入。确定不存在和假阳性的次数见CacheStats的BloomNegatives和BloomFalsePositives。
+// 适合未命中比例高的读取负载。
+func WithPersistBloomFilter(interval time.Duration) Option {
+	return func(ng *NGCache) {
+		if interval > 0 {
+			ng.bloomInterval = interval
+			ng.bloom.Store(newBloomFilter(0))
+		}
+	}
+}
+
+// WithLazyExpiryReclaim 每隔interval回收持久化数据中已经过期的键
+//
+// Deprecated: 带过期时间的写入现在直接从持久化数据删除被覆盖的永久值，不再需要回收，该选项不起作用。
+func WithLazyExpiryReclaim(interval time.Duration) Option {
+	return func(ng *NGCache) {}
+}
+
+// WithAdaptivePersistInterval 定时持久化的保存耗时超过间隔的一半时拉长间隔为两倍的保存耗时
+//
+// 未启用时，保存期间到达的tick同样会被跳过，但保存完成后的下一次tick仍会立即保存。
+func WithAdaptivePersistInterval() Option {
+	return func(ng *NGCache) {
+		ng.adaptivePersist = true
+	}
+}
+
+// WithLoadFailurePolicy 设置启动时持久化文件加载失败的处理策略，默认为FailStartup
+func WithLoadFailurePolicy(policy LoadFailurePolicy) Option {
+	return func(ng *NGCache) {
+		ng.loadFailurePolicy = policy
+	}
+}
+
+// WithConsistencyCheck 设置加载持久化文件后是否检查持久化数据与freecache的一致性，结果写入日志
+func WithConsistencyCheck(mode ConsistencyCheck) Option {
+	return func(ng *NGCache) {
+		ng.consistencyCheck = mode
+	}
+}
+
+// WithOTelTracer 为每次类型化的Set*/Get*调用创建OpenTelemetry span（ngcache.Set和ngcache.Get），
+// 带有cache.key、cache.type属性，Get还带有cache.hit属性。未设置时不创建span，也没有额外开销。
+//
+// Set*/Get*不接收context，span没有父span，在trace中作为独立的根span出现。
+func WithOTelTracer(tp trace.TracerProvider) Option {
+	return func(ng *NGCache) {
+		ng.tracer = tp.Tracer(tracerName)
+	}
+}
+
+// WithLogger 设置日志输出，默认为slog.Default()
+func WithLogger(logger *slog.Logger) Option {
+	return func(ng *NGCache) {
+		ng.logger = logger
+	}
+}
+
+// WithBackend 设置缓存背后的数据源，供RefreshAhead重新加载缓存值
+func WithBackend(backend Backend) Option {
+	return func(ng *NGCache) {
+		ng.backend = backend
+	}
+}
+
+// WithOnError 设置后台任务（如RefreshAhead）出错时的回调，未设置时错误写入日志
+func WithOnError(fn func(err error)) Option {
+	return func(ng *NGCache) {
+		ng.onError = fn
+	}
+}
+
+// WithOnExpire 设置通过NotifyOnExpire登记的键过期时的回调，并启动过期检查协程，
+// interval为检查间隔，不大于0时使用DefaultJanitorInterval
+func WithOnExpire(fn func(key string), interval time.Duration) Option {
+	return func(ng *NGCache) {
+		ng.janitor = newJanitor(fn, interval)
+	}
+}
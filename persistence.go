@@ -1,12 +1,20 @@
 package ngcat
 
 import (
+	"bufio"
+	"context"
+	"encoding/base64"
 	"encoding/binary"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
+	"math/rand"
 	"os"
 	"path/filepath"
+	"sort"
+	"strconv"
+	"syscall"
 	"time"
 )
 
@@ -18,61 +26,309 @@ type PersistEntry struct {
 
 // PersistData 持久化数据结构
 type PersistData struct {
-	Version   int             `json:"version"`
-	Timestamp int64           `json:"timestamp"`
-	Entries   []PersistEntry  `json:"entries"`
+	Version   int            `json:"version"`
+	Timestamp int64          `json:"timestamp"`
+	Entries   []PersistEntry `json:"entries"`
+	// FormatCaps 写入文件时启用的可选特性（Cap*位的组合），只保存在二进制格式的文件头中
+	FormatCaps uint32 `json:"-"`
+	// GroupEntries 按分组保存的条目，由CacheGroup.SaveAll写入
+	//
+	// JSON格式写在entries之前的groups字段中；二进制格式没有分组区段，
+	// 分组条目以"分组名/键"为键追加在Entries之后。读取时两种格式都以"分组名/键"的形式返回。
+	GroupEntries []GroupPersistData `json:"groups,omitempty"`
+	// jsonOptions JSON格式写出键时使用的选项，只有EscapeHTML生效；为nil时使用defaultJSONOptions
+	jsonOptions *JSONOptions
+}
+
+// GroupPersistData 一个分组的持久化条目
+type GroupPersistData struct {
+	Name    string         `json:"name"`
+	Entries []PersistEntry `json:"entries"`
 }
 
 // 二进制格式常量
 const (
 	// BinaryMagic 二进制文件魔数
 	BinaryMagic = 0x4E474341 // "NGCA"
-	// BinaryVersion 二进制格式版本
-	BinaryVersion = 1
+	// BinaryVersion 二进制格式版本，版本2在版本号之后加入了FormatCaps，版本3在条目之后加入了索引（见CapIndex）
+	BinaryVersion = 3
+	// JSONVersion JSON格式版本
+	JSONVersion = 1
+)
+
+// FormatCaps的特性位，读取时遇到未定义的位返回ErrUnsupportedFeature
+const (
+	// CapCompression 值带有WithCompression的压缩头部
+	CapCompression uint32 = 1 << iota
+	// CapCreationTime 值带有WithCreationTracking的创建时间前缀
+	CapCreationTime
+	// CapWAL 快照之后的写入记录在预写日志中
+	CapWAL
+	// CapIndex 条目之后有按键哈希排序的偏移索引，可以不读取整个文件而读取单个键
+	CapIndex
+
+	// knownFormatCaps 当前版本能够识别的全部特性位
+	knownFormatCaps = CapCompression | CapCreationTime | CapWAL | CapIndex
 )
 
-// persistRoutine 持久化协程
-func (ng *NGCache) persistRoutine() {
+// binaryCountOffset 二进制文件头中条目数量字段的偏移（魔数+版本+特性位+时间戳）
+const binaryCountOffset = 4 + 4 + 4 + 8
+
+// startPersistRoutine 创建定时器并启动持久化协程
+//
+// 定时器在调用方协程中创建，保证返回后推进时钟即可触发持久化。
+func (ng *NGCache) startPersistRoutine() {
 	if ng.persistConfig == nil || !ng.persistConfig.Enabled {
 		return
 	}
+	ng.persistRoutineDone = make(chan struct{})
+	go ng.persistRoutine(ng.clock.NewTicker(ng.persistConfig.Interval), ng.stopChan, ng.persistRoutineDone)
+}
 
-	ticker := time.NewTicker(ng.persistConfig.Interval)
+// persistRoutine 持久化协程，stop关闭时退出，退出后关闭done
+func (ng *NGCache) persistRoutine(ticker Ticker, stop <-chan struct{}, done chan<- struct{}) {
+	defer close(done)
 	defer ticker.Stop()
 
 	for {
 		select {
-		case <-ticker.C:
-			ng.saveToPersist()
-		case <-ng.stopChan:
+		case tick := <-ticker.C():
+			ng.persistTick(tick)
+		case <-stop:
 			return
 		}
 	}
 }
 
+// 定时持久化断路器参数
+const (
+	// persistBreakerThreshold 连续失败多少次后暂停定时持久化
+	persistBreakerThreshold = 3
+	// persistBackoffMin 第一次暂停的时长，之后每次暂停时长翻倍
+	persistBackoffMin = 30 * time.Second
+	// persistBackoffMax 暂停时长的上限
+	persistBackoffMax = 10 * time.Minute
+)
+
+// persistTick 执行一次定时持久化，失败时通过WithOnError报告
+//
+// 连续失败persistBreakerThreshold次后断路器打开，暂停持久化persistBackoffMin，避免磁盘故障时每个周期都报告错误。
+// 暂停结束后的第一次尝试仍失败时立即再次暂停，时长翻倍直到persistBackoffMax；任意一次成功后断路器复位。
+// 断路器状态只在持久化协程中访问，Close时的最后一次持久化不受影响。
+//
+// 其他保存（Save、SaveContext）正在进行，或tick是在上一次保存期间到达的，跳过本次tick并计入
+// Stats的PersistSkippedTicks，保存慢于间隔时不会连续保存。
+func (ng *NGCache) persistTick(tick time.Time) {
+	now := ng.clock.Now()
+	if now.Before(ng.persistRetryAt) {
+		return
+	}
+	if ng.savesInFlight.Load() > 0 || tick.Before(ng.persistNextAt) {
+		ng.persistSkipped.Add(1)
+		return
+	}
+
+	err := ng.saveToPersist()
+	ng.schedulePersist(now, ng.clock.Now())
+	if err == nil {
+		ng.persistFailures = 0
+		ng.persistBackoff = 0
+		ng.autoCompact()
+		return
+	}
+	ng.reportError(err)
+	ng.persistFailures++
+	if ng.persistBackoff == 0 && ng.persistFailures < persistBreakerThreshold {
+		return
+	}
+
+	if ng.persistBackoff == 0 {
+		ng.persistBackoff = persistBackoffMin
+	} else {
+		ng.persistBackoff = min(ng.persistBackoff*2, persistBackoffMax)
+	}
+	ng.persistRetryAt = now.Add(ng.persistBackoff)
+	ng.logger.Warn("ngcat: 持久化连续失败，暂停定时持久化",
+		"failures", ng.persistFailures, "backoff", ng.persistBackoff)
+}
+
+// schedulePersist 记录一次定时保存的起止时间，在此之前到达的tick都会被跳过
+//
+// 启用WithAdaptivePersistInterval时下一次保存不早于start加两倍的保存耗时，
+// 保存耗时超过间隔的一半时间隔被拉长，保存最多占用一半的时间。
+func (ng *NGCache) schedulePersist(start, end time.Time) {
+	ng.persistNextAt = end
+	if ng.adaptivePersist {
+		ng.persistNextAt = start.Add(2 * end.Sub(start))
+	}
+}
+
+// persistFilePath 构建持久化文件完整路径
+func (ng *NGCache) persistFilePath() string {
+	dir := ng.persistConfig.FilePath
+	if dir == "" {
+		dir = "."
+	}
+	return filepath.Join(dir, ng.persistConfig.FileName)
+}
+
+// ctxCheckInterval 流式读写时每处理多少个条目检查一次ctx
+const ctxCheckInterval = 256
+
+// Save 立即将持久化数据保存到配置的持久化文件
+func (ng *NGCache) Save() error {
+	return ng.SaveContext(context.Background())
+}
+
+// SaveContext 保存持久化数据，ctx取消时中止并返回ctx.Err()
+//
+// 数据先写入同目录的临时文件，成功后再替换目标文件，中止时目标文件保持不变。
+func (ng *NGCache) SaveContext(ctx context.Context) error {
+	return ng.saveToPersistContext(ctx)
+}
+
+// Load 从配置的持久化文件重新加载数据
+func (ng *NGCache) Load() error {
+	return ng.LoadContext(context.Background())
+}
+
+// LoadContext 从持久化文件加载数据，ctx取消时中止并返回ctx.Err()，已加载的条目保留
+func (ng *NGCache) LoadContext(ctx context.Context) error {
+	return ng.loadFromPersistContext(ctx)
+}
+
+// Export 将持久化数据（永久缓存）导出到指定文件
+func (ng *NGCache) Export(filePath string, format PersistFormat) error {
+	return ng.ExportContext(context.Background(), filePath, format)
+}
+
+// ExportContext 导出持久化数据，ctx取消时中止并清理临时文件，目标文件保持不变
+func (ng *NGCache) ExportContext(ctx context.Context, filePath string, format PersistFormat) error {
+	ng.flushCoalesced()
+	return writePersistFile(ctx, filePath, format, ng.collectPersistData(), false)
+}
+
+// Import 从指定文件导入条目，导入的条目作为永久缓存写入
+func (ng *NGCache) Import(filePath string, format PersistFormat) error {
+	return ng.ImportContext(context.Background(), filePath, format)
+}
+
+// ImportContext 导入条目，ctx取消时中止并返回ctx.Err()，已导入的条目保留
+func (ng *NGCache) ImportContext(ctx context.Context, filePath string, format PersistFormat) error {
+	file, err := os.Open(filePath)
+	if err != nil {
+		return newError(CodeOpenFile, filePath, err)
+	}
+	defer file.Close()
+
+	return ng.loadEntries(ctx, file, format)
+}
+
 // saveToPersist 保存到持久化文件
 func (ng *NGCache) saveToPersist() error {
+	return ng.saveToPersistContext(context.Background())
+}
+
+// saveToPersistContext 保存到持久化文件，支持取消
+func (ng *NGCache) saveToPersistContext(ctx context.Context) error {
 	if ng.persistConfig == nil || !ng.persistConfig.Enabled {
 		return nil
 	}
+	if ng.persistReadOnly {
+		return ErrReadOnly
+	}
+
+	ng.savesInFlight.Add(1)
+	defer ng.savesInFlight.Add(-1)
+
+	// 先写入合并中的永久缓存，快照才能包含所有已确认的写入
+	ng.flushCoalesced()
 
 	ng.persistMutex.Lock()
 	defer ng.persistMutex.Unlock()
+	ng.saveHotKeys()
 
-	// 确保目录存在
-	dir := ng.persistConfig.FilePath
-	if dir == "" {
-		dir = "."
-	}
-	err := os.MkdirAll(dir, 0755)
-	if err != nil {
-		return fmt.Errorf("创建持久化目录失败: %v", err)
+	// WAL模式下记录已随写入追加，只需将缓冲刷到磁盘
+	if ng.persistConfig.Format == FormatWAL {
+		err := ng.flushWAL()
+		ng.saveShadow(ctx, nil)
+		return err
 	}
 
-	// 构建完整文件路径
-	filePath := filepath.Join(dir, ng.persistConfig.FileName)
+	if ng.persistConfig.Format == FormatDelta {
+		err := ng.saveDelta(ctx)
+		ng.saveShadow(ctx, nil)
+		return err
+	}
 
 	// 收集持久化数据
+	filePath := ng.persistFilePath()
+	persistData := ng.collectPersistData()
+	ng.applyPersistLimits(persistData, ng.persistConfig.Format)
+
+	// 根据格式保存，快照文件整体重写，遇到暂时性错误时可以安全地重试
+	defer ng.saveShadow(ctx, persistData)
+	return ng.retryTransient(ctx, func() error {
+		switch ng.persistConfig.Format {
+		case FormatJSON:
+			return ng.saveToJSON(ctx, filePath, persistData)
+		case FormatBinary:
+			return ng.saveToBinary(ctx, filePath, persistData)
+		case FormatMMap:
+			return writeMMapFile(ctx, filePath, persistData)
+		default:
+			return newError(CodeUnsupportedFormat, strconv.Itoa(int(ng.persistConfig.Format)), nil)
+		}
+	})
+}
+
+// DefaultRetryBackoff 设置了PersistConfig.MaxRetries但未设置RetryBackoff时第一次重试前的等待时间
+const DefaultRetryBackoff = 100 * time.Millisecond
+
+// retryTransient 执行save，遇到暂时性错误（EAGAIN、EINTR）时最多重试PersistConfig.MaxRetries次
+//
+// 第n次重试前等待RetryBackoff*2^(n-1)，随机抖动到其一半至全部之间，上限为persistBackoffMax；
+// 所有重试失败后返回最后一次的错误，ctx取消时返回ctx.Err()。
+func (ng *NGCache) retryTransient(ctx context.Context, save func() error) error {
+	backoff := ng.persistConfig.RetryBackoff
+	if backoff <= 0 {
+		backoff = DefaultRetryBackoff
+	}
+	for attempt := 0; ; attempt++ {
+		err := save()
+		if err == nil || attempt >= ng.persistConfig.MaxRetries || !isTransient(err) {
+			return err
+		}
+
+		delay := backoff << attempt
+		if delay <= 0 || delay > persistBackoffMax {
+			delay = persistBackoffMax
+		}
+		delay = delay/2 + time.Duration(rand.Int63n(int64(delay/2)+1))
+		ng.logger.Warn("ngcat: 持久化遇到暂时性错误，稍后重试",
+			"attempt", attempt+1, "delay", delay, "error", err)
+
+		ticker := ng.clock.NewTicker(delay)
+		select {
+		case <-ticker.C():
+			ticker.Stop()
+		case <-ctx.Done():
+			ticker.Stop()
+			return ctx.Err()
+		}
+	}
+}
+
+// isTransient 判断错误是否为重试可能成功的暂时性错误
+func isTransient(err error) bool {
+	return errors.Is(err, syscall.EAGAIN) || errors.Is(err, syscall.EINTR)
+}
+
+// collectPersistData 收集当前的持久化数据
+//
+// 在一次加锁内复制所有键和值的引用，得到同一时间点的视图。写入路径总是替换值而不修改已存储的切片，
+// 因此释放锁后这些值保持不变。
+func (ng *NGCache) collectPersistData() *PersistData {
 	ng.persistDataMutex.RLock()
 	entries := make([]PersistEntry, 0, len(ng.persistData))
 	for key, value := range ng.persistData {
@@ -83,109 +339,238 @@ func (ng *NGCache) saveToPersist() error {
 	}
 	ng.persistDataMutex.RUnlock()
 
-	persistData := PersistData{
-		Version:   1,
-		Timestamp: time.Now().Unix(),
-		Entries:   entries,
+	return &PersistData{
+		Version:     JSONVersion,
+		Timestamp:   ng.clock.Now().Unix(),
+		Entries:     entries,
+		FormatCaps:  ng.formatCaps(),
+		jsonOptions: &ng.jsonOptions,
 	}
+}
 
-	// 根据格式保存
-	switch ng.persistConfig.Format {
-	case FormatJSON:
-		return ng.saveToJSON(filePath, &persistData)
-	case FormatBinary:
-		return ng.saveToBinary(filePath, &persistData)
-	default:
-		return fmt.Errorf("不支持的持久化格式: %d", ng.persistConfig.Format)
+// formatCaps 返回写出持久化文件时需要在文件头中声明的特性位
+func (ng *NGCache) formatCaps() uint32 {
+	var caps uint32
+	if ng.compressOver > 0 {
+		caps |= CapCompression
 	}
+	if ng.trackCreation {
+		caps |= CapCreationTime
+	}
+	if ng.wal != nil {
+		caps |= CapWAL
+	}
+	return caps
 }
 
-// saveToJSON 保存为JSON格式
-func (ng *NGCache) saveToJSON(filePath string, data *PersistData) error {
-	file, err := os.Create(filePath)
-	if err != nil {
-		return fmt.Errorf("创建JSON文件失败: %v", err)
+// applyPersistLimits 按MaxPersistEntries和MaxFileSizeBytes裁剪将要写出的条目
+func (ng *NGCache) applyPersistLimits(data *PersistData, format PersistFormat) {
+	ng.trimPersistEntries(data)
+	ng.trimPersistSize(data, format)
+}
+
+// trimPersistEntries 条目数量超过MaxPersistEntries时按键排序，丢弃排在最后的条目
+//
+// 只影响写出的文件，内存中的持久化数据保持不变。
+func (ng *NGCache) trimPersistEntries(data *PersistData) {
+	max := ng.persistConfig.MaxPersistEntries
+	if max <= 0 || len(data.Entries) <= max {
+		return
 	}
-	defer file.Close()
 
-	encoder := json.NewEncoder(file)
-	encoder.SetIndent("", "  ")
-	return encoder.Encode(data)
+	sort.Slice(data.Entries, func(i, j int) bool {
+		return data.Entries[i].Key < data.Entries[j].Key
+	})
+	ng.logger.Warn("持久化条目数量超过上限，已丢弃多余条目",
+		"entries", len(data.Entries), "max", max, "file", ng.persistFilePath())
+	data.Entries = data.Entries[:max]
 }
 
-// saveToBinary 保存为二进制格式
-func (ng *NGCache) saveToBinary(filePath string, data *PersistData) error {
-	file, err := os.Create(filePath)
-	if err != nil {
-		return fmt.Errorf("创建二进制文件失败: %v", err)
+// trimPersistSize 写出的文件大小超过MaxFileSizeBytes时，从值最大的条目开始丢弃直到满足上限
+//
+// 只影响写出的文件，内存中的持久化数据保持不变。
+func (ng *NGCache) trimPersistSize(data *PersistData, format PersistFormat) {
+	max := ng.persistConfig.MaxFileSizeBytes
+	if max <= 0 {
+		return
 	}
-	defer file.Close()
 
-	// 写入魔数
-	err = binary.Write(file, binary.LittleEndian, uint32(BinaryMagic))
-	if err != nil {
-		return err
+	sizes := make([]int64, len(data.Entries))
+	total := persistOverheadSize(format, data.Timestamp)
+	for i, entry := range data.Entries {
+		sizes[i] = persistEntrySize(format, entry)
+		total += sizes[i]
+	}
+	if total <= max {
+		return
 	}
 
-	// 写入版本
-	err = binary.Write(file, binary.LittleEndian, uint32(BinaryVersion))
-	if err != nil {
-		return err
+	order := make([]int, len(data.Entries))
+	for i := range order {
+		order[i] = i
 	}
+	sort.Slice(order, func(i, j int) bool {
+		return len(data.Entries[order[i]].Value) > len(data.Entries[order[j]].Value)
+	})
 
-	// 写入时间戳
-	err = binary.Write(file, binary.LittleEndian, data.Timestamp)
-	if err != nil {
-		return err
+	drop := make(map[int]bool)
+	var dropped []string
+	for _, i := range order {
+		if total <= max {
+			break
+		}
+		drop[i] = true
+		dropped = append(dropped, data.Entries[i].Key)
+		total -= sizes[i]
 	}
 
-	// 写入条目数量
-	err = binary.Write(file, binary.LittleEndian, uint32(len(data.Entries)))
-	if err != nil {
-		return err
+	kept := make([]PersistEntry, 0, len(data.Entries)-len(drop))
+	for i, entry := range data.Entries {
+		if !drop[i] {
+			kept = append(kept, entry)
+		}
 	}
+	data.Entries = kept
+	ng.logger.Warn("持久化文件大小超过上限，已丢弃最大的条目",
+		"max", max, "size", total, "dropped", dropped, "file", ng.persistFilePath())
+}
+
+// persistOverheadSize 持久化文件中条目以外部分的字节数
+func persistOverheadSize(format PersistFormat, timestamp int64) int64 {
+	if format == FormatJSON {
+		header := fmt.Sprintf("{\n  \"version\": %d,\n  \"timestamp\": %d,\n  \"entries\": [", JSONVersion, timestamp)
+		return int64(len(header) + len("\n  ]\n}\n"))
+	}
+	return binaryCountOffset + 4 + binaryIndexTrailerSize
+}
 
-	// 写入每个条目
-	for _, entry := range data.Entries {
-		// 写入键长度和键
-		err = binary.Write(file, binary.LittleEndian, uint32(len(entry.Key)))
+// persistEntrySize 单个条目写出后的字节数，二进制格式包括条目在索引中占用的字节
+func persistEntrySize(format PersistFormat, entry PersistEntry) int64 {
+	if format == FormatJSON {
+		data, err := json.MarshalIndent(entry, "    ", "  ")
 		if err != nil {
-			return err
+			return 0
 		}
-		_, err = file.Write([]byte(entry.Key))
+		return int64(len(",\n    ") + len(data))
+	}
+	return int64(4 + len(entry.Key) + 4 + len(entry.Value) + binaryIndexSlotSize)
+}
+
+// saveToJSON 保存为JSON格式
+func (ng *NGCache) saveToJSON(ctx context.Context, filePath string, data *PersistData) error {
+	return writePersistFile(ctx, filePath, FormatJSON, data, ng.persistConfig.SyncOnWrite)
+}
+
+// saveToBinary 保存为二进制格式
+func (ng *NGCache) saveToBinary(ctx context.Context, filePath string, data *PersistData) error {
+	return writePersistFile(ctx, filePath, FormatBinary, data, ng.persistConfig.SyncOnWrite)
+}
+
+// writePersistFile 通过临时文件写出持久化数据，成功后重命名为目标文件
+//
+// sync为true时在关闭临时文件前调用fsync。任何错误（包括ctx取消）都会删除临时文件，目标文件保持原样。
+func writePersistFile(ctx context.Context, filePath string, format PersistFormat, data *PersistData, sync bool) (err error) {
+	// 确保目录存在
+	dir := filepath.Dir(filePath)
+	err = os.MkdirAll(dir, 0755)
+	if err != nil {
+		return newError(CodeCreateDir, dir, err)
+	}
+
+	file, err := os.CreateTemp(dir, filepath.Base(filePath)+".tmp*")
+	if err != nil {
+		return newError(CodeCreateTemp, dir, err)
+	}
+	defer func() {
 		if err != nil {
-			return err
+			file.Close()
+			os.Remove(file.Name())
 		}
+	}()
 
-		// 写入值长度和值
-		err = binary.Write(file, binary.LittleEndian, uint32(len(entry.Value)))
+	err = writePersistData(ctx, wrapPersistFile(file), format, data)
+	if err != nil {
+		return err
+	}
+	if sync {
+		err = syncPersistFile(file)
 		if err != nil {
-			return err
+			return newError(CodeSyncFile, file.Name(), err)
 		}
-		_, err = file.Write(entry.Value)
+	}
+	err = file.Close()
+	if err != nil {
+		return newError(CodeWriteFile, file.Name(), err)
+	}
+	err = os.Rename(file.Name(), filePath)
+	if err != nil {
+		return newError(CodeReplaceFile, filePath, err)
+	}
+	return nil
+}
+
+// wrapPersistFile 包装写入持久化临时文件的Writer，测试中用于注入写入错误
+var wrapPersistFile = func(w io.Writer) io.Writer { return w }
+
+// syncPersistFile 将持久化临时文件刷到磁盘，测试中用于注入同步错误
+var syncPersistFile = (*os.File).Sync
+
+// writePersistData 按指定格式写出完整的持久化数据
+func writePersistData(ctx context.Context, w io.Writer, format PersistFormat, data *PersistData) error {
+	entries := data.Entries
+	var groups []GroupPersistData
+	if format == FormatJSON {
+		groups = data.GroupEntries
+	} else if len(data.GroupEntries) > 0 {
+		entries = append(entries[:len(entries):len(entries)], flattenGroups(data.GroupEntries)...)
+	}
+
+	pw, err := newPersistWriter(w, format, data.Timestamp, data.FormatCaps, len(entries), groups)
+	if err != nil {
+		return err
+	}
+	if data.jsonOptions != nil {
+		pw.jsonOptions = *data.jsonOptions
+	}
+	for i, entry := range entries {
+		if i%ctxCheckInterval == 0 {
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+		}
+		err = pw.write(entry)
 		if err != nil {
 			return err
 		}
 	}
-
-	return nil
+	return pw.finish()
 }
 
-// loadFromPersist 从持久化文件加载
+// loadFromPersist 创建缓存时从持久化文件加载，启用WithLazyLoad时可能在后台完成（见startLazyLoad）
 func (ng *NGCache) loadFromPersist() error {
+	if ng.startLazyLoad() {
+		return nil
+	}
+	return ng.loadFromPersistContext(context.Background())
+}
+
+// loadFromPersistContext 从持久化文件加载，支持取消
+func (ng *NGCache) loadFromPersistContext(ctx context.Context) error {
 	if ng.persistConfig == nil || !ng.persistConfig.Enabled {
 		return nil
 	}
 
 	ng.persistMutex.Lock()
 	defer ng.persistMutex.Unlock()
+	ng.preparePreload()
 
-	// 构建完整文件路径
-	dir := ng.persistConfig.FilePath
-	if dir == "" {
-		dir = "."
+	// WAL模式的快照和日志都可能不存在，单独处理
+	if ng.persistConfig.Format == FormatWAL {
+		return ng.loadFromWAL(ctx)
 	}
-	filePath := filepath.Join(dir, ng.persistConfig.FileName)
+
+	// 构建完整文件路径
+	filePath := ng.persistFilePath()
 
 	// 检查文件是否存在
 	if _, err := os.Stat(filePath); os.IsNotExist(err) {
@@ -195,125 +580,613 @@ func (ng *NGCache) loadFromPersist() error {
 	// 根据格式加载
 	switch ng.persistConfig.Format {
 	case FormatJSON:
-		return ng.loadFromJSON(filePath)
-	case FormatBinary:
-		return ng.loadFromBinary(filePath)
+		return ng.loadFromJSON(ctx, filePath)
+	case FormatBinary, FormatDelta:
+		return ng.loadFromBinary(ctx, filePath)
+	case FormatMMap:
+		return ng.loadFromMMap(ctx, filePath)
 	default:
-		return fmt.Errorf("不支持的持久化格式: %d", ng.persistConfig.Format)
+		return newError(CodeUnsupportedFormat, strconv.Itoa(int(ng.persistConfig.Format)), nil)
 	}
 }
 
 // loadFromJSON 从JSON格式加载
-func (ng *NGCache) loadFromJSON(filePath string) error {
+func (ng *NGCache) loadFromJSON(ctx context.Context, filePath string) error {
 	file, err := os.Open(filePath)
 	if err != nil {
-		return fmt.Errorf("打开JSON文件失败: %v", err)
+		return newError(CodeOpenFile, filePath, err)
 	}
 	defer file.Close()
 
-	var data PersistData
-	decoder := json.NewDecoder(file)
-	err = decoder.Decode(&data)
+	return ng.loadEntries(ctx, file, FormatJSON)
+}
+
+// loadFromBinary 从二进制格式加载
+func (ng *NGCache) loadFromBinary(ctx context.Context, filePath string) error {
+	file, err := os.Open(filePath)
 	if err != nil {
-		return fmt.Errorf("解析JSON文件失败: %v", err)
+		return newError(CodeOpenFile, filePath, err)
 	}
+	defer file.Close()
+
+	return ng.loadEntries(ctx, file, FormatBinary)
+}
 
-	// 加载数据到内存
+// loadEntries 流式读取条目并加载到内存
+func (ng *NGCache) loadEntries(ctx context.Context, r io.Reader, format PersistFormat) error {
+	pr, err := newPersistReader(r, format)
+	if err != nil {
+		return err
+	}
+
+	stats := ng.newLoadStats()
+	defer stats.report(ng)
 	ng.persistDataMutex.Lock()
-	for _, entry := range data.Entries {
-		ng.persistData[entry.Key] = entry.Value
-		// 同时加载到freecache（永久缓存）
-		ng.cache.Set([]byte(entry.Key), entry.Value, 0)
+	defer ng.persistDataMutex.Unlock()
+	return ng.loadEntriesLocked(ctx, pr, &stats, nil)
+}
+
+// loadEntriesLocked 逐个读取条目并加载到内存，跳过skip中已经加载的键，调用方需持有persistDataMutex
+func (ng *NGCache) loadEntriesLocked(ctx context.Context, pr *persistReader, stats *loadStats, skip map[string]struct{}) error {
+	for i := 0; ; i++ {
+		if i%ctxCheckInterval == 0 {
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+		}
+		entry, err := pr.next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if _, ok := skip[entry.Key]; ok {
+			continue
+		}
+
+		ng.loadEntry(entry.Key, entry.Value, stats)
 	}
-	ng.persistDataMutex.Unlock()
+}
 
-	return nil
+// loadStats 一次加载的条目数量和耗时
+type loadStats struct {
+	// start 开始加载的时间
+	start time.Time
+	// loaded 加载到持久化数据中的条目
+	loaded int
+	// preloaded 同时写入freecache的条目
+	preloaded int
+	// keyTooLong 键超过上限而跳过的条目
+	keyTooLong int
+	// cacheFailed 按预加载策略应写入、但未能写入freecache的条目
+	cacheFailed int
 }
 
-// loadFromBinary 从二进制格式加载
-func (ng *NGCache) loadFromBinary(filePath string) error {
-	file, err := os.Open(filePath)
+func (ng *NGCache) newLoadStats() loadStats {
+	return loadStats{start: ng.clock.Now()}
+}
+
+// loadEntry 将加载的永久缓存写入持久化数据，按WithPreload的策略写入freecache，调用方需持有persistDataMutex
+func (ng *NGCache) loadEntry(key string, value []byte, stats *loadStats) {
+	if ng.checkKeyLen(len(key)) != nil {
+		stats.keyTooLong++
+		return
+	}
+	ng.persistData[key] = value
+	ng.bloomAdd(key)
+	stats.loaded++
+	if !ng.shouldPreload(key) {
+		return
+	}
+	// 同时加载到freecache（永久缓存），失败时读取仍可从持久化数据获取
+	if ng.storeSet(key, value, 0) != nil {
+		stats.cacheFailed++
+		return
+	}
+	stats.preloaded++
+}
+
+// report 报告加载的条目数量、耗时和跳过的条目
+func (s *loadStats) report(ng *NGCache) {
+	if s.loaded > 0 {
+		ng.logger.Info("ngcat: 已加载永久缓存",
+			"loaded", s.loaded, "preloaded", s.preloaded, "duration", ng.clock.Now().Sub(s.start))
+	}
+	if s.keyTooLong > 0 {
+		ng.reportError(newError(CodeKeyTooLong, strconv.Itoa(s.keyTooLong)+" entries skipped on load", nil))
+	}
+	if s.cacheFailed > 0 {
+		ng.logger.Warn("ngcat: 部分永久缓存未能在加载时写入freecache，读取时从持久化数据获取",
+			"count", s.cacheFailed)
+	}
+}
+
+// corruptEntryError 单个条目损坏错误
+type corruptEntryError struct {
+	// index 条目序号
+	index int
+	// err 原始错误
+	err error
+	// fatal 是否无法继续读取后续条目
+	fatal bool
+}
+
+func (e *corruptEntryError) Error() string {
+	return e.err.Error()
+}
+
+func (e *corruptEntryError) Unwrap() error {
+	return e.err
+}
+
+// MaxPersistValueSize 持久化文件中单个值的最大字节数，超过时视为文件损坏
+const MaxPersistValueSize = 512 * 1024 * 1024
+
+// persistReadChunk 长度较大时按块读取，避免损坏的长度字段导致一次性分配大量内存
+const persistReadChunk = 1024 * 1024
+
+// readPersistBytes 读取n个字节，内存随实际读到的数据增长
+func readPersistBytes(r io.Reader, n uint32) ([]byte, error) {
+	if n <= persistReadChunk {
+		buf := make([]byte, n)
+		_, err := io.ReadFull(r, buf)
+		return buf, err
+	}
+	buf, err := io.ReadAll(io.LimitReader(r, int64(n)))
 	if err != nil {
-		return fmt.Errorf("打开二进制文件失败: %v", err)
+		return nil, err
 	}
-	defer file.Close()
+	if len(buf) != int(n) {
+		return nil, io.ErrUnexpectedEOF
+	}
+	return buf, nil
+}
+
+// persistReader 持久化文件流式读取器，逐条返回条目而不一次性载入整个文件
+type persistReader struct {
+	format PersistFormat
+	// version 文件格式版本
+	version int
+	// caps 二进制格式文件头中的特性位
+	caps uint32
+	// timestamp 文件写入时间戳
+	timestamp int64
+	// count 二进制格式声明的条目数量，JSON格式为-1
+	count int
+	// index 下一个条目的序号
+	index int
+	// done 是否已读完所有条目
+	done bool
+	// grouped JSON格式groups字段中尚未返回的条目，键为"分组名/键"
+	grouped []PersistEntry
+
+	r   io.Reader
+	dec *json.Decoder
+}
 
+// newPersistReader 创建流式读取器并读取文件头
+func newPersistReader(r io.Reader, format PersistFormat) (*persistReader, error) {
+	pr := &persistReader{format: format, count: -1}
+	switch format {
+	case FormatJSON:
+		pr.dec = json.NewDecoder(r)
+		return pr, pr.readJSONHeader()
+	case FormatBinary:
+		r, err := migrateBinary(r)
+		if err != nil {
+			return nil, err
+		}
+		pr.r = bufio.NewReader(r)
+		return pr, pr.readBinaryHeader()
+	default:
+		return nil, newError(CodeUnsupportedFormat, strconv.Itoa(int(format)), nil)
+	}
+}
+
+// readBinaryHeader 读取二进制文件头
+func (pr *persistReader) readBinaryHeader() error {
 	// 读取魔数
 	var magic uint32
-	err = binary.Read(file, binary.LittleEndian, &magic)
+	err := binary.Read(pr.r, binary.LittleEndian, &magic)
 	if err != nil {
-		return fmt.Errorf("读取魔数失败: %v", err)
+		return newError(CodeCorruptFile, "read magic", err)
 	}
 	if magic != BinaryMagic {
-		return fmt.Errorf("无效的二进制文件魔数: 0x%X", magic)
+		return corruptf("invalid binary magic 0x%X", magic)
 	}
 
 	// 读取版本
 	var version uint32
-	err = binary.Read(file, binary.LittleEndian, &version)
+	err = binary.Read(pr.r, binary.LittleEndian, &version)
 	if err != nil {
-		return fmt.Errorf("读取版本失败: %v", err)
+		return newError(CodeCorruptFile, "read version", err)
+	}
+	if version > BinaryVersion {
+		return newError(CodeUnsupportedFeature, fmt.Sprintf("binary version %d written by a newer version", version), nil)
 	}
 	if version != BinaryVersion {
-		return fmt.Errorf("不支持的二进制文件版本: %d", version)
+		return corruptf("unsupported binary version %d", version)
+	}
+	pr.version = int(version)
+
+	// 读取特性位，有不认识的位时无法正确解读条目
+	err = binary.Read(pr.r, binary.LittleEndian, &pr.caps)
+	if err != nil {
+		return newError(CodeCorruptFile, "read format caps", err)
+	}
+	if unknown := pr.caps &^ knownFormatCaps; unknown != 0 {
+		return newError(CodeUnsupportedFeature, fmt.Sprintf("unknown format caps 0x%X", unknown), nil)
 	}
 
 	// 读取时间戳
-	var timestamp int64
-	err = binary.Read(file, binary.LittleEndian, &timestamp)
+	err = binary.Read(pr.r, binary.LittleEndian, &pr.timestamp)
 	if err != nil {
-		return fmt.Errorf("读取时间戳失败: %v", err)
+		return newError(CodeCorruptFile, "read timestamp", err)
 	}
 
 	// 读取条目数量
 	var entryCount uint32
-	err = binary.Read(file, binary.LittleEndian, &entryCount)
+	err = binary.Read(pr.r, binary.LittleEndian, &entryCount)
 	if err != nil {
-		return fmt.Errorf("读取条目数量失败: %v", err)
+		return newError(CodeCorruptFile, "read entry count", err)
 	}
+	pr.count = int(entryCount)
+	return nil
+}
 
-	// 读取每个条目
-	ng.persistDataMutex.Lock()
-	for i := uint32(0); i < entryCount; i++ {
-		// 读取键长度
-		var keyLen uint32
-		err = binary.Read(file, binary.LittleEndian, &keyLen)
+// readJSONHeader 读取JSON对象直到entries数组开始处
+func (pr *persistReader) readJSONHeader() error {
+	err := pr.expectDelim('{')
+	if err != nil {
+		return err
+	}
+	for pr.dec.More() {
+		tok, err := pr.dec.Token()
 		if err != nil {
-			ng.persistDataMutex.Unlock()
-			return fmt.Errorf("读取键长度失败: %v", err)
+			return newError(CodeCorruptFile, "parse JSON", err)
 		}
+		switch tok {
+		case "version":
+			err = pr.dec.Decode(&pr.version)
+		case "timestamp":
+			err = pr.dec.Decode(&pr.timestamp)
+		case "groups":
+			err = pr.decodeGroups()
+		case "entries":
+			return pr.openJSONEntries()
+		default:
+			var skip json.RawMessage
+			err = pr.dec.Decode(&skip)
+		}
+		if err != nil {
+			return newError(CodeCorruptFile, "parse JSON", err)
+		}
+	}
+	// 没有entries字段
+	pr.done = true
+	return nil
+}
+
+// openJSONEntries 进入entries数组，null视为空数组
+func (pr *persistReader) openJSONEntries() error {
+	tok, err := pr.dec.Token()
+	if err != nil {
+		return newError(CodeCorruptFile, "parse JSON", err)
+	}
+	if tok == nil {
+		pr.done = true
+		return nil
+	}
+	if tok != json.Delim('[') {
+		return corruptf("parse JSON: entries is not an array")
+	}
+	return nil
+}
+
+// decodeGroups 读取groups字段，分组条目展开后在entries之前返回
+func (pr *persistReader) decodeGroups() error {
+	var groups []GroupPersistData
+	err := pr.dec.Decode(&groups)
+	if err != nil {
+		return err
+	}
+	pr.grouped = append(pr.grouped, flattenGroups(groups)...)
+	return nil
+}
 
-		// 读取键
-		keyBytes := make([]byte, keyLen)
-		_, err = io.ReadFull(file, keyBytes)
+// readJSONTrailer 读取entries数组之后的字段，其中的groups字段同样展开返回
+func (pr *persistReader) readJSONTrailer() error {
+	err := pr.expectDelim(']')
+	if err != nil {
+		return err
+	}
+	for pr.dec.More() {
+		tok, err := pr.dec.Token()
 		if err != nil {
-			ng.persistDataMutex.Unlock()
-			return fmt.Errorf("读取键失败: %v", err)
+			return newError(CodeCorruptFile, "parse JSON", err)
 		}
+		if tok == "groups" {
+			err = pr.decodeGroups()
+		} else {
+			var skip json.RawMessage
+			err = pr.dec.Decode(&skip)
+		}
+		if err != nil {
+			return newError(CodeCorruptFile, "parse JSON", err)
+		}
+	}
+	return nil
+}
 
-		// 读取值长度
-		var valueLen uint32
-		err = binary.Read(file, binary.LittleEndian, &valueLen)
+// expectDelim 读取下一个分隔符并校验
+func (pr *persistReader) expectDelim(delim json.Delim) error {
+	tok, err := pr.dec.Token()
+	if err != nil {
+		return newError(CodeCorruptFile, "parse JSON", err)
+	}
+	if tok != delim {
+		return corruptf("parse JSON: expected %v", delim)
+	}
+	return nil
+}
+
+// next 读取下一个条目，全部读完时返回io.EOF
+func (pr *persistReader) next() (PersistEntry, error) {
+	if len(pr.grouped) > 0 {
+		entry := pr.grouped[0]
+		pr.grouped = pr.grouped[1:]
+		return entry, nil
+	}
+	if pr.done {
+		return PersistEntry{}, io.EOF
+	}
+	index := pr.index
+	pr.index++
+	switch pr.format {
+	case FormatJSON:
+		return pr.nextJSON(index)
+	default:
+		return pr.nextBinary(index)
+	}
+}
+
+// nextJSON 读取下一个JSON条目
+func (pr *persistReader) nextJSON(index int) (PersistEntry, error) {
+	if !pr.dec.More() {
+		pr.done = true
+		err := pr.readJSONTrailer()
 		if err != nil {
-			ng.persistDataMutex.Unlock()
-			return fmt.Errorf("读取值长度失败: %v", err)
+			return PersistEntry{}, err
+		}
+		return pr.next()
+	}
+
+	var entry PersistEntry
+	err := pr.dec.Decode(&entry)
+	if err != nil {
+		// 类型错误和base64错误时解码器已越过该条目，可以继续读取
+		var typeErr *json.UnmarshalTypeError
+		var b64Err base64.CorruptInputError
+		fatal := !errors.As(err, &typeErr) && !errors.As(err, &b64Err)
+		if fatal {
+			pr.done = true
 		}
+		return PersistEntry{}, &corruptEntryError{
+			index: index,
+			err:   newError(CodeCorruptFile, "parse JSON entry "+strconv.Itoa(index), err),
+			fatal: fatal,
+		}
+	}
+	return entry, nil
+}
+
+// nextBinary 读取下一个二进制条目
+func (pr *persistReader) nextBinary(index int) (PersistEntry, error) {
+	if index >= pr.count {
+		pr.done = true
+		return PersistEntry{}, io.EOF
+	}
+
+	fail := func(what string, err error) (PersistEntry, error) {
+		// 二进制条目损坏后无法定位下一个条目
+		pr.done = true
+		detail := "entry " + strconv.Itoa(index) + ": " + what
+		return PersistEntry{}, &corruptEntryError{index: index, err: newError(CodeCorruptFile, detail, err), fatal: true}
+	}
+
+	// 读取键长度
+	var keyLen uint32
+	err := binary.Read(pr.r, binary.LittleEndian, &keyLen)
+	if err != nil {
+		return fail("read key length", err)
+	}
+
+	// 超过键长度上限的键仍按格式读出，由loadEntries跳过
+	if keyLen > MaxPersistValueSize {
+		return fail("key length "+strconv.FormatUint(uint64(keyLen), 10)+" exceeds limit", nil)
+	}
+
+	// 读取键
+	keyBytes, err := readPersistBytes(pr.r, keyLen)
+	if err != nil {
+		return fail("read key", err)
+	}
+
+	// 读取值长度
+	var valueLen uint32
+	err = binary.Read(pr.r, binary.LittleEndian, &valueLen)
+	if err != nil {
+		return fail("read value length", err)
+	}
+
+	if valueLen > MaxPersistValueSize {
+		return fail("value length "+strconv.FormatUint(uint64(valueLen), 10)+" exceeds limit", nil)
+	}
+
+	// 读取值
+	valueBytes, err := readPersistBytes(pr.r, valueLen)
+	if err != nil {
+		return fail("read value", err)
+	}
 
-		// 读取值
-		valueBytes := make([]byte, valueLen)
-		_, err = io.ReadFull(file, valueBytes)
+	return PersistEntry{Key: string(keyBytes), Value: valueBytes}, nil
+}
+
+// persistWriter 持久化文件流式写入器
+type persistWriter struct {
+	format PersistFormat
+	// declared 文件头中声明的条目数量（二进制格式）
+	declared int
+	// written 已写入的条目数量
+	written int
+	// offset 二进制格式下一个条目在文件中的偏移
+	offset int64
+	// index 二进制格式已写入条目的索引，finish时写在条目之后
+	index []indexSlot
+
+	dst io.Writer
+	w   *bufio.Writer
+	// jsonOptions JSON格式写出条目时使用的选项
+	jsonOptions JSONOptions
+}
+
+// newPersistWriter 创建流式写入器并写出文件头
+//
+// 二进制格式需要在文件头声明条目数量，若实际写入数量与count不同，
+// finish时会通过io.WriterAt回填，否则返回错误。caps只用于二进制格式，groups只用于JSON格式，写在entries之前。
+func newPersistWriter(w io.Writer, format PersistFormat, timestamp int64, caps uint32, count int, groups []GroupPersistData) (*persistWriter, error) {
+	pw := &persistWriter{format: format, declared: count, dst: w, w: bufio.NewWriter(w), jsonOptions: defaultJSONOptions}
+	switch format {
+	case FormatJSON:
+		_, err := fmt.Fprintf(pw.w, "{\n  \"version\": %d,\n  \"timestamp\": %d,\n", JSONVersion, timestamp)
 		if err != nil {
-			ng.persistDataMutex.Unlock()
-			return fmt.Errorf("读取值失败: %v", err)
+			return pw, err
 		}
+		if len(groups) > 0 {
+			data, err := json.MarshalIndent(groups, "  ", "  ")
+			if err != nil {
+				return pw, err
+			}
+			_, err = fmt.Fprintf(pw.w, "  \"groups\": %s,\n", data)
+			if err != nil {
+				return pw, err
+			}
+		}
+		_, err = pw.w.WriteString("  \"entries\": [")
+		return pw, err
+	case FormatBinary:
+		var header [binaryCountOffset + 4]byte
+		binary.LittleEndian.PutUint32(header[0:], BinaryMagic)
+		binary.LittleEndian.PutUint32(header[4:], BinaryVersion)
+		binary.LittleEndian.PutUint32(header[8:], caps|CapIndex)
+		binary.LittleEndian.PutUint64(header[12:], uint64(timestamp))
+		binary.LittleEndian.PutUint32(header[binaryCountOffset:], uint32(count))
+		pw.offset = int64(len(header))
+		if count > 0 {
+			pw.index = make([]indexSlot, 0, count)
+		}
+		_, err := pw.w.Write(header[:])
+		return pw, err
+	default:
+		return nil, newError(CodeUnsupportedFormat, strconv.Itoa(int(format)), nil)
+	}
+}
+
+// write 写入一个条目
+func (pw *persistWriter) write(entry PersistEntry) error {
+	var err error
+	switch pw.format {
+	case FormatJSON:
+		err = pw.writeJSON(entry)
+	default:
+		err = pw.writeBinary(entry)
+	}
+	if err != nil {
+		return err
+	}
+	pw.written++
+	return nil
+}
+
+// writeJSON 以与json.Encoder缩进输出一致的布局写入条目
+func (pw *persistWriter) writeJSON(entry PersistEntry) error {
+	data, err := pw.jsonOptions.marshalIndent(entry, "    ", "  ")
+	if err != nil {
+		return err
+	}
+	sep := ",\n    "
+	if pw.written == 0 {
+		sep = "\n    "
+	}
+	_, err = pw.w.WriteString(sep)
+	if err != nil {
+		return err
+	}
+	_, err = pw.w.Write(data)
+	return err
+}
+
+// writeBinary 写入二进制条目
+func (pw *persistWriter) writeBinary(entry PersistEntry) error {
+	pw.index = append(pw.index, indexSlot{hash: indexKeyHash(entry.Key), offset: pw.offset})
+	pw.offset += int64(4 + len(entry.Key) + 4 + len(entry.Value))
+	var lenBuf [4]byte
+
+	// 写入键长度和键
+	binary.LittleEndian.PutUint32(lenBuf[:], uint32(len(entry.Key)))
+	_, err := pw.w.Write(lenBuf[:])
+	if err != nil {
+		return err
+	}
+	_, err = pw.w.WriteString(entry.Key)
+	if err != nil {
+		return err
+	}
 
-		// 存储到内存
-		key := string(keyBytes)
-		ng.persistData[key] = valueBytes
-		// 同时加载到freecache（永久缓存）
-		ng.cache.Set(keyBytes, valueBytes, 0)
+	// 写入值长度和值
+	binary.LittleEndian.PutUint32(lenBuf[:], uint32(len(entry.Value)))
+	_, err = pw.w.Write(lenBuf[:])
+	if err != nil {
+		return err
 	}
-	ng.persistDataMutex.Unlock()
+	_, err = pw.w.Write(entry.Value)
+	return err
+}
 
+// finish 写出文件尾并刷新缓冲区
+func (pw *persistWriter) finish() error {
+	if pw.format == FormatJSON {
+		tail := "\n  ]\n}\n"
+		if pw.written == 0 {
+			tail = "]\n}\n"
+		}
+		_, err := pw.w.WriteString(tail)
+		if err != nil {
+			return err
+		}
+	} else {
+		buf := make([]byte, binaryIndexSize(len(pw.index)))
+		putBinaryIndex(buf, pw.index, pw.offset)
+		_, err := pw.w.Write(buf)
+		if err != nil {
+			return err
+		}
+	}
+	err := pw.w.Flush()
+	if err != nil {
+		return err
+	}
+
+	if pw.format == FormatBinary && pw.written != pw.declared {
+		wa, ok := pw.dst.(io.WriterAt)
+		if !ok {
+			return newError(CodeEntryCountMismatch, fmt.Sprintf("declared %d, written %d", pw.declared, pw.written), nil)
+		}
+		var countBuf [4]byte
+		binary.LittleEndian.PutUint32(countBuf[:], uint32(pw.written))
+		_, err = wa.WriteAt(countBuf[:], binaryCountOffset)
+		return err
+	}
 	return nil
-}
\ No newline at end of file
+}
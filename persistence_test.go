@@ -1,18 +1,32 @@
 package ngcat
 
 import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"syscall"
 	"testing"
 	"time"
 )
 
 func TestPersistence(t *testing.T) {
+	clock := newFakeClock()
+	dir := t.TempDir()
 	nc := NewNGCache(1024*1024, &PersistConfig{
 		Enabled:  true,
-		FilePath: "",
+		FilePath: dir,
 		FileName: "test.cat",
 		Format:   FormatBinary,
-		Interval: 5,
-	})
+		Interval: 5 * time.Second,
+	}, WithClock(clock))
 
 	nc.SetString("string", "test", 0)
 	nc.SetBool("bool", true, 0)
@@ -31,32 +45,568 @@ func TestPersistence(t *testing.T) {
 	vfloat, _ := nc.GetFloat32("float")
 	t.Log(vfloat)
 
-	time.Sleep(time.Duration(1) * time.Second)
+	// 推进一个持久化间隔，由持久化协程写出文件
+	clock.Add(5 * time.Second)
+	waitFor(t, func() bool { return fileExists(filepath.Join(dir, "test.cat")) })
 	nc.Close()
 }
 
 func TestPersistenceLoad(t *testing.T) {
-	nc := NewNGCache(1024*1024, &PersistConfig{
+	config := &PersistConfig{
 		Enabled:  true,
-		FilePath: "",
+		FilePath: t.TempDir(),
 		FileName: "test.cat",
 		Format:   FormatBinary,
-		Interval: 5,
-	})
+		Interval: 5 * time.Second,
+	}
+	writer := NewNGCache(1024*1024, config, WithClock(newFakeClock()))
+	writer.SetString("string", "test", 0)
+	writer.SetBool("bool", true, 0)
+	writer.SetInt32("int", 1, 0)
+	writer.SetFloat32("float", 0.99, 0)
+	writer.Close()
 
 	// 自动加载持久化数据
+	nc := NewNGCache(1024*1024, config, WithClock(newFakeClock()))
 
-	vstring, _ := nc.GetString("string")
-	t.Log(vstring)
+	if v, err := nc.GetString("string"); err != nil || v != "test" {
+		t.Fatalf("GetString = %q, %v", v, err)
+	}
+	if v, err := nc.GetBool("bool"); err != nil || !v {
+		t.Fatalf("GetBool = %v, %v", v, err)
+	}
+	if v, err := nc.GetInt32("int"); err != nil || v != 1 {
+		t.Fatalf("GetInt32 = %d, %v", v, err)
+	}
+	if v, err := nc.GetFloat32("float"); err != nil || v != 0.99 {
+		t.Fatalf("GetFloat32 = %v, %v", v, err)
+	}
 
-	vbool, _ := nc.GetBool("bool")
-	t.Log(vbool)
+	nc.Close()
+}
 
-	vint, _ := nc.GetInt32("int")
-	t.Log(vint)
+func TestMaxPersistEntries(t *testing.T) {
+	dir := t.TempDir()
+	var logs bytes.Buffer
+	config := &PersistConfig{Enabled: true, FilePath: dir, FileName: "cache.bin", Format: FormatBinary, Interval: time.Hour, MaxPersistEntries: 5}
+	nc := NewNGCache(1024*1024, config, WithLogger(slog.New(slog.NewTextHandler(&logs, nil))))
+	for i := 0; i < 20; i++ {
+		nc.SetString(fmt.Sprintf("k%02d", i), "v", 0)
+		if err := nc.Save(); err != nil {
+			t.Fatal(err)
+		}
+		entries, err := readSnapshot(context.Background(), nc.persistFilePath())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(entries) > 5 {
+			t.Fatalf("file contains %d entries", len(entries))
+		}
+	}
+	nc.Close()
 
-	vfloat, _ := nc.GetFloat32("float")
-	t.Log(vfloat)
+	entries, _ := readSnapshot(context.Background(), filepath.Join(dir, "cache.bin"))
+	if _, ok := entries["k04"]; !ok || len(entries) != 5 {
+		t.Fatalf("expected the alphabetically-first keys to be kept: %v", entries)
+	}
+	if !strings.Contains(logs.String(), "level=WARN") {
+		t.Fatalf("expected a warning, got %q", logs.String())
+	}
+}
 
-	nc.Close()
+func TestMaxFileSizeBytes(t *testing.T) {
+	for _, format := range []PersistFormat{FormatBinary, FormatJSON} {
+		dir := t.TempDir()
+		var logs bytes.Buffer
+		config := &PersistConfig{Enabled: true, FilePath: dir, FileName: "cache", Format: format, Interval: time.Hour, MaxFileSizeBytes: 50000}
+		nc := NewNGCache(1024*1024, config, WithLogger(slog.New(slog.NewTextHandler(&logs, nil))))
+		// 共100KB：10个5KB的大值和50个1KB的小值
+		for i := 0; i < 10; i++ {
+			nc.SetString(fmt.Sprintf("big%d", i), strings.Repeat("b", 5*1024), 0)
+		}
+		for i := 0; i < 50; i++ {
+			nc.SetString(fmt.Sprintf("small%d", i), strings.Repeat("s", 1024), 0)
+		}
+		if err := nc.Close(); err != nil {
+			t.Fatal(err)
+		}
+
+		info, err := os.Stat(filepath.Join(dir, "cache"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if info.Size() > 50000 {
+			t.Fatalf("format %d: file is %d bytes", format, info.Size())
+		}
+		if !strings.Contains(logs.String(), "dropped=") || !strings.Contains(logs.String(), "big") {
+			t.Fatalf("format %d: dropped keys not logged: %q", format, logs.String())
+		}
+
+		// 先丢弃最大的条目，JSON的base64编码使体积膨胀，还会丢弃部分小条目
+		reloaded := NewNGCache(1024*1024, config)
+		kept := reloaded.DumpPermanent()
+		reloaded.Close()
+		if len(kept) == 0 {
+			t.Fatalf("format %d: nothing was kept", format)
+		}
+		for key := range kept {
+			if strings.HasPrefix(key, "big") {
+				t.Fatalf("format %d: %s kept while small entries were dropped", format, key)
+			}
+		}
+	}
+}
+
+func TestLoadSkipsLongKeys(t *testing.T) {
+	longKey := strings.Repeat("k", 70*1024)
+	for _, format := range []PersistFormat{FormatBinary, FormatJSON} {
+		config := &PersistConfig{
+			Enabled:  true,
+			FilePath: t.TempDir(),
+			FileName: "crafted",
+			Format:   format,
+			Interval: time.Hour,
+		}
+		// 构造包含超长键的快照，正常写入路径无法产生这样的文件
+		var buf bytes.Buffer
+		err := writePersistData(context.Background(), &buf, format, &PersistData{
+			Entries: []PersistEntry{{Key: longKey, Value: []byte("x")}, {Key: "ok", Value: []byte("v")}},
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		path := (&NGCache{persistConfig: config}).persistFilePath()
+		if err := os.WriteFile(path, buf.Bytes(), 0644); err != nil {
+			t.Fatal(err)
+		}
+
+		var reported []error
+		nc, err := Open(1024*1024, config, WithOnError(func(err error) { reported = append(reported, err) }))
+		if err != nil {
+			t.Fatalf("format %d: %v", format, err)
+		}
+		if v, _ := nc.GetString("ok"); v != "v" {
+			t.Fatalf("format %d: ok = %q", format, v)
+		}
+		if _, err := nc.GetString(longKey); err != ErrKeyNotFound {
+			t.Fatalf("format %d: long key loaded: %v", format, err)
+		}
+		if len(reported) != 1 || !errors.Is(reported[0], ErrKeyTooLarge) {
+			t.Fatalf("format %d: reported %v", format, reported)
+		}
+		nc.Close()
+	}
+}
+
+func TestPersistCircuitBreaker(t *testing.T) {
+	// 以普通文件作为持久化目录，每次持久化都在创建目录时失败
+	blocker := filepath.Join(t.TempDir(), "blocker")
+	if err := os.WriteFile(blocker, nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+	clock := newFakeClock()
+	var mu sync.Mutex
+	failures := 0
+	count := func() int {
+		mu.Lock()
+		defer mu.Unlock()
+		return failures
+	}
+	nc := NewNGCache(1024*1024, &PersistConfig{
+		Enabled:  true,
+		FilePath: blocker,
+		FileName: "cache.bin",
+		Format:   FormatBinary,
+		Interval: time.Second,
+	}, WithClock(clock), WithLogger(slog.New(slog.NewTextHandler(io.Discard, nil))), WithOnError(func(err error) {
+		mu.Lock()
+		failures++
+		mu.Unlock()
+	}))
+	defer nc.Close()
+	nc.SetString("k", "v", 0)
+
+	for i := 1; i <= 3; i++ {
+		clock.Add(time.Second)
+		waitFor(t, func() bool { return count() == i })
+	}
+
+	// 断路器打开后的30秒内不再尝试
+	for i := 0; i < 28; i++ {
+		clock.Add(time.Second)
+	}
+	time.Sleep(20 * time.Millisecond)
+	if n := count(); n != 3 {
+		t.Fatalf("breaker should be open, got %d failures", n)
+	}
+
+	// 暂停结束后的一次失败立即再次打开断路器，暂停时长翻倍
+	clock.Add(3 * time.Second)
+	waitFor(t, func() bool { return count() == 4 })
+	for i := 0; i < 58; i++ {
+		clock.Add(time.Second)
+	}
+	time.Sleep(20 * time.Millisecond)
+	if n := count(); n != 4 {
+		t.Fatalf("breaker should reopen for 60s, got %d failures", n)
+	}
+
+	// 磁盘恢复后下一次持久化成功并复位断路器
+	if err := os.Remove(blocker); err != nil {
+		t.Fatal(err)
+	}
+	clock.Add(3 * time.Second)
+	path := filepath.Join(blocker, "cache.bin")
+	waitFor(t, func() bool { return fileExists(path) })
+	if n := count(); n != 4 {
+		t.Fatalf("unexpected failures after recovery: %d", n)
+	}
+}
+
+// flakyWriter 前failures次写入返回EAGAIN的Writer
+type flakyWriter struct {
+	w        io.Writer
+	failures *int
+}
+
+func (f flakyWriter) Write(p []byte) (int, error) {
+	if *f.failures > 0 {
+		*f.failures--
+		return 0, &os.PathError{Op: "write", Path: "mock", Err: syscall.EAGAIN}
+	}
+	return f.w.Write(p)
+}
+
+func TestPersistRetryTransient(t *testing.T) {
+	failures := 0
+	attempts := 0
+	orig := wrapPersistFile
+	wrapPersistFile = func(w io.Writer) io.Writer {
+		attempts++
+		return flakyWriter{w: w, failures: &failures}
+	}
+	t.Cleanup(func() { wrapPersistFile = orig })
+
+	config := &PersistConfig{
+		Enabled:      true,
+		FilePath:     t.TempDir(),
+		FileName:     "cache.bin",
+		Format:       FormatBinary,
+		Interval:     time.Hour,
+		MaxRetries:   3,
+		RetryBackoff: time.Millisecond,
+	}
+	nc := NewNGCache(1024*1024, config, WithLogger(slog.New(slog.NewTextHandler(io.Discard, nil))))
+	defer nc.Close()
+	nc.SetString("k", "v", 0)
+
+	// 前两次写入失败，第三次成功
+	failures = 2
+	if err := nc.Save(); err != nil {
+		t.Fatalf("Save should succeed after retries: %v", err)
+	}
+	if attempts != 3 {
+		t.Fatalf("attempts = %d, want 3", attempts)
+	}
+	if !fileExists(nc.persistFilePath()) {
+		t.Fatal("snapshot not written")
+	}
+
+	// 重试次数用尽后返回最后一次的错误
+	failures, attempts = 10, 0
+	if err := nc.Save(); !errors.Is(err, syscall.EAGAIN) {
+		t.Fatalf("expected EAGAIN after retries, got %v", err)
+	}
+	if attempts != 4 {
+		t.Fatalf("attempts = %d, want 4", attempts)
+	}
+
+	// 非暂时性错误不重试
+	nc.persistConfig.FilePath = filepath.Join(config.FilePath, "cache.bin", "nested")
+	failures, attempts = 0, 0
+	if err := nc.Save(); err == nil || attempts != 0 {
+		t.Fatalf("non-transient error: %v, attempts = %d", err, attempts)
+	}
+}
+
+func TestPersistTickSkipsSlowSaves(t *testing.T) {
+	tests := []struct {
+		name      string
+		saveTime  time.Duration
+		adaptive  bool
+		wantSaves int
+	}{
+		// 保存耗时小于间隔，每个tick都保存
+		{"fast", 800 * time.Millisecond, false, 10},
+		// 保存期间到达的tick被跳过，不会连续保存
+		{"slow", 1500 * time.Millisecond, false, 5},
+		// 保存耗时超过间隔的一半，间隔拉长为1.6秒
+		{"adaptive fast", 800 * time.Millisecond, true, 5},
+		// 间隔拉长为3秒
+		{"adaptive slow", 1500 * time.Millisecond, true, 4},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			clock := newFakeClock()
+			saves := 0
+			orig := wrapPersistFile
+			wrapPersistFile = func(w io.Writer) io.Writer {
+				saves++
+				clock.Add(tt.saveTime)
+				return w
+			}
+			t.Cleanup(func() { wrapPersistFile = orig })
+
+			opts := []Option{WithClock(clock)}
+			if tt.adaptive {
+				opts = append(opts, WithAdaptivePersistInterval())
+			}
+			nc := NewNGCache(1024*1024, &PersistConfig{
+				Enabled:  true,
+				FilePath: t.TempDir(),
+				FileName: "cache.bin",
+				Format:   FormatBinary,
+				Interval: time.Hour,
+			}, opts...)
+			nc.SetString("k", "v", 0)
+
+			// 直接驱动persistTick，每秒一个tick，且保存期间的tick都不丢弃
+			start := clock.Now()
+			for i := 1; i <= 10; i++ {
+				tick := start.Add(time.Duration(i) * time.Second)
+				if now := clock.Now(); now.Before(tick) {
+					clock.Add(tick.Sub(now))
+				}
+				nc.persistTick(tick)
+			}
+			if saves != tt.wantSaves {
+				t.Fatalf("saves = %d, want %d", saves, tt.wantSaves)
+			}
+			if skipped := nc.Stats().PersistSkippedTicks; skipped != int64(10-tt.wantSaves) {
+				t.Fatalf("PersistSkippedTicks = %d, want %d", skipped, 10-tt.wantSaves)
+			}
+			wrapPersistFile = orig
+			nc.Close()
+		})
+	}
+}
+
+func TestPersistTickSkipsDuringSave(t *testing.T) {
+	clock := newFakeClock()
+	nc := NewNGCache(1024*1024, &PersistConfig{
+		Enabled:  true,
+		FilePath: t.TempDir(),
+		FileName: "cache.bin",
+		Format:   FormatBinary,
+		Interval: time.Hour,
+	}, WithClock(clock))
+	defer nc.Close()
+
+	// 模拟Save正在进行
+	nc.savesInFlight.Add(1)
+	nc.persistTick(clock.Now())
+	nc.savesInFlight.Add(-1)
+	if skipped := nc.Stats().PersistSkippedTicks; skipped != 1 {
+		t.Fatalf("PersistSkippedTicks = %d, want 1", skipped)
+	}
+	if fileExists(filepath.Join(nc.persistConfig.FilePath, "cache.bin")) {
+		t.Fatal("tick during a save should not write")
+	}
+}
+
+func TestPersistSyncOnWrite(t *testing.T) {
+	syncs := 0
+	syncErr := errors.New("disk gone")
+	var fail bool
+	orig := syncPersistFile
+	syncPersistFile = func(f *os.File) error {
+		syncs++
+		if fail {
+			return syncErr
+		}
+		return f.Sync()
+	}
+	t.Cleanup(func() { syncPersistFile = orig })
+
+	for _, format := range []PersistFormat{FormatJSON, FormatBinary} {
+		syncs = 0
+		fail = false
+		config := &PersistConfig{
+			Enabled:  true,
+			FilePath: t.TempDir(),
+			FileName: "cache.dat",
+			Format:   format,
+			Interval: time.Hour,
+		}
+		nc := NewNGCache(1024*1024, config)
+		nc.SetString("k", "v1", 0)
+		if err := nc.Save(); err != nil || syncs != 0 {
+			t.Fatalf("format %d: Save without SyncOnWrite: err=%v syncs=%d", format, err, syncs)
+		}
+
+		nc.persistConfig.SyncOnWrite = true
+		if err := nc.Save(); err != nil || syncs != 1 {
+			t.Fatalf("format %d: Save with SyncOnWrite: err=%v syncs=%d", format, err, syncs)
+		}
+
+		// 同步失败时报告错误，原有的持久化文件保持不变
+		path := filepath.Join(config.FilePath, config.FileName)
+		before, _ := os.ReadFile(path)
+		fail = true
+		nc.SetString("k", "v2", 0)
+		err := nc.Save()
+		if !errors.Is(err, &CacheError{Code: CodeSyncFile}) || !errors.Is(err, syncErr) {
+			t.Fatalf("format %d: Save error = %v, want sync_file", format, err)
+		}
+		after, _ := os.ReadFile(path)
+		if !bytes.Equal(before, after) {
+			t.Fatalf("format %d: persist file changed after a failed sync", format)
+		}
+		matches, _ := filepath.Glob(path + ".tmp*")
+		if len(matches) != 0 {
+			t.Fatalf("format %d: temporary files left behind: %v", format, matches)
+		}
+		fail = false
+		nc.Close()
+	}
+}
+
+// gatedStore 写入gate键时通知entered，并阻塞到release被关闭
+type gatedStore struct {
+	*MapStore
+	gate    string
+	entered chan struct{}
+	release chan struct{}
+}
+
+func (s *gatedStore) Set(key, value []byte, expireSeconds int) error {
+	if string(key) == s.gate {
+		close(s.entered)
+		<-s.release
+	}
+	return s.MapStore.Set(key, value, expireSeconds)
+}
+
+func TestCloseWaitsForInflightWrites(t *testing.T) {
+	config := &PersistConfig{
+		Enabled:  true,
+		FilePath: t.TempDir(),
+		FileName: "cache.bin",
+		Format:   FormatBinary,
+		Interval: time.Hour,
+	}
+	store := &gatedStore{MapStore: NewMapStore(nil), gate: "key", entered: make(chan struct{}), release: make(chan struct{})}
+	nc := NewNGCacheWithStore(store, config)
+
+	written := make(chan error)
+	go func() { written <- nc.SetString("key", "v", 0) }()
+	<-store.entered
+
+	closed := make(chan error)
+	go func() { closed <- nc.Close() }()
+	// Close开始等待进行中的写入后，写入放行之前不能返回
+	waitFor(t, func() bool {
+		nc.inflightMutex.RLock()
+		defer nc.inflightMutex.RUnlock()
+		return nc.closing
+	})
+	select {
+	case err := <-closed:
+		t.Fatalf("Close returned before the write finished: %v", err)
+	default:
+	}
+	close(store.release)
+	if err := <-closed; err != nil {
+		t.Fatal(err)
+	}
+	if err := <-written; err != nil {
+		t.Fatal(err)
+	}
+
+	reloaded := NewNGCache(1024*1024, config)
+	defer reloaded.Close()
+	if _, err := reloaded.GetString("key"); err != nil {
+		t.Fatalf("key missing from saved file: %v", err)
+	}
+}
+
+func TestFormatCaps(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "cache.bin")
+	nc := NewNGCache(1024*1024, nil, WithCompressValuesOver(16))
+	defer nc.Close()
+	nc.SetString("k", strings.Repeat("v", 100), 0)
+	if err := writePersistFile(nc.ctx, path, FormatBinary, nc.collectPersistData(), false); err != nil {
+		t.Fatal(err)
+	}
+	summary, err := InspectPersistFile(path)
+	if err != nil || summary.FormatCaps != CapCompression|CapIndex {
+		t.Fatalf("summary = %+v, %v", summary, err)
+	}
+
+	// 设置一个当前版本不认识的位
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	binary.LittleEndian.PutUint32(data[8:], CapCompression|1<<31)
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		t.Fatal(err)
+	}
+	other := NewNGCache(1024*1024, nil, WithCompressValuesOver(16))
+	defer other.Close()
+	err = other.Import(path, FormatBinary)
+	if !errors.Is(err, ErrUnsupportedFeature) || !strings.Contains(err.Error(), "0x80000000") {
+		t.Fatalf("Import err = %v", err)
+	}
+	if _, err := InspectPersistFile(path); !errors.Is(err, ErrUnsupportedFeature) {
+		t.Fatalf("Inspect err = %v", err)
+	}
+	if _, err := other.GetString("k"); !errors.Is(err, ErrKeyNotFound) {
+		t.Fatalf("rejected file should load nothing, got %v", err)
+	}
+
+	// 更新版本写出的文件同样提示升级，而不是报告文件损坏
+	binary.LittleEndian.PutUint32(data[8:], CapCompression|CapIndex)
+	binary.LittleEndian.PutUint32(data[4:], BinaryVersion+1)
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		t.Fatal(err)
+	}
+	err = other.Import(path, FormatBinary)
+	if !errors.Is(err, ErrUnsupportedFeature) || errors.Is(err, ErrCorruptFile) || !strings.Contains(err.Error(), "newer version") {
+		t.Fatalf("Import newer version err = %v", err)
+	}
+}
+
+func TestTTLOverwriteDropsPermanent(t *testing.T) {
+	config := &PersistConfig{Enabled: true, FilePath: t.TempDir(), FileName: "cache.bin", Format: FormatBinary, Interval: time.Hour}
+	nc := NewNGCache(1024*1024, config)
+	nc.SetString("set", "permanent", 0)
+	nc.SetString("bundle", "permanent", 0)
+	nc.SetString("kept", "permanent", 0)
+	if err := nc.SetString("set", "temporary", 60); err != nil {
+		t.Fatal(err)
+	}
+	if err := nc.SetBundle([]BundleEntry{{Key: "bundle", Value: "temporary"}}, 60); err != nil {
+		t.Fatal(err)
+	}
+	for _, key := range []string{"set", "bundle"} {
+		if _, ok := persisted(nc, key); ok {
+			t.Fatalf("%s: stale permanent value left in persistData", key)
+		}
+	}
+	if err := nc.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	reloaded := NewNGCache(1024*1024, config)
+	defer reloaded.Close()
+	for _, key := range []string{"set", "bundle"} {
+		if v, err := reloaded.GetString(key); !errors.Is(err, ErrKeyNotFound) {
+			t.Fatalf("%s after reopen = %q, %v, want ErrKeyNotFound", key, v, err)
+		}
+	}
+	if v, err := reloaded.GetString("kept"); err != nil || v != "permanent" {
+		t.Fatalf("kept after reopen = %q, %v", v, err)
+	}
 }
@@ -0,0 +1,117 @@
+package ngcat
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// PreloadPolicy 加载持久化数据时哪些永久缓存同时写入freecache
+//
+// 未预加载的永久缓存只保存在持久化数据中，第一次读取时按WithPromotePolicy写回freecache。
+type PreloadPolicy int
+
+const (
+	// PreloadAll 全部写入freecache，默认值
+	PreloadAll PreloadPolicy = iota
+	// PreloadNone 都不写入freecache
+	PreloadNone
+	// PreloadTopN 只写入上次运行中访问次数最多的N个键（见WithPreloadTopN），
+	// 需要通过WithHotKeys启用热点键统计，保存时热点键列表写入持久化文件旁的.hot文件
+	PreloadTopN
+	// PreloadPrefixes 只写入以指定前缀开头的键（见WithPreloadPrefixes）
+	PreloadPrefixes
+)
+
+// hotKeysSuffix 热点键列表文件相对持久化文件的后缀
+const hotKeysSuffix = ".hot"
+
+// hotKeysPath 热点键列表文件的路径
+func (ng *NGCache) hotKeysPath() string {
+	return ng.persistFilePath() + hotKeysSuffix
+}
+
+// saveHotKeys 将热点键统计写入热点键列表文件，未启用WithHotKeys时不做任何事
+//
+// 列表只用于下次启动时的PreloadTopN，写入失败时通过WithOnError报告，不影响快照的保存。
+func (ng *NGCache) saveHotKeys() {
+	if ng.hotKeys == nil {
+		return
+	}
+	data, err := json.Marshal(ng.hotKeys.top(-1))
+	if err != nil {
+		ng.reportError(newError(CodeEncode, "hot keys", err))
+		return
+	}
+	path := ng.hotKeysPath()
+	tmp := path + ".tmp"
+	err = os.MkdirAll(filepath.Dir(path), 0755)
+	if err == nil {
+		err = os.WriteFile(tmp, data, 0644)
+	}
+	if err == nil {
+		err = os.Rename(tmp, path)
+	}
+	if err != nil {
+		os.Remove(tmp)
+		ng.reportError(newError(CodeWriteFile, path, err))
+	}
+}
+
+// loadHotKeys 读取上次运行保存的访问次数最多的n个键，文件不存在或无法解析时返回nil
+func (ng *NGCache) loadHotKeys(n int) map[string]struct{} {
+	data, err := os.ReadFile(ng.hotKeysPath())
+	if err != nil {
+		if !os.IsNotExist(err) {
+			ng.reportError(newError(CodeReadFile, ng.hotKeysPath(), err))
+		}
+		return nil
+	}
+	var freqs []KeyFreq
+	err = json.Unmarshal(data, &freqs)
+	if err != nil {
+		ng.reportError(newError(CodeDecode, ng.hotKeysPath(), err))
+		return nil
+	}
+	// 文件中的列表已按访问次数降序排列
+	if len(freqs) > n {
+		freqs = freqs[:n]
+	}
+	hot := make(map[string]struct{}, len(freqs))
+	for _, f := range freqs {
+		hot[f.Key] = struct{}{}
+	}
+	return hot
+}
+
+// preparePreload 加载持久化文件之前读取PreloadTopN使用的热点键列表
+func (ng *NGCache) preparePreload() {
+	if ng.preloadPolicy != PreloadTopN {
+		return
+	}
+	hot := ng.loadHotKeys(ng.preloadTopN)
+	ng.persistDataMutex.Lock()
+	ng.preloadHot = hot
+	ng.persistDataMutex.Unlock()
+}
+
+// shouldPreload 加载时是否将键写入freecache，调用方需持有persistDataMutex
+func (ng *NGCache) shouldPreload(key string) bool {
+	switch ng.preloadPolicy {
+	case PreloadNone:
+		return false
+	case PreloadTopN:
+		_, ok := ng.preloadHot[key]
+		return ok
+	case PreloadPrefixes:
+		for _, prefix := range ng.preloadPrefixes {
+			if strings.HasPrefix(key, prefix) {
+				return true
+			}
+		}
+		return false
+	default:
+		return true
+	}
+}
@@ -0,0 +1,107 @@
+package ngcat
+
+import (
+	"fmt"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// preloadConfig 返回测试使用的持久化配置
+func preloadConfig(t *testing.T) *PersistConfig {
+	return &PersistConfig{
+		Enabled:  true,
+		FilePath: t.TempDir(),
+		FileName: "cache.bin",
+		Format:   FormatBinary,
+		Interval: time.Hour,
+	}
+}
+
+// seedPersistFile 写入keys个永久缓存并保存
+func seedPersistFile(t *testing.T, config *PersistConfig, keys []string, opts ...Option) {
+	t.Helper()
+	nc := NewNGCache(1024*1024, config, opts...)
+	for _, key := range keys {
+		if err := nc.SetString(key, "v-"+key, 0); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := nc.Close(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestPreloadNone(t *testing.T) {
+	config := preloadConfig(t)
+	var keys []string
+	for i := 0; i < 20; i++ {
+		keys = append(keys, fmt.Sprintf("key%d", i))
+	}
+	seedPersistFile(t, config, keys)
+
+	nc := NewNGCache(1024*1024, config, WithPreload(PreloadNone))
+	defer nc.Close()
+	if n := nc.cache.EntryCount(); n != 0 {
+		t.Fatalf("PreloadNone should not touch freecache, got %d entries", n)
+	}
+	if n := nc.Stats().PersistEntries; n != 20 {
+		t.Fatalf("PersistEntries = %d, want 20", n)
+	}
+
+	// 第一次读取从持久化数据获取并写回freecache
+	if v, err := nc.GetString("key3"); err != nil || v != "v-key3" {
+		t.Fatalf("GetString = %q, %v", v, err)
+	}
+	if !nc.inFreecache("key3") || nc.cache.EntryCount() != 1 {
+		t.Fatal("first Get should promote the key into freecache")
+	}
+	if n := nc.Stats().Promotions; n != 1 {
+		t.Fatalf("Promotions = %d, want 1", n)
+	}
+}
+
+func TestPreloadTopN(t *testing.T) {
+	config := preloadConfig(t)
+	nc := NewNGCache(1024*1024, config, WithHotKeys(64, 1))
+	for _, key := range []string{"a", "b", "c", "d"} {
+		nc.SetString(key, "v-"+key, 0)
+	}
+	for i := 0; i < 5; i++ {
+		nc.GetString("c")
+	}
+	for i := 0; i < 3; i++ {
+		nc.GetString("a")
+	}
+	nc.GetString("d")
+	if err := nc.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if !fileExists(filepath.Join(config.FilePath, "cache.bin"+hotKeysSuffix)) {
+		t.Fatal("hot key list should be saved alongside the snapshot")
+	}
+
+	nc = NewNGCache(1024*1024, config, WithHotKeys(64, 1), WithPreloadTopN(2))
+	defer nc.Close()
+	for key, want := range map[string]bool{"a": true, "b": false, "c": true, "d": false} {
+		if got := nc.inFreecache(key); got != want {
+			t.Fatalf("%s preloaded = %v, want %v", key, got, want)
+		}
+	}
+	if v, err := nc.GetString("b"); err != nil || v != "v-b" {
+		t.Fatalf("lazy key: %q, %v", v, err)
+	}
+}
+
+func TestPreloadPrefixes(t *testing.T) {
+	config := preloadConfig(t)
+	seedPersistFile(t, config, []string{"user:1", "user:2", "session:1", "cfg"})
+
+	nc := NewNGCache(1024*1024, config, WithPreloadPrefixes("user:", "cfg"))
+	defer nc.Close()
+	for key, want := range map[string]bool{"user:1": true, "user:2": true, "session:1": false, "cfg": true} {
+		if got := nc.inFreecache(key); got != want {
+			t.Fatalf("%s preloaded = %v, want %v", key, got, want)
+		}
+	}
+}
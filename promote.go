@@ -0,0 +1,123 @@
+package ngcat
+
+import (
+	"bytes"
+	"math/rand"
+	"sync"
+	"time"
+)
+
+// PromotePolicy 读取时将仅存在于持久化数据中的永久缓存写回freecache的策略
+type PromotePolicy int
+
+const (
+	// PromoteAlways 每次从持久化数据读取时都写回freecache
+	PromoteAlways PromotePolicy = iota
+	// PromoteNever 从不写回，被淘汰的永久缓存直接由持久化数据提供
+	PromoteNever
+	// PromoteProbabilistic 按概率写回，避免冷数据的读取挤掉热点条目
+	PromoteProbabilistic
+)
+
+// freecache的分段数量和条目头部长度，用于计算单个条目的上限
+const (
+	freecacheSegments    = 256
+	freecacheEntryHeader = 24
+	freecacheMinSize     = 512 * 1024
+)
+
+// promoteDedupWindow 同一键写回完成后的这段时间内，其他读取不再写回
+const promoteDedupWindow = 5 * time.Millisecond
+
+// promoteRecentLimit recent中的键超过该数量时清理已过去重窗口的键
+const promoteRecentLimit = 1024
+
+// promoteTracker 合并同一键的并发写回：同一时刻只有一个读取执行写回，其他读取直接返回值
+type promoteTracker struct {
+	mu       sync.Mutex
+	inflight map[string]struct{}
+	// recent 最近完成写回的键及完成时间
+	recent map[string]time.Time
+}
+
+// begin 登记键的写回，已有写回进行中或刚完成时返回false
+func (t *promoteTracker) begin(key string, now time.Time) bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if _, ok := t.inflight[key]; ok {
+		return false
+	}
+	if done, ok := t.recent[key]; ok && now.Sub(done) < promoteDedupWindow {
+		return false
+	}
+	if t.inflight == nil {
+		t.inflight = make(map[string]struct{})
+		t.recent = make(map[string]time.Time)
+	}
+	t.inflight[key] = struct{}{}
+	return true
+}
+
+// end 结束键的写回，promoted为true时记录完成时间
+func (t *promoteTracker) end(key string, now time.Time, promoted bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	delete(t.inflight, key)
+	if !promoted {
+		return
+	}
+	if len(t.recent) >= promoteRecentLimit {
+		for k, done := range t.recent {
+			if now.Sub(done) >= promoteDedupWindow {
+				delete(t.recent, k)
+			}
+		}
+	}
+	t.recent[key] = now
+}
+
+// maxEntrySize 计算freecache可接受的键和值的总长度上限
+func maxEntrySize(size int) int {
+	if size < freecacheMinSize {
+		size = freecacheMinSize
+	}
+	return size/freecacheSegments/4 - freecacheEntryHeader
+}
+
+// promote 按写回策略将持久化数据中的值写回freecache
+//
+// 写入在持久化数据锁之外进行，超过freecache条目上限的值不会写回。写回后若持久化
+// 数据已被并发修改，则删除刚写入的旧值，下一次读取会重新从持久化数据获取。
+// 同一键同时只有一个读取执行写回，其他读取以及写回完成后promoteDedupWindow内的读取跳过写回，
+// 计入Stats().PromotionsDeduplicated。
+func (ng *NGCache) promote(key string, value []byte) {
+	switch ng.promotePolicy {
+	case PromoteNever:
+		return
+	case PromoteProbabilistic:
+		if rand.Float64() >= ng.promoteProbability {
+			return
+		}
+	}
+	if len(key)+len(value) > ng.maxEntrySize {
+		return
+	}
+
+	if !ng.promoteTracker.begin(key, ng.clock.Now()) {
+		ng.promotionsDeduped.Add(1)
+		return
+	}
+	if ng.storeSet(key, value, 0) != nil {
+		ng.promoteTracker.end(key, ng.clock.Now(), false)
+		return
+	}
+	ng.promotions.Add(1)
+	ng.promoteTracker.end(key, ng.clock.Now(), true)
+
+	ng.persistDataMutex.RLock()
+	current, exists := ng.persistData[key]
+	ng.persistDataMutex.RUnlock()
+	if !exists || !bytes.Equal(current, value) {
+		ng.storeDel(key)
+	}
+}
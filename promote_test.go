@@ -0,0 +1,115 @@
+package ngcat
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestPromoteNever(t *testing.T) {
+	nc := NewNGCache(1024*1024, nil, WithPromotePolicy(PromoteNever, 0))
+	defer nc.Close()
+
+	nc.SetString("k", "v", 0)
+	nc.cache.Del([]byte("k"))
+	for i := 0; i < 3; i++ {
+		if v, err := nc.GetString("k"); err != nil || v != "v" {
+			t.Fatalf("GetString = %q, %v", v, err)
+		}
+	}
+	if _, err := nc.storePeek("k"); err == nil {
+		t.Fatal("PromoteNever should serve from persistData only")
+	}
+	if s := nc.Stats(); s.Promotions != 0 || s.PersistEntries != 1 {
+		t.Fatalf("stats = %+v", s)
+	}
+
+	always := NewNGCache(1024*1024, nil)
+	defer always.Close()
+	always.SetString("k", "v", 0)
+	always.cache.Del([]byte("k"))
+	always.GetString("k")
+	if _, err := always.storePeek("k"); err != nil || always.Stats().Promotions != 1 {
+		t.Fatal("PromoteAlways should write the value back")
+	}
+}
+
+func TestPromoteSkipsOversize(t *testing.T) {
+	nc := NewNGCache(1024*1024, nil)
+	defer nc.Close()
+
+	// 模拟从较大缓存的持久化文件加载的超大值
+	big := strings.Repeat("x", nc.maxEntrySize+1)
+	nc.persistDataMutex.Lock()
+	nc.persistData["big"] = []byte(big)
+	nc.persistDataMutex.Unlock()
+
+	for i := 0; i < 3; i++ {
+		if v, err := nc.GetString("big"); err != nil || v != big {
+			t.Fatalf("GetString err = %v", err)
+		}
+	}
+	if n := nc.Stats().Promotions; n != 0 {
+		t.Fatalf("oversize value promoted %d times", n)
+	}
+}
+
+func TestPromoteConcurrentMisses(t *testing.T) {
+	nc := NewNGCache(1024*1024, nil)
+	defer nc.Close()
+
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for n := 0; n < 200; n++ {
+				nc.cache.Del([]byte("k"))
+				nc.GetString("k")
+			}
+		}()
+	}
+	for n := 0; n < 200; n++ {
+		nc.SetString("k", fmt.Sprint(n), 0)
+	}
+	wg.Wait()
+
+	if v, _ := nc.GetString("k"); v != "199" {
+		t.Fatalf("stale value promoted: %q", v)
+	}
+}
+
+func TestPromoteDeduplicated(t *testing.T) {
+	clock := newFakeClock()
+	nc := NewNGCache(1024*1024, nil, WithClock(clock))
+	defer nc.Close()
+
+	nc.SetString("k", "v", 0)
+	nc.cache.Del([]byte("k"))
+	nc.GetString("k")
+	// 写回刚完成，去重窗口内再次被淘汰时不写回
+	nc.cache.Del([]byte("k"))
+	if v, err := nc.GetString("k"); err != nil || v != "v" {
+		t.Fatalf("GetString = %q, %v", v, err)
+	}
+	if s := nc.Stats(); s.Promotions != 1 || s.PromotionsDeduplicated != 1 {
+		t.Fatalf("stats = %+v", s)
+	}
+
+	clock.Add(promoteDedupWindow)
+	nc.GetString("k")
+	if _, err := nc.storePeek("k"); err != nil || nc.Stats().Promotions != 2 {
+		t.Fatal("promotion after the window should write the value back")
+	}
+
+	// 写回进行中时其他读取直接返回
+	if !nc.promoteTracker.begin("busy", clock.Now()) {
+		t.Fatal("begin on idle key")
+	}
+	nc.promote("busy", []byte("x"))
+	if _, err := nc.storePeek("busy"); err == nil || nc.Stats().PromotionsDeduplicated != 2 {
+		t.Fatal("concurrent promotion should be skipped")
+	}
+	nc.promoteTracker.end("busy", clock.Now(), false)
+}
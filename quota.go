@@ -0,0 +1,161 @@
+package ngcat
+
+import (
+	"strings"
+	"sync"
+)
+
+// Quota 键前缀的空间配额
+//
+// 用量按条目的键和存储的值长度计算，永久缓存另外计入持久化数据中的副本。
+// 所有写入和删除路径都会更新匹配前缀的配额用量，但只有SetWithQuota会检查上限；
+// 过期或被淘汰的条目在被覆盖或删除之前仍计入用量。
+type Quota struct {
+	// Prefix 配额对应的键前缀
+	Prefix string
+	// MaxBytes 允许使用的最大字节数
+	MaxBytes int64
+
+	mu    sync.Mutex
+	used  int64
+	sizes map[string]int64
+}
+
+// Used 返回当前用量
+func (q *Quota) Used() int64 {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return q.used
+}
+
+// set 记录键的占用字节数，0表示键已删除，返回之前记录的字节数
+func (q *Quota) set(key string, size int64) int64 {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	old := q.sizes[key]
+	q.used += size - old
+	if size == 0 {
+		delete(q.sizes, key)
+	} else {
+		q.sizes[key] = size
+	}
+	return old
+}
+
+// reserve 在不超过上限时预先记录键的占用字节数，返回之前记录的字节数
+func (q *Quota) reserve(key string, size int64) (int64, bool) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	old := q.sizes[key]
+	if q.used-old+size > q.MaxBytes {
+		return old, false
+	}
+	q.used += size - old
+	q.sizes[key] = size
+	return old, true
+}
+
+// RegisterQuota 为键前缀登记配额，返回的Quota可传给SetWithQuota
+//
+// 登记时统计已存在的该前缀条目作为初始用量。前缀已登记时更新其上限并返回原有的配额。
+func (ng *NGCache) RegisterQuota(prefix string, maxBytes int64) *Quota {
+	ng.quotasMutex.Lock()
+	defer ng.quotasMutex.Unlock()
+	for _, q := range ng.quotas {
+		if q.Prefix == prefix {
+			q.mu.Lock()
+			q.MaxBytes = maxBytes
+			q.mu.Unlock()
+			return q
+		}
+	}
+
+	q := &Quota{Prefix: prefix, MaxBytes: maxBytes, sizes: make(map[string]int64)}
+	ng.storeRange(func(key, value []byte, _ uint32) bool {
+		if k := string(key); strings.HasPrefix(k, prefix) {
+			q.sizes[k] += int64(len(k) + len(value))
+		}
+		return true
+	})
+	ng.persistDataMutex.RLock()
+	for key, value := range ng.persistData {
+		if strings.HasPrefix(key, prefix) {
+			q.sizes[key] += int64(len(key) + len(value))
+		}
+	}
+	ng.persistDataMutex.RUnlock()
+	for _, size := range q.sizes {
+		q.used += size
+	}
+
+	ng.quotas = append(ng.quotas, q)
+	return q
+}
+
+// QuotaUsage 返回键前缀的配额用量，前缀未登记时返回0
+func (ng *NGCache) QuotaUsage(prefix string) int64 {
+	ng.quotasMutex.RLock()
+	defer ng.quotasMutex.RUnlock()
+	for _, q := range ng.quotas {
+		if q.Prefix == prefix {
+			return q.Used()
+		}
+	}
+	return 0
+}
+
+// SetWithQuota 在配额允许时写入值，写入后用量超过quota.MaxBytes时返回ErrQuotaExceeded且不写入
+//
+// 覆盖已有的键时只计算新旧大小之差，key必须以quota.Prefix开头。
+func (ng *NGCache) SetWithQuota(key string, value []byte, expire int, quota *Quota) error {
+	if !strings.HasPrefix(key, quota.Prefix) {
+		return newError(CodeQuotaPrefix, key+" not under "+quota.Prefix, nil)
+	}
+	started, err := ng.beginWrite()
+	if err != nil {
+		return err
+	}
+	if started {
+		defer ng.inflight.Done()
+	}
+
+	mu := ng.keyLock(key)
+	mu.Lock()
+	defer mu.Unlock()
+
+	value, expire, err = ng.prepareSet(key, value, expire)
+	if err != nil {
+		return err
+	}
+	size := entryFootprint(key, value, expire <= 0)
+	old, ok := quota.reserve(key, size)
+	if !ok {
+		return ErrQuotaExceeded
+	}
+	err = ng.storeLocked(key, value, expire)
+	if err != nil {
+		quota.set(key, old)
+		return err
+	}
+	return nil
+}
+
+// entryFootprint 计算条目计入配额的字节数，永久缓存包括持久化数据中的副本
+func entryFootprint(key string, value []byte, permanent bool) int64 {
+	size := int64(len(key) + len(value))
+	if permanent {
+		size *= 2
+	}
+	return size
+}
+
+// accountQuota 更新匹配键前缀的配额用量，size为0表示键已删除
+func (ng *NGCache) accountQuota(key string, size int64) {
+	ng.quotasMutex.RLock()
+	defer ng.quotasMutex.RUnlock()
+	for _, q := range ng.quotas {
+		if strings.HasPrefix(key, q.Prefix) {
+			q.set(key, size)
+		}
+	}
+}
@@ -0,0 +1,83 @@
+package ngcat
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestSetWithQuota(t *testing.T) {
+	nc := NewNGCache(1024*1024, nil)
+	defer nc.Close()
+
+	// 每个条目占用 len("t1:kN")+len(value) = 5+15 = 20 字节
+	quota := nc.RegisterQuota("t1:", 100)
+	value := make([]byte, 15)
+	for i := 0; i < 5; i++ {
+		if err := nc.SetWithQuota(fmt.Sprintf("t1:k%d", i), value, 60, quota); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if used := nc.QuotaUsage("t1:"); used != 100 {
+		t.Fatalf("usage = %d, want 100", used)
+	}
+	if err := nc.SetWithQuota("t1:k5", value, 60, quota); err != ErrQuotaExceeded {
+		t.Fatalf("expected ErrQuotaExceeded, got %v", err)
+	}
+	if _, err := nc.GetBytes("t1:k5"); err != ErrKeyNotFound {
+		t.Fatal("rejected write should not be stored")
+	}
+
+	// 覆盖只计算差值
+	if err := nc.SetWithQuota("t1:k0", value[:10], 60, quota); err != nil {
+		t.Fatal(err)
+	}
+	if used := nc.QuotaUsage("t1:"); used != 95 {
+		t.Fatalf("usage after overwrite = %d, want 95", used)
+	}
+
+	nc.Delete("t1:k1")
+	if used := nc.QuotaUsage("t1:"); used != 75 {
+		t.Fatalf("usage after delete = %d, want 75", used)
+	}
+	if err := nc.SetWithQuota("t1:k5", value, 60, quota); err != nil {
+		t.Fatalf("write after delete: %v", err)
+	}
+
+	// 其他前缀不受影响
+	if nc.QuotaUsage("t2:") != 0 {
+		t.Fatal("unregistered prefix should report zero usage")
+	}
+	if err := nc.SetWithQuota("t2:k", value, 60, quota); err == nil {
+		t.Fatal("key outside the quota prefix should be rejected")
+	}
+}
+
+func TestQuotaCountsPersistCopy(t *testing.T) {
+	nc := NewNGCache(1024*1024, nil)
+	defer nc.Close()
+
+	nc.SetString("p:existing", "xxxxx", 0)
+	quota := nc.RegisterQuota("p:", 1000)
+	if used := quota.Used(); used != 30 {
+		t.Fatalf("initial usage = %d, want 30", used)
+	}
+
+	if err := nc.SetWithQuota("p:perm", []byte("12345"), 0, quota); err != nil {
+		t.Fatal(err)
+	}
+	if used := quota.Used(); used != 30+2*(6+5) {
+		t.Fatalf("permanent key should count its persistData copy: usage = %d", used)
+	}
+
+	// 普通写入不检查上限，但同样计入用量
+	nc.SetString("p:plain", "1", 60)
+	if used := quota.Used(); used != 30+22+8 {
+		t.Fatalf("usage = %d", used)
+	}
+	nc.Delete("p:perm")
+	nc.Delete("p:plain")
+	nc.Delete("p:existing")
+	if used := quota.Used(); used != 0 {
+		t.Fatalf("usage after deleting everything = %d", used)
+	}
+}
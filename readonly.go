@@ -0,0 +1,353 @@
+package ngcat
+
+import (
+	"math/big"
+	"net"
+	"time"
+)
+
+// ReadOnlyCache NGCache的只读视图，读取委托给底层缓存，所有写入都返回ErrReadOnly
+//
+// 只读视图不拥有底层缓存：Close和Drain同样返回ErrReadOnly，底层缓存由创建者关闭。
+// 读取未命中时会写入的方法（GetOrCompute、GetOrRefresh）和会延长过期时间的GetSliding不提供；
+// 底层缓存通过WithSlidingTTL或SetSlidingTTL设置的滑动过期仍然生效。
+type ReadOnlyCache struct {
+	ng *NGCache
+}
+
+// ReadOnly 返回缓存的只读视图，可以交给审计等不应修改缓存的代码使用
+//
+// 视图本身不启动任何协程。只读副本应以WithReadOnlyPersistence创建底层缓存，
+// 这样既不启动持久化协程，也不会覆盖主实例的持久化文件。
+func (ng *NGCache) ReadOnly() *ReadOnlyCache {
+	return &ReadOnlyCache{ng: ng}
+}
+
+// GetString 获取字符串值
+func (r *ReadOnlyCache) GetString(key string) (string, error) {
+	return r.ng.GetString(key)
+}
+
+// GetStringZeroCopy 获取字符串值，不复制读取到的缓冲区
+func (r *ReadOnlyCache) GetStringZeroCopy(key string) (string, error) {
+	return r.ng.GetStringZeroCopy(key)
+}
+
+// GetBytes 获取字节数组值
+func (r *ReadOnlyCache) GetBytes(key string) ([]byte, error) {
+	return r.ng.GetBytes(key)
+}
+
+// GetBytesByKey 以字节数组作为键读取值
+func (r *ReadOnlyCache) GetBytesByKey(key []byte) ([]byte, error) {
+	return r.ng.GetBytesByKey(key)
+}
+
+// GetPermanent 读取键
+//
+// Deprecated: 使用GetBytesByKey。
+func (r *ReadOnlyCache) GetPermanent(key []byte) ([]byte, error) {
+	return r.ng.GetBytesByKey(key)
+}
+
+// GetBool 获取布尔值
+func (r *ReadOnlyCache) GetBool(key string) (bool, error) {
+	return r.ng.GetBool(key)
+}
+
+// GetInt 获取int类型值
+func (r *ReadOnlyCache) GetInt(key string) (int, error) {
+	return r.ng.GetInt(key)
+}
+
+// GetInt32 获取int32类型值
+func (r *ReadOnlyCache) GetInt32(key string) (int32, error) {
+	return r.ng.GetInt32(key)
+}
+
+// GetInt64 获取int64类型值
+func (r *ReadOnlyCache) GetInt64(key string) (int64, error) {
+	return r.ng.GetInt64(key)
+}
+
+// GetInt64Atomic 读取计数器
+func (r *ReadOnlyCache) GetInt64Atomic(key string) (int64, error) {
+	return r.ng.GetInt64Atomic(key)
+}
+
+// GetNumberAsInt64 以int64读取4字节或8字节的整数
+func (r *ReadOnlyCache) GetNumberAsInt64(key string) (int64, error) {
+	return r.ng.GetNumberAsInt64(key)
+}
+
+// GetFloat32 获取float32类型值
+func (r *ReadOnlyCache) GetFloat32(key string) (float32, error) {
+	return r.ng.GetFloat32(key)
+}
+
+// GetFloat64 获取float64类型值
+func (r *ReadOnlyCache) GetFloat64(key string) (float64, error) {
+	return r.ng.GetFloat64(key)
+}
+
+// GetComplex64 获取complex64类型值
+func (r *ReadOnlyCache) GetComplex64(key string) (complex64, error) {
+	return r.ng.GetComplex64(key)
+}
+
+// GetComplex128 获取complex128类型值
+func (r *ReadOnlyCache) GetComplex128(key string) (complex128, error) {
+	return r.ng.GetComplex128(key)
+}
+
+// GetRune 获取rune类型值
+func (r *ReadOnlyCache) GetRune(key string) (rune, error) {
+	return r.ng.GetRune(key)
+}
+
+// GetBigFloat 获取*big.Float类型值
+func (r *ReadOnlyCache) GetBigFloat(key string) (*big.Float, error) {
+	return r.ng.GetBigFloat(key)
+}
+
+// GetUUID 获取UUID
+func (r *ReadOnlyCache) GetUUID(key string) ([16]byte, error) {
+	return r.ng.GetUUID(key)
+}
+
+// GetIP 获取IP地址
+func (r *ReadOnlyCache) GetIP(key string) (net.IP, error) {
+	return r.ng.GetIP(key)
+}
+
+// GetJSON 获取JSON值
+func (r *ReadOnlyCache) GetJSON(key string, value interface{}) error {
+	return r.ng.GetJSON(key, value)
+}
+
+// GetJSONMulti 批量获取JSON值
+func (r *ReadOnlyCache) GetJSONMulti(keys []string, newValue func() interface{}) (map[string]interface{}, []string, error) {
+	return r.ng.GetJSONMulti(keys, newValue)
+}
+
+// GetStruct 获取结构体值
+func (r *ReadOnlyCache) GetStruct(key string, value interface{}) error {
+	return r.ng.GetStruct(key, value)
+}
+
+// GetAny 使用默认序列化方式获取值
+func (r *ReadOnlyCache) GetAny(key string, value interface{}) error {
+	return r.ng.GetAny(key, value)
+}
+
+// GetAnyMulti 使用默认序列化方式批量获取值
+func (r *ReadOnlyCache) GetAnyMulti(keys []string, newValue func() interface{}) (map[string]interface{}, []string, error) {
+	return r.ng.GetAnyMulti(keys, newValue)
+}
+
+// GetAuto 获取SetAuto写入的值
+func (r *ReadOnlyCache) GetAuto(key string, value interface{}) error {
+	return r.ng.GetAuto(key, value)
+}
+
+// GetWithVersion 获取值及其版本
+func (r *ReadOnlyCache) GetWithVersion(key string) ([]byte, uint64, error) {
+	return r.ng.GetWithVersion(key)
+}
+
+// GetCreationTime 获取键的创建时间
+func (r *ReadOnlyCache) GetCreationTime(key string) (time.Time, error) {
+	return r.ng.GetCreationTime(key)
+}
+
+// GetMeta 获取键的元数据
+func (r *ReadOnlyCache) GetMeta(key string) (EntryMeta, error) {
+	return r.ng.GetMeta(key)
+}
+
+// ViewBytes 以回调的形式访问键的值，规则见NGCache.ViewBytes
+func (r *ReadOnlyCache) ViewBytes(key string, fn func(value []byte) error) error {
+	return r.ng.ViewBytes(key, fn)
+}
+
+// ScanPrefix 遍历以prefix开头的键
+func (r *ReadOnlyCache) ScanPrefix(prefix string, fn func(key string, value []byte) bool) error {
+	return r.ng.ScanPrefix(prefix, fn)
+}
+
+// KeysMatching 返回匹配正则表达式的键
+func (r *ReadOnlyCache) KeysMatching(pattern string) ([]string, error) {
+	return r.ng.KeysMatching(pattern)
+}
+
+// SizeOf 返回键占用的字节数
+func (r *ReadOnlyCache) SizeOf(key string) (int, error) {
+	return r.ng.SizeOf(key)
+}
+
+// Stats 返回统计信息
+func (r *ReadOnlyCache) Stats() CacheStats {
+	return r.ng.Stats()
+}
+
+// SetString 返回ErrReadOnly
+func (r *ReadOnlyCache) SetString(key string, value string, expireSeconds int) error {
+	return ErrReadOnly
+}
+
+// SetBytes 返回ErrReadOnly
+func (r *ReadOnlyCache) SetBytes(key string, value []byte, expireSeconds int) error {
+	return ErrReadOnly
+}
+
+// SetBytesWithTTL 返回ErrReadOnly
+func (r *ReadOnlyCache) SetBytesWithTTL(key []byte, value []byte, expireSeconds int) error {
+	return ErrReadOnly
+}
+
+// SetPermanent 返回ErrReadOnly
+//
+// Deprecated: 使用SetBytesWithTTL。
+func (r *ReadOnlyCache) SetPermanent(key []byte, value []byte) error {
+	return ErrReadOnly
+}
+
+// SetPermanentBatch 返回ErrReadOnly
+func (r *ReadOnlyCache) SetPermanentBatch(entries map[string][]byte) error {
+	return ErrReadOnly
+}
+
+// SetBool 返回ErrReadOnly
+func (r *ReadOnlyCache) SetBool(key string, value bool, expireSeconds int) error {
+	return ErrReadOnly
+}
+
+// SetInt 返回ErrReadOnly
+func (r *ReadOnlyCache) SetInt(key string, value int, expireSeconds int) error {
+	return ErrReadOnly
+}
+
+// SetInt32 返回ErrReadOnly
+func (r *ReadOnlyCache) SetInt32(key string, value int32, expireSeconds int) error {
+	return ErrReadOnly
+}
+
+// SetInt64 返回ErrReadOnly
+func (r *ReadOnlyCache) SetInt64(key string, value int64, expireSeconds int) error {
+	return ErrReadOnly
+}
+
+// SetInt64AtomicAdd 返回ErrReadOnly
+func (r *ReadOnlyCache) SetInt64AtomicAdd(key string, delta int64) (int64, error) {
+	return 0, ErrReadOnly
+}
+
+// SetFloat32 返回ErrReadOnly
+func (r *ReadOnlyCache) SetFloat32(key string, value float32, expireSeconds int) error {
+	return ErrReadOnly
+}
+
+// SetFloat64 返回ErrReadOnly
+func (r *ReadOnlyCache) SetFloat64(key string, value float64, expireSeconds int) error {
+	return ErrReadOnly
+}
+
+// SetComplex64 返回ErrReadOnly
+func (r *ReadOnlyCache) SetComplex64(key string, value complex64, expireSeconds int) error {
+	return ErrReadOnly
+}
+
+// SetComplex128 返回ErrReadOnly
+func (r *ReadOnlyCache) SetComplex128(key string, value complex128, expireSeconds int) error {
+	return ErrReadOnly
+}
+
+// SetRune 返回ErrReadOnly
+func (r *ReadOnlyCache) SetRune(key string, value rune, expireSeconds int) error {
+	return ErrReadOnly
+}
+
+// SetBigFloat 返回ErrReadOnly
+func (r *ReadOnlyCache) SetBigFloat(key string, value *big.Float, expireSeconds int) error {
+	return ErrReadOnly
+}
+
+// SetUUID 返回ErrReadOnly
+func (r *ReadOnlyCache) SetUUID(key string, id [16]byte, expireSeconds int) error {
+	return ErrReadOnly
+}
+
+// ParseAndSetUUID 返回ErrReadOnly
+func (r *ReadOnlyCache) ParseAndSetUUID(key, uuidStr string, expireSeconds int) error {
+	return ErrReadOnly
+}
+
+// SetIP 返回ErrReadOnly
+func (r *ReadOnlyCache) SetIP(key string, ip net.IP, expireSeconds int) error {
+	return ErrReadOnly
+}
+
+// SetJSON 返回ErrReadOnly
+func (r *ReadOnlyCache) SetJSON(key string, value interface{}, expireSeconds int) error {
+	return ErrReadOnly
+}
+
+// SetStruct 返回ErrReadOnly
+func (r *ReadOnlyCache) SetStruct(key string, value interface{}, expireSeconds int) error {
+	return ErrReadOnly
+}
+
+// SetAny 返回ErrReadOnly
+func (r *ReadOnlyCache) SetAny(key string, value interface{}, expireSeconds int) error {
+	return ErrReadOnly
+}
+
+// SetAuto 返回ErrReadOnly
+func (r *ReadOnlyCache) SetAuto(key string, value interface{}, expireSeconds int) error {
+	return ErrReadOnly
+}
+
+// SetBundle 返回ErrReadOnly
+func (r *ReadOnlyCache) SetBundle(entries []BundleEntry, expireSeconds int) error {
+	return ErrReadOnly
+}
+
+// SetWithVersion 返回ErrReadOnly
+func (r *ReadOnlyCache) SetWithVersion(key string, value []byte, expectedVersion uint64, expireSeconds int) (uint64, error) {
+	return 0, ErrReadOnly
+}
+
+// SetWithCreationTime 返回ErrReadOnly
+func (r *ReadOnlyCache) SetWithCreationTime(key string, value []byte, expireSeconds int) error {
+	return ErrReadOnly
+}
+
+// SetWithQuota 返回ErrReadOnly
+func (r *ReadOnlyCache) SetWithQuota(key string, value []byte, expire int, quota *Quota) error {
+	return ErrReadOnly
+}
+
+// Delete 返回ErrReadOnly
+func (r *ReadOnlyCache) Delete(key string) error {
+	return ErrReadOnly
+}
+
+// DeletePermanent 返回ErrReadOnly
+func (r *ReadOnlyCache) DeletePermanent(key []byte) error {
+	return ErrReadOnly
+}
+
+// Flush 返回ErrReadOnly
+func (r *ReadOnlyCache) Flush() error {
+	return ErrReadOnly
+}
+
+// Drain 返回ErrReadOnly
+func (r *ReadOnlyCache) Drain() error {
+	return ErrReadOnly
+}
+
+// Close 返回ErrReadOnly，底层缓存由创建者关闭
+func (r *ReadOnlyCache) Close() error {
+	return ErrReadOnly
+}
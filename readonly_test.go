@@ -0,0 +1,85 @@
+package ngcat
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestReadOnlyCache(t *testing.T) {
+	nc := NewNGCache(1024*1024, nil)
+	defer nc.Close()
+	nc.SetString("a", "1", 0)
+	ro := nc.ReadOnly()
+
+	if err := ro.SetString("b", "2", 0); !errors.Is(err, ErrReadOnly) {
+		t.Fatalf("SetString: %v", err)
+	}
+	if _, err := nc.GetString("b"); !errors.Is(err, ErrKeyNotFound) {
+		t.Fatalf("underlying cache modified: %v", err)
+	}
+	if _, ok := persisted(nc, "b"); ok {
+		t.Fatal("underlying persist data modified")
+	}
+	if v, err := ro.GetString("a"); err != nil || v != "1" {
+		t.Fatalf("GetString: %q, %v", v, err)
+	}
+
+	for name, err := range map[string]error{
+		"Delete": ro.Delete("a"),
+		"Flush":  ro.Flush(),
+		"Drain":  ro.Drain(),
+		"Close":  ro.Close(),
+	} {
+		if !errors.Is(err, ErrReadOnly) {
+			t.Fatalf("%s: %v", name, err)
+		}
+	}
+	if _, err := ro.SetInt64AtomicAdd("n", 1); !errors.Is(err, ErrReadOnly) {
+		t.Fatalf("SetInt64AtomicAdd: %v", err)
+	}
+	if v, err := nc.GetString("a"); err != nil || v != "1" {
+		t.Fatalf("underlying cache changed: %q, %v", v, err)
+	}
+}
+
+func TestReadOnlyPersistence(t *testing.T) {
+	dir := t.TempDir()
+	config := &PersistConfig{
+		Enabled:  true,
+		FilePath: dir,
+		FileName: "cache.bin",
+		Format:   FormatBinary,
+		Interval: time.Hour,
+	}
+	path := filepath.Join(dir, "cache.bin")
+	primary := NewNGCache(1024*1024, config)
+	primary.SetString("a", "1", 0)
+	if err := primary.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	replica := NewNGCache(1024*1024, config, WithReadOnlyPersistence())
+	if v, err := replica.ReadOnly().GetString("a"); err != nil || v != "1" {
+		t.Fatalf("replica did not load: %q, %v", v, err)
+	}
+	if err := replica.Save(); !errors.Is(err, ErrReadOnly) {
+		t.Fatalf("Save: %v", err)
+	}
+	if err := replica.ReloadConfig(config); !errors.Is(err, ErrReadOnly) {
+		t.Fatalf("ReloadConfig: %v", err)
+	}
+
+	// Close不应写回文件
+	if err := os.Remove(path); err != nil {
+		t.Fatal(err)
+	}
+	if err := replica.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if fileExists(path) {
+		t.Fatal("read-only cache saved on Close")
+	}
+}
@@ -0,0 +1,205 @@
+// Package redistool 在Redis与NGCache之间导入导出字符串键
+package redistool
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+
+	"ngcat"
+)
+
+// batchSize 每批SCAN返回的键数量提示，以及每个管道中的命令数量
+const batchSize = 1000
+
+// ImportFromRedis 将Redis中匹配pattern（Redis的glob模式）的字符串键导入ng，返回导入的键数量
+//
+// 通过SCAN分批遍历，每批用一个管道执行GET和PTTL，内存占用与键总数无关。非字符串键和
+// 遍历期间过期或被删除的键会被跳过。preserveTTL为true时保留剩余过期时间（不足一秒按一秒计算），
+// 没有过期时间的键导入为永久缓存；为false时以ngcat.TTLDefault写入。ctx取消时返回ctx.Err()，
+// 已导入的键保留。
+func ImportFromRedis(ctx context.Context, rdb redis.UniversalClient, ng *ngcat.NGCache, pattern string, preserveTTL bool) (int, error) {
+	imported := 0
+	var cursor uint64
+	for {
+		if err := ctx.Err(); err != nil {
+			return imported, err
+		}
+		keys, next, err := rdb.Scan(ctx, cursor, pattern, batchSize).Result()
+		if err != nil {
+			return imported, fmt.Errorf("redistool: SCAN: %w", err)
+		}
+
+		n, err := importBatch(ctx, rdb, ng, keys, preserveTTL)
+		imported += n
+		if err != nil {
+			return imported, err
+		}
+
+		cursor = next
+		if cursor == 0 {
+			return imported, nil
+		}
+	}
+}
+
+// importBatch 导入一批键
+func importBatch(ctx context.Context, rdb redis.UniversalClient, ng *ngcat.NGCache, keys []string, preserveTTL bool) (int, error) {
+	if len(keys) == 0 {
+		return 0, nil
+	}
+
+	pipe := rdb.Pipeline()
+	gets := make([]*redis.StringCmd, len(keys))
+	ttls := make([]*redis.DurationCmd, len(keys))
+	for i, key := range keys {
+		gets[i] = pipe.Get(ctx, key)
+		ttls[i] = pipe.PTTL(ctx, key)
+	}
+	// 单个命令的错误（如非字符串键的WRONGTYPE）在下面逐个处理
+	_, err := pipe.Exec(ctx)
+	if err != nil && ctx.Err() != nil {
+		return 0, ctx.Err()
+	}
+
+	imported := 0
+	for i, key := range keys {
+		value, err := gets[i].Bytes()
+		if err != nil {
+			if errors.Is(err, redis.Nil) || isWrongType(err) {
+				continue
+			}
+			return imported, fmt.Errorf("redistool: GET %s: %w", key, err)
+		}
+
+		expireSeconds := ngcat.TTLDefault
+		if preserveTTL {
+			ttl, err := ttls[i].Result()
+			if err != nil {
+				return imported, fmt.Errorf("redistool: PTTL %s: %w", key, err)
+			}
+			switch {
+			case ttl == -2:
+				continue // 已过期或被删除
+			case ttl < 0:
+				expireSeconds = 0
+			default:
+				expireSeconds = int((ttl + time.Second - 1) / time.Second)
+			}
+		}
+
+		err = ng.SetBytes(key, value, expireSeconds)
+		if err != nil {
+			return imported, fmt.Errorf("redistool: store %s: %w", key, err)
+		}
+		imported++
+	}
+	return imported, nil
+}
+
+// isWrongType 判断是否为对非字符串键执行GET的错误
+func isWrongType(err error) bool {
+	return strings.HasPrefix(err.Error(), "WRONGTYPE")
+}
+
+// ExportToRedis 将ng中匹配pattern（Redis的glob模式，为空时匹配所有键）的条目写入Redis，返回写入的键数量
+//
+// 条目以剩余过期时间写入，永久缓存写为没有过期时间的键。遍历时每batchSize个条目执行一次管道，
+// 内存占用与键总数无关。ctx取消时停止遍历并返回ctx.Err()，已写入Redis的键保留。
+func ExportToRedis(ctx context.Context, ng *ngcat.NGCache, rdb redis.UniversalClient, pattern string) (int, error) {
+	var re *regexp.Regexp
+	if pattern != "" && pattern != "*" {
+		var err error
+		re, err = regexp.Compile(globToRegexp(pattern))
+		if err != nil {
+			return 0, fmt.Errorf("redistool: invalid pattern: %w", err)
+		}
+	}
+
+	var (
+		exported int
+		pending  int
+		execErr  error
+	)
+	pipe := rdb.Pipeline()
+	flush := func() {
+		if pending == 0 {
+			return
+		}
+		_, execErr = pipe.Exec(ctx)
+		if execErr == nil {
+			exported += pending
+		}
+		pending = 0
+	}
+
+	ng.ForEachWithExpire(func(key string, value []byte, remaining time.Duration) bool {
+		if re != nil && !re.MatchString(key) {
+			return true
+		}
+		if ctx.Err() != nil {
+			return false
+		}
+		pipe.Set(ctx, key, value, remaining)
+		pending++
+		if pending >= batchSize {
+			flush()
+		}
+		return execErr == nil
+	})
+	if err := ctx.Err(); err != nil {
+		return exported, err
+	}
+	if execErr == nil {
+		flush()
+	}
+	if execErr != nil {
+		return exported, fmt.Errorf("redistool: write to Redis: %w", execErr)
+	}
+	return exported, nil
+}
+
+// globToRegexp 将Redis的glob模式（*、?、[...]和\转义）转换为正则表达式
+func globToRegexp(pattern string) string {
+	var b strings.Builder
+	b.WriteString("^")
+	for i := 0; i < len(pattern); i++ {
+		c := pattern[i]
+		switch c {
+		case '*':
+			b.WriteString("(?s:.*)")
+		case '?':
+			b.WriteString("(?s:.)")
+		case '[':
+			end := strings.IndexByte(pattern[i+1:], ']')
+			if end < 0 {
+				b.WriteString(`\[`)
+				continue
+			}
+			class := pattern[i+1 : i+1+end]
+			if strings.HasPrefix(class, "^") {
+				class = "^" + regexp.QuoteMeta(class[1:])
+			} else {
+				class = regexp.QuoteMeta(class)
+			}
+			b.WriteString("[" + class + "]")
+			i += end + 1
+		case '\\':
+			if i+1 < len(pattern) {
+				i++
+				b.WriteString(regexp.QuoteMeta(string(pattern[i])))
+			} else {
+				b.WriteString(`\\`)
+			}
+		default:
+			b.WriteString(regexp.QuoteMeta(string(c)))
+		}
+	}
+	b.WriteString("$")
+	return b.String()
+}
@@ -0,0 +1,146 @@
+package redistool
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+
+	"ngcat"
+)
+
+func newRedis(t *testing.T) (*miniredis.Miniredis, *redis.Client) {
+	t.Helper()
+	mr := miniredis.RunT(t)
+	rdb := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	t.Cleanup(func() { rdb.Close() })
+	return mr, rdb
+}
+
+func TestImportFromRedis(t *testing.T) {
+	mr, rdb := newRedis(t)
+	ctx := context.Background()
+	for i := 0; i < 2500; i++ {
+		mr.Set(fmt.Sprintf("user:%d", i), fmt.Sprintf("u%d", i))
+	}
+	mr.Set("session:1", "s")
+	mr.SetTTL("session:1", 90*time.Second)
+	mr.Lpush("user:list", "not a string")
+	mr.Set("other", "x")
+
+	ng := ngcat.NewNGCache(4*1024*1024, nil)
+	defer ng.Close()
+
+	n, err := ImportFromRedis(ctx, rdb, ng, "user:*", true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != 2500 {
+		t.Fatalf("imported %d keys, want 2500", n)
+	}
+	if v, _ := ng.GetString("user:42"); v != "u42" {
+		t.Fatalf("user:42 = %q", v)
+	}
+	if meta, err := ng.GetMeta("user:42"); err != nil || !meta.ExpireAt.IsZero() {
+		t.Fatalf("key without TTL should be permanent: %+v, %v", meta, err)
+	}
+	if _, err := ng.GetString("other"); err != ngcat.ErrKeyNotFound {
+		t.Fatal("non-matching key imported")
+	}
+
+	if _, err := ImportFromRedis(ctx, rdb, ng, "session:*", true); err != nil {
+		t.Fatal(err)
+	}
+	meta, err := ng.GetMeta("session:1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ttl := time.Until(meta.ExpireAt); ttl <= 80*time.Second || ttl > 91*time.Second {
+		t.Fatalf("TTL not preserved: %v", ttl)
+	}
+}
+
+func TestExportToRedis(t *testing.T) {
+	mr, rdb := newRedis(t)
+	ctx := context.Background()
+
+	ng := ngcat.NewNGCache(4*1024*1024, nil)
+	defer ng.Close()
+	for i := 0; i < 2500; i++ {
+		ng.SetString(fmt.Sprintf("user:%d", i), "u", 0)
+	}
+	ng.SetString("session:1", "s", 60)
+	ng.SetString("product:1", "p", 0)
+
+	n, err := ExportToRedis(ctx, ng, rdb, "[su]*")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != 2501 {
+		t.Fatalf("exported %d keys, want 2501", n)
+	}
+	if mr.Exists("product:1") {
+		t.Fatal("non-matching key exported")
+	}
+	if v, _ := mr.Get("user:7"); v != "u" {
+		t.Fatalf("user:7 = %q", v)
+	}
+	if ttl := mr.TTL("user:7"); ttl != 0 {
+		t.Fatalf("permanent key should not expire in Redis: %v", ttl)
+	}
+	if ttl := mr.TTL("session:1"); ttl <= 0 || ttl > 60*time.Second {
+		t.Fatalf("session:1 TTL = %v", ttl)
+	}
+}
+
+func TestCancellation(t *testing.T) {
+	mr, rdb := newRedis(t)
+	mr.Set("k", "v")
+	ng := ngcat.NewNGCache(1024*1024, nil)
+	defer ng.Close()
+	ng.SetString("k", "v", 0)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := ImportFromRedis(ctx, rdb, ng, "*", true); err != context.Canceled {
+		t.Fatalf("import: expected context.Canceled, got %v", err)
+	}
+	if n, err := ExportToRedis(ctx, ng, rdb, ""); err != context.Canceled || n != 0 {
+		t.Fatalf("export: expected context.Canceled, got %d, %v", n, err)
+	}
+}
+
+func TestGlobToRegexp(t *testing.T) {
+	for _, tc := range []struct {
+		pattern, key string
+		match        bool
+	}{
+		{"user:*", "user:1", true},
+		{"user:*", "users", false},
+		{"h?llo", "hello", true},
+		{"h[ae]llo", "hallo", true},
+		{"h[^e]llo", "hello", false},
+		{"h[a-c]llo", "hbllo", true},
+		{`a\*b`, "a*b", true},
+		{`a\*b`, "axb", false},
+		{"a.b", "axb", false},
+	} {
+		re := mustGlob(t, tc.pattern)
+		if re.MatchString(tc.key) != tc.match {
+			t.Errorf("%q match %q = %v, want %v", tc.pattern, tc.key, !tc.match, tc.match)
+		}
+	}
+}
+
+func mustGlob(t *testing.T, pattern string) *regexp.Regexp {
+	t.Helper()
+	re, err := regexp.Compile(globToRegexp(pattern))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return re
+}
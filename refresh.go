@@ -0,0 +1,58 @@
+package ngcat
+
+import (
+	"encoding/binary"
+	"time"
+)
+
+// freshnessHeaderSize 新鲜度时间戳头部长度（写入时间的Unix纳秒）
+const freshnessHeaderSize = 8
+
+// GetOrRefresh 获取值，支持stale-while-revalidate
+//
+// 写入不超过ttl的值直接返回；超过ttl但仍在staleFor窗口内的值会立即返回，
+// 同时在后台触发一次刷新（同一键的并发刷新会被合并），此时bool返回true；
+// 不存在或超出窗口的值会阻塞等待loader，并发的阻塞加载同样会被合并。
+// 值与写入时间一同存储，因此由GetOrRefresh管理的键只应通过GetOrRefresh读取。
+func (ng *NGCache) GetOrRefresh(key string, ttl, staleFor time.Duration, loader func() ([]byte, error)) ([]byte, bool, error) {
+	data, err := ng.getWithPersist(key)
+	if err == nil && len(data) >= freshnessHeaderSize {
+		written := time.Unix(0, int64(binary.LittleEndian.Uint64(data)))
+		age := ng.clock.Now().Sub(written)
+		value := data[freshnessHeaderSize:]
+		if age < ttl {
+			return value, false, nil
+		}
+		if age < ttl+staleFor {
+			ng.refreshGroup.doAsync(key, func() ([]byte, error) {
+				return ng.refresh(key, ttl, staleFor, loader)
+			})
+			return value, true, nil
+		}
+	}
+
+	value, err := ng.refreshGroup.do(key, func() ([]byte, error) {
+		return ng.refresh(key, ttl, staleFor, loader)
+	})
+	return value, false, err
+}
+
+// refresh 调用loader并写入带新鲜度时间戳的值
+func (ng *NGCache) refresh(key string, ttl, staleFor time.Duration, loader func() ([]byte, error)) ([]byte, error) {
+	value, err := loader()
+	if err != nil {
+		return nil, err
+	}
+
+	data := make([]byte, freshnessHeaderSize+len(value))
+	binary.LittleEndian.PutUint64(data, uint64(ng.clock.Now().UnixNano()))
+	copy(data[freshnessHeaderSize:], value)
+
+	// freecache按秒过期，多保留一秒以免在过期窗口结束前被提前淘汰
+	expireSeconds := int((ttl+staleFor+time.Second-1)/time.Second) + 1
+	err = ng.setWithPersist(key, data, expireSeconds)
+	if err != nil {
+		return nil, err
+	}
+	return value, nil
+}
@@ -0,0 +1,68 @@
+package ngcat
+
+import (
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestGetOrRefreshStaleWhileRevalidate(t *testing.T) {
+	clock := newFakeClock()
+	nc := NewNGCache(1024*1024, nil, WithClock(clock))
+	defer nc.Close()
+
+	var loads int32
+	release := make(chan struct{})
+	loader := func() ([]byte, error) {
+		n := atomic.AddInt32(&loads, 1)
+		if n > 1 {
+			<-release
+		}
+		return []byte{byte(n)}, nil
+	}
+
+	value, stale, err := nc.GetOrRefresh("k", time.Minute, time.Minute, loader)
+	if err != nil || stale || value[0] != 1 {
+		t.Fatalf("initial load: %v %v %v", value, stale, err)
+	}
+	value, stale, _ = nc.GetOrRefresh("k", time.Minute, time.Minute, loader)
+	if stale || value[0] != 1 || loads != 1 {
+		t.Fatalf("fresh read should not reload: %v %v loads=%d", value, stale, loads)
+	}
+
+	// 进入stale窗口，并发读取都立即得到旧值，只触发一次后台刷新
+	clock.Add(90 * time.Second)
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			value, stale, err := nc.GetOrRefresh("k", time.Minute, time.Minute, loader)
+			if err != nil || !stale || value[0] != 1 {
+				t.Errorf("stale read: %v %v %v", value, stale, err)
+			}
+		}()
+	}
+	wg.Wait()
+	close(release)
+
+	deadline := time.Now().Add(time.Second)
+	for {
+		value, stale, _ = nc.GetOrRefresh("k", time.Minute, time.Minute, loader)
+		if !stale || time.Now().After(deadline) {
+			break
+		}
+		time.Sleep(time.Millisecond)
+	}
+	if loads != 2 || value[0] != 2 || stale {
+		t.Fatalf("expected exactly one background refresh, loads=%d value=%v stale=%v", loads, value, stale)
+	}
+
+	// 超出stale窗口后阻塞加载
+	clock.Add(3 * time.Minute)
+	value, stale, _ = nc.GetOrRefresh("k", time.Minute, time.Minute, loader)
+	if stale || value[0] != 3 {
+		t.Fatalf("expired entry should block on loader: %v %v", value, stale)
+	}
+}
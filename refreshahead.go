@@ -0,0 +1,120 @@
+package ngcat
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"sync"
+	"time"
+)
+
+// refreshAheadConcurrency 每轮预刷新中并发调用Backend.Load的协程数量
+const refreshAheadConcurrency = 4
+
+// RefreshAhead 登记后台预刷新任务，每隔every对键匹配pattern（正则表达式）的永久缓存
+// 调用Backend.Load并覆盖缓存值，使这些键的值不会比every更旧
+//
+// 每轮开始时确定要刷新的键，本轮中被删除或改写为非永久缓存的键不会被重新写入。
+// 同一轮中所有失败的键合并为一个错误交给WithOnError设置的回调。任务在Close时停止。
+// 未设置WithBackend时返回ErrNoBackend，pattern无效时返回错误。
+func (ng *NGCache) RefreshAhead(pattern string, every time.Duration) error {
+	if ng.backend == nil {
+		return ErrNoBackend
+	}
+	re, err := ng.compilePattern(pattern)
+	if err != nil {
+		return err
+	}
+
+	ticker := ng.clock.NewTicker(every)
+	ng.tasks.Add(1)
+	go func() {
+		defer ng.tasks.Done()
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C():
+				ng.refreshAheadCycle(ng.ctx, re)
+			case <-ng.ctx.Done():
+				return
+			}
+		}
+	}()
+	return nil
+}
+
+// refreshAheadCycle 执行一轮预刷新
+func (ng *NGCache) refreshAheadCycle(ctx context.Context, re *regexp.Regexp) {
+	var keys []string
+	ng.scanKeys("", func(key string, expireAt uint32) bool {
+		if expireAt == 0 && re.MatchString(key) {
+			keys = append(keys, key)
+		}
+		return true
+	})
+
+	var (
+		errs []error
+		mu   sync.Mutex
+		wg   sync.WaitGroup
+	)
+	jobs := make(chan string)
+	for i := 0; i < refreshAheadConcurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for key := range jobs {
+				if err := ng.refreshAheadKey(ctx, key); err != nil {
+					mu.Lock()
+					errs = append(errs, err)
+					mu.Unlock()
+				}
+			}
+		}()
+	}
+
+dispatch:
+	for _, key := range keys {
+		select {
+		case jobs <- key:
+		case <-ctx.Done():
+			break dispatch
+		}
+	}
+	close(jobs)
+	wg.Wait()
+
+	if len(errs) > 0 {
+		ng.reportError(newError(CodeRefreshFailed, fmt.Sprintf("%d/%d keys", len(errs), len(keys)), errors.Join(errs...)))
+	}
+}
+
+// refreshAheadKey 从Backend加载单个键并在其仍为永久缓存时覆盖
+func (ng *NGCache) refreshAheadKey(ctx context.Context, key string) error {
+	value, err := ng.backend.Load(ctx, key)
+	if err != nil {
+		return newError(CodeLoadFailed, key, err)
+	}
+
+	started, err := ng.beginWrite()
+	if err != nil {
+		// Drain之后不再刷新
+		return nil
+	}
+	if started {
+		defer ng.inflight.Done()
+	}
+
+	mu := ng.keyLock(key)
+	mu.Lock()
+	defer mu.Unlock()
+	if ttl, exists := ng.remainingTTL(key); !exists || ttl != 0 {
+		return nil
+	}
+	err = ng.setLocked(key, value, 0)
+	if err != nil {
+		return newError(CodeStoreFailed, key, err)
+	}
+	return nil
+}
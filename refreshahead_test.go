@@ -0,0 +1,115 @@
+package ngcat
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeBackend 返回"<键>@<版本>"的数据源，版本可在测试中推进
+type fakeBackend struct {
+	mu      sync.Mutex
+	version int
+	fail    map[string]bool
+}
+
+func (b *fakeBackend) Load(ctx context.Context, key string) ([]byte, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.fail[key] {
+		return nil, errors.New("backend unavailable")
+	}
+	return []byte(fmt.Sprintf("%s@%d", key, b.version)), nil
+}
+
+func (b *fakeBackend) bump() {
+	b.mu.Lock()
+	b.version++
+	b.mu.Unlock()
+}
+
+func TestRefreshAheadConverges(t *testing.T) {
+	clock := newFakeClock()
+	backend := &fakeBackend{}
+	nc := NewNGCache(1024*1024, nil, WithClock(clock), WithBackend(backend))
+	defer nc.Close()
+
+	nc.SetString("user:1", "stale", 0)
+	nc.SetString("user:2", "stale", 0)
+	nc.SetString("user:ttl", "stale", 3600)
+	nc.SetString("other", "stale", 0)
+	if err := nc.RefreshAhead("^user:", time.Minute); err != nil {
+		t.Fatal(err)
+	}
+
+	for version := 1; version <= 3; version++ {
+		backend.bump()
+		clock.Add(time.Minute)
+		want := fmt.Sprintf("user:1@%d", version)
+		waitFor(t, func() bool {
+			v1, _ := nc.GetString("user:1")
+			v2, _ := nc.GetString("user:2")
+			return v1 == want && strings.HasSuffix(v2, fmt.Sprintf("@%d", version))
+		})
+	}
+
+	if v, _ := nc.GetString("user:ttl"); v != "stale" {
+		t.Fatalf("expiring key should not be refreshed: %q", v)
+	}
+	if v, _ := nc.GetString("other"); v != "stale" {
+		t.Fatalf("non-matching key should not be refreshed: %q", v)
+	}
+}
+
+func TestRefreshAheadReportsErrors(t *testing.T) {
+	clock := newFakeClock()
+	backend := &fakeBackend{fail: map[string]bool{"a": true, "b": true}}
+	errs := make(chan error, 1)
+	nc := NewNGCache(1024*1024, nil, WithClock(clock), WithBackend(backend),
+		WithOnError(func(err error) { errs <- err }))
+	defer nc.Close()
+
+	nc.SetString("a", "x", 0)
+	nc.SetString("b", "x", 0)
+	nc.SetString("c", "x", 0)
+	if err := nc.RefreshAhead(".", time.Minute); err != nil {
+		t.Fatal(err)
+	}
+	clock.Add(time.Minute)
+
+	select {
+	case err := <-errs:
+		if !strings.Contains(err.Error(), "2/3") || !strings.Contains(err.Error(), "backend unavailable") {
+			t.Fatalf("unexpected aggregated error: %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("error hook not called")
+	}
+	if v, _ := nc.GetString("c"); v != "c@0" {
+		t.Fatalf("c = %q", v)
+	}
+}
+
+func TestRefreshAheadSkipsDeletedKeys(t *testing.T) {
+	backend := &fakeBackend{}
+	nc := NewNGCache(1024*1024, nil, WithBackend(backend))
+	defer nc.Close()
+
+	nc.SetString("gone", "x", 0)
+	// 模拟本轮开始后、Load返回前键被删除
+	nc.Delete("gone")
+	if err := nc.refreshAheadKey(context.Background(), "gone"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := nc.GetString("gone"); err != ErrKeyNotFound {
+		t.Fatalf("deleted key was recreated: %v", err)
+	}
+
+	if err := NewNGCache(1024*1024, nil).RefreshAhead(".", time.Minute); err != ErrNoBackend {
+		t.Fatalf("expected ErrNoBackend, got %v", err)
+	}
+}
@@ -0,0 +1,70 @@
+package ngcat
+
+// ReloadConfig 在运行时更新持久化配置，并以新的间隔重新启动定时持久化
+//
+// 可以修改Interval、MaxPersistEntries、MaxFileSizeBytes、MaxRetries、RetryBackoff、SyncOnWrite和AutoCompactRatio；
+// Enabled、Format、FilePath和FileName决定了已加载的数据和WAL所在的文件，必须与当前配置一致，
+// 否则返回CodeInvalidConfig错误。新配置的字段被复制到缓存创建时保存的配置副本中，
+// 不会修改传给NewNGCache的配置，之后修改newConfig也不会生效。
+// 正在进行的保存完成后才会应用新配置；缓存已关闭时返回ErrClosed，使用WithReadOnlyPersistence时返回ErrReadOnly。
+func (ng *NGCache) ReloadConfig(newConfig *PersistConfig) error {
+	ng.persistRoutineMutex.Lock()
+	defer ng.persistRoutineMutex.Unlock()
+	if ng.ctx.Err() != nil {
+		return ErrClosed
+	}
+	if ng.persistReadOnly {
+		return ErrReadOnly
+	}
+	err := ng.checkReloadConfig(newConfig)
+	if err != nil {
+		return err
+	}
+
+	// 停止当前的持久化协程，等待其退出后才能修改配置和重新启动
+	close(ng.stopChan)
+	<-ng.persistRoutineDone
+
+	ng.persistMutex.Lock()
+	old := *ng.persistConfig
+	ng.persistConfig.Interval = newConfig.Interval
+	ng.persistConfig.MaxPersistEntries = newConfig.MaxPersistEntries
+	ng.persistConfig.MaxFileSizeBytes = newConfig.MaxFileSizeBytes
+	ng.persistConfig.MaxRetries = newConfig.MaxRetries
+	ng.persistConfig.RetryBackoff = newConfig.RetryBackoff
+	ng.persistConfig.SyncOnWrite = newConfig.SyncOnWrite
+	ng.persistConfig.AutoCompactRatio = newConfig.AutoCompactRatio
+	ng.persistMutex.Unlock()
+
+	ng.stopChan = make(chan struct{})
+	ng.startPersistRoutine()
+
+	ng.logger.Info("ngcat: 持久化配置已更新",
+		"old_interval", old.Interval, "interval", newConfig.Interval,
+		"max_persist_entries", newConfig.MaxPersistEntries,
+		"max_file_size_bytes", newConfig.MaxFileSizeBytes,
+		"max_retries", newConfig.MaxRetries,
+		"retry_backoff", newConfig.RetryBackoff,
+		"sync_on_write", newConfig.SyncOnWrite,
+		"auto_compact_ratio", newConfig.AutoCompactRatio)
+	return nil
+}
+
+// checkReloadConfig 检查新的持久化配置能否在运行时应用
+func (ng *NGCache) checkReloadConfig(newConfig *PersistConfig) error {
+	current := ng.persistConfig
+	switch {
+	case current == nil || !current.Enabled:
+		return newError(CodeInvalidConfig, "persistence is not enabled", nil)
+	case newConfig == nil:
+		return newError(CodeInvalidConfig, "nil config", nil)
+	case newConfig.Interval <= 0:
+		return newError(CodeInvalidConfig, "interval must be positive", nil)
+	case newConfig.Enabled != current.Enabled,
+		newConfig.Format != current.Format,
+		newConfig.FilePath != current.FilePath,
+		newConfig.FileName != current.FileName:
+		return newError(CodeInvalidConfig, "Enabled, Format, FilePath and FileName cannot change at runtime", nil)
+	}
+	return nil
+}
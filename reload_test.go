@@ -0,0 +1,93 @@
+package ngcat
+
+import (
+	"errors"
+	"io"
+	"log/slog"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestReloadConfigInterval(t *testing.T) {
+	var saves atomic.Int32
+	orig := wrapPersistFile
+	wrapPersistFile = func(w io.Writer) io.Writer {
+		saves.Add(1)
+		return w
+	}
+	t.Cleanup(func() { wrapPersistFile = orig })
+
+	clock := newFakeClock()
+	config := &PersistConfig{
+		Enabled:  true,
+		FilePath: t.TempDir(),
+		FileName: "cache.bin",
+		Format:   FormatBinary,
+		Interval: 5 * time.Minute,
+	}
+	nc := NewNGCache(1024*1024, config, WithClock(clock), WithLogger(slog.New(slog.NewTextHandler(io.Discard, nil))))
+	defer nc.Close()
+	nc.SetString("k", "v", 0)
+
+	newConfig := *config
+	newConfig.Interval = 30 * time.Second
+	newConfig.AutoCompactRatio = 4
+	if err := nc.ReloadConfig(&newConfig); err != nil {
+		t.Fatal(err)
+	}
+	if nc.persistConfig.Interval != 30*time.Second || nc.persistConfig.AutoCompactRatio != 4 {
+		t.Fatalf("config = %+v", nc.persistConfig)
+	}
+	if config.Interval != 5*time.Minute || config.AutoCompactRatio != 0 {
+		t.Fatalf("caller's config modified: %+v", config)
+	}
+
+	// 两次相邻的定时持久化间隔为新的30秒
+	clock.Add(29 * time.Second)
+	time.Sleep(20 * time.Millisecond)
+	if n := saves.Load(); n != 0 {
+		t.Fatalf("saved %d times before the new interval elapsed", n)
+	}
+	clock.Add(time.Second)
+	waitFor(t, func() bool { return saves.Load() == 1 })
+	clock.Add(30 * time.Second)
+	waitFor(t, func() bool { return saves.Load() == 2 })
+}
+
+func TestReloadConfigRejects(t *testing.T) {
+	config := &PersistConfig{
+		Enabled:  true,
+		FilePath: t.TempDir(),
+		FileName: "cache.bin",
+		Format:   FormatBinary,
+		Interval: time.Minute,
+	}
+	nc := NewNGCache(1024*1024, config)
+
+	invalid := &CacheError{Code: CodeInvalidConfig}
+	if err := nc.ReloadConfig(nil); !errors.Is(err, invalid) {
+		t.Fatalf("nil config: %v", err)
+	}
+	moved := *config
+	moved.FileName = "other.bin"
+	if err := nc.ReloadConfig(&moved); !errors.Is(err, invalid) {
+		t.Fatalf("FileName change: %v", err)
+	}
+	zero := *config
+	zero.Interval = 0
+	if err := nc.ReloadConfig(&zero); !errors.Is(err, invalid) {
+		t.Fatalf("zero interval: %v", err)
+	}
+
+	nc.Close()
+	if err := nc.ReloadConfig(config); !errors.Is(err, ErrClosed) {
+		t.Fatalf("after Close: %v", err)
+	}
+
+	plain := NewNGCache(1024*1024, nil)
+	defer plain.Close()
+	if err := plain.ReloadConfig(config); !errors.Is(err, invalid) {
+		t.Fatalf("persistence disabled: %v", err)
+	}
+}
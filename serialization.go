@@ -2,54 +2,94 @@ package ngcat
 
 import (
 	"bytes"
+	"encoding"
 	"encoding/gob"
-	"encoding/json"
+	"fmt"
 	"reflect"
 )
 
-// SetAny 设置任意类型值（使用gob序列化）
+// SetAny 设置任意类型值（使用WithDefaultCodec设置的序列化方式，默认为gob）
 func (ng *NGCache) SetAny(key string, value interface{}, expireSeconds int) error {
-	var buf bytes.Buffer
-	encoder := gob.NewEncoder(&buf)
-	err := encoder.Encode(value)
+	err := ng.precheckValueSize(value)
+	if err != nil {
+		return err
+	}
+
+	data, err := ng.codec.Marshal(value)
 	if err != nil {
 		return err
 	}
-	return ng.setWithPersist(key, buf.Bytes(), expireSeconds)
+	return ng.setTyped("any", key, data, expireSeconds)
 }
 
-// GetAny 获取任意类型值（使用gob反序列化）
+// GetAny 获取任意类型值（使用WithDefaultCodec设置的序列化方式，默认为gob）
 func (ng *NGCache) GetAny(key string, value interface{}) error {
-	data, err := ng.getWithPersist(key)
+	data, err := ng.getTyped("any", key)
 	if err != nil {
 		return err
 	}
-
-	buf := bytes.NewBuffer(data)
-	decoder := gob.NewDecoder(buf)
-	return decoder.Decode(value)
+	return ng.codec.Unmarshal(data, value)
 }
 
 // SetJSON 设置任意类型值（使用JSON序列化）
 func (ng *NGCache) SetJSON(key string, value interface{}, expireSeconds int) error {
-	data, err := json.Marshal(value)
+	err := ng.precheckValueSize(value)
 	if err != nil {
 		return err
 	}
-	return ng.setWithPersist(key, data, expireSeconds)
+
+	data, err := ng.jsonOptions.marshal(value)
+	if err != nil {
+		return err
+	}
+	return ng.setTyped("json", key, data, expireSeconds)
 }
 
 // GetJSON 获取任意类型值（使用JSON反序列化）
 func (ng *NGCache) GetJSON(key string, value interface{}) error {
-	data, err := ng.getWithPersist(key)
+	data, err := ng.getTyped("json", key)
 	if err != nil {
 		return err
 	}
-	return json.Unmarshal(data, value)
+	return ng.jsonOptions.unmarshal(data, value)
 }
 
+// encodeGob 使用gob序列化值
+func encodeGob(value interface{}) ([]byte, error) {
+	var buf bytes.Buffer
+	encoder := gob.NewEncoder(&buf)
+	err := encoder.Encode(value)
+	if err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// 实现了encoding.BinaryMarshaler或encoding.TextMarshaler的值序列化后的前缀：标记字节加格式字节
+//
+// 0xC1不会出现在gob、JSON和MessagePack编码的开头，因此可以与没有前缀的旧值区分。
+const (
+	marshalerMarker byte = 0xC1
+	// marshalerBinary 之后是MarshalBinary的结果
+	marshalerBinary byte = 'b'
+	// marshalerText 之后是MarshalText的结果
+	marshalerText byte = 't'
+)
+
 // SetStruct 设置结构体（自动选择最优序列化方式）
+//
+// 实现了encoding.BinaryMarshaler的值（如time.Time）使用MarshalBinary，其次是实现了
+// encoding.TextMarshaler的值（如*big.Int、net.IP），都不是时可以用gob序列化的类型使用SetAny
+// 的序列化方式，否则使用JSON。
 func (ng *NGCache) SetStruct(key string, value interface{}, expireSeconds int) error {
+	data, ok, err := marshalStd(value)
+	if err != nil {
+		return err
+	}
+	if ok {
+		return ng.setTyped("struct", key, data, expireSeconds)
+	}
+
 	// 检查类型是否可以用gob序列化
 	if ng.canUseGob(value) {
 		return ng.SetAny(key, value, expireSeconds)
@@ -59,22 +99,114 @@ func (ng *NGCache) SetStruct(key string, value interface{}, expireSeconds int) e
 }
 
 // GetStruct 获取结构体（自动选择反序列化方式）
+//
+// 由MarshalBinary或MarshalText写入的值要求value实现对应的Unmarshaler，否则返回ErrInvalidType。
 func (ng *NGCache) GetStruct(key string, value interface{}) error {
-	data, err := ng.getWithPersist(key)
+	data, err := ng.getTyped("struct", key)
 	if err != nil {
 		return err
 	}
+	if ok, err := unmarshalStd(data, value); ok {
+		return err
+	}
 
-	// 尝试gob反序列化
-	buf := bytes.NewBuffer(data)
-	decoder := gob.NewDecoder(buf)
-	err = decoder.Decode(value)
+	// 尝试SetAny使用的序列化方式
+	err = ng.codec.Unmarshal(data, value)
 	if err == nil {
 		return nil
 	}
 
-	// 如果gob失败，尝试JSON
-	return json.Unmarshal(data, value)
+	// 如果失败，尝试JSON
+	return ng.jsonOptions.unmarshal(data, value)
+}
+
+// SetAuto 与SetStruct相同，先尝试MarshalBinary和MarshalText，但之后总是使用WithDefaultCodec设置的序列化方式
+func (ng *NGCache) SetAuto(key string, value interface{}, expireSeconds int) error {
+	data, ok, err := marshalStd(value)
+	if err != nil {
+		return err
+	}
+	if ok {
+		return ng.setTyped("auto", key, data, expireSeconds)
+	}
+	return ng.SetAny(key, value, expireSeconds)
+}
+
+// GetAuto 获取SetAuto写入的值
+func (ng *NGCache) GetAuto(key string, value interface{}) error {
+	data, err := ng.getTyped("auto", key)
+	if err != nil {
+		return err
+	}
+	if ok, err := unmarshalStd(data, value); ok {
+		return err
+	}
+	return ng.codec.Unmarshal(data, value)
+}
+
+// marshalStd 使用值实现的MarshalBinary或MarshalText序列化并加上前缀，都未实现时ok为false
+func marshalStd(value interface{}) (data []byte, ok bool, err error) {
+	var kind byte
+	var raw []byte
+	switch v := value.(type) {
+	case encoding.BinaryMarshaler:
+		kind = marshalerBinary
+		raw, err = v.MarshalBinary()
+	case encoding.TextMarshaler:
+		kind = marshalerText
+		raw, err = v.MarshalText()
+	default:
+		return nil, false, nil
+	}
+	if err != nil {
+		return nil, true, newError(CodeEncode, fmt.Sprintf("%T", value), err)
+	}
+
+	data = make([]byte, 2+len(raw))
+	data[0], data[1] = marshalerMarker, kind
+	copy(data[2:], raw)
+	return data, true, nil
+}
+
+// unmarshalStd 反序列化marshalStd写入的值，数据没有对应的前缀时ok为false
+func unmarshalStd(data []byte, value interface{}) (ok bool, err error) {
+	if len(data) < 2 || data[0] != marshalerMarker {
+		return false, nil
+	}
+
+	switch data[1] {
+	case marshalerBinary:
+		u, ok := value.(encoding.BinaryUnmarshaler)
+		if !ok {
+			return true, ErrInvalidType
+		}
+		err = u.UnmarshalBinary(data[2:])
+	case marshalerText:
+		u, ok := value.(encoding.TextUnmarshaler)
+		if !ok {
+			return true, ErrInvalidType
+		}
+		err = u.UnmarshalText(data[2:])
+	default:
+		return false, nil
+	}
+	if err != nil {
+		return true, newError(CodeDecode, fmt.Sprintf("%T", value), err)
+	}
+	return true, nil
+}
+
+// precheckValueSize 序列化前检查大小可预知的值，避免为注定被拒绝的值付出序列化开销
+//
+// 字符串和字节数组序列化后不会小于其原始长度，其他类型在序列化后由setWithPersist检查。
+func (ng *NGCache) precheckValueSize(value interface{}) error {
+	switch v := value.(type) {
+	case string:
+		return ng.checkValueSize(len(v))
+	case []byte:
+		return ng.checkValueSize(len(v))
+	}
+	return nil
 }
 
 // canUseGob 检查类型是否可以使用gob序列化
@@ -0,0 +1,145 @@
+package ngcat
+
+import (
+	"context"
+	"hash/fnv"
+	"os"
+	"sort"
+	"strconv"
+)
+
+// shadowTarget 影子持久化的目标文件
+type shadowTarget struct {
+	path   string
+	format PersistFormat
+}
+
+// saveShadow 将与主持久化文件相同的快照写入影子文件，data为nil时重新收集
+//
+// 影子文件的错误包装为CodeShadowPersist后通过WithOnError报告，不影响主持久化的结果。
+// 调用方需持有persistMutex。
+func (ng *NGCache) saveShadow(ctx context.Context, data *PersistData) {
+	if ng.shadow == nil {
+		return
+	}
+	if data == nil {
+		data = ng.collectPersistData()
+		ng.applyPersistLimits(data, ng.shadow.format)
+	}
+
+	var err error
+	switch ng.shadow.format {
+	case FormatJSON, FormatBinary:
+		err = writePersistFile(ctx, ng.shadow.path, ng.shadow.format, data, ng.persistConfig.SyncOnWrite)
+	case FormatMMap:
+		err = writeMMapFile(ctx, ng.shadow.path, data)
+	default:
+		err = newError(CodeUnsupportedFormat, strconv.Itoa(int(ng.shadow.format)), nil)
+	}
+	if err != nil {
+		ng.reportError(newError(CodeShadowPersist, ng.shadow.path, err))
+	}
+}
+
+// diffReportKeys DiffReport中每类最多列出的键数量
+const diffReportKeys = 100
+
+// DiffReport 两个持久化文件的比较结果
+type DiffReport struct {
+	// EntriesA、EntriesB 两个文件中不同键的数量
+	EntriesA, EntriesB int
+	// Matched 两个文件中值相同的键数量
+	Matched int
+	// OnlyInA 只在a中的键数量
+	OnlyInA int
+	// OnlyInB 只在b中的键数量
+	OnlyInB int
+	// Mismatched 两个文件中都有但值不同的键数量
+	Mismatched int
+	// OnlyInAKeys、OnlyInBKeys、MismatchedKeys 按字典序排列的前diffReportKeys个键
+	OnlyInAKeys, OnlyInBKeys, MismatchedKeys []string
+	// CorruptA、CorruptB 读取时跳过的损坏条目数量
+	CorruptA, CorruptB int
+}
+
+// Equal 两个文件的内容是否相同
+func (r *DiffReport) Equal() bool {
+	return r.OnlyInA == 0 && r.OnlyInB == 0 && r.Mismatched == 0
+}
+
+// ComparePersistFiles 比较两个持久化文件的内容，自动识别JSON和二进制格式，两个文件的格式可以不同
+//
+// 两个文件都流式读取，内存中只保存键和值的128位哈希，不保存值本身。
+// 与加载时的行为一致，同一键出现多次时以最后一次的值为准。
+func ComparePersistFiles(a, b string) (*DiffReport, error) {
+	report := &DiffReport{}
+	hashesA, err := hashPersistFile(a, &report.CorruptA)
+	if err != nil {
+		return nil, err
+	}
+	hashesB, err := hashPersistFile(b, &report.CorruptB)
+	if err != nil {
+		return nil, err
+	}
+	report.EntriesA, report.EntriesB = len(hashesA), len(hashesB)
+
+	for key, hashA := range hashesA {
+		hashB, ok := hashesB[key]
+		switch {
+		case !ok:
+			report.OnlyInA++
+			report.OnlyInAKeys = append(report.OnlyInAKeys, key)
+		case hashA != hashB:
+			report.Mismatched++
+			report.MismatchedKeys = append(report.MismatchedKeys, key)
+		default:
+			report.Matched++
+		}
+	}
+	for key := range hashesB {
+		if _, ok := hashesA[key]; !ok {
+			report.OnlyInB++
+			report.OnlyInBKeys = append(report.OnlyInBKeys, key)
+		}
+	}
+	report.OnlyInAKeys = firstSorted(report.OnlyInAKeys, diffReportKeys)
+	report.OnlyInBKeys = firstSorted(report.OnlyInBKeys, diffReportKeys)
+	report.MismatchedKeys = firstSorted(report.MismatchedKeys, diffReportKeys)
+	return report, nil
+}
+
+// hashPersistFile 流式读取持久化文件，返回每个键的值的哈希
+func hashPersistFile(path string, corrupt *int) (map[string][16]byte, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, newError(CodeOpenFile, path, err)
+	}
+	defer file.Close()
+
+	pr, err := openPersistFile(file)
+	if err != nil {
+		return nil, err
+	}
+	hashes := make(map[string][16]byte)
+	h := fnv.New128a()
+	err = eachPersistEntry(pr, func(entry PersistEntry) {
+		h.Reset()
+		h.Write(entry.Value)
+		var sum [16]byte
+		h.Sum(sum[:0])
+		hashes[entry.Key] = sum
+	}, corrupt)
+	if err != nil {
+		return nil, err
+	}
+	return hashes, nil
+}
+
+// firstSorted 排序后返回前n个键
+func firstSorted(keys []string, n int) []string {
+	sort.Strings(keys)
+	if len(keys) > n {
+		keys = keys[:n]
+	}
+	return keys
+}
@@ -0,0 +1,114 @@
+package ngcat
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestShadowPersistEquivalent(t *testing.T) {
+	dir := t.TempDir()
+	shadowPath := filepath.Join(dir, "shadow", "cache.bin")
+	config := &PersistConfig{
+		Enabled:  true,
+		FilePath: dir,
+		FileName: "cache.json",
+		Format:   FormatJSON,
+		Interval: time.Hour,
+	}
+	nc := NewNGCache(1024*1024, config, WithShadowPersist(shadowPath, FormatBinary),
+		WithLogger(slog.New(slog.NewTextHandler(io.Discard, nil))))
+	defer nc.Close()
+	for i := 0; i < 50; i++ {
+		nc.SetString(fmt.Sprintf("key%d", i), fmt.Sprintf("value<%d>&", i), 0)
+	}
+	nc.SetPermanent([]byte("bin"), []byte{0, 1, 2, 0xff})
+	if err := nc.Save(); err != nil {
+		t.Fatal(err)
+	}
+
+	summary, err := InspectPersistFile(shadowPath)
+	if err != nil || summary.Format != FormatBinary {
+		t.Fatalf("shadow summary = %+v, %v", summary, err)
+	}
+	report, err := ComparePersistFiles(filepath.Join(dir, "cache.json"), shadowPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !report.Equal() || report.Matched != 51 || report.EntriesA != 51 || report.EntriesB != 51 {
+		t.Fatalf("report = %+v", report)
+	}
+}
+
+func TestComparePersistFilesDiff(t *testing.T) {
+	dir := t.TempDir()
+	save := func(name string, format PersistFormat, entries map[string]string) string {
+		nc := NewNGCache(1024*1024, nil)
+		defer nc.Close()
+		for k, v := range entries {
+			nc.SetString(k, v, 0)
+		}
+		path := filepath.Join(dir, name)
+		data := nc.collectPersistData()
+		if err := writePersistFile(nc.ctx, path, format, data, false); err != nil {
+			t.Fatal(err)
+		}
+		return path
+	}
+	a := save("a.json", FormatJSON, map[string]string{"same": "1", "changed": "old", "onlyA": "x"})
+	b := save("b.bin", FormatBinary, map[string]string{"same": "1", "changed": "new", "onlyB1": "y", "onlyB2": "z"})
+
+	report, err := ComparePersistFiles(a, b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report.Equal() || report.EntriesA != 3 || report.EntriesB != 4 || report.Matched != 1 ||
+		report.OnlyInA != 1 || report.OnlyInB != 2 || report.Mismatched != 1 {
+		t.Fatalf("report = %+v", report)
+	}
+	if fmt.Sprint(report.OnlyInAKeys, report.OnlyInBKeys, report.MismatchedKeys) != "[onlyA] [onlyB1 onlyB2] [changed]" {
+		t.Fatalf("keys = %v %v %v", report.OnlyInAKeys, report.OnlyInBKeys, report.MismatchedKeys)
+	}
+
+	if _, err := ComparePersistFiles(a, filepath.Join(dir, "missing")); !errors.Is(err, os.ErrNotExist) {
+		t.Fatalf("missing file err = %v", err)
+	}
+}
+
+func TestShadowPersistErrorIsolated(t *testing.T) {
+	dir := t.TempDir()
+	// 影子文件的父目录是一个普通文件，写入必然失败
+	blocker := filepath.Join(dir, "blocker")
+	if err := os.WriteFile(blocker, nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+	var reported []error
+	config := &PersistConfig{
+		Enabled:  true,
+		FilePath: dir,
+		FileName: "cache.bin",
+		Format:   FormatBinary,
+		Interval: time.Hour,
+	}
+	nc := NewNGCache(1024*1024, config, WithShadowPersist(filepath.Join(blocker, "cache.json"), FormatJSON),
+		WithLogger(slog.New(slog.NewTextHandler(io.Discard, nil))),
+		WithOnError(func(err error) { reported = append(reported, err) }))
+	defer nc.Close()
+	nc.SetString("k", "v", 0)
+
+	if err := nc.Save(); err != nil {
+		t.Fatalf("primary save failed: %v", err)
+	}
+	if !fileExists(filepath.Join(dir, "cache.bin")) {
+		t.Fatal("primary file not written")
+	}
+	var ce *CacheError
+	if len(reported) != 1 || !errors.As(reported[0], &ce) || ce.Code != CodeShadowPersist {
+		t.Fatalf("reported = %v", reported)
+	}
+}
@@ -0,0 +1,337 @@
+package ngcat
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+	"net"
+	"sort"
+)
+
+// ShardedNGCache 由多个独立NGCache组成的分片缓存，每个分片有自己的锁和持久化数据，
+// 适合大量协程并发读写的场景
+type ShardedNGCache struct {
+	// shards 分片
+	shards []*NGCache
+}
+
+// NewShardedNGCache 创建numShards个分片，每个分片的容量为sizePerShard
+//
+// 启用持久化时每个分片写入单独的文件，文件名为"<FileName>.<分片序号>"。
+// 键按FNV-1a 32位哈希（与hash/fnv.New32a相同）对分片数取模路由，因此分片数改变后已有的持久化文件不能再使用。
+func NewShardedNGCache(numShards, sizePerShard int, config *PersistConfig, opts ...Option) *ShardedNGCache {
+	return NewShardedNGCacheFunc(numShards, sizePerShard, func(i int) *PersistConfig {
+		if config == nil {
+			return nil
+		}
+		c := *config
+		c.FileName = fmt.Sprintf("%s.%d", config.FileName, i)
+		return &c
+	}, opts...)
+}
+
+// NewShardedNGCacheFunc 与NewShardedNGCache相同，但每个分片的持久化配置由cfgFactory(分片序号)给出
+//
+// 适合将分片放在不同目录或磁盘上。各分片的配置必须指向不同的文件；cfgFactory为nil或返回nil时该分片不持久化。
+// 路由方式与NewShardedNGCache相同，分片数固定，改变后需要重新生成持久化文件。
+func NewShardedNGCacheFunc(numShards, sizePerShard int, cfgFactory func(i int) *PersistConfig, opts ...Option) *ShardedNGCache {
+	if numShards <= 0 {
+		numShards = 1
+	}
+	s := &ShardedNGCache{shards: make([]*NGCache, numShards)}
+	for i := range s.shards {
+		var shardConfig *PersistConfig
+		if cfgFactory != nil {
+			shardConfig = cfgFactory(i)
+		}
+		s.shards[i] = NewNGCache(sizePerShard, shardConfig, opts...)
+	}
+	return s
+}
+
+// Shard 返回键所在的分片，用于访问ShardedNGCache未直接提供的方法
+func (s *ShardedNGCache) Shard(key string) *NGCache {
+	return s.shards[stringHash(key)%uint32(len(s.shards))]
+}
+
+// Shards 返回所有分片
+func (s *ShardedNGCache) Shards() []*NGCache {
+	return s.shards
+}
+
+// Close 关闭所有分片并执行最后一次持久化，返回所有分片的错误
+func (s *ShardedNGCache) Close() error {
+	var errs []error
+	for _, ng := range s.shards {
+		errs = append(errs, ng.Close())
+	}
+	return errors.Join(errs...)
+}
+
+// Drain 依次对所有分片调用Drain，返回所有分片的错误
+func (s *ShardedNGCache) Drain() error {
+	var errs []error
+	for _, ng := range s.shards {
+		errs = append(errs, ng.Drain())
+	}
+	return errors.Join(errs...)
+}
+
+// Stats 返回所有分片统计信息的合计，直方图按桶相加
+func (s *ShardedNGCache) Stats() CacheStats {
+	var total CacheStats
+	for _, ng := range s.shards {
+		stats := ng.Stats()
+		total.HitCount += stats.HitCount
+		total.MissCount += stats.MissCount
+		total.EntryCount += stats.EntryCount
+		total.EvacuateCount += stats.EvacuateCount
+		total.ExpiredCount += stats.ExpiredCount
+		total.PersistEntries += stats.PersistEntries
+		total.Promotions += stats.Promotions
+		total.PromotionsDeduplicated += stats.PromotionsDeduplicated
+		total.RecoveredEntries += stats.RecoveredEntries
+		total.PersistSkippedTicks += stats.PersistSkippedTicks
+		total.Compactions += stats.Compactions
+		total.CompactedBytes += stats.CompactedBytes
+		total.BloomNegatives += stats.BloomNegatives
+		total.BloomFalsePositives += stats.BloomFalsePositives
+		total.ValueSizes.add(stats.ValueSizes)
+		total.GetLatencyHit.add(stats.GetLatencyHit)
+		total.GetLatencyFallback.add(stats.GetLatencyFallback)
+		total.GetLatencyMiss.add(stats.GetLatencyMiss)
+	}
+	return total
+}
+
+// Keys 返回所有分片中存活的键，已按字典序排序
+func (s *ShardedNGCache) Keys() []string {
+	var keys []string
+	for _, ng := range s.shards {
+		keys = append(keys, ng.allKeys()...)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// KeysMatching 返回所有分片中匹配正则表达式pattern的键，已按字典序排序
+func (s *ShardedNGCache) KeysMatching(pattern string) ([]string, error) {
+	var keys []string
+	for _, ng := range s.shards {
+		matched, err := ng.KeysMatching(pattern)
+		if err != nil {
+			return nil, err
+		}
+		keys = append(keys, matched...)
+	}
+	sort.Strings(keys)
+	return keys, nil
+}
+
+// Flush 删除所有分片中的条目（包括永久缓存），返回删除的键数量
+func (s *ShardedNGCache) Flush() int {
+	removed := 0
+	for _, ng := range s.shards {
+		removed += ng.Flush()
+	}
+	return removed
+}
+
+// SetInt32 设置int32类型值
+func (s *ShardedNGCache) SetInt32(key string, value int32, expireSeconds int) error {
+	return s.Shard(key).SetInt32(key, value, expireSeconds)
+}
+
+// GetInt32 获取int32类型值
+func (s *ShardedNGCache) GetInt32(key string) (int32, error) {
+	return s.Shard(key).GetInt32(key)
+}
+
+// SetInt64 设置int64类型值
+func (s *ShardedNGCache) SetInt64(key string, value int64, expireSeconds int) error {
+	return s.Shard(key).SetInt64(key, value, expireSeconds)
+}
+
+// GetInt64 获取int64类型值
+func (s *ShardedNGCache) GetInt64(key string) (int64, error) {
+	return s.Shard(key).GetInt64(key)
+}
+
+// SetBool 设置bool类型值
+func (s *ShardedNGCache) SetBool(key string, value bool, expireSeconds int) error {
+	return s.Shard(key).SetBool(key, value, expireSeconds)
+}
+
+// GetBool 获取bool类型值
+func (s *ShardedNGCache) GetBool(key string) (bool, error) {
+	return s.Shard(key).GetBool(key)
+}
+
+// SetFloat32 设置float32类型值
+func (s *ShardedNGCache) SetFloat32(key string, value float32, expireSeconds int) error {
+	return s.Shard(key).SetFloat32(key, value, expireSeconds)
+}
+
+// GetFloat32 获取float32类型值
+func (s *ShardedNGCache) GetFloat32(key string) (float32, error) {
+	return s.Shard(key).GetFloat32(key)
+}
+
+// SetFloat64 设置float64类型值
+func (s *ShardedNGCache) SetFloat64(key string, value float64, expireSeconds int) error {
+	return s.Shard(key).SetFloat64(key, value, expireSeconds)
+}
+
+// GetFloat64 获取float64类型值
+func (s *ShardedNGCache) GetFloat64(key string) (float64, error) {
+	return s.Shard(key).GetFloat64(key)
+}
+
+// SetRune 设置rune类型值
+func (s *ShardedNGCache) SetRune(key string, value rune, expireSeconds int) error {
+	return s.Shard(key).SetRune(key, value, expireSeconds)
+}
+
+// GetRune 获取rune类型值
+func (s *ShardedNGCache) GetRune(key string) (rune, error) {
+	return s.Shard(key).GetRune(key)
+}
+
+// SetInt 设置int类型值
+func (s *ShardedNGCache) SetInt(key string, value int, expireSeconds int) error {
+	return s.Shard(key).SetInt(key, value, expireSeconds)
+}
+
+// GetInt 获取int类型值
+func (s *ShardedNGCache) GetInt(key string) (int, error) {
+	return s.Shard(key).GetInt(key)
+}
+
+// GetNumberAsInt64 获取4字节或8字节编码的整数
+func (s *ShardedNGCache) GetNumberAsInt64(key string) (int64, error) {
+	return s.Shard(key).GetNumberAsInt64(key)
+}
+
+// SetUUID 设置UUID
+func (s *ShardedNGCache) SetUUID(key string, id [16]byte, expireSeconds int) error {
+	return s.Shard(key).SetUUID(key, id, expireSeconds)
+}
+
+// ParseAndSetUUID 解析并设置UUID
+func (s *ShardedNGCache) ParseAndSetUUID(key, uuidStr string, expireSeconds int) error {
+	return s.Shard(key).ParseAndSetUUID(key, uuidStr, expireSeconds)
+}
+
+// GetUUID 获取UUID
+func (s *ShardedNGCache) GetUUID(key string) ([16]byte, error) {
+	return s.Shard(key).GetUUID(key)
+}
+
+// SetIP 设置IP地址
+func (s *ShardedNGCache) SetIP(key string, ip net.IP, expireSeconds int) error {
+	return s.Shard(key).SetIP(key, ip, expireSeconds)
+}
+
+// GetIP 获取IP地址
+func (s *ShardedNGCache) GetIP(key string) (net.IP, error) {
+	return s.Shard(key).GetIP(key)
+}
+
+// SetBigFloat 设置*big.Float类型值
+func (s *ShardedNGCache) SetBigFloat(key string, value *big.Float, expireSeconds int) error {
+	return s.Shard(key).SetBigFloat(key, value, expireSeconds)
+}
+
+// GetBigFloat 获取*big.Float类型值
+func (s *ShardedNGCache) GetBigFloat(key string) (*big.Float, error) {
+	return s.Shard(key).GetBigFloat(key)
+}
+
+// SetComplex64 设置complex64类型值
+func (s *ShardedNGCache) SetComplex64(key string, value complex64, expireSeconds int) error {
+	return s.Shard(key).SetComplex64(key, value, expireSeconds)
+}
+
+// GetComplex64 获取complex64类型值
+func (s *ShardedNGCache) GetComplex64(key string) (complex64, error) {
+	return s.Shard(key).GetComplex64(key)
+}
+
+// SetComplex128 设置complex128类型值
+func (s *ShardedNGCache) SetComplex128(key string, value complex128, expireSeconds int) error {
+	return s.Shard(key).SetComplex128(key, value, expireSeconds)
+}
+
+// GetComplex128 获取complex128类型值
+func (s *ShardedNGCache) GetComplex128(key string) (complex128, error) {
+	return s.Shard(key).GetComplex128(key)
+}
+
+// SetBytes 设置字节数组值
+func (s *ShardedNGCache) SetBytes(key string, value []byte, expireSeconds int) error {
+	return s.Shard(key).SetBytes(key, value, expireSeconds)
+}
+
+// GetBytes 获取字节数组值
+func (s *ShardedNGCache) GetBytes(key string) ([]byte, error) {
+	return s.Shard(key).GetBytes(key)
+}
+
+// SetString 设置字符串值
+func (s *ShardedNGCache) SetString(key string, value string, expireSeconds int) error {
+	return s.Shard(key).SetString(key, value, expireSeconds)
+}
+
+// GetString 获取字符串值
+func (s *ShardedNGCache) GetString(key string) (string, error) {
+	return s.Shard(key).GetString(key)
+}
+
+// SetAny 设置任意类型值（使用gob序列化）
+func (s *ShardedNGCache) SetAny(key string, value interface{}, expireSeconds int) error {
+	return s.Shard(key).SetAny(key, value, expireSeconds)
+}
+
+// GetAny 获取任意类型值（使用gob反序列化）
+func (s *ShardedNGCache) GetAny(key string, value interface{}) error {
+	return s.Shard(key).GetAny(key, value)
+}
+
+// SetJSON 设置任意类型值（使用JSON序列化）
+func (s *ShardedNGCache) SetJSON(key string, value interface{}, expireSeconds int) error {
+	return s.Shard(key).SetJSON(key, value, expireSeconds)
+}
+
+// GetJSON 获取任意类型值（使用JSON反序列化）
+func (s *ShardedNGCache) GetJSON(key string, value interface{}) error {
+	return s.Shard(key).GetJSON(key, value)
+}
+
+// SetBytesWithTTL 以字节数组作为键设置值
+func (s *ShardedNGCache) SetBytesWithTTL(key []byte, value []byte, expireSeconds int) error {
+	return s.Shard(string(key)).SetBytesWithTTL(key, value, expireSeconds)
+}
+
+// GetBytesByKey 以字节数组作为键读取值
+func (s *ShardedNGCache) GetBytesByKey(key []byte) ([]byte, error) {
+	return s.Shard(string(key)).GetBytesByKey(key)
+}
+
+// SetPermanent 设置永久缓存
+//
+// Deprecated: 使用SetBytesWithTTL。
+func (s *ShardedNGCache) SetPermanent(key []byte, value []byte) error {
+	return s.Shard(string(key)).SetBytesWithTTL(key, value, TTLPermanent)
+}
+
+// GetPermanent 读取键
+//
+// Deprecated: 使用GetBytesByKey。
+func (s *ShardedNGCache) GetPermanent(key []byte) ([]byte, error) {
+	return s.Shard(string(key)).GetBytesByKey(key)
+}
+
+// Delete 删除键，返回键是否存在
+func (s *ShardedNGCache) Delete(key string) bool {
+	return s.Shard(key).Delete(key)
+}
@@ -0,0 +1,181 @@
+package ngcat
+
+import (
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestShardedNGCacheRouting(t *testing.T) {
+	s := NewShardedNGCache(8, 1024*1024, nil)
+	defer s.Close()
+
+	for _, key := range []string{"a", "user:1", "product:42", ""} {
+		h := fnv.New32a()
+		h.Write([]byte(key))
+		if s.Shard(key) != s.Shards()[h.Sum32()%8] {
+			t.Fatalf("key %q routed to the wrong shard", key)
+		}
+	}
+
+	var wg sync.WaitGroup
+	for g := 0; g < 16; g++ {
+		wg.Add(1)
+		go func(g int) {
+			defer wg.Done()
+			for i := 0; i < 200; i++ {
+				key := fmt.Sprintf("g%d:k%d", g, i)
+				s.SetInt64(key, int64(i), 0)
+				if v, err := s.GetInt64(key); err != nil || v != int64(i) {
+					t.Errorf("%s = %d, %v", key, v, err)
+					return
+				}
+			}
+		}(g)
+	}
+	wg.Wait()
+
+	if !s.Delete("g0:k0") {
+		t.Fatal("Delete should report existing key")
+	}
+	if _, err := s.GetInt64("g0:k0"); err != ErrKeyNotFound {
+		t.Fatalf("deleted key: %v", err)
+	}
+}
+
+func TestShardedNGCachePersistence(t *testing.T) {
+	dir := t.TempDir()
+	config := &PersistConfig{
+		Enabled:  true,
+		FilePath: dir,
+		FileName: "sharded.cat",
+		Format:   FormatBinary,
+		Interval: time.Hour,
+	}
+
+	s := NewShardedNGCache(4, 1024*1024, config)
+	for i := 0; i < 100; i++ {
+		s.SetString(fmt.Sprintf("key%d", i), fmt.Sprintf("value%d", i), 0)
+	}
+	if err := s.Close(); err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 4; i++ {
+		if !fileExists(filepath.Join(dir, fmt.Sprintf("sharded.cat.%d", i))) {
+			t.Fatalf("shard %d file missing", i)
+		}
+	}
+	if config.FileName != "sharded.cat" {
+		t.Fatal("caller's config should not be modified")
+	}
+
+	reloaded := NewShardedNGCache(4, 1024*1024, config)
+	defer reloaded.Close()
+	for i := 0; i < 100; i++ {
+		if v, _ := reloaded.GetString(fmt.Sprintf("key%d", i)); v != fmt.Sprintf("value%d", i) {
+			t.Fatalf("key%d = %q", i, v)
+		}
+	}
+}
+
+func BenchmarkShardedSetPermanentParallel(b *testing.B) {
+	s := NewShardedNGCache(16, 4*1024*1024, nil)
+	defer s.Close()
+	b.ReportAllocs()
+	b.ResetTimer()
+	b.RunParallel(func(pb *testing.PB) {
+		for i := 0; pb.Next(); i++ {
+			j := i & (benchKeyCount - 1)
+			s.SetString(benchKeys[j], benchStrings[j], 0)
+		}
+	})
+}
+
+func TestShardedNGCacheDistribution(t *testing.T) {
+	const shards, keys = 16, 100000
+	s := NewShardedNGCache(shards, 1024*1024, nil)
+	defer s.Close()
+
+	counts := make(map[*NGCache]int)
+	for i := 0; i < keys; i++ {
+		counts[s.Shard(fmt.Sprintf("key:%d", i))]++
+	}
+	// 均匀分布时每个分片约6250个键，允许±10%
+	for i, ng := range s.Shards() {
+		if n := counts[ng]; n < keys/shards*9/10 || n > keys/shards*11/10 {
+			t.Fatalf("shard %d has %d of %d keys", i, n, keys)
+		}
+	}
+}
+
+func TestShardedNGCacheFunc(t *testing.T) {
+	dir := t.TempDir()
+	s := NewShardedNGCacheFunc(3, 1024*1024, func(i int) *PersistConfig {
+		return &PersistConfig{
+			Enabled:  true,
+			FilePath: filepath.Join(dir, fmt.Sprintf("disk%d", i)),
+			FileName: "cache.bin",
+			Format:   FormatBinary,
+			Interval: time.Hour,
+		}
+	}, WithHistograms(1))
+	for i := 0; i < 30; i++ {
+		key := fmt.Sprintf("key%d", i)
+		s.SetString(key, "v", 0)
+		if _, err := s.Shard(key).GetString(key); err != nil {
+			t.Fatalf("%s not in its shard: %v", key, err)
+		}
+	}
+	s.GetString("missing")
+
+	stats := s.Stats()
+	if stats.PersistEntries != 30 || stats.EntryCount != 30 || stats.HitCount != 30 || stats.MissCount != 1 {
+		t.Fatalf("stats = %+v", stats)
+	}
+	if stats.ValueSizes.Count != 30 || stats.ValueSizes.Sum != 30 {
+		t.Fatalf("value sizes = %+v", stats.ValueSizes)
+	}
+	if keys := s.Keys(); len(keys) != 30 || keys[0] != "key0" || keys[1] != "key1" || keys[2] != "key10" {
+		t.Fatalf("Keys = %v", keys)
+	}
+	if keys, err := s.KeysMatching(`^key2\d?$`); err != nil || len(keys) != 11 {
+		t.Fatalf("KeysMatching = %v, %v", keys, err)
+	}
+
+	if err := s.Close(); err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 3; i++ {
+		if !fileExists(filepath.Join(dir, fmt.Sprintf("disk%d", i), "cache.bin")) {
+			t.Fatalf("shard %d file missing", i)
+		}
+	}
+}
+
+func TestShardedNGCacheFlushAndCloseErrors(t *testing.T) {
+	dir := t.TempDir()
+	config := &PersistConfig{Enabled: true, FilePath: filepath.Join(dir, "data"), FileName: "cache.bin", Format: FormatBinary, Interval: time.Hour}
+	s := NewShardedNGCache(2, 1024*1024, config, WithLogger(slog.New(slog.NewTextHandler(io.Discard, nil))))
+	for i := 0; i < 10; i++ {
+		s.SetString(fmt.Sprintf("key%d", i), "v", 0)
+	}
+	if n := s.Flush(); n != 10 || len(s.Keys()) != 0 {
+		t.Fatalf("Flush = %d, remaining %v", n, s.Keys())
+	}
+
+	// 持久化目录被普通文件占用，每个分片的最后一次保存都会失败
+	os.RemoveAll(config.FilePath)
+	os.WriteFile(config.FilePath, nil, 0644)
+	err := s.Close()
+	var joined interface{ Unwrap() []error }
+	if !errors.As(err, &joined) || len(joined.Unwrap()) != 2 {
+		t.Fatalf("Close err = %v", err)
+	}
+}
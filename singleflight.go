@@ -0,0 +1,68 @@
+package ngcat
+
+import (
+	"sync"
+)
+
+// flightCall 一次进行中的加载
+type flightCall struct {
+	wg  sync.WaitGroup
+	val []byte
+	err error
+}
+
+// flightGroup 按键合并并发加载，同一键同时只执行一次加载函数
+type flightGroup struct {
+	mu    sync.Mutex
+	calls map[string]*flightCall
+}
+
+// do 执行加载函数，同一键已有加载进行中时等待其结果
+func (g *flightGroup) do(key string, fn func() ([]byte, error)) ([]byte, error) {
+	g.mu.Lock()
+	if c, ok := g.calls[key]; ok {
+		g.mu.Unlock()
+		c.wg.Wait()
+		return c.val, c.err
+	}
+	c := g.begin(key)
+	g.mu.Unlock()
+
+	g.run(key, c, fn)
+	return c.val, c.err
+}
+
+// doAsync 在后台执行加载函数，同一键已有加载进行中时直接返回false
+func (g *flightGroup) doAsync(key string, fn func() ([]byte, error)) bool {
+	g.mu.Lock()
+	if _, ok := g.calls[key]; ok {
+		g.mu.Unlock()
+		return false
+	}
+	c := g.begin(key)
+	g.mu.Unlock()
+
+	go g.run(key, c, fn)
+	return true
+}
+
+// begin 登记新的加载，调用方需持有g.mu
+func (g *flightGroup) begin(key string) *flightCall {
+	if g.calls == nil {
+		g.calls = make(map[string]*flightCall)
+	}
+	c := &flightCall{}
+	c.wg.Add(1)
+	g.calls[key] = c
+	return c
+}
+
+// run 执行加载并唤醒等待者
+func (g *flightGroup) run(key string, c *flightCall, fn func() ([]byte, error)) {
+	c.val, c.err = fn()
+	c.wg.Done()
+
+	g.mu.Lock()
+	delete(g.calls, key)
+	g.mu.Unlock()
+}
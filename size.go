@@ -0,0 +1,121 @@
+package ngcat
+
+import (
+	"container/heap"
+	"sort"
+	"strings"
+)
+
+// KeySizeEntry 单个条目占用的字节数
+type KeySizeEntry struct {
+	// Key 键
+	Key string
+	// ValueSize 存储的值长度（启用值压缩时为压缩后的长度）
+	ValueSize int
+	// TotalSize 键和值的总长度
+	TotalSize int
+}
+
+// SizeOf 返回单个条目占用的字节数（键长度加存储的值长度）
+//
+// 先查找freecache，没有时查找持久化数据，不计入命中统计也不写回freecache。键不存在时返回ErrKeyNotFound。
+func (ng *NGCache) SizeOf(key string) (int, error) {
+	value, err := ng.storePeek(key)
+	if err == nil {
+		return len(key) + len(value), nil
+	}
+
+	persistValue, exists := ng.persistValue(key)
+	if !exists {
+		return 0, ErrKeyNotFound
+	}
+	return len(key) + len(persistValue), nil
+}
+
+// TopNBySize 返回值最大的n个条目，按值长度从大到小排序
+//
+// 遍历freecache和只存在于持久化数据中的永久缓存，n不大于0时返回nil。
+func (ng *NGCache) TopNBySize(n int) ([]KeySizeEntry, error) {
+	if n <= 0 {
+		return nil, nil
+	}
+
+	h := make(keySizeHeap, 0, n)
+	push := func(key string, valueSize int) {
+		if len(h) < n {
+			heap.Push(&h, KeySizeEntry{Key: key, ValueSize: valueSize, TotalSize: len(key) + valueSize})
+		} else if valueSize > h[0].ValueSize {
+			h[0] = KeySizeEntry{Key: key, ValueSize: valueSize, TotalSize: len(key) + valueSize}
+			heap.Fix(&h, 0)
+		}
+	}
+
+	ng.eachStoredSize(push)
+
+	result := []KeySizeEntry(h)
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].ValueSize != result[j].ValueSize {
+			return result[i].ValueSize > result[j].ValueSize
+		}
+		return result[i].Key < result[j].Key
+	})
+	return result, nil
+}
+
+// DefaultPrefixGrouper MemoryBreakdown的默认分组函数，返回键中第一个':'之前的部分，没有':'时返回整个键
+func DefaultPrefixGrouper(key string) string {
+	if i := strings.IndexByte(key, ':'); i >= 0 {
+		return key[:i]
+	}
+	return key
+}
+
+// MemoryBreakdown 按分组统计存储的值占用的字节数
+//
+// 遍历freecache和只存在于持久化数据中的永久缓存，groupByPrefix由键得到分组名，
+// 为nil时使用DefaultPrefixGrouper。
+func (ng *NGCache) MemoryBreakdown(groupByPrefix func(key string) string) map[string]int64 {
+	if groupByPrefix == nil {
+		groupByPrefix = DefaultPrefixGrouper
+	}
+	breakdown := make(map[string]int64)
+	ng.eachStoredSize(func(key string, valueSize int) {
+		breakdown[groupByPrefix(key)] += int64(valueSize)
+	})
+	return breakdown
+}
+
+// eachStoredSize 遍历所有条目存储的值长度，只存在于持久化数据中的永久缓存也包含在内
+func (ng *NGCache) eachStoredSize(fn func(key string, valueSize int)) {
+	ng.storeRange(func(key, value []byte, _ uint32) bool {
+		fn(string(key), len(value))
+		return true
+	})
+
+	ng.persistDataMutex.RLock()
+	sizes := make(map[string]int, len(ng.persistData))
+	for key, value := range ng.persistData {
+		sizes[key] = len(value)
+	}
+	ng.persistDataMutex.RUnlock()
+	for key, size := range sizes {
+		if _, err := ng.storePeek(key); err == nil {
+			continue // 已在freecache遍历中统计过
+		}
+		fn(key, size)
+	}
+}
+
+// keySizeHeap 按值长度排序的最小堆，用于保留最大的若干条目
+type keySizeHeap []KeySizeEntry
+
+func (h keySizeHeap) Len() int            { return len(h) }
+func (h keySizeHeap) Less(i, j int) bool  { return h[i].ValueSize < h[j].ValueSize }
+func (h keySizeHeap) Swap(i, j int)       { h[i], h[j] = h[j], h[i] }
+func (h *keySizeHeap) Push(x interface{}) { *h = append(*h, x.(KeySizeEntry)) }
+func (h *keySizeHeap) Pop() interface{} {
+	old := *h
+	x := old[len(old)-1]
+	*h = old[:len(old)-1]
+	return x
+}
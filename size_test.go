@@ -0,0 +1,102 @@
+package ngcat
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSizeOf(t *testing.T) {
+	nc := NewNGCache(1024*1024, &PersistConfig{
+		Enabled:  true,
+		FilePath: t.TempDir(),
+		FileName: "size.cat",
+		Interval: time.Hour,
+	})
+	defer nc.Close()
+
+	nc.SetString("key", "value", 60)
+	if size, err := nc.SizeOf("key"); err != nil || size != 8 {
+		t.Fatalf("SizeOf(key) = %d, %v", size, err)
+	}
+
+	nc.SetString("perm", "12345678", 0)
+	nc.cache.Del([]byte("perm"))
+	if size, err := nc.SizeOf("perm"); err != nil || size != 12 {
+		t.Fatalf("SizeOf(perm) from persistData = %d, %v", size, err)
+	}
+
+	if _, err := nc.SizeOf("missing"); err != ErrKeyNotFound {
+		t.Fatalf("expected ErrKeyNotFound, got %v", err)
+	}
+}
+
+func TestTopNBySize(t *testing.T) {
+	nc := NewNGCache(1024*1024, &PersistConfig{
+		Enabled:  true,
+		FilePath: t.TempDir(),
+		FileName: "size.cat",
+		Interval: time.Hour,
+	})
+	defer nc.Close()
+
+	nc.SetString("small", "x", 0)
+	nc.SetString("medium", strings.Repeat("x", 100), 60)
+	nc.SetString("large", strings.Repeat("x", 1000), 0)
+	nc.SetString("huge", strings.Repeat("x", 5000), 0)
+	// 只存在于持久化数据中的条目也要统计
+	nc.cache.Del([]byte("huge"))
+
+	top, err := nc.TopNBySize(3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []KeySizeEntry{
+		{Key: "huge", ValueSize: 5000, TotalSize: 5004},
+		{Key: "large", ValueSize: 1000, TotalSize: 1005},
+		{Key: "medium", ValueSize: 100, TotalSize: 106},
+	}
+	if len(top) != len(want) {
+		t.Fatalf("TopNBySize(3) = %v", top)
+	}
+	for i := range want {
+		if top[i] != want[i] {
+			t.Fatalf("top[%d] = %+v, want %+v", i, top[i], want[i])
+		}
+	}
+
+	if top, _ := nc.TopNBySize(0); top != nil {
+		t.Fatalf("TopNBySize(0) = %v", top)
+	}
+}
+
+func TestMemoryBreakdown(t *testing.T) {
+	nc := NewNGCache(1024*1024, nil)
+	defer nc.Close()
+
+	for i := 0; i < 100; i++ {
+		nc.SetString(fmt.Sprintf("user:%d", i), "0123456789", 0)
+	}
+	for i := 0; i < 50; i++ {
+		nc.SetString(fmt.Sprintf("product:%d", i), "0123456789", 60)
+	}
+	nc.SetString("plain", "x", 0)
+
+	breakdown := nc.MemoryBreakdown(nil)
+	user, product := breakdown["user"], breakdown["product"]
+	if user != 1000 || product != 500 {
+		t.Fatalf("user = %d, product = %d", user, product)
+	}
+	if ratio := float64(user) / float64(product); ratio < 1.9 || ratio > 2.1 {
+		t.Fatalf("user/product ratio = %.2f", ratio)
+	}
+	if breakdown["plain"] != 1 {
+		t.Fatalf("key without colon should be its own bucket: %v", breakdown)
+	}
+
+	all := nc.MemoryBreakdown(func(string) string { return "all" })
+	if all["all"] != 1501 {
+		t.Fatalf("custom grouper total = %d", all["all"])
+	}
+}
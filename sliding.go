@@ -0,0 +1,108 @@
+package ngcat
+
+import (
+	"sort"
+	"strings"
+	"time"
+)
+
+// GetSliding 读取键，成功时将带过期时间的键的过期时间延长为当前时间加slideSeconds
+//
+// 延长在键的分段锁内原地修改freecache中的过期时间，不会与并发的写入交错，值不变。同一键每秒最多延长一次，
+// 剩余时间已不少于slideSeconds时不做修改，因此不会缩短较长的过期时间。永久缓存不受影响。
+func (ng *NGCache) GetSliding(key string, slideSeconds int) ([]byte, error) {
+	value, err := ng.getWithPersist(key)
+	if err == nil && slideSeconds > 0 {
+		ng.slide(key, slideSeconds)
+	}
+	return value, err
+}
+
+// SetSlidingTTL 为键前缀设置滑动过期时间：通过Get*读取该前缀的带过期时间的键时，
+// 与GetSliding一样将过期时间延长为当前时间加slide，前缀已设置时覆盖原有的值
+//
+// 使用匹配的最长前缀，没有匹配的前缀时使用WithSlidingTTL设置的默认值。slide不足1秒时按不延长处理。
+func (ng *NGCache) SetSlidingTTL(prefix string, slide time.Duration) {
+	ng.slidingMutex.Lock()
+	defer ng.slidingMutex.Unlock()
+	defer ng.updateSlidingActive()
+	for i := range ng.slidingPolicies {
+		if ng.slidingPolicies[i].Prefix == prefix {
+			ng.slidingPolicies[i].TTL = slide
+			return
+		}
+	}
+	ng.slidingPolicies = append(ng.slidingPolicies, TTLPolicy{Prefix: prefix, TTL: slide})
+	// 按前缀长度降序排列，查找时第一个匹配的就是最长前缀
+	sort.SliceStable(ng.slidingPolicies, func(i, j int) bool {
+		return len(ng.slidingPolicies[i].Prefix) > len(ng.slidingPolicies[j].Prefix)
+	})
+}
+
+// RemoveSlidingTTL 删除键前缀的滑动过期时间，返回该前缀是否已设置
+func (ng *NGCache) RemoveSlidingTTL(prefix string) bool {
+	ng.slidingMutex.Lock()
+	defer ng.slidingMutex.Unlock()
+	for i := range ng.slidingPolicies {
+		if ng.slidingPolicies[i].Prefix == prefix {
+			ng.slidingPolicies = append(ng.slidingPolicies[:i], ng.slidingPolicies[i+1:]...)
+			ng.updateSlidingActive()
+			return true
+		}
+	}
+	return false
+}
+
+// updateSlidingActive 更新是否存在滑动过期设置，调用方需持有slidingMutex
+func (ng *NGCache) updateSlidingActive() {
+	ng.slidingActive.Store(ng.slidingDefault > 0 || len(ng.slidingPolicies) > 0)
+}
+
+// slideFor 返回键匹配的最长前缀的滑动过期秒数，没有匹配的前缀时返回默认值
+func (ng *NGCache) slideFor(key string) int {
+	ng.slidingMutex.RLock()
+	defer ng.slidingMutex.RUnlock()
+	for _, p := range ng.slidingPolicies {
+		if strings.HasPrefix(key, p.Prefix) {
+			return int(p.TTL / time.Second)
+		}
+	}
+	return int(ng.slidingDefault / time.Second)
+}
+
+// slideByPolicy freecache命中后按滑动过期设置延长过期时间，未设置时只有一次原子读取的开销
+func (ng *NGCache) slideByPolicy(key string) {
+	if !ng.slidingActive.Load() {
+		return
+	}
+	if slide := ng.slideFor(key); slide > 0 {
+		ng.slide(key, slide)
+	}
+}
+
+// slide 将带过期时间的键的过期时间延长为当前时间加slideSeconds
+//
+// 剩余时间不少于slideSeconds说明一秒内已经延长过（或原有的过期时间更长），此时不加锁直接返回。
+func (ng *NGCache) slide(key string, slideSeconds int) {
+	if !ng.needsSlide(key, slideSeconds) {
+		return
+	}
+	mu := ng.keyLock(key)
+	mu.Lock()
+	defer mu.Unlock()
+	// 加锁后重新检查，期间键可能被改写为永久缓存或已被其他读取延长
+	if !ng.needsSlide(key, slideSeconds) || ng.storeTouch(key, slideSeconds) != nil {
+		return
+	}
+	expireAt := ng.clock.Now().Unix() + int64(slideSeconds)
+	ng.meta.extended(key, expireAt)
+	if ng.janitor != nil {
+		ng.janitor.extend(key, expireAt)
+	}
+}
+
+// needsSlide 键在freecache中带有过期时间且剩余时间少于slideSeconds
+func (ng *NGCache) needsSlide(key string, slideSeconds int) bool {
+	ttl, err := ng.cache.TTL([]byte(key))
+	return err == nil && ttl > 0 && int(ttl) < slideSeconds
+}
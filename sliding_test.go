@@ -0,0 +1,84 @@
+package ngcat
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestGetSliding(t *testing.T) {
+	clock := newFakeClock()
+	nc := NewNGCache(1024*1024, nil, WithClock(clock))
+	defer nc.Close()
+
+	nc.SetString("active", "a", 3)
+	nc.SetString("idle", "i", 3)
+	// 持续读取的键在原有的3秒之后仍然存在
+	for i := 0; i < 10; i++ {
+		clock.Add(time.Second)
+		if _, err := nc.GetSliding("active", 3); err != nil {
+			t.Fatalf("active expired after %ds: %v", i+1, err)
+		}
+	}
+	if _, err := nc.GetBytes("idle"); !errors.Is(err, ErrKeyNotFound) {
+		t.Fatalf("idle key err = %v", err)
+	}
+	clock.Add(3 * time.Second)
+	if _, err := nc.GetBytes("active"); !errors.Is(err, ErrKeyNotFound) {
+		t.Fatalf("active key did not expire after reads stopped: %v", err)
+	}
+
+	// 同一秒内的多次读取只延长一次，较长的剩余时间不会被缩短
+	nc.SetString("hot", "h", 10)
+	clock.Add(time.Second)
+	touched := nc.LowLevelStats().TouchedCount
+	for i := 0; i < 5; i++ {
+		nc.GetSliding("hot", 20)
+	}
+	nc.GetSliding("hot", 5)
+	if n := nc.LowLevelStats().TouchedCount - touched; n != 1 {
+		t.Fatalf("touched %d times within one second", n)
+	}
+	if ttl, _ := ttlOf(t, nc, "hot"); ttl != 20 {
+		t.Fatalf("ttl = %d", ttl)
+	}
+
+	nc.SetString("permanent", "p", 0)
+	nc.GetSliding("permanent", 3)
+	if ttl, permanent := ttlOf(t, nc, "permanent"); ttl != 0 || !permanent {
+		t.Fatalf("permanent key ttl = %d", ttl)
+	}
+}
+
+func TestSlidingTTLPolicy(t *testing.T) {
+	clock := newFakeClock()
+	nc := NewNGCache(1024*1024, nil, WithClock(clock))
+	defer nc.Close()
+	nc.SetSlidingTTL("session:", 3*time.Second)
+
+	nc.SetString("session:1", "s", 3)
+	nc.SetString("other", "o", 3)
+	for i := 0; i < 6; i++ {
+		clock.Add(time.Second)
+		if _, err := nc.GetString("session:1"); err != nil {
+			t.Fatalf("session expired after %ds: %v", i+1, err)
+		}
+		nc.GetString("other")
+	}
+	if _, err := nc.GetString("other"); !errors.Is(err, ErrKeyNotFound) {
+		t.Fatalf("key without sliding policy err = %v", err)
+	}
+	meta, err := nc.GetMeta("session:1")
+	if err != nil || meta.ExpireAt.Unix() != clock.Now().Unix()+3 {
+		t.Fatalf("meta = %+v, %v", meta, err)
+	}
+
+	if !nc.RemoveSlidingTTL("session:") || nc.slidingActive.Load() {
+		t.Fatal("RemoveSlidingTTL did not clear the policy")
+	}
+	clock.Add(time.Second)
+	nc.GetString("session:1")
+	if ttl, _ := ttlOf(t, nc, "session:1"); ttl != 2 {
+		t.Fatalf("ttl after removing policy = %d", ttl)
+	}
+}
@@ -0,0 +1,53 @@
+package ngcat
+
+import (
+	"context"
+	"io"
+)
+
+// SnapshotExport 将持久化数据（永久缓存）的一致快照按format（FormatJSON或FormatBinary）写入w
+//
+// 一致性保证：
+//   - 单个键是原子的：快照中每个键的值都是某一次完整写入的结果；
+//   - 跨键为同一时间点：快照在一次持久化数据加锁内复制所有键及其值的引用，
+//     因此若写入X先于写入Y完成，包含Y的新值的快照一定也包含X的新值（或X之后的值），
+//     SetBundle写入的一组永久缓存要么全部出现在快照中，要么全部不出现。
+//
+// 复制完成后立即释放锁，序列化和写入w期间不阻塞其他写入。
+func (ng *NGCache) SnapshotExport(w io.Writer, format PersistFormat) error {
+	return ng.SnapshotExportContext(context.Background(), w, format)
+}
+
+// SnapshotExportContext 导出一致快照，ctx取消时中止并返回ctx.Err()，w中可能已写入部分数据
+func (ng *NGCache) SnapshotExportContext(ctx context.Context, w io.Writer, format PersistFormat) error {
+	ng.flushCoalesced()
+	return writePersistData(ctx, w, format, ng.collectPersistData())
+}
+
+// SnapshotExport 将所有分片的持久化数据的一致快照写入w
+//
+// 复制时按分片顺序同时持有所有分片的持久化数据锁，跨分片的键同样满足NGCache.SnapshotExport的时间点一致性。
+func (s *ShardedNGCache) SnapshotExport(w io.Writer, format PersistFormat) error {
+	for _, ng := range s.shards {
+		ng.flushCoalesced()
+	}
+	for _, ng := range s.shards {
+		ng.persistDataMutex.RLock()
+	}
+	var entries []PersistEntry
+	for _, ng := range s.shards {
+		for key, value := range ng.persistData {
+			entries = append(entries, PersistEntry{Key: key, Value: value})
+		}
+	}
+	for i := len(s.shards) - 1; i >= 0; i-- {
+		s.shards[i].persistDataMutex.RUnlock()
+	}
+
+	return writePersistData(context.Background(), w, format, &PersistData{
+		Version:    JSONVersion,
+		Timestamp:  s.shards[0].clock.Now().Unix(),
+		Entries:    entries,
+		FormatCaps: s.shards[0].formatCaps(),
+	})
+}
@@ -0,0 +1,88 @@
+package ngcat
+
+import (
+	"bytes"
+	"encoding/binary"
+	"sync"
+	"sync/atomic"
+	"testing"
+)
+
+// snapshotPair 从快照中读取不变量中的两个键
+func snapshotPair(t *testing.T, data []byte) (a, b uint64) {
+	t.Helper()
+	pr, err := newPersistReader(bytes.NewReader(data), FormatBinary)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for {
+		entry, err := pr.next()
+		if err != nil {
+			break
+		}
+		switch entry.Key {
+		case "A":
+			a = binary.LittleEndian.Uint64(entry.Value)
+		case "B":
+			b = binary.LittleEndian.Uint64(entry.Value)
+		}
+	}
+	return a, b
+}
+
+// testSnapshotInvariant 后台按先B后A的顺序写入递增的版本号，因此任一时间点都满足A <= B，
+// 快照中出现A > B即说明快照混合了不同时间点的状态
+func testSnapshotInvariant(t *testing.T, set func(key string, v uint64), export func(w *bytes.Buffer) error) {
+	var (
+		stop atomic.Bool
+		wg   sync.WaitGroup
+	)
+	set("B", 0)
+	set("A", 0)
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for v := uint64(1); !stop.Load(); v++ {
+			set("B", v)
+			set("A", v)
+		}
+	}()
+
+	for i := 0; i < 200; i++ {
+		var buf bytes.Buffer
+		if err := export(&buf); err != nil {
+			t.Fatal(err)
+		}
+		if a, b := snapshotPair(t, buf.Bytes()); a > b {
+			stop.Store(true)
+			t.Fatalf("snapshot has new A (%d) with old B (%d)", a, b)
+		}
+	}
+	stop.Store(true)
+	wg.Wait()
+}
+
+func TestSnapshotExportConsistent(t *testing.T) {
+	nc := NewNGCache(1024*1024, nil)
+	defer nc.Close()
+
+	testSnapshotInvariant(t, func(key string, v uint64) {
+		nc.SetInt64(key, int64(v), 0)
+	}, func(w *bytes.Buffer) error {
+		return nc.SnapshotExport(w, FormatBinary)
+	})
+}
+
+func TestShardedSnapshotExportConsistent(t *testing.T) {
+	s := NewShardedNGCache(8, 1024*1024, nil)
+	defer s.Close()
+	if s.Shard("A") == s.Shard("B") {
+		t.Skip("A and B should live in different shards")
+	}
+
+	testSnapshotInvariant(t, func(key string, v uint64) {
+		s.SetInt64(key, int64(v), 0)
+	}, func(w *bytes.Buffer) error {
+		return s.SnapshotExport(w, FormatBinary)
+	})
+}
@@ -0,0 +1,167 @@
+package ngcat
+
+// CacheStats 缓存统计信息
+type CacheStats struct {
+	// HitCount freecache命中次数
+	HitCount int64
+	// MissCount freecache未命中次数
+	MissCount int64
+	// EntryCount freecache中的条目数量
+	EntryCount int64
+	// EvacuateCount 因空间不足被淘汰的条目数量
+	EvacuateCount int64
+	// ExpiredCount 过期被清除的条目数量
+	ExpiredCount int64
+	// PersistEntries 持久化数据（永久缓存）中的条目数量
+	PersistEntries int64
+	// Promotions 读取时从持久化数据写回freecache的次数
+	Promotions int64
+	// PromotionsDeduplicated 因同一键的写回正在进行或刚刚完成而跳过的写回次数
+	PromotionsDeduplicated int64
+	// RecoveredEntries 启动时以RecoverPartial策略从损坏的持久化文件中恢复的条目数量
+	RecoveredEntries int64
+	// PersistSkippedTicks 因其他保存正在进行、tick在上一次保存期间到达或间隔被拉长而跳过的定时持久化次数
+	PersistSkippedTicks int64
+	// Compactions 压缩持久化文件的次数，包括Compact和PersistConfig.AutoCompactRatio触发的压缩
+	Compactions int64
+	// CompactedBytes 压缩持久化文件累计回收的字节数
+	CompactedBytes int64
+	// BloomNegatives 布隆过滤器确定键不存在、未读取持久化数据的次数（见WithPersistBloomFilter）
+	BloomNegatives int64
+	// BloomFalsePositives 布隆过滤器报告可能存在、但持久化数据中没有的次数
+	BloomFalsePositives int64
+	// ValueSizes 写入的值大小分布（字节），以下直方图均需通过WithHistograms启用，未启用时为零值，
+	// 计数为采样后的次数
+	ValueSizes Histogram
+	// GetLatencyHit freecache命中的读取延迟分布（秒）
+	GetLatencyHit Histogram
+	// GetLatencyFallback 从持久化数据回退的读取延迟分布（秒）
+	GetLatencyFallback Histogram
+	// GetLatencyMiss 未命中的读取延迟分布（秒）
+	GetLatencyMiss Histogram
+}
+
+// Stats 返回缓存统计信息
+func (ng *NGCache) Stats() CacheStats {
+	ng.persistDataMutex.RLock()
+	persistEntries := len(ng.persistData)
+	recovered := ng.recoveredEntries
+	ng.persistDataMutex.RUnlock()
+
+	stats := CacheStats{
+		EntryCount:             ng.storeEntryCount(),
+		PersistEntries:         int64(persistEntries),
+		Promotions:             ng.promotions.Load(),
+		PromotionsDeduplicated: ng.promotionsDeduped.Load(),
+		RecoveredEntries:       recovered,
+		PersistSkippedTicks:    ng.persistSkipped.Load(),
+		Compactions:            ng.compactions.Load(),
+		CompactedBytes:         ng.compactedBytes.Load(),
+		BloomNegatives:         ng.bloomNegatives.Load(),
+		BloomFalsePositives:    ng.bloomFalsePositives.Load(),
+	}
+	if c, ok := ng.ownFreecache(); ok {
+		stats.HitCount = c.HitCount()
+		stats.MissCount = c.MissCount()
+		stats.EvacuateCount = c.EvacuateCount()
+		stats.ExpiredCount = c.ExpiredCount()
+	}
+	ng.histogramStats(&stats)
+	return stats
+}
+
+// HitRate 返回freecache的命中率，范围为[0,1]，尚无读取时返回0
+//
+// 命中率由Stats的HitCount和MissCount计算，从持久化数据回退读取到的永久缓存计为未命中。
+func (ng *NGCache) HitRate() float64 {
+	stats := ng.Stats()
+	total := stats.HitCount + stats.MissCount
+	if total == 0 {
+		return 0
+	}
+	return float64(stats.HitCount) / float64(total)
+}
+
+// ResetStats 将freecache的统计计数（命中、未命中、淘汰、过期等）、写回次数、跳过的定时持久化次数、布隆过滤器计数和直方图清零，
+// 条目数量和持久化数据不受影响；NewNGCacheWithStore传入的存储可能与应用共享，其统计计数不会被清零
+func (ng *NGCache) ResetStats() {
+	if c, ok := ng.ownFreecache(); ok {
+		c.ResetStatistics()
+	}
+	ng.promotions.Store(0)
+	ng.promotionsDeduped.Store(0)
+	ng.persistSkipped.Store(0)
+	ng.compactions.Store(0)
+	ng.compactedBytes.Store(0)
+	ng.bloomNegatives.Store(0)
+	ng.bloomFalsePositives.Store(0)
+	ng.resetHistograms()
+}
+
+// LowLevelStats freecache自身的统计信息
+//
+// 与Stats不同，这里只反映freecache：从持久化数据回退读取到的永久缓存在freecache中
+// 记为未命中，被淘汰但仍在持久化数据中的永久缓存也不计入EntryCount。
+type LowLevelStats struct {
+	// HitCount 命中次数
+	HitCount int64
+	// MissCount 未命中次数
+	MissCount int64
+	// LookupCount 查找次数
+	LookupCount int64
+	// HitRate 命中率
+	HitRate float64
+	// EntryCount 条目数量
+	EntryCount int64
+	// EvacuateCount 因空间不足被淘汰的条目数量
+	EvacuateCount int64
+	// ExpiredCount 过期被清除的条目数量
+	ExpiredCount int64
+	// OverwriteCount 覆盖写入的次数
+	OverwriteCount int64
+	// TouchedCount 刷新过期时间的次数
+	TouchedCount int64
+	// AverageAccessTime 条目的平均访问时间（Unix秒）
+	AverageAccessTime int64
+}
+
+// LowLevelStats 返回freecache的统计信息，使用NewNGCacheWithStore传入的存储时只有EntryCount（本实例写入的存活键数）
+func (ng *NGCache) LowLevelStats() LowLevelStats {
+	c, ok := ng.ownFreecache()
+	if !ok {
+		return LowLevelStats{EntryCount: ng.storeEntryCount()}
+	}
+	return LowLevelStats{
+		HitCount:          c.HitCount(),
+		MissCount:         c.MissCount(),
+		LookupCount:       c.LookupCount(),
+		HitRate:           c.HitRate(),
+		EntryCount:        c.EntryCount(),
+		EvacuateCount:     c.EvacuateCount(),
+		ExpiredCount:      c.ExpiredCount(),
+		OverwriteCount:    c.OverwriteCount(),
+		TouchedCount:      c.TouchedCount(),
+		AverageAccessTime: c.AverageAccessTime(),
+	}
+}
+
+// LoadFactor 返回freecache中存活条目占用的字节数与缓存容量之比
+//
+// 占用按每个条目的头部、键和值长度估算，需要遍历所有条目，适合在健康检查中低频调用。
+// freecache按键哈希分为256个分段，各分段独立淘汰，因此在负载因子接近1之前就可能开始淘汰条目。
+func (ng *NGCache) LoadFactor() float64 {
+	var used int64
+	if ng.capacity == 0 {
+		return 0
+	}
+	ng.storeRange(func(key, value []byte, _ uint32) bool {
+		used += int64(freecacheEntryHeader + len(key) + len(value))
+		return true
+	})
+	return float64(used) / float64(ng.capacity)
+}
+
+// IsNearCapacity 负载因子达到threshold时返回true，用于健康检查和告警
+func (ng *NGCache) IsNearCapacity(threshold float64) bool {
+	return ng.LoadFactor() >= threshold
+}
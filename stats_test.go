@@ -0,0 +1,99 @@
+package ngcat
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestLowLevelStatsEviction(t *testing.T) {
+	nc := NewNGCache(512*1024, nil)
+	defer nc.Close()
+
+	value := make([]byte, 400)
+	for i := 0; i < 5000; i++ {
+		nc.SetBytes(fmt.Sprintf("key_%d", i), value, 60)
+	}
+	low := nc.LowLevelStats()
+	if low.EvacuateCount == 0 {
+		t.Fatalf("overfilled cache reported no evictions: %+v", low)
+	}
+	if low.EntryCount >= 5000 {
+		t.Fatalf("EntryCount = %d", low.EntryCount)
+	}
+
+	nc.SetBytes("key_0", value, 60)
+	nc.SetBytes("key_0", value, 60)
+	nc.GetBytes("key_0")
+	nc.GetBytes("missing")
+	low = nc.LowLevelStats()
+	if low.OverwriteCount == 0 || low.HitCount == 0 || low.MissCount == 0 {
+		t.Fatalf("counters did not move: %+v", low)
+	}
+}
+
+func TestLowLevelStatsIgnoresPersistData(t *testing.T) {
+	nc := NewNGCache(1024*1024, nil, WithPromotePolicy(PromoteNever, 0))
+	defer nc.Close()
+
+	nc.SetString("k", "v", 0)
+	nc.cache.Del([]byte("k"))
+	nc.GetString("k")
+	if low := nc.LowLevelStats(); low.MissCount != 1 || low.EntryCount != 0 {
+		t.Fatalf("LowLevelStats = %+v", low)
+	}
+	if s := nc.Stats(); s.PersistEntries != 1 {
+		t.Fatalf("Stats = %+v", s)
+	}
+}
+
+func TestLoadFactor(t *testing.T) {
+	nc := NewNGCache(1024*1024, nil)
+	defer nc.Close()
+
+	if lf := nc.LoadFactor(); lf != 0 {
+		t.Fatalf("empty cache load factor = %f", lf)
+	}
+
+	// 每个条目占用 24 + 8 + 100 = 132 字节，写入总量为容量的1.5倍，
+	// 各分段写满后开始淘汰，存活条目仍占容量的80%以上
+	value := make([]byte, 100)
+	for i := 0; i < 1024*1024*3/2/132; i++ {
+		nc.SetBytes(fmt.Sprintf("key%05d", i), value, 0)
+	}
+	if lf := nc.LoadFactor(); lf < 0.8 || lf > 1 {
+		t.Fatalf("load factor = %f", lf)
+	}
+	if !nc.IsNearCapacity(0.8) {
+		t.Fatal("IsNearCapacity(0.8) should be true")
+	}
+	if nc.IsNearCapacity(1.01) {
+		t.Fatal("IsNearCapacity(1.01) should be false")
+	}
+}
+
+func TestHitRateAndResetStats(t *testing.T) {
+	nc := NewNGCache(1024*1024, nil)
+	defer nc.Close()
+
+	if rate := nc.HitRate(); rate != 0 {
+		t.Fatalf("HitRate with no reads = %f", rate)
+	}
+
+	nc.SetString("k", "v", 60)
+	for i := 0; i < 3; i++ {
+		nc.GetString("k")
+	}
+	nc.GetString("missing")
+	if rate := nc.HitRate(); rate != 0.75 {
+		t.Fatalf("HitRate = %f, want 0.75", rate)
+	}
+
+	nc.ResetStats()
+	stats := nc.Stats()
+	if stats.HitCount != 0 || stats.MissCount != 0 || nc.HitRate() != 0 {
+		t.Fatalf("counters not reset: %+v", stats)
+	}
+	if stats.EntryCount != 1 {
+		t.Fatalf("ResetStats should not drop entries: %+v", stats)
+	}
+}
@@ -0,0 +1,339 @@
+package ngcat
+
+import (
+	"sync"
+
+	"github.com/coocood/freecache"
+)
+
+// ByteStore NGCache底层存放所有条目的字节存储，默认为freecache
+//
+// *freecache.Cache直接实现了该接口，可以通过NewNGCacheWithStore传入已调优或与应用其他部分共享的实例；
+// 也可以为ristretto、bigcache等实现适配器。Get、TTL在键不存在或已过期时必须返回错误，
+// TTL对永久条目（expireSeconds<=0写入）返回0。
+type ByteStore interface {
+	// Set 写入键值，expireSeconds<=0表示永不过期
+	Set(key, value []byte, expireSeconds int) error
+	// Get 读取键的值
+	Get(key []byte) ([]byte, error)
+	// Del 删除键，返回键是否存在
+	Del(key []byte) bool
+	// TTL 返回键的剩余过期秒数
+	TTL(key []byte) (uint32, error)
+	// EntryCount 返回条目数量
+	EntryCount() int64
+}
+
+var (
+	_ ByteStore = (*freecache.Cache)(nil)
+	_ ByteStore = (*MapStore)(nil)
+)
+
+// freecache实现了以下可选方法，其他存储没有实现时NGCache以ByteStore的方法模拟
+type (
+	peekStore interface {
+		Peek(key []byte) ([]byte, error)
+		PeekFn(key []byte, fn func([]byte) error) error
+	}
+	getFnStore interface {
+		GetFn(key []byte, fn func([]byte) error) error
+	}
+	touchStore interface {
+		Touch(key []byte, expireSeconds int) error
+	}
+	expirationStore interface {
+		GetWithExpiration(key []byte) ([]byte, uint32, error)
+	}
+)
+
+// storeGet 读取键的值
+//
+// 通过接口调用时[]byte(key)会逃逸到堆上，freecache直接调用以保持未命中的读取不分配内存。
+func (ng *NGCache) storeGet(key string) ([]byte, error) {
+	if c, ok := ng.cache.(*freecache.Cache); ok {
+		return c.Get([]byte(key))
+	}
+	return ng.cache.Get([]byte(key))
+}
+
+// storePeek 读取键的值，不更新存储的访问统计（存储支持时）
+func (ng *NGCache) storePeek(key string) ([]byte, error) {
+	if s, ok := ng.cache.(peekStore); ok {
+		return s.Peek([]byte(key))
+	}
+	return ng.cache.Get([]byte(key))
+}
+
+// storePeekFn 以回调的形式读取键的值，不更新存储的访问统计（存储支持时）
+func (ng *NGCache) storePeekFn(key string, fn func([]byte) error) error {
+	if s, ok := ng.cache.(peekStore); ok {
+		return s.PeekFn([]byte(key), fn)
+	}
+	value, err := ng.cache.Get([]byte(key))
+	if err != nil {
+		return err
+	}
+	return fn(value)
+}
+
+// storeGetFn 以回调的形式读取键的值，freecache中fn直接访问其内部缓冲区
+func (ng *NGCache) storeGetFn(key string, fn func([]byte) error) error {
+	if s, ok := ng.cache.(getFnStore); ok {
+		return s.GetFn([]byte(key), fn)
+	}
+	value, err := ng.cache.Get([]byte(key))
+	if err != nil {
+		return err
+	}
+	return fn(value)
+}
+
+// storeGetWithExpiration 读取键的值和过期时间的Unix秒数，永久条目返回0
+func (ng *NGCache) storeGetWithExpiration(key string) ([]byte, uint32, error) {
+	if s, ok := ng.cache.(expirationStore); ok {
+		return s.GetWithExpiration([]byte(key))
+	}
+	value, err := ng.cache.Get([]byte(key))
+	if err != nil {
+		return nil, 0, err
+	}
+	ttl, err := ng.cache.TTL([]byte(key))
+	if err != nil {
+		return nil, 0, err
+	}
+	if ttl == 0 {
+		return value, 0, nil
+	}
+	return value, uint32(ng.clock.Now().Unix()) + ttl, nil
+}
+
+// storeTouch 将键的过期时间重置为expireSeconds秒后，调用方需持有键的分段锁
+func (ng *NGCache) storeTouch(key string, expireSeconds int) error {
+	if s, ok := ng.cache.(touchStore); ok {
+		return s.Touch([]byte(key), expireSeconds)
+	}
+	value, err := ng.cache.Get([]byte(key))
+	if err != nil {
+		return err
+	}
+	return ng.cache.Set([]byte(key), value, expireSeconds)
+}
+
+// storeSet 写入键值，共享存储中记录该键由本实例写入
+//
+// 先写入存储再记录，forgetOwned在锁内重新检查存储，因此不会丢失并发写入的记录。
+func (ng *NGCache) storeSet(key string, value []byte, expireSeconds int) error {
+	err := ng.cache.Set([]byte(key), value, expireSeconds)
+	if err == nil && ng.sharedStore {
+		ng.ownedMutex.Lock()
+		ng.ownedKeys[key] = struct{}{}
+		ng.ownedMutex.Unlock()
+	}
+	return err
+}
+
+// storeDel 删除键，返回键是否存在
+func (ng *NGCache) storeDel(key string) bool {
+	affected := ng.cache.Del([]byte(key))
+	if ng.sharedStore {
+		ng.ownedMutex.Lock()
+		delete(ng.ownedKeys, key)
+		ng.ownedMutex.Unlock()
+	}
+	return affected
+}
+
+// storeRange 遍历存储中的所有存活条目，共享存储只遍历本实例写入的键
+func (ng *NGCache) storeRange(fn func(key, value []byte, expireAt uint32) bool) {
+	if ng.sharedStore {
+		ng.rangeOwned(fn)
+		return
+	}
+	if c, ok := ng.cache.(*freecache.Cache); ok {
+		it := c.NewIterator()
+		for entry := it.Next(); entry != nil; entry = it.Next() {
+			if !fn(entry.Key, entry.Value, entry.ExpireAt) {
+				return
+			}
+		}
+	}
+}
+
+// rangeOwned 遍历共享存储中本实例写入的存活条目，已过期或被淘汰的键不再记录
+func (ng *NGCache) rangeOwned(fn func(key, value []byte, expireAt uint32) bool) {
+	ng.ownedMutex.Lock()
+	keys := make([]string, 0, len(ng.ownedKeys))
+	for key := range ng.ownedKeys {
+		keys = append(keys, key)
+	}
+	ng.ownedMutex.Unlock()
+
+	now := uint32(ng.clock.Now().Unix())
+	for _, key := range keys {
+		// TTL检查过期且不计入存储的命中统计，Peek同样不计入
+		ttl, err := ng.cache.TTL([]byte(key))
+		var value []byte
+		if err == nil {
+			value, err = ng.storePeek(key)
+		}
+		if err != nil {
+			ng.forgetOwned(key)
+			continue
+		}
+		var expireAt uint32
+		if ttl > 0 {
+			expireAt = now + ttl
+		}
+		if !fn([]byte(key), value, expireAt) {
+			return
+		}
+	}
+}
+
+// forgetOwned 键已不在共享存储中时删除其记录
+func (ng *NGCache) forgetOwned(key string) {
+	ng.ownedMutex.Lock()
+	defer ng.ownedMutex.Unlock()
+	if _, err := ng.cache.TTL([]byte(key)); err != nil {
+		delete(ng.ownedKeys, key)
+	}
+}
+
+// storeEntryCount 返回存储中的条目数量，共享存储只统计本实例写入的存活键
+func (ng *NGCache) storeEntryCount() int64 {
+	if !ng.sharedStore {
+		return ng.cache.EntryCount()
+	}
+	var n int64
+	ng.rangeOwned(func(_, _ []byte, _ uint32) bool {
+		n++
+		return true
+	})
+	return n
+}
+
+// ownFreecache 返回NGCache自己创建的freecache，共享存储的统计包含应用其他部分的条目，返回false
+func (ng *NGCache) ownFreecache() (*freecache.Cache, bool) {
+	c, ok := ng.cache.(*freecache.Cache)
+	return c, ok && !ng.sharedStore
+}
+
+// MapStore 以map和读写锁实现的ByteStore，不限制容量、不淘汰条目
+//
+// 作为实现其他存储适配器的参考，也适合在测试中替代freecache。过期的条目在读取时删除。
+type MapStore struct {
+	mu      sync.RWMutex
+	entries map[string]mapStoreEntry
+	clock   Clock
+}
+
+// mapStoreEntry MapStore中的一个条目
+type mapStoreEntry struct {
+	value    []byte
+	expireAt uint32
+}
+
+// NewMapStore 创建MapStore，clock为nil时使用系统时间
+func NewMapStore(clock Clock) *MapStore {
+	if clock == nil {
+		clock = realClock{}
+	}
+	return &MapStore{entries: make(map[string]mapStoreEntry), clock: clock}
+}
+
+// now 当前时间的Unix秒数
+func (s *MapStore) now() uint32 {
+	return uint32(s.clock.Now().Unix())
+}
+
+// Set 写入键值，值会被复制
+func (s *MapStore) Set(key, value []byte, expireSeconds int) error {
+	entry := mapStoreEntry{value: append([]byte(nil), value...)}
+	if expireSeconds > 0 {
+		entry.expireAt = s.now() + uint32(expireSeconds)
+	}
+	s.mu.Lock()
+	s.entries[string(key)] = entry
+	s.mu.Unlock()
+	return nil
+}
+
+// lookup 查找在now时未过期的条目，过期的条目被删除
+func (s *MapStore) lookup(key []byte, now uint32) (mapStoreEntry, bool) {
+	s.mu.RLock()
+	entry, ok := s.entries[string(key)]
+	s.mu.RUnlock()
+	if !ok {
+		return entry, false
+	}
+	if entry.expireAt != 0 && entry.expireAt <= now {
+		s.mu.Lock()
+		if current, ok := s.entries[string(key)]; ok && current.expireAt == entry.expireAt {
+			delete(s.entries, string(key))
+		}
+		s.mu.Unlock()
+		return entry, false
+	}
+	return entry, true
+}
+
+// Get 读取键的值，返回值的副本
+func (s *MapStore) Get(key []byte) ([]byte, error) {
+	entry, ok := s.lookup(key, s.now())
+	if !ok {
+		return nil, freecache.ErrNotFound
+	}
+	return append([]byte(nil), entry.value...), nil
+}
+
+// Del 删除键
+func (s *MapStore) Del(key []byte) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	_, ok := s.entries[string(key)]
+	delete(s.entries, string(key))
+	return ok
+}
+
+// TTL 返回键的剩余过期秒数，永久条目返回0
+//
+// 过期判断和剩余时间使用同一次读取的时间，跨越秒边界时剩余时间不会下溢。
+func (s *MapStore) TTL(key []byte) (uint32, error) {
+	now := s.now()
+	entry, ok := s.lookup(key, now)
+	if !ok {
+		return 0, freecache.ErrNotFound
+	}
+	if entry.expireAt == 0 {
+		return 0, nil
+	}
+	return entry.expireAt - now, nil
+}
+
+// EntryCount 返回条目数量，包括尚未被读取删除的过期条目
+func (s *MapStore) EntryCount() int64 {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return int64(len(s.entries))
+}
+
+// Range 遍历所有未过期的条目，expireAt为过期时间的Unix秒数，0表示永不过期；
+// 遍历的是调用时的快照，fn中可以读写MapStore，fn返回false时停止
+func (s *MapStore) Range(fn func(key, value []byte, expireAt uint32) bool) {
+	now := s.now()
+	s.mu.RLock()
+	keys := make([]string, 0, len(s.entries))
+	entries := make([]mapStoreEntry, 0, len(s.entries))
+	for key, entry := range s.entries {
+		if entry.expireAt == 0 || entry.expireAt > now {
+			keys = append(keys, key)
+			entries = append(entries, entry)
+		}
+	}
+	s.mu.RUnlock()
+	for i, key := range keys {
+		if !fn([]byte(key), append([]byte(nil), entries[i].value...), entries[i].expireAt) {
+			return
+		}
+	}
+}
@@ -0,0 +1,159 @@
+package ngcat
+
+import (
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/coocood/freecache"
+)
+
+func TestNGCacheWithMapStore(t *testing.T) {
+	clock := newFakeClock()
+	store := NewMapStore(clock)
+	dir := t.TempDir()
+	config := &PersistConfig{
+		Enabled:  true,
+		FilePath: dir,
+		FileName: "cache.bin",
+		Format:   FormatBinary,
+		Interval: time.Hour,
+	}
+	nc := NewNGCacheWithStore(store, config, WithClock(clock))
+
+	if err := nc.SetString("user:1", "alice", 0); err != nil {
+		t.Fatal(err)
+	}
+	if err := nc.SetInt64("user:2", 42, 10); err != nil {
+		t.Fatal(err)
+	}
+	if v, err := nc.GetString("user:1"); err != nil || v != "alice" {
+		t.Fatalf("GetString: %q, %v", v, err)
+	}
+	if v, err := nc.GetInt64("user:2"); err != nil || v != 42 {
+		t.Fatalf("GetInt64: %d, %v", v, err)
+	}
+	if store.EntryCount() != 2 || nc.Stats().EntryCount != 2 {
+		t.Fatalf("store has %d entries, stats %d", store.EntryCount(), nc.Stats().EntryCount)
+	}
+
+	var keys []string
+	nc.ScanPrefix("user:", func(key string, _ []byte) bool {
+		keys = append(keys, key)
+		return true
+	})
+	if len(keys) != 2 {
+		t.Fatalf("ScanPrefix over MapStore: %v", keys)
+	}
+
+	clock.Add(11 * time.Second)
+	if _, err := nc.GetInt64("user:2"); !errors.Is(err, ErrKeyNotFound) {
+		t.Fatalf("expired key: %v", err)
+	}
+
+	// 被淘汰的永久缓存仍可从持久化数据读取
+	store.Del([]byte("user:1"))
+	if v, err := nc.GetString("user:1"); err != nil || v != "alice" {
+		t.Fatalf("fallback to persist data: %q, %v", v, err)
+	}
+	if err := nc.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if v, err := ReadEntry(filepath.Join(dir, "cache.bin"), "user:1"); err != nil || len(v) == 0 {
+		t.Fatalf("not persisted: %v, %v", v, err)
+	}
+
+	// 重新加载到新的存储
+	reloaded := NewMapStore(clock)
+	nc = NewNGCacheWithStore(reloaded, config, WithClock(clock))
+	defer nc.Close()
+	if _, err := reloaded.Get([]byte("user:1")); err != nil {
+		t.Fatalf("load did not fill store: %v", err)
+	}
+}
+
+// steppingClock 每次读取时间后推进step，模拟两次读取之间跨越秒边界
+type steppingClock struct {
+	*fakeClock
+	step time.Duration
+}
+
+func (c steppingClock) Now() time.Time {
+	now := c.fakeClock.Now()
+	c.fakeClock.Add(c.step)
+	return now
+}
+
+func TestMapStoreTTLAcrossSecondBoundary(t *testing.T) {
+	store := NewMapStore(steppingClock{fakeClock: newFakeClock(), step: 2 * time.Second})
+	store.Set([]byte("k"), []byte("v"), 3)
+	ttl, err := store.TTL([]byte("k"))
+	if err != nil || ttl != 1 {
+		t.Fatalf("TTL = %d, %v, want 1", ttl, err)
+	}
+}
+
+func TestNGCacheWithSharedFreecache(t *testing.T) {
+	fc := freecache.NewCache(1024 * 1024)
+	fc.Set([]byte("other"), []byte("app data"), 0)
+	nc := NewNGCacheWithStore(fc, nil)
+	defer nc.Close()
+
+	if err := nc.SetString("k", "v", 0); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := fc.Get([]byte("k")); err != nil {
+		t.Fatalf("write not visible in shared freecache: %v", err)
+	}
+	// 共享freecache的统计包含应用其他部分的读写，不属于本实例
+	fc.Get([]byte("other"))
+	if stats := nc.Stats(); stats.HitCount != 0 || stats.EntryCount != 1 {
+		t.Fatalf("shared freecache statistics reported: %+v", stats)
+	}
+	nc.ResetStats()
+	if fc.HitCount() == 0 {
+		t.Fatal("ResetStats cleared the shared freecache counters")
+	}
+	if v, err := fc.Get([]byte("other")); err != nil || string(v) != "app data" {
+		t.Fatalf("shared entry changed: %q, %v", v, err)
+	}
+	if nc.LoadFactor() != 0 {
+		t.Fatal("LoadFactor of injected store should be 0")
+	}
+}
+
+func TestSharedStoreForeignKeys(t *testing.T) {
+	fc := freecache.NewCache(1024 * 1024)
+	fc.Set([]byte("other"), []byte("app data"), 0)
+	nc := NewNGCacheWithStore(fc, nil)
+	defer nc.Close()
+
+	nc.SetString("k1", "v", 0)
+	nc.SetString("k2", "v", 60)
+	if keys := nc.allKeys(); len(keys) != 2 || keys[0] != "k1" || keys[1] != "k2" {
+		t.Fatalf("allKeys = %v", keys)
+	}
+	if keys, err := nc.KeysMatching("^k"); err != nil || len(keys) != 2 {
+		t.Fatalf("KeysMatching = %v, %v", keys, err)
+	}
+	if n := nc.LowLevelStats().EntryCount; n != 2 {
+		t.Fatalf("LowLevelStats().EntryCount = %d", n)
+	}
+
+	// 被存储淘汰或由应用删除的键不再被遍历
+	fc.Del([]byte("k2"))
+	if keys := nc.allKeys(); len(keys) != 1 || keys[0] != "k1" {
+		t.Fatalf("allKeys after external delete = %v", keys)
+	}
+
+	if n := nc.Flush(); n != 1 {
+		t.Fatalf("Flush removed %d keys", n)
+	}
+	if v, err := fc.Get([]byte("other")); err != nil || string(v) != "app data" {
+		t.Fatalf("foreign key after Flush: %q, %v", v, err)
+	}
+	if keys := nc.allKeys(); len(keys) != 0 {
+		t.Fatalf("allKeys after Flush = %v", keys)
+	}
+}
@@ -0,0 +1,90 @@
+// Package testutil 提供在单元测试中使用NGCache的辅助函数
+package testutil
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"ngcat"
+)
+
+// DefaultSize NewTestingCache创建的缓存大小
+const DefaultSize = 1024 * 1024
+
+// NewTestingCache 创建持久化文件位于t.TempDir()的缓存，测试结束时自动关闭
+//
+// 持久化使用二进制格式，定时持久化间隔为一小时，测试中需要落盘时调用Save或Close。
+// 临时目录由testing包在测试结束后删除，不会在工作目录中留下文件。
+func NewTestingCache(t testing.TB, opts ...ngcat.Option) *ngcat.NGCache {
+	t.Helper()
+	config := &ngcat.PersistConfig{
+		Enabled:  true,
+		FilePath: t.TempDir(),
+		FileName: "ngcat.bin",
+		Format:   ngcat.FormatBinary,
+		Interval: time.Hour,
+	}
+	cache, err := ngcat.Open(DefaultSize, config, opts...)
+	if err != nil {
+		t.Fatalf("testutil: open cache: %v", err)
+	}
+	t.Cleanup(func() {
+		if err := cache.Close(); err != nil {
+			t.Errorf("testutil: close cache: %v", err)
+		}
+	})
+	return cache
+}
+
+// AssertKey 断言键存在且值为expected，值按GetString读取，SetBytes写入的值同样适用
+func AssertKey(t testing.TB, cache *ngcat.NGCache, key, expected string) {
+	t.Helper()
+	value, err := cache.GetString(key)
+	if err != nil {
+		t.Fatalf("key %q: %v", key, err)
+	}
+	if value != expected {
+		t.Fatalf("key %q = %q, want %q", key, value, expected)
+	}
+}
+
+// AssertMissing 断言键不存在
+func AssertMissing(t testing.TB, cache *ngcat.NGCache, key string) {
+	t.Helper()
+	value, err := cache.GetBytes(key)
+	if err == nil {
+		t.Fatalf("key %q = %q, want missing", key, value)
+	}
+	if !errors.Is(err, ngcat.ErrKeyNotFound) {
+		t.Fatalf("key %q: %v, want ErrKeyNotFound", key, err)
+	}
+}
+
+// MustGetString 读取字符串值，出错时终止测试
+func MustGetString(t testing.TB, cache *ngcat.NGCache, key string) string {
+	t.Helper()
+	value, err := cache.GetString(key)
+	if err != nil {
+		t.Fatalf("GetString(%q): %v", key, err)
+	}
+	return value
+}
+
+// MustGetInt64 读取int64类型值，出错时终止测试
+func MustGetInt64(t testing.TB, cache *ngcat.NGCache, key string) int64 {
+	t.Helper()
+	value, err := cache.GetInt64(key)
+	if err != nil {
+		t.Fatalf("GetInt64(%q): %v", key, err)
+	}
+	return value
+}
+
+// MustGetJSON 将JSON值解码到out，出错时终止测试
+func MustGetJSON(t testing.TB, cache *ngcat.NGCache, key string, out interface{}) {
+	t.Helper()
+	if err := cache.GetJSON(key, out); err != nil {
+		t.Fatalf("GetJSON(%q): %v", key, err)
+	}
+}
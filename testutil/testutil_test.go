@@ -0,0 +1,73 @@
+package testutil
+
+import (
+	"errors"
+	"os"
+	"testing"
+
+	"ngcat"
+)
+
+func TestNewTestingCache(t *testing.T) {
+	var cache *ngcat.NGCache
+	t.Run("inner", func(t *testing.T) {
+		cache = NewTestingCache(t)
+		if err := cache.SetString("k", "v", 0); err != nil {
+			t.Fatal(err)
+		}
+		AssertKey(t, cache, "k", "v")
+		AssertMissing(t, cache, "missing")
+		if err := cache.Save(); err != nil {
+			t.Fatal(err)
+		}
+	})
+	// 子测试结束后缓存已关闭
+	if err := cache.ReloadConfig(&ngcat.PersistConfig{}); !errors.Is(err, ngcat.ErrClosed) {
+		t.Fatal("cache not closed by cleanup")
+	}
+	if entries, _ := os.ReadDir("."); len(entries) != 2 {
+		t.Fatalf("test left files in the working directory: %v", entries)
+	}
+}
+
+func TestMustGet(t *testing.T) {
+	cache := NewTestingCache(t)
+	cache.SetString("s", "v", 0)
+	cache.SetInt64("n", 42, 0)
+	cache.SetJSON("j", map[string]int{"a": 1}, 0)
+
+	if v := MustGetString(t, cache, "s"); v != "v" {
+		t.Fatalf("MustGetString = %q", v)
+	}
+	if v := MustGetInt64(t, cache, "n"); v != 42 {
+		t.Fatalf("MustGetInt64 = %d", v)
+	}
+	var out map[string]int
+	MustGetJSON(t, cache, "j", &out)
+	if out["a"] != 1 {
+		t.Fatalf("MustGetJSON = %v", out)
+	}
+
+	// 出错时通过Fatalf终止测试
+	rec := &recordingTB{TB: t}
+	func() {
+		defer func() { recover() }()
+		MustGetInt64(rec, cache, "missing")
+	}()
+	if !rec.failed {
+		t.Fatal("MustGetInt64 on a missing key did not fail the test")
+	}
+}
+
+// recordingTB 记录Fatalf调用而不终止外层测试
+type recordingTB struct {
+	testing.TB
+	failed bool
+}
+
+func (r *recordingTB) Helper() {}
+
+func (r *recordingTB) Fatalf(format string, args ...interface{}) {
+	r.failed = true
+	panic("fatal")
+}
@@ -0,0 +1,63 @@
+package ngcat
+
+import (
+	"context"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// tracerName 创建Tracer时使用的instrumentation名称
+const tracerName = "ngcat"
+
+// setTyped 写入值，配置了Tracer时创建名为ngcache.Set的span，启用WithStrictTypes时加上类型标记
+func (ng *NGCache) setTyped(typ, key string, value []byte, expireSeconds int) error {
+	value = ng.tagValue(typ, value)
+	if ng.tracer == nil {
+		return ng.setWithPersist(key, value, expireSeconds)
+	}
+	_, span := ng.tracer.Start(context.Background(), "ngcache.Set", trace.WithAttributes(
+		attribute.String("cache.key", key),
+		attribute.String("cache.type", typ),
+	))
+	err := ng.setWithPersist(key, value, expireSeconds)
+	endSpan(span, err)
+	return err
+}
+
+// getTyped 读取值，配置了Tracer时创建名为ngcache.Get的span，cache.hit表示键是否存在；
+// 启用WithStrictTypes时检查并去掉类型标记
+func (ng *NGCache) getTyped(typ, key string) ([]byte, error) {
+	if ng.tracer == nil {
+		data, err := ng.getWithPersist(key)
+		if err != nil {
+			return nil, err
+		}
+		return ng.untagValue(typ, data)
+	}
+	_, span := ng.tracer.Start(context.Background(), "ngcache.Get", trace.WithAttributes(
+		attribute.String("cache.key", key),
+		attribute.String("cache.type", typ),
+	))
+	data, err := ng.getWithPersist(key)
+	span.SetAttributes(attribute.Bool("cache.hit", err == nil))
+	if err == nil {
+		data, err = ng.untagValue(typ, data)
+	}
+	if err == ErrKeyNotFound {
+		span.End() // 未命中不记为错误
+	} else {
+		endSpan(span, err)
+	}
+	return data, err
+}
+
+// endSpan 记录错误并结束span
+func endSpan(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
@@ -0,0 +1,46 @@
+package ngcat
+
+import (
+	"testing"
+
+	"go.opentelemetry.io/otel/attribute"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+func TestOTelTracer(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	tp := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+	nc := NewNGCache(1024*1024, nil, WithOTelTracer(tp))
+	defer nc.Close()
+
+	nc.SetString("k", "v", 0)
+	nc.GetString("k")
+	nc.GetInt64("missing")
+
+	spans := recorder.Ended()
+	if len(spans) != 3 {
+		t.Fatalf("got %d spans, want 3", len(spans))
+	}
+	want := []struct {
+		name string
+		typ  string
+		hit  attribute.Value
+	}{
+		{"ngcache.Set", "string", attribute.Value{}},
+		{"ngcache.Get", "string", attribute.BoolValue(true)},
+		{"ngcache.Get", "int64", attribute.BoolValue(false)},
+	}
+	for i, span := range spans {
+		attrs := make(map[attribute.Key]attribute.Value)
+		for _, kv := range span.Attributes() {
+			attrs[kv.Key] = kv.Value
+		}
+		if span.Name() != want[i].name || attrs["cache.key"].AsString() == "" || attrs["cache.type"].AsString() != want[i].typ {
+			t.Errorf("span %d: %s %v", i, span.Name(), attrs)
+		}
+		if attrs["cache.hit"] != want[i].hit {
+			t.Errorf("span %d: cache.hit = %v, want %v", i, attrs["cache.hit"], want[i].hit)
+		}
+	}
+}
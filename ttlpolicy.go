@@ -0,0 +1,139 @@
+package ngcat
+
+import (
+	"sort"
+	"strings"
+	"time"
+)
+
+// TTLPolicy 键前缀的默认过期时间
+type TTLPolicy struct {
+	// Prefix 键前缀
+	Prefix string
+	// TTL 以TTLDefault写入该前缀的键时使用的过期时间，0表示永久缓存
+	TTL time.Duration
+}
+
+// SetTTLPolicy 设置键前缀的默认过期时间，前缀已设置时覆盖原有的过期时间
+//
+// 以TTLDefault写入时使用匹配的最长前缀的过期时间，没有匹配的前缀时使用WithDefaultTTL设置的默认值。
+// 修改只影响之后的写入，已写入的条目保持原有的过期时间；需要应用到已有的永久缓存时调用ReconcilePermanence。
+func (ng *NGCache) SetTTLPolicy(prefix string, ttl time.Duration) {
+	ng.ttlPoliciesMutex.Lock()
+	defer ng.ttlPoliciesMutex.Unlock()
+	for i := range ng.ttlPolicies {
+		if ng.ttlPolicies[i].Prefix == prefix {
+			ng.ttlPolicies[i].TTL = ttl
+			return
+		}
+	}
+	ng.ttlPolicies = append(ng.ttlPolicies, TTLPolicy{Prefix: prefix, TTL: ttl})
+	// 按前缀长度降序排列，查找时第一个匹配的就是最长前缀
+	sort.SliceStable(ng.ttlPolicies, func(i, j int) bool {
+		return len(ng.ttlPolicies[i].Prefix) > len(ng.ttlPolicies[j].Prefix)
+	})
+}
+
+// RemoveTTLPolicy 删除键前缀的默认过期时间，返回该前缀是否已设置
+func (ng *NGCache) RemoveTTLPolicy(prefix string) bool {
+	ng.ttlPoliciesMutex.Lock()
+	defer ng.ttlPoliciesMutex.Unlock()
+	for i := range ng.ttlPolicies {
+		if ng.ttlPolicies[i].Prefix == prefix {
+			ng.ttlPolicies = append(ng.ttlPolicies[:i], ng.ttlPolicies[i+1:]...)
+			return true
+		}
+	}
+	return false
+}
+
+// TTLPolicies 返回所有键前缀的默认过期时间，按前缀排序
+func (ng *NGCache) TTLPolicies() []TTLPolicy {
+	ng.ttlPoliciesMutex.RLock()
+	policies := append([]TTLPolicy(nil), ng.ttlPolicies...)
+	ng.ttlPoliciesMutex.RUnlock()
+	sort.Slice(policies, func(i, j int) bool { return policies[i].Prefix < policies[j].Prefix })
+	return policies
+}
+
+// defaultTTLFor 返回键匹配的最长前缀的过期时间，没有匹配的前缀时返回缓存的默认过期时间
+func (ng *NGCache) defaultTTLFor(key string) time.Duration {
+	ng.ttlPoliciesMutex.RLock()
+	defer ng.ttlPoliciesMutex.RUnlock()
+	for _, p := range ng.ttlPolicies {
+		if strings.HasPrefix(key, p.Prefix) {
+			return p.TTL
+		}
+	}
+	return ng.defaultTTL
+}
+
+// reconcileBatch ReconcilePermanence每批处理的键数量，每批之间释放锁
+const reconcileBatch = 256
+
+// ReconcilePermanence 按新的保留策略整理已有的永久缓存，policy对仍应永久保存的键返回true，返回被转为非永久的键数量
+//
+// policy返回false的键从持久化数据中删除，下一次保存的持久化文件不再包含这些键（WAL和增量格式同样记录删除）；
+// freecache中的值按TTLDefault解析的过期时间（SetTTLPolicy或WithDefaultTTL）重新写入，解析结果仍为永久时
+// 从freecache删除。
+//
+// 遍历时先不加锁地对键的快照调用policy，再按reconcileBatch个键一批加锁处理，每批之间释放锁，
+// 不会长时间阻塞读写。policy在锁外调用，可以是较慢的函数，但不能调用同一缓存的写入方法。
+func (ng *NGCache) ReconcilePermanence(policy func(key string) bool) (changed int) {
+	// 先写入合并中的永久缓存，避免删除后被再次写入持久化数据
+	ng.flushCoalesced()
+
+	ng.persistDataMutex.RLock()
+	keys := make([]string, 0, len(ng.persistData))
+	for key := range ng.persistData {
+		keys = append(keys, key)
+	}
+	ng.persistDataMutex.RUnlock()
+
+	var drop []string
+	for _, key := range keys {
+		if !policy(key) {
+			drop = append(drop, key)
+		}
+	}
+	for start := 0; start < len(drop); start += reconcileBatch {
+		batch := drop[start:min(start+reconcileBatch, len(drop))]
+		unlock := ng.lockKeys(batch...)
+		for _, key := range batch {
+			if ng.dropPermanence(key) {
+				changed++
+			}
+		}
+		unlock()
+	}
+	if changed > 0 {
+		ng.logger.Info("ngcat: 已按新的保留策略整理永久缓存", "checked", len(keys), "changed", changed)
+	}
+	return changed
+}
+
+// dropPermanence 从持久化数据删除键，并按默认过期时间重写freecache中的值，调用方需持有键的分段锁
+func (ng *NGCache) dropPermanence(key string) bool {
+	ng.dropCoalesced(key)
+	ng.persistDataMutex.Lock()
+	value, exists := ng.persistData[key]
+	delete(ng.persistData, key)
+	ng.persistDataMutex.Unlock()
+	// 快照之后被删除的键不需要处理
+	if !exists {
+		return false
+	}
+	ng.bloomNoteDelete()
+	ng.appendWAL(walOpDelete, key, nil)
+	ng.markDirty(key)
+
+	ng.forgetExpiry(key)
+	ttl := ng.resolveTTL(key, TTLDefault)
+	if ttl <= 0 || ng.storeSet(key, value, ttl) != nil {
+		ng.storeDel(key)
+		ng.noteDelete(key)
+		return true
+	}
+	ng.noteWrite(key, value, ttl, false)
+	return true
+}
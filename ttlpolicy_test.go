@@ -0,0 +1,186 @@
+package ngcat
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+// ttlOf 返回键在freecache中的剩余过期秒数，以及键是否为永久缓存
+func ttlOf(t *testing.T, nc *NGCache, key string) (uint32, bool) {
+	t.Helper()
+	ttl, err := nc.cache.TTL([]byte(key))
+	if err != nil {
+		t.Fatalf("%s: %v", key, err)
+	}
+	_, permanent := persisted(nc, key)
+	return ttl, permanent
+}
+
+func TestTTLPolicy(t *testing.T) {
+	config := &PersistConfig{
+		Enabled:  true,
+		FilePath: t.TempDir(),
+		FileName: "cache.bin",
+		Format:   FormatBinary,
+		Interval: time.Hour,
+	}
+	nc := NewNGCache(1024*1024, config, WithDefaultTTL(time.Minute))
+	defer nc.Close()
+	nc.SetTTLPolicy("session:", 30*time.Minute)
+	nc.SetTTLPolicy("cfg:", 0)
+	nc.SetTTLPolicy("cfg:secret:", time.Hour)
+
+	nc.SetString("session:1", "v", TTLDefault)
+	nc.SetString("cfg:mode", "v", TTLDefault)
+	nc.SetString("cfg:secret:key", "v", TTLDefault)
+	nc.SetString("other", "v", TTLDefault)
+	// 显式的过期时间不受前缀策略影响
+	nc.SetString("session:2", "v", 10)
+
+	cases := []struct {
+		key       string
+		ttl       uint32
+		permanent bool
+	}{
+		{"session:1", 1800, false},
+		{"cfg:mode", 0, true},
+		{"cfg:secret:key", 3600, false},
+		{"other", 60, false},
+		{"session:2", 10, false},
+	}
+	for _, c := range cases {
+		ttl, permanent := ttlOf(t, nc, c.key)
+		if ttl != c.ttl || permanent != c.permanent {
+			t.Errorf("%s: ttl = %d, permanent = %v; want %d, %v", c.key, ttl, permanent, c.ttl, c.permanent)
+		}
+	}
+
+	want := []TTLPolicy{{"cfg:", 0}, {"cfg:secret:", time.Hour}, {"session:", 30 * time.Minute}}
+	if got := nc.TTLPolicies(); !reflect.DeepEqual(got, want) {
+		t.Fatalf("policies = %v", got)
+	}
+
+	// 修改策略只影响之后的写入
+	nc.SetTTLPolicy("session:", 5*time.Minute)
+	nc.SetString("session:3", "v", TTLDefault)
+	if ttl, _ := ttlOf(t, nc, "session:3"); ttl != 300 {
+		t.Fatalf("session:3 ttl = %d", ttl)
+	}
+	if ttl, _ := ttlOf(t, nc, "session:1"); ttl != 1800 {
+		t.Fatalf("session:1 ttl changed to %d", ttl)
+	}
+
+	if !nc.RemoveTTLPolicy("cfg:") || nc.RemoveTTLPolicy("cfg:") {
+		t.Fatal("RemoveTTLPolicy should report whether the prefix was set")
+	}
+	nc.SetString("cfg:other", "v", TTLDefault)
+	if ttl, permanent := ttlOf(t, nc, "cfg:other"); ttl != 60 || permanent {
+		t.Fatalf("cfg:other after removal: ttl = %d, permanent = %v", ttl, permanent)
+	}
+}
+
+func TestTTLSentinels(t *testing.T) {
+	nc := NewNGCache(1024*1024, nil, WithDefaultTTL(time.Minute), WithStrictTTL())
+	defer nc.Close()
+
+	nc.SetString("permanent", "v", TTLPermanent)
+	if ttl, permanent := ttlOf(t, nc, "permanent"); ttl != 0 || !permanent {
+		t.Fatalf("TTLPermanent: ttl=%d permanent=%v", ttl, permanent)
+	}
+	nc.SetString("default", "v", TTLDefault)
+	if ttl, permanent := ttlOf(t, nc, "default"); ttl != 60 || permanent {
+		t.Fatalf("TTLDefault: ttl=%d permanent=%v", ttl, permanent)
+	}
+
+	// 所有类型化写入都拒绝其他负数
+	setters := map[string]func(key string) error{
+		"SetString": func(key string) error { return nc.SetString(key, "v", -5) },
+		"SetBytes":  func(key string) error { return nc.SetBytes(key, []byte("v"), -5) },
+		"SetInt64":  func(key string) error { return nc.SetInt64(key, 1, -5) },
+		"SetBool":   func(key string) error { return nc.SetBool(key, true, -5) },
+		"SetJSON":   func(key string) error { return nc.SetJSON(key, map[string]int{"a": 1}, -5) },
+		"SetAny":    func(key string) error { return nc.SetAny(key, 1, -5) },
+	}
+	for name, set := range setters {
+		if err := set(name); !errors.Is(err, ErrInvalidTTL) {
+			t.Fatalf("%s: err = %v, want ErrInvalidTTL", name, err)
+		}
+		if _, err := nc.GetBytes(name); !errors.Is(err, ErrKeyNotFound) {
+			t.Fatalf("%s: rejected write was stored", name)
+		}
+	}
+}
+
+func TestNegativeTTLWithoutStrict(t *testing.T) {
+	nc := NewNGCache(1024*1024, nil, WithDefaultTTL(time.Minute), WithLogger(slog.New(slog.NewTextHandler(io.Discard, nil))))
+	defer nc.Close()
+
+	// 未启用WithStrictTTL时其他负数按TTLPermanent写入
+	if err := nc.SetString("k", "v", -5); err != nil {
+		t.Fatal(err)
+	}
+	if ttl, permanent := ttlOf(t, nc, "k"); ttl != 0 || !permanent {
+		t.Fatalf("ttl=%d permanent=%v, want permanent", ttl, permanent)
+	}
+}
+
+func TestReconcilePermanence(t *testing.T) {
+	dir := t.TempDir()
+	config := &PersistConfig{
+		Enabled:  true,
+		FilePath: dir,
+		FileName: "cache.bin",
+		Format:   FormatBinary,
+		Interval: time.Hour,
+	}
+	nc := NewNGCache(1024*1024, config, WithLogger(slog.New(slog.NewTextHandler(io.Discard, nil))))
+	defer nc.Close()
+
+	for i := 0; i < 2*reconcileBatch; i++ {
+		nc.SetString(fmt.Sprintf("session:%d", i), "s", 0)
+	}
+	nc.SetString("user:1", "u", 0)
+	// 被淘汰出freecache的永久缓存从持久化数据重新写入
+	nc.cache.Del([]byte("session:0"))
+	if err := nc.Save(); err != nil {
+		t.Fatal(err)
+	}
+
+	nc.SetTTLPolicy("session:", time.Hour)
+	changed := nc.ReconcilePermanence(func(key string) bool {
+		return !strings.HasPrefix(key, "session:")
+	})
+	if changed != 2*reconcileBatch {
+		t.Fatalf("changed = %d", changed)
+	}
+	for _, key := range []string{"session:0", "session:1"} {
+		if ttl, permanent := ttlOf(t, nc, key); permanent || ttl == 0 || ttl > 3600 {
+			t.Fatalf("%s: ttl = %d, permanent = %v", key, ttl, permanent)
+		}
+	}
+	if ttl, permanent := ttlOf(t, nc, "user:1"); !permanent || ttl != 0 {
+		t.Fatalf("user:1: ttl = %d, permanent = %v", ttl, permanent)
+	}
+
+	if err := nc.Save(); err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(dir, "cache.bin")
+	summary, err := InspectPersistFile(path)
+	if err != nil || summary.Entries != 1 {
+		t.Fatalf("snapshot = %+v, %v", summary, err)
+	}
+	if _, err := ReadEntry(path, "session:1"); !errors.Is(err, ErrKeyNotFound) {
+		t.Fatalf("session:1 still in snapshot: %v", err)
+	}
+	if v, err := ReadEntry(path, "user:1"); err != nil || string(v) != "u" {
+		t.Fatalf("user:1 = %q, %v", v, err)
+	}
+}
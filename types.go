@@ -2,85 +2,154 @@ package ngcat
 
 import (
 	"encoding/binary"
+	"encoding/hex"
+	"math/big"
+	"net"
+	"strconv"
+	"unicode/utf8"
 	"unsafe"
 )
 
 // SetInt32 设置int32类型值
 func (ng *NGCache) SetInt32(key string, value int32, expireSeconds int) error {
-	buf := make([]byte, 4)
-	binary.LittleEndian.PutUint32(buf, uint32(value))
-	return ng.setWithPersist(key, buf, expireSeconds)
+	return ng.setTyped("int32", key, encodeInt32(value), expireSeconds)
 }
 
 // GetInt32 获取int32类型值
 func (ng *NGCache) GetInt32(key string) (int32, error) {
-	data, err := ng.getWithPersist(key)
+	data, err := ng.getTyped("int32", key)
 	if err != nil {
 		return 0, err
 	}
 	if len(data) != 4 {
-		return 0, ErrInvalidType
+		return 0, invalidLength("int32", 4, len(data))
 	}
 	return int32(binary.LittleEndian.Uint32(data)), nil
 }
 
+// SetRune 设置rune类型值，编码与SetInt32相同
+func (ng *NGCache) SetRune(key string, value rune, expireSeconds int) error {
+	return ng.setTyped("rune", key, encodeInt32(value), expireSeconds)
+}
+
+// GetRune 获取rune类型值，值不是有效的Unicode码点（如代理区0xD800-0xDFFF）时返回ErrInvalidType
+func (ng *NGCache) GetRune(key string) (rune, error) {
+	data, err := ng.getTyped("rune", key)
+	if err != nil {
+		return 0, err
+	}
+	if len(data) != 4 {
+		return 0, invalidLength("rune", 4, len(data))
+	}
+	value := rune(binary.LittleEndian.Uint32(data))
+	if !utf8.ValidRune(value) {
+		return 0, newError(CodeInvalidType, "rune: "+strconv.Itoa(int(value))+" is not a valid Unicode code point", nil)
+	}
+	return value, nil
+}
+
 // SetInt64 设置int64类型值
 func (ng *NGCache) SetInt64(key string, value int64, expireSeconds int) error {
-	buf := make([]byte, 8)
-	binary.LittleEndian.PutUint64(buf, uint64(value))
-	return ng.setWithPersist(key, buf, expireSeconds)
+	return ng.setTyped("int64", key, encodeInt64(value), expireSeconds)
 }
 
 // GetInt64 获取int64类型值
 func (ng *NGCache) GetInt64(key string) (int64, error) {
-	data, err := ng.getWithPersist(key)
+	data, err := ng.getTyped("int64", key)
 	if err != nil {
 		return 0, err
 	}
 	if len(data) != 8 {
-		return 0, ErrInvalidType
+		return 0, invalidLength("int64", 8, len(data))
 	}
 	return int64(binary.LittleEndian.Uint64(data)), nil
 }
 
 // SetBool 设置bool类型值
 func (ng *NGCache) SetBool(key string, value bool, expireSeconds int) error {
-	var buf []byte
-	if value {
-		buf = []byte{1}
-	} else {
-		buf = []byte{0}
-	}
-	return ng.setWithPersist(key, buf, expireSeconds)
+	return ng.setTyped("bool", key, encodeBool(value), expireSeconds)
 }
 
-// GetBool 获取bool类型值
+// GetBool 获取bool类型值，值不是SetBool写入的单个字节0或1时返回ErrInvalidType
 func (ng *NGCache) GetBool(key string) (bool, error) {
-	data, err := ng.getWithPersist(key)
+	data, err := ng.getTyped("bool", key)
 	if err != nil {
 		return false, err
 	}
-	if len(data) != 1 {
+	if len(data) != 1 || data[0] > 1 {
 		return false, ErrInvalidType
 	}
 	return data[0] == 1, nil
 }
 
+// SetInt 设置int类型值，与平台无关地编码为8字节，与SetInt64写入的值相同
+func (ng *NGCache) SetInt(key string, value int, expireSeconds int) error {
+	return ng.setTyped("int", key, encodeInt64(int64(value)), expireSeconds)
+}
+
+// GetInt 获取int类型值，值必须是8字节；32位平台上超出int范围时返回ErrInvalidType
+func (ng *NGCache) GetInt(key string) (int, error) {
+	data, err := ng.getTyped("int", key)
+	if err != nil {
+		return 0, err
+	}
+	if len(data) != 8 {
+		return 0, invalidLength("int", 8, len(data))
+	}
+	value := int64(binary.LittleEndian.Uint64(data))
+	if int64(int(value)) != value {
+		return 0, newError(CodeInvalidType, "int: "+strconv.FormatInt(value, 10)+" overflows int", nil)
+	}
+	return int(value), nil
+}
+
+// GetNumberAsInt64 获取SetInt32、SetInt64或SetInt写入的整数，接受4字节和8字节两种编码
+//
+// 不记得写入时使用的宽度时使用。浮点数的长度同样是4或8字节，无法区分，会被按整数解释。
+func (ng *NGCache) GetNumberAsInt64(key string) (int64, error) {
+	data, err := ng.getTyped("number", key)
+	if err != nil {
+		return 0, err
+	}
+	switch len(data) {
+	case 4:
+		return int64(int32(binary.LittleEndian.Uint32(data))), nil
+	case 8:
+		return int64(binary.LittleEndian.Uint64(data)), nil
+	}
+	return 0, newError(CodeInvalidType, "int64: expected 4 or 8 bytes, got "+strconv.Itoa(len(data)), nil)
+}
+
+// lengthHints 各长度的值通常由哪些方法写入，用于类型不匹配时的提示
+var lengthHints = map[int]string{
+	1:  "SetBool",
+	4:  "SetInt32, SetRune or SetFloat32",
+	8:  "SetInt64, SetInt, SetFloat64 or SetComplex64",
+	16: "SetComplex128 or SetUUID",
+}
+
+// invalidLength 返回带期望长度和实际长度的类型不匹配错误，可通过errors.Is匹配ErrInvalidType
+func invalidLength(typ string, want, got int) error {
+	detail := typ + ": expected " + strconv.Itoa(want) + " bytes, got " + strconv.Itoa(got)
+	if hint, ok := lengthHints[got]; ok {
+		detail += " (written by " + hint + "?)"
+	}
+	return newError(CodeInvalidType, detail, nil)
+}
+
 // SetFloat32 设置float32类型值
 func (ng *NGCache) SetFloat32(key string, value float32, expireSeconds int) error {
-	buf := make([]byte, 4)
-	binary.LittleEndian.PutUint32(buf, *(*uint32)(unsafe.Pointer(&value)))
-	return ng.setWithPersist(key, buf, expireSeconds)
+	return ng.setTyped("float32", key, encodeFloat32(value), expireSeconds)
 }
 
 // GetFloat32 获取float32类型值
 func (ng *NGCache) GetFloat32(key string) (float32, error) {
-	data, err := ng.getWithPersist(key)
+	data, err := ng.getTyped("float32", key)
 	if err != nil {
 		return 0, err
 	}
 	if len(data) != 4 {
-		return 0, ErrInvalidType
+		return 0, invalidLength("float32", 4, len(data))
 	}
 	uintVal := binary.LittleEndian.Uint32(data)
 	return *(*float32)(unsafe.Pointer(&uintVal)), nil
@@ -88,80 +157,462 @@ func (ng *NGCache) GetFloat32(key string) (float32, error) {
 
 // SetFloat64 设置float64类型值
 func (ng *NGCache) SetFloat64(key string, value float64, expireSeconds int) error {
-	buf := make([]byte, 8)
-	binary.LittleEndian.PutUint64(buf, *(*uint64)(unsafe.Pointer(&value)))
-	return ng.setWithPersist(key, buf, expireSeconds)
+	return ng.setTyped("float64", key, encodeFloat64(value), expireSeconds)
 }
 
 // GetFloat64 获取float64类型值
 func (ng *NGCache) GetFloat64(key string) (float64, error) {
-	data, err := ng.getWithPersist(key)
+	data, err := ng.getTyped("float64", key)
 	if err != nil {
 		return 0, err
 	}
 	if len(data) != 8 {
-		return 0, ErrInvalidType
+		return 0, invalidLength("float64", 8, len(data))
 	}
 	uintVal := binary.LittleEndian.Uint64(data)
 	return *(*float64)(unsafe.Pointer(&uintVal)), nil
 }
 
+// SetComplex64 设置complex64类型值，依次存储实部和虚部两个float32，共8字节
+func (ng *NGCache) SetComplex64(key string, value complex64, expireSeconds int) error {
+	return ng.setTyped("complex64", key, encodeComplex64(value), expireSeconds)
+}
+
+// GetComplex64 获取complex64类型值
+func (ng *NGCache) GetComplex64(key string) (complex64, error) {
+	data, err := ng.getTyped("complex64", key)
+	if err != nil {
+		return 0, err
+	}
+	if len(data) != 8 {
+		return 0, invalidLength("complex64", 8, len(data))
+	}
+	realVal := binary.LittleEndian.Uint32(data)
+	imagVal := binary.LittleEndian.Uint32(data[4:])
+	return complex(*(*float32)(unsafe.Pointer(&realVal)), *(*float32)(unsafe.Pointer(&imagVal))), nil
+}
+
+// SetComplex128 设置complex128类型值，依次存储实部和虚部两个float64，共16字节
+func (ng *NGCache) SetComplex128(key string, value complex128, expireSeconds int) error {
+	return ng.setTyped("complex128", key, encodeComplex128(value), expireSeconds)
+}
+
+// GetComplex128 获取complex128类型值
+func (ng *NGCache) GetComplex128(key string) (complex128, error) {
+	data, err := ng.getTyped("complex128", key)
+	if err != nil {
+		return 0, err
+	}
+	if len(data) != 16 {
+		return 0, invalidLength("complex128", 16, len(data))
+	}
+	realVal := binary.LittleEndian.Uint64(data)
+	imagVal := binary.LittleEndian.Uint64(data[8:])
+	return complex(*(*float64)(unsafe.Pointer(&realVal)), *(*float64)(unsafe.Pointer(&imagVal))), nil
+}
+
+// SetBigFloat 设置*big.Float类型值，以GobEncode编码，保留精度、舍入模式和±Inf
+//
+// big.Float不能表示NaN；value为nil时返回CodeEncode错误。
+func (ng *NGCache) SetBigFloat(key string, value *big.Float, expireSeconds int) error {
+	if value == nil {
+		return newError(CodeEncode, "nil *big.Float", nil)
+	}
+	data, err := value.GobEncode()
+	if err != nil {
+		return newError(CodeEncode, "big.Float", err)
+	}
+	return ng.setTyped("bigfloat", key, data, expireSeconds)
+}
+
+// GetBigFloat 获取*big.Float类型值，值不是GobEncode编码的big.Float时返回ErrInvalidType
+func (ng *NGCache) GetBigFloat(key string) (*big.Float, error) {
+	data, err := ng.getTyped("bigfloat", key)
+	if err != nil {
+		return nil, err
+	}
+	value := new(big.Float)
+	// 空的值会被GobDecode解码为0，但SetBigFloat不会写入空值
+	if len(data) == 0 || value.GobDecode(data) != nil {
+		return nil, ErrInvalidType
+	}
+	return value, nil
+}
+
+// SetUUID 设置UUID，以16字节存储
+//
+// github.com/google/uuid的uuid.UUID底层类型为[16]byte，可以直接传入。
+func (ng *NGCache) SetUUID(key string, id [16]byte, expireSeconds int) error {
+	return ng.setTyped("uuid", key, id[:], expireSeconds)
+}
+
+// GetUUID 获取UUID，值不是16字节时返回ErrInvalidType
+func (ng *NGCache) GetUUID(key string) ([16]byte, error) {
+	var id [16]byte
+	data, err := ng.getTyped("uuid", key)
+	if err != nil {
+		return id, err
+	}
+	if len(data) != 16 {
+		return id, invalidLength("uuid", 16, len(data))
+	}
+	copy(id[:], data)
+	return id, nil
+}
+
+// ParseAndSetUUID 解析"xxxxxxxx-xxxx-xxxx-xxxx-xxxxxxxxxxxx"格式（十六进制不区分大小写）的UUID并以16字节存储
+//
+// 格式不符时返回CodeEncode错误，不写入。
+func (ng *NGCache) ParseAndSetUUID(key, uuidStr string, expireSeconds int) error {
+	id, ok := parseUUID(uuidStr)
+	if !ok {
+		return newError(CodeEncode, "uuid: invalid format "+strconv.Quote(uuidStr), nil)
+	}
+	return ng.SetUUID(key, id, expireSeconds)
+}
+
+// parseUUID 解析8-4-4-4-12格式的UUID
+func parseUUID(s string) ([16]byte, bool) {
+	var id [16]byte
+	if len(s) != 36 || s[8] != '-' || s[13] != '-' || s[18] != '-' || s[23] != '-' {
+		return id, false
+	}
+	digits := s[0:8] + s[9:13] + s[14:18] + s[19:23] + s[24:36]
+	if _, err := hex.Decode(id[:], []byte(digits)); err != nil {
+		return id, false
+	}
+	return id, true
+}
+
+// net.IP存储时第一个字节的地址族标记
+const (
+	ipFamilyNil byte = 0
+	ipFamilyV4  byte = 4
+	ipFamilyV6  byte = 6
+)
+
+// SetIP 设置IP地址，以一个字节的地址族标记加4字节（IPv4）或16字节（IPv6）存储
+//
+// IPv4映射的IPv6地址（::ffff:a.b.c.d）按IPv4存储；nil只存储标记，读取时返回nil；
+// 长度既不是4也不是16的value返回CodeEncode错误。
+func (ng *NGCache) SetIP(key string, ip net.IP, expireSeconds int) error {
+	var data []byte
+	switch {
+	case ip == nil:
+		data = []byte{ipFamilyNil}
+	case ip.To4() != nil:
+		data = append([]byte{ipFamilyV4}, ip.To4()...)
+	case len(ip) == net.IPv6len:
+		data = append([]byte{ipFamilyV6}, ip...)
+	default:
+		return newError(CodeEncode, "net.IP: invalid length "+strconv.Itoa(len(ip)), nil)
+	}
+	return ng.setTyped("ip", key, data, expireSeconds)
+}
+
+// GetIP 获取IP地址，IPv4返回4字节的net.IP，地址族标记与长度不符时返回ErrInvalidType
+func (ng *NGCache) GetIP(key string) (net.IP, error) {
+	data, err := ng.getTyped("ip", key)
+	if err != nil {
+		return nil, err
+	}
+	if len(data) == 0 {
+		return nil, invalidLength("ip", 1, 0)
+	}
+	want := 0
+	switch data[0] {
+	case ipFamilyNil:
+	case ipFamilyV4:
+		want = net.IPv4len
+	case ipFamilyV6:
+		want = net.IPv6len
+	default:
+		return nil, newError(CodeInvalidType, "ip: unknown address family "+strconv.Itoa(int(data[0])), nil)
+	}
+	if len(data) != 1+want {
+		return nil, invalidLength("ip", 1+want, len(data))
+	}
+	if want == 0 {
+		return nil, nil
+	}
+	return net.IP(append([]byte(nil), data[1:]...)), nil
+}
+
 // SetBytes 设置字节数组值
 func (ng *NGCache) SetBytes(key string, value []byte, expireSeconds int) error {
-	return ng.setWithPersist(key, value, expireSeconds)
+	return ng.setTyped("bytes", key, value, expireSeconds)
 }
 
 // GetBytes 获取字节数组值
 func (ng *NGCache) GetBytes(key string) ([]byte, error) {
-	return ng.getWithPersist(key)
+	return ng.getTyped("bytes", key)
 }
 
 // SetString 设置字符串值
 func (ng *NGCache) SetString(key string, value string, expireSeconds int) error {
-	return ng.setWithPersist(key, []byte(value), expireSeconds)
+	return ng.setTyped("string", key, []byte(value), expireSeconds)
 }
 
-// GetString 获取字符串值
+// GetString 获取字符串值，值不是有效的UTF-8时返回ErrInvalidEncoding
+//
+// 数值类型只按长度检查（如GetInt64要求8字节），SetFloat64写入的值仍会被GetInt64按位解释；
+// 需要读取任意字节时使用GetBytes。
 func (ng *NGCache) GetString(key string) (string, error) {
-	data, err := ng.getWithPersist(key)
+	data, err := ng.getTyped("string", key)
 	if err != nil {
 		return "", err
 	}
+	if !utf8.Valid(data) {
+		return "", ErrInvalidEncoding
+	}
 	return string(data), nil
 }
 
+// encodeInt32 编码int32类型值
+func encodeInt32(value int32) []byte {
+	buf := make([]byte, 4)
+	binary.LittleEndian.PutUint32(buf, uint32(value))
+	return buf
+}
+
+// encodeInt64 编码int64类型值
+func encodeInt64(value int64) []byte {
+	buf := make([]byte, 8)
+	binary.LittleEndian.PutUint64(buf, uint64(value))
+	return buf
+}
+
+// encodeBool 编码bool类型值
+func encodeBool(value bool) []byte {
+	if value {
+		return []byte{1}
+	}
+	return []byte{0}
+}
+
+// encodeFloat32 编码float32类型值
+func encodeFloat32(value float32) []byte {
+	buf := make([]byte, 4)
+	binary.LittleEndian.PutUint32(buf, *(*uint32)(unsafe.Pointer(&value)))
+	return buf
+}
+
+// encodeFloat64 编码float64类型值
+func encodeFloat64(value float64) []byte {
+	buf := make([]byte, 8)
+	binary.LittleEndian.PutUint64(buf, *(*uint64)(unsafe.Pointer(&value)))
+	return buf
+}
+
+// encodeComplex64 编码complex64类型值
+func encodeComplex64(value complex64) []byte {
+	realVal, imagVal := real(value), imag(value)
+	buf := make([]byte, 8)
+	binary.LittleEndian.PutUint32(buf, *(*uint32)(unsafe.Pointer(&realVal)))
+	binary.LittleEndian.PutUint32(buf[4:], *(*uint32)(unsafe.Pointer(&imagVal)))
+	return buf
+}
+
+// encodeComplex128 编码complex128类型值
+func encodeComplex128(value complex128) []byte {
+	realVal, imagVal := real(value), imag(value)
+	buf := make([]byte, 16)
+	binary.LittleEndian.PutUint64(buf, *(*uint64)(unsafe.Pointer(&realVal)))
+	binary.LittleEndian.PutUint64(buf[8:], *(*uint64)(unsafe.Pointer(&imagVal)))
+	return buf
+}
+
 // setWithPersist 内部设置方法，支持持久化
 func (ng *NGCache) setWithPersist(key string, value []byte, expireSeconds int) error {
+	started, err := ng.beginWrite()
+	if err != nil {
+		return err
+	}
+	if started {
+		defer ng.inflight.Done()
+	}
+	return ng.writeKey(key, value, expireSeconds)
+}
+
+// writeKey 加键锁写入值并发布写入事件，不检查Drain，供Drain和Close写回计数器
+func (ng *NGCache) writeKey(key string, value []byte, expireSeconds int) error {
+	mu := ng.keyLock(key)
+	mu.Lock()
+	err := ng.setLocked(key, value, expireSeconds)
+	mu.Unlock()
+	if err == nil {
+		ng.publishSet(key, value, expireSeconds)
+	}
+	return err
+}
+
+// setLocked 写入值，调用方需持有键的分段锁
+func (ng *NGCache) setLocked(key string, value []byte, expireSeconds int) error {
+	value, expireSeconds, err := ng.prepareSet(key, value, expireSeconds)
+	if err != nil {
+		return err
+	}
+	return ng.storeLocked(key, value, expireSeconds)
+}
+
+// storeLocked 写入已编码的值，调用方需持有键的分段锁
+func (ng *NGCache) storeLocked(key string, value []byte, expireSeconds int) error {
+	return ng.storeEntryLocked(key, value, expireSeconds, false)
+}
+
+// storeEntryLocked 写入已编码的值，调用方需持有键的分段锁
+//
+// 值不被持久化时（expireSeconds>0或cacheOnly为true）删除持久化数据中该键的旧值，
+// 否则旧的永久值会在过期后或重启后重新出现。cacheOnly为true时即使expireSeconds<=0也只写入存储。
+func (ng *NGCache) storeEntryLocked(key string, value []byte, expireSeconds int, cacheOnly bool) error {
+	persisted := expireSeconds <= 0 && !cacheOnly
+	if !persisted {
+		ng.dropPersisted(key)
+	}
 	// 如果是永久缓存（expireSeconds <= 0），存储到持久化数据中
-	if expireSeconds <= 0 {
-		ng.persistDataMutex.Lock()
-		ng.persistData[key] = make([]byte, len(value))
-		copy(ng.persistData[key], value)
-		ng.persistDataMutex.Unlock()
+	if persisted {
+		if ng.coalescer != nil {
+			ng.coalesceLocked(key, value)
+		} else {
+			ng.persistDataMutex.Lock()
+			ng.persistData[key] = cloneBytes(value)
+			ng.bloomAdd(key)
+			ng.persistDataMutex.Unlock()
+			ng.appendWAL(walOpSet, key, value)
+			ng.markDirty(key)
+		}
 	}
 
+	// 普通写入取代SetInt64AtomicAdd维护的计数器
+	ng.counters.Delete(key)
+
 	// 同时存储到freecache中
-	return ng.cache.Set([]byte(key), value, expireSeconds)
+	ng.forgetExpiry(key)
+	err := ng.storeSet(key, value, expireSeconds)
+	if err != nil {
+		return err
+	}
+	ng.noteWrite(key, value, expireSeconds, persisted)
+	return nil
+}
+
+// prepareSet 检查过期时间、键和值的长度，解析过期时间并编码值
+func (ng *NGCache) prepareSet(key string, value []byte, expireSeconds int) ([]byte, int, error) {
+	err := ng.checkTTL(expireSeconds)
+	if err != nil {
+		return nil, 0, err
+	}
+	err = ng.checkKeyLen(len(key))
+	if err != nil {
+		return nil, 0, err
+	}
+	err = ng.checkValueSize(len(value))
+	if err != nil {
+		return nil, 0, err
+	}
+	value, err = ng.encodeValue(value)
+	if err != nil {
+		return nil, 0, err
+	}
+	return value, ng.resolveTTL(key, expireSeconds), nil
 }
 
 // getWithPersist 内部获取方法，支持持久化
 func (ng *NGCache) getWithPersist(key string) ([]byte, error) {
+	if ng.beginOp() {
+		defer ng.inflight.Done()
+	}
+	start, timed := ng.timeGet()
+	value, outcome, err := ng.lookupWithPersist(key)
+	if timed {
+		ng.observeGet(start, outcome)
+	}
+	if outcome == getHit {
+		ng.slideByPolicy(key)
+	}
+	ng.publishEvent(EventGet, key, value, 0, err == nil)
+	return value, err
+}
+
+// lookupWithPersist 依次从freecache、尚未合并的写入和持久化数据读取，同时返回读取的结果
+func (ng *NGCache) lookupWithPersist(key string) ([]byte, getOutcome, error) {
+	if ng.hotKeys != nil {
+		ng.hotKeys.record(key)
+	}
+
 	// 首先尝试从freecache获取
-	value, err := ng.cache.Get([]byte(key))
+	value, err := ng.storeGet(key)
 	if err == nil {
-		return value, nil
+		ng.noteAccess(key)
+		value, err = ng.decodeValue(value)
+		return value, getHit, err
 	}
 
-	// 如果freecache中没有，尝试从持久化数据获取
-	ng.persistDataMutex.RLock()
-	persistValue, exists := ng.persistData[key]
-	ng.persistDataMutex.RUnlock()
-
+	// 如果freecache中没有，尝试从尚未合并的写入和持久化数据获取
+	if pending, ok := ng.coalescedValue(key); ok {
+		ng.noteAccess(key)
+		value, err = ng.decodeValue(pending)
+		return value, getFallback, err
+	}
+	if value, found, loading := ng.lazyLookup(key); loading {
+		if !found {
+			return nil, getMiss, ErrKeyNotFound
+		}
+		ng.noteAccess(key)
+		value, err = ng.decodeValue(value)
+		return value, getFallback, err
+	}
+	persistValue, exists := ng.persistValue(key)
 	if exists {
-		// 将持久化数据重新加载到freecache中（永久缓存）
-		ng.cache.Set([]byte(key), persistValue, 0)
-		return persistValue, nil
+		ng.noteAccess(key)
+		// 按写回策略将持久化数据重新加载到freecache中（永久缓存）
+		ng.promote(key, persistValue)
+		// 复制一份，调用方修改返回值不会影响持久化数据
+		value, err = ng.decodeValue(cloneBytes(persistValue))
+		return value, getFallback, err
 	}
 
-	return nil, ErrKeyNotFound
+	return nil, getMiss, ErrKeyNotFound
+}
+
+// deleteWithPersist 内部删除方法，同时删除freecache和持久化数据中的键
+func (ng *NGCache) deleteWithPersist(key string) bool {
+	if ng.beginOp() {
+		defer ng.inflight.Done()
+	}
+	mu := ng.keyLock(key)
+	mu.Lock()
+	deleted := ng.deleteLocked(key)
+	mu.Unlock()
+	if deleted {
+		ng.publishEvent(EventDelete, key, nil, 0, true)
+	}
+	return deleted
+}
+
+// deleteLocked 删除键，调用方需持有键的分段锁
+func (ng *NGCache) deleteLocked(key string) bool {
+	affected := ng.storeDel(key)
+	counted := ng.counters.Delete(key)
+	ng.forgetExpiry(key)
+	exists := ng.dropPersisted(key)
+	ng.noteDelete(key)
+
+	return affected || exists || counted
+}
+
+// dropPersisted 删除键在持久化数据和尚未合并的写入中的值，返回持久化数据中是否有该键，调用方需持有键的分段锁
+func (ng *NGCache) dropPersisted(key string) bool {
+	ng.dropCoalesced(key)
+
+	ng.persistDataMutex.Lock()
+	_, exists := ng.persistData[key]
+	delete(ng.persistData, key)
+	ng.persistDataMutex.Unlock()
+	if exists {
+		ng.bloomNoteDelete()
+		ng.appendWAL(walOpDelete, key, nil)
+		ng.markDirty(key)
+	}
+	return exists
 }
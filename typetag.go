@@ -0,0 +1,157 @@
+package ngcat
+
+// 严格类型模式下值的首字节
+//
+// 0x80-0xBF是UTF-8的后续字节，不会出现在合法UTF-8文本的开头，也不是gob、JSON编码的开头。
+// 兼容模式下旧值仍可能以这些字节开头，识别方式见hasTag。
+const (
+	typeTagInt32 byte = 0x80 + iota
+	typeTagInt64
+	typeTagFloat32
+	typeTagFloat64
+	typeTagBool
+	typeTagString
+	typeTagBytes
+	typeTagJSON
+	typeTagCodec
+	typeTagComplex64
+	typeTagComplex128
+	typeTagBigFloat
+	typeTagUUID
+	typeTagIP
+)
+
+// typeTagNames 类型标记的名称，用于错误信息
+var typeTagNames = map[byte]string{
+	typeTagInt32:      "int32",
+	typeTagInt64:      "int64",
+	typeTagFloat32:    "float32",
+	typeTagFloat64:    "float64",
+	typeTagBool:       "bool",
+	typeTagString:     "string",
+	typeTagBytes:      "bytes",
+	typeTagJSON:       "json",
+	typeTagCodec:      "codec",
+	typeTagComplex64:  "complex64",
+	typeTagComplex128: "complex128",
+	typeTagBigFloat:   "bigfloat",
+	typeTagUUID:       "uuid",
+	typeTagIP:         "ip",
+}
+
+// writeTags setTyped的类型名对应的类型标记，编码相同的类型共用标记
+var writeTags = map[string]byte{
+	"int32":      typeTagInt32,
+	"rune":       typeTagInt32,
+	"int64":      typeTagInt64,
+	"int":        typeTagInt64,
+	"float32":    typeTagFloat32,
+	"float64":    typeTagFloat64,
+	"bool":       typeTagBool,
+	"string":     typeTagString,
+	"bytes":      typeTagBytes,
+	"json":       typeTagJSON,
+	"any":        typeTagCodec,
+	"struct":     typeTagCodec,
+	"auto":       typeTagCodec,
+	"complex64":  typeTagComplex64,
+	"complex128": typeTagComplex128,
+	"bigfloat":   typeTagBigFloat,
+	"uuid":       typeTagUUID,
+	"ip":         typeTagIP,
+}
+
+// typeTagWidths 定长类型不含标记的字节数，带标记的值比它多一个字节
+var typeTagWidths = map[byte]int{
+	typeTagInt32:      4,
+	typeTagInt64:      8,
+	typeTagFloat32:    4,
+	typeTagFloat64:    8,
+	typeTagBool:       1,
+	typeTagComplex64:  8,
+	typeTagComplex128: 16,
+	typeTagUUID:       16,
+}
+
+// readTags getTyped的类型名可以读取的其他类型标记，写入时使用的标记总是可以读取
+var readTags = map[string][]byte{
+	// 字符串和字节数组可以互相读取
+	"string": {typeTagBytes},
+	"bytes":  {typeTagString},
+	// GetNumberAsInt64接受4字节和8字节的整数
+	"number": {typeTagInt32, typeTagInt64},
+	// GetStruct读取SetStruct写入的值，SetStruct可能回退到SetJSON
+	"struct": {typeTagJSON},
+}
+
+// tagValue 严格类型模式下在值前加上类型标记
+func (ng *NGCache) tagValue(typ string, value []byte) []byte {
+	tag, ok := writeTags[typ]
+	if !ng.strictTypes || !ok {
+		return value
+	}
+	tagged := make([]byte, len(value)+1)
+	tagged[0] = tag
+	copy(tagged[1:], value)
+	return tagged
+}
+
+// untagValue 严格类型模式下检查值的类型标记并去掉标记
+func (ng *NGCache) untagValue(typ string, data []byte) ([]byte, error) {
+	if !ng.strictTypes {
+		return data, nil
+	}
+	if ng.hasTag(typ, data) {
+		if data[0] == writeTags[typ] {
+			return data[1:], nil
+		}
+		for _, tag := range readTags[typ] {
+			if data[0] == tag {
+				return data[1:], nil
+			}
+		}
+		return nil, newError(CodeTypeMismatch, "stored "+typeTagNames[data[0]]+", requested "+typ, nil)
+	}
+	if ng.acceptUntagged {
+		return data, nil
+	}
+	return nil, newError(CodeTypeMismatch, "stored untagged value, requested "+typ, nil)
+}
+
+// hasTag 判断值是否以类型标记开头
+//
+// 定长类型带标记的值必须恰好比该类型的宽度多一个字节。兼容模式下旧值也可能以标记字节开头，
+// 但定长类型的旧值恰好为请求类型的宽度，带标记的值不会是这个长度，因此按没有标记的旧值处理。
+// 变长类型无法按长度区分：合法UTF-8的字符串、JSON和gob编码的旧值不会以标记字节开头，
+// 字节数组、非UTF-8字符串和自定义编解码器的旧值以0x80-0x8D开头时按带标记的值处理。
+func (ng *NGCache) hasTag(typ string, data []byte) bool {
+	if len(data) == 0 {
+		return false
+	}
+	if _, ok := typeTagNames[data[0]]; !ok {
+		return false
+	}
+	if width, ok := typeTagWidths[data[0]]; ok && len(data) != width+1 {
+		return false
+	}
+	if ng.acceptUntagged {
+		for _, width := range untaggedWidths(typ) {
+			if len(data) == width {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// untaggedWidths 定长类型的旧值（没有标记）可能的字节数，变长类型返回nil
+func untaggedWidths(typ string) []int {
+	// GetNumberAsInt64接受4字节和8字节的整数
+	if typ == "number" {
+		return []int{4, 8}
+	}
+	if width, ok := typeTagWidths[writeTags[typ]]; ok {
+		return []int{width}
+	}
+	return nil
+}
@@ -0,0 +1,205 @@
+package ngcat
+
+import (
+	"errors"
+	"math/big"
+	"net"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestStrictTypesCrossType(t *testing.T) {
+	nc := NewNGCache(1024*1024, nil, WithStrictTypes(false))
+	defer nc.Close()
+
+	object := map[string]int{"a": 1}
+	setters := map[string]func(key string) error{
+		"int32":      func(k string) error { return nc.SetInt32(k, 65, 0) },
+		"rune":       func(k string) error { return nc.SetRune(k, 'A', 0) },
+		"int64":      func(k string) error { return nc.SetInt64(k, 65, 0) },
+		"int":        func(k string) error { return nc.SetInt(k, 65, 0) },
+		"float32":    func(k string) error { return nc.SetFloat32(k, 1.5, 0) },
+		"float64":    func(k string) error { return nc.SetFloat64(k, 1.5, 0) },
+		"bool":       func(k string) error { return nc.SetBool(k, true, 0) },
+		"string":     func(k string) error { return nc.SetString(k, "12345678", 0) },
+		"bytes":      func(k string) error { return nc.SetBytes(k, []byte("12345678"), 0) },
+		"json":       func(k string) error { return nc.SetJSON(k, object, 0) },
+		"any":        func(k string) error { return nc.SetAny(k, object, 0) },
+		"complex64":  func(k string) error { return nc.SetComplex64(k, 1+2i, 0) },
+		"complex128": func(k string) error { return nc.SetComplex128(k, 1+2i, 0) },
+		"bigfloat":   func(k string) error { return nc.SetBigFloat(k, big.NewFloat(1.5), 0) },
+		"uuid":       func(k string) error { return nc.SetUUID(k, [16]byte{1}, 0) },
+		"ip":         func(k string) error { return nc.SetIP(k, net.IPv4(10, 0, 0, 1), 0) },
+	}
+	getters := map[string]func(key string) error{
+		"GetInt32":          func(k string) error { _, err := nc.GetInt32(k); return err },
+		"GetRune":           func(k string) error { _, err := nc.GetRune(k); return err },
+		"GetInt64":          func(k string) error { _, err := nc.GetInt64(k); return err },
+		"GetInt":            func(k string) error { _, err := nc.GetInt(k); return err },
+		"GetNumberAsInt64":  func(k string) error { _, err := nc.GetNumberAsInt64(k); return err },
+		"GetFloat32":        func(k string) error { _, err := nc.GetFloat32(k); return err },
+		"GetFloat64":        func(k string) error { _, err := nc.GetFloat64(k); return err },
+		"GetBool":           func(k string) error { _, err := nc.GetBool(k); return err },
+		"GetString":         func(k string) error { _, err := nc.GetString(k); return err },
+		"GetStringZeroCopy": func(k string) error { _, err := nc.GetStringZeroCopy(k); return err },
+		"GetBytes":          func(k string) error { _, err := nc.GetBytes(k); return err },
+		"GetJSON":           func(k string) error { var v map[string]int; return nc.GetJSON(k, &v) },
+		"GetAny":            func(k string) error { var v map[string]int; return nc.GetAny(k, &v) },
+		"GetStruct":         func(k string) error { var v map[string]int; return nc.GetStruct(k, &v) },
+		"GetComplex64":      func(k string) error { _, err := nc.GetComplex64(k); return err },
+		"GetComplex128":     func(k string) error { _, err := nc.GetComplex128(k); return err },
+		"GetBigFloat":       func(k string) error { _, err := nc.GetBigFloat(k); return err },
+		"GetUUID":           func(k string) error { _, err := nc.GetUUID(k); return err },
+		"GetIP":             func(k string) error { _, err := nc.GetIP(k); return err },
+	}
+	ints32 := []string{"GetInt32", "GetRune", "GetNumberAsInt64"}
+	ints64 := []string{"GetInt64", "GetInt", "GetNumberAsInt64"}
+	texts := []string{"GetString", "GetStringZeroCopy", "GetBytes"}
+	compatible := map[string][]string{
+		"int32":      ints32,
+		"rune":       ints32,
+		"int64":      ints64,
+		"int":        ints64,
+		"float32":    {"GetFloat32"},
+		"float64":    {"GetFloat64"},
+		"bool":       {"GetBool"},
+		"string":     texts,
+		"bytes":      texts,
+		"json":       {"GetJSON", "GetStruct"},
+		"any":        {"GetAny", "GetStruct"},
+		"complex64":  {"GetComplex64"},
+		"complex128": {"GetComplex128"},
+		"bigfloat":   {"GetBigFloat"},
+		"uuid":       {"GetUUID"},
+		"ip":         {"GetIP"},
+	}
+
+	for stored, set := range setters {
+		if err := set("k"); err != nil {
+			t.Fatalf("set %s: %v", stored, err)
+		}
+		ok := make(map[string]bool)
+		for _, name := range compatible[stored] {
+			ok[name] = true
+		}
+		for name, get := range getters {
+			err := get("k")
+			if ok[name] {
+				if err != nil {
+					t.Errorf("%s after set %s: %v", name, stored, err)
+				}
+				continue
+			}
+			if !errors.Is(err, ErrTypeMismatch) {
+				t.Errorf("%s after set %s: %v, want ErrTypeMismatch", name, stored, err)
+				continue
+			}
+			if !strings.Contains(err.Error(), "stored "+stored) && !strings.Contains(err.Error(), "stored "+typeTagNames[writeTags[stored]]) {
+				t.Errorf("%s after set %s: message %q does not name the stored type", name, stored, err)
+			}
+		}
+	}
+
+	nc.SetString("s", "12345678", 0)
+	if _, err := nc.GetInt64("s"); err == nil || !strings.Contains(err.Error(), "stored string, requested int64") {
+		t.Fatalf("GetInt64 of an 8-byte string: %v", err)
+	}
+	nc.SetAny("obj", object, 0)
+	_, _, err := nc.GetJSONMulti([]string{"obj"}, func() interface{} { return new(map[string]int) })
+	var multi MultiDecodeError
+	if !errors.As(err, &multi) || !errors.Is(multi["obj"], ErrTypeMismatch) {
+		t.Fatalf("GetJSONMulti of a codec value: %v", err)
+	}
+}
+
+func TestStrictTypesUntagged(t *testing.T) {
+	config := &PersistConfig{
+		Enabled:  true,
+		FilePath: t.TempDir(),
+		FileName: "cache.bin",
+		Format:   FormatBinary,
+		Interval: time.Hour,
+	}
+	legacy := NewNGCache(1024*1024, config)
+	legacy.SetInt64("n", 7, 0)
+	legacy.SetString("s", "text", 0)
+	legacy.Close()
+
+	compat := NewNGCache(1024*1024, config, WithStrictTypes(true))
+	if v, err := compat.GetInt64("n"); err != nil || v != 7 {
+		t.Fatalf("compat GetInt64 = %d, %v", v, err)
+	}
+	if v, err := compat.GetString("s"); err != nil || v != "text" {
+		t.Fatalf("compat GetString = %q, %v", v, err)
+	}
+	// 新写入的定长类型的值带有标记，持久化后仍能检查
+	compat.SetInt32("tagged", 1, 0)
+	if _, err := compat.SetInt64AtomicAdd("hits", 2); err != nil {
+		t.Fatal(err)
+	}
+	compat.Close()
+
+	strict := NewNGCache(1024*1024, config, WithStrictTypes(false))
+	defer strict.Close()
+	if _, err := strict.GetInt64("n"); !errors.Is(err, ErrTypeMismatch) {
+		t.Fatalf("strict GetInt64 of untagged value: %v", err)
+	}
+	if _, err := strict.GetInt64("tagged"); err == nil || !strings.Contains(err.Error(), "stored int32") {
+		t.Fatalf("strict GetInt64 of persisted int32: %v", err)
+	}
+	if v, err := strict.GetInt64("hits"); err != nil || v != 2 {
+		t.Fatalf("flushed counter = %d, %v", v, err)
+	}
+}
+
+func TestStrictTypesUntaggedTagLikeBytes(t *testing.T) {
+	store := NewMapStore(nil)
+	legacy := NewNGCacheWithStore(store, nil)
+	defer legacy.Close()
+	// 旧值的首字节恰好与类型标记相同：133的小端序首字节为0x85（字符串的标记）
+	legacy.SetInt64("n", 133, 0)
+	legacy.SetInt32("i", 0x81, 0)
+	legacy.SetBytes("b", []byte{0xFF, 'x'}, 0)
+	legacy.SetString("s", "text", 0)
+
+	compat := NewNGCacheWithStore(store, nil, WithStrictTypes(true))
+	defer compat.Close()
+	if v, err := compat.GetInt64("n"); err != nil || v != 133 {
+		t.Fatalf("GetInt64 of legacy 133 = %d, %v", v, err)
+	}
+	if v, err := compat.GetNumberAsInt64("i"); err != nil || v != 0x81 {
+		t.Fatalf("GetNumberAsInt64 of legacy int32 = %d, %v", v, err)
+	}
+	if v, err := compat.GetBytes("b"); err != nil || string(v) != string([]byte{0xFF, 'x'}) {
+		t.Fatalf("GetBytes of legacy bytes = %q, %v", v, err)
+	}
+	if v, err := compat.GetString("s"); err != nil || v != "text" {
+		t.Fatalf("GetString of legacy string = %q, %v", v, err)
+	}
+
+	// 兼容模式下写入的定长类型仍带有标记，跨类型读取返回ErrTypeMismatch
+	compat.SetInt32("tagged32", 7, 0)
+	compat.SetFloat64("tagged64", 1.5, 0)
+	if v, err := compat.GetInt32("tagged32"); err != nil || v != 7 {
+		t.Fatalf("GetInt32 = %d, %v", v, err)
+	}
+	if _, err := compat.GetFloat32("tagged32"); err == nil || !strings.Contains(err.Error(), "stored int32") {
+		t.Fatalf("GetFloat32 of int32: %v", err)
+	}
+	if _, err := compat.GetInt64("tagged64"); err == nil || !strings.Contains(err.Error(), "stored float64") {
+		t.Fatalf("GetInt64 of float64: %v", err)
+	}
+	// 变长类型同样带有标记，8字节的字符串不会被当作int64的旧值
+	compat.SetString("str", "12345678", 0)
+	if _, err := compat.GetInt64("str"); !errors.Is(err, ErrTypeMismatch) {
+		t.Fatalf("GetInt64 of an 8-byte string: %v", err)
+	}
+	if v, err := compat.GetString("str"); err != nil || v != "12345678" {
+		t.Fatalf("GetString = %q, %v", v, err)
+	}
+	compat.SetBytes("raw", []byte("x"), 0)
+	if v, _ := legacy.GetBytes("raw"); string(v) != string([]byte{typeTagBytes, 'x'}) {
+		t.Fatalf("bytes written in compat mode = %q, want tagged", v)
+	}
+}
@@ -0,0 +1,66 @@
+package ngcat
+
+import (
+	"encoding/binary"
+)
+
+// versionHeaderSize 版本头部长度：8字节版本号+4字节值长度
+const versionHeaderSize = 12
+
+// SetWithVersion 乐观并发写入：存储的版本等于expectedVersion时写入新值并将版本加一
+//
+// 不存在的键版本为0。版本不一致时返回ErrVersionMismatch，已存在但不是由
+// SetWithVersion写入的值返回ErrInvalidType。由SetWithVersion管理的键只应通过
+// GetWithVersion读取。
+func (ng *NGCache) SetWithVersion(key string, value []byte, expectedVersion uint64, expireSeconds int) (uint64, error) {
+	started, err := ng.beginWrite()
+	if err != nil {
+		return 0, err
+	}
+	if started {
+		defer ng.inflight.Done()
+	}
+	mu := ng.keyLock(key)
+	mu.Lock()
+	defer mu.Unlock()
+
+	_, current, err := ng.GetWithVersion(key)
+	if err == ErrKeyNotFound {
+		current, err = 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+	if current != expectedVersion {
+		return current, ErrVersionMismatch
+	}
+
+	newVersion := current + 1
+	data := make([]byte, versionHeaderSize+len(value))
+	binary.LittleEndian.PutUint64(data, newVersion)
+	binary.LittleEndian.PutUint32(data[8:], uint32(len(value)))
+	copy(data[versionHeaderSize:], value)
+
+	err = ng.setLocked(key, data, expireSeconds)
+	if err != nil {
+		return current, err
+	}
+	return newVersion, nil
+}
+
+// GetWithVersion 获取由SetWithVersion写入的值及其版本
+func (ng *NGCache) GetWithVersion(key string) ([]byte, uint64, error) {
+	data, err := ng.getWithPersist(key)
+	if err != nil {
+		return nil, 0, err
+	}
+	if len(data) < versionHeaderSize {
+		return nil, 0, ErrInvalidType
+	}
+	version := binary.LittleEndian.Uint64(data)
+	size := binary.LittleEndian.Uint32(data[8:])
+	if int(size) != len(data)-versionHeaderSize {
+		return nil, 0, ErrInvalidType
+	}
+	return data[versionHeaderSize:], version, nil
+}
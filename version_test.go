@@ -0,0 +1,66 @@
+package ngcat
+
+import (
+	"sync"
+	"testing"
+)
+
+func TestSetWithVersion(t *testing.T) {
+	nc := NewNGCache(1024*1024, nil)
+	defer nc.Close()
+
+	v1, err := nc.SetWithVersion("cfg", []byte("a"), 0, 0)
+	if err != nil || v1 != 1 {
+		t.Fatalf("first write = %d, %v", v1, err)
+	}
+	if current, err := nc.SetWithVersion("cfg", []byte("stale"), 0, 0); err != ErrVersionMismatch || current != 1 {
+		t.Fatalf("stale write = %d, %v", current, err)
+	}
+	v2, err := nc.SetWithVersion("cfg", []byte("b"), v1, 0)
+	if err != nil || v2 != 2 {
+		t.Fatalf("second write = %d, %v", v2, err)
+	}
+	value, version, err := nc.GetWithVersion("cfg")
+	if err != nil || string(value) != "b" || version != 2 {
+		t.Fatalf("GetWithVersion = %q, %d, %v", value, version, err)
+	}
+
+	nc.SetString("plain", "x", 0)
+	if _, _, err := nc.GetWithVersion("plain"); err != ErrInvalidType {
+		t.Fatalf("expected ErrInvalidType, got %v", err)
+	}
+	if _, _, err := nc.GetWithVersion("missing"); err != ErrKeyNotFound {
+		t.Fatalf("expected ErrKeyNotFound, got %v", err)
+	}
+}
+
+func TestSetWithVersionConcurrent(t *testing.T) {
+	nc := NewNGCache(1024*1024, nil)
+	defer nc.Close()
+
+	const workers, updates = 8, 50
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for n := 0; n < updates; {
+				_, version, err := nc.GetWithVersion("counter")
+				if err == ErrKeyNotFound {
+					version = 0
+				}
+				if _, err := nc.SetWithVersion("counter", []byte("v"), version, 0); err == nil {
+					n++
+				} else if err != ErrVersionMismatch {
+					t.Error(err)
+					return
+				}
+			}
+		}()
+	}
+	wg.Wait()
+
+	if _, version, _ := nc.GetWithVersion("counter"); version != workers*updates {
+		t.Fatalf("final version = %d, want %d", version, workers*updates)
+	}
+}
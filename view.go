@@ -0,0 +1,77 @@
+package ngcat
+
+import (
+	"unicode/utf8"
+	"unsafe"
+)
+
+// GetStringZeroCopy 与GetString相同，但直接以读取到的缓冲区构造字符串，省去一次复制
+//
+// 缓冲区是freecache为本次读取复制出的（或持久化数据中不会被修改的）切片，不与任何调用方共享，
+// 因此返回的字符串与普通字符串一样可以长期持有。大值读取时可节省一次与值等长的分配和复制。
+func (ng *NGCache) GetStringZeroCopy(key string) (string, error) {
+	data, err := ng.getWithPersist(key)
+	if err != nil {
+		return "", err
+	}
+	data, err = ng.untagValue("string", data)
+	if err != nil {
+		return "", err
+	}
+	if !utf8.Valid(data) {
+		return "", ErrInvalidEncoding
+	}
+	if len(data) == 0 {
+		return "", nil
+	}
+	return unsafe.String(unsafe.SliceData(data), len(data)), nil
+}
+
+// ViewBytes 以回调的形式访问键的值，值在freecache中时直接暴露其内部缓冲区，不做任何复制
+//
+// 生命周期规则：
+//   - value只在fn执行期间有效，fn返回后不得保留value或其任何子切片，需要保留时自行复制；
+//   - fn不得修改value；
+//   - 值在freecache中时fn在freecache的分段锁内执行，fn应尽快返回，且不得再调用同一缓存的任何方法，否则可能死锁。
+//
+// 启用值压缩（WithCompressValuesOver）时压缩过的值会先解压，此时value是新分配的缓冲区。
+// fn返回的错误原样返回，键不存在时返回ErrKeyNotFound且不调用fn。
+func (ng *NGCache) ViewBytes(key string, fn func(value []byte) error) error {
+	if ng.hotKeys != nil {
+		ng.hotKeys.record(key)
+	}
+
+	found := false
+	err := ng.storeGetFn(key, func(data []byte) error {
+		found = true
+		value, err := ng.decodeValue(data)
+		if err != nil {
+			return err
+		}
+		return fn(value)
+	})
+	if found {
+		if err == nil {
+			ng.noteAccess(key)
+		}
+		return err
+	}
+
+	// freecache中没有时回退到合并中的写入和持久化数据，与GetBytes一致
+	data, ok := ng.coalescedValue(key)
+	if !ok {
+		ng.persistDataMutex.RLock()
+		data, ok = ng.persistData[key]
+		ng.persistDataMutex.RUnlock()
+		if !ok {
+			return ErrKeyNotFound
+		}
+		ng.promote(key, data)
+	}
+	ng.noteAccess(key)
+	value, err := ng.decodeValue(data)
+	if err != nil {
+		return err
+	}
+	return fn(value)
+}
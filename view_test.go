@@ -0,0 +1,101 @@
+package ngcat
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+)
+
+func TestGetStringZeroCopy(t *testing.T) {
+	nc := NewNGCache(1024*1024, nil)
+	defer nc.Close()
+	nc.SetString("k", "hello", 0)
+	nc.SetString("empty", "", 0)
+
+	if v, err := nc.GetStringZeroCopy("k"); err != nil || v != "hello" {
+		t.Fatalf("k = %q, %v", v, err)
+	}
+	if v, err := nc.GetStringZeroCopy("empty"); err != nil || v != "" {
+		t.Fatalf("empty = %q, %v", v, err)
+	}
+	if _, err := nc.GetStringZeroCopy("missing"); err != ErrKeyNotFound {
+		t.Fatalf("missing: %v", err)
+	}
+}
+
+func TestViewBytes(t *testing.T) {
+	nc := NewNGCache(1024*1024, nil, WithCompressValuesOver(16))
+	defer nc.Close()
+	nc.SetString("small", "abc", 0)
+	big := bytes.Repeat([]byte("x"), 1024)
+	nc.SetBytes("big", big, 0)
+
+	for key, want := range map[string][]byte{"small": []byte("abc"), "big": big} {
+		var got []byte
+		err := nc.ViewBytes(key, func(value []byte) error {
+			got = append(got, value...)
+			return nil
+		})
+		if err != nil || !bytes.Equal(got, want) {
+			t.Fatalf("%s: %q, %v", key, got, err)
+		}
+	}
+
+	// 永久缓存被freecache淘汰后从持久化数据读取
+	nc.cache.Del([]byte("small"))
+	if err := nc.ViewBytes("small", func(value []byte) error {
+		if string(value) != "abc" {
+			t.Fatalf("fallback value = %q", value)
+		}
+		return nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	errStop := errors.New("stop")
+	if err := nc.ViewBytes("small", func([]byte) error { return errStop }); err != errStop {
+		t.Fatalf("callback error = %v", err)
+	}
+	called := false
+	if err := nc.ViewBytes("missing", func([]byte) error { called = true; return nil }); err != ErrKeyNotFound || called {
+		t.Fatalf("missing: %v, called = %v", err, called)
+	}
+}
+
+// benchmarkLargeValue 写入一个1MB的值，freecache单个条目上限为缓存大小的1/1024，因此需要约1GB的缓存
+func benchmarkLargeValue(b *testing.B) *NGCache {
+	nc := NewNGCache(1100*1024*1024, nil)
+	b.Cleanup(func() { nc.Close() })
+	if err := nc.SetBytes("large", bytes.Repeat([]byte("x"), 1024*1024), 60); err != nil {
+		b.Fatal(err)
+	}
+	b.SetBytes(1024 * 1024)
+	b.ReportAllocs()
+	b.ResetTimer()
+	return nc
+}
+
+func BenchmarkGetString1MB(b *testing.B) {
+	nc := benchmarkLargeValue(b)
+	for i := 0; i < b.N; i++ {
+		nc.GetString("large")
+	}
+}
+
+func BenchmarkGetStringZeroCopy1MB(b *testing.B) {
+	nc := benchmarkLargeValue(b)
+	for i := 0; i < b.N; i++ {
+		nc.GetStringZeroCopy("large")
+	}
+}
+
+func BenchmarkViewBytes1MB(b *testing.B) {
+	nc := benchmarkLargeValue(b)
+	var n int
+	for i := 0; i < b.N; i++ {
+		nc.ViewBytes("large", func(value []byte) error {
+			n += len(value)
+			return nil
+		})
+	}
+}
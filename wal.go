@@ -0,0 +1,324 @@
+package ngcat
+
+import (
+	"bufio"
+	"context"
+	"encoding/binary"
+	"io"
+	"os"
+	"path/filepath"
+)
+
+// WAL文件常量
+const (
+	// WALMagic WAL文件魔数
+	WALMagic = 0x4E474357 // "NGCW"
+	// WALVersion WAL格式版本
+	WALVersion = 1
+	// walSuffix WAL文件相对快照文件名的后缀
+	walSuffix = ".wal"
+	// walHeaderSize WAL文件头的字节数（魔数+版本）
+	walHeaderSize = 4 + 4
+)
+
+// WAL记录操作类型
+const (
+	walOpSet    byte = 1
+	walOpDelete byte = 2
+)
+
+// walLog 追加写入的操作日志
+//
+// 记录格式: [操作:1字节][键长度:4字节][键][值长度:4字节][值]，删除记录没有值部分。
+type walLog struct {
+	file *os.File
+	w    *bufio.Writer
+	// records 上一次压缩以来快照条目数与WAL记录数之和，减去存活条目数即为已失效的记录数
+	records int
+}
+
+// walPath WAL文件路径
+func (ng *NGCache) walPath() string {
+	return ng.persistFilePath() + walSuffix
+}
+
+// loadFromWAL 加载二进制快照并重放WAL，随后打开WAL用于追加
+func (ng *NGCache) loadFromWAL(ctx context.Context) error {
+	var snapshotErr error
+	filePath := ng.persistFilePath()
+	if _, err := os.Stat(filePath); err == nil {
+		snapshotErr = ng.loadFromBinary(ctx, filePath)
+	}
+	ng.persistDataMutex.RLock()
+	snapshotEntries := len(ng.persistData)
+	ng.persistDataMutex.RUnlock()
+
+	// 快照损坏时仍重放日志并打开WAL，由加载失败策略决定保留哪些数据
+	replayed, validSize, replayErr := ng.replayWAL()
+	if !ng.persistReadOnly {
+		err := ng.openWAL(validSize)
+		if err != nil {
+			return err
+		}
+		ng.walMutex.Lock()
+		ng.wal.records = snapshotEntries + replayed
+		ng.walMutex.Unlock()
+	}
+	if snapshotErr != nil {
+		return snapshotErr
+	}
+	return replayErr
+}
+
+// replayWAL 将WAL中的操作重放到freecache和持久化数据，返回重放的记录数和最后一条完整记录之后的偏移
+//
+// 文件不存在或文件头无效时偏移为-1，openWAL不截断文件。
+func (ng *NGCache) replayWAL() (replayed int, validSize int64, err error) {
+	file, err := os.Open(ng.walPath())
+	if os.IsNotExist(err) {
+		return 0, -1, nil
+	}
+	if err != nil {
+		return 0, -1, newError(CodeOpenFile, ng.walPath(), err)
+	}
+	defer file.Close()
+	info, err := file.Stat()
+	if err != nil {
+		return 0, -1, newError(CodeOpenFile, ng.walPath(), err)
+	}
+
+	r := bufio.NewReader(file)
+	err = readWALHeader(r)
+	if err != nil {
+		return 0, -1, err
+	}
+	validSize = walHeaderSize
+
+	stats := ng.newLoadStats()
+	defer stats.report(ng)
+	ng.persistDataMutex.Lock()
+	defer ng.persistDataMutex.Unlock()
+	for ; ; replayed++ {
+		op, key, value, n, err := readWALRecord(r, info.Size()-validSize)
+		if err == io.EOF {
+			return replayed, validSize, nil
+		}
+		if err != nil {
+			// 末尾不完整的记录来自写入中途崩溃，之前的记录仍然有效
+			if err == io.ErrUnexpectedEOF {
+				return replayed, validSize, nil
+			}
+			return replayed, validSize, err
+		}
+		validSize += n
+
+		switch op {
+		case walOpSet:
+			ng.loadEntry(key, value, &stats)
+		case walOpDelete:
+			delete(ng.persistData, key)
+			ng.bloomNoteDelete()
+			ng.storeDel(key)
+		}
+	}
+}
+
+// readWALHeader 读取并校验WAL文件头
+func readWALHeader(r io.Reader) error {
+	var header [walHeaderSize]byte
+	_, err := io.ReadFull(r, header[:])
+	if err != nil {
+		return newError(CodeCorruptFile, "read WAL header", err)
+	}
+	if magic := binary.LittleEndian.Uint32(header[0:]); magic != WALMagic {
+		return corruptf("invalid WAL magic 0x%X", magic)
+	}
+	if version := binary.LittleEndian.Uint32(header[4:]); version != WALVersion {
+		return corruptf("unsupported WAL version %d", version)
+	}
+	return nil
+}
+
+// readWALRecord 读取一条WAL记录，remaining为文件中剩余的字节数，返回记录的字节数
+func readWALRecord(r io.Reader, remaining int64) (op byte, key string, value []byte, n int64, err error) {
+	var opBuf [1]byte
+	_, err = io.ReadFull(r, opBuf[:])
+	if err != nil {
+		return 0, "", nil, 0, err
+	}
+	op = opBuf[0]
+	if op != walOpSet && op != walOpDelete {
+		return 0, "", nil, 0, corruptf("invalid WAL op %d", op)
+	}
+	n = 1
+
+	keyBytes, err := readWALBytes(r, remaining-n)
+	if err != nil {
+		return 0, "", nil, 0, err
+	}
+	n += 4 + int64(len(keyBytes))
+	if op == walOpSet {
+		value, err = readWALBytes(r, remaining-n)
+		if err != nil {
+			return 0, "", nil, 0, err
+		}
+		n += 4 + int64(len(value))
+	}
+	return op, string(keyBytes), value, n, nil
+}
+
+// readWALBytes 读取带长度前缀的字节串，记录被截断时返回io.ErrUnexpectedEOF
+//
+// 长度超过文件剩余的字节数时同样视为截断，不会按损坏的长度分配内存。
+func readWALBytes(r io.Reader, remaining int64) ([]byte, error) {
+	var lenBuf [4]byte
+	_, err := io.ReadFull(r, lenBuf[:])
+	if err == io.EOF {
+		return nil, io.ErrUnexpectedEOF
+	}
+	if err != nil {
+		return nil, err
+	}
+	length := int64(binary.LittleEndian.Uint32(lenBuf[:]))
+	if length > remaining-4 {
+		return nil, io.ErrUnexpectedEOF
+	}
+	data := make([]byte, length)
+	_, err = io.ReadFull(r, data)
+	if err == io.EOF {
+		return nil, io.ErrUnexpectedEOF
+	}
+	return data, err
+}
+
+// openWAL 打开WAL文件用于追加，新文件写入文件头
+//
+// validSize>=0时先将文件截断到该偏移，丢弃崩溃时写了一半的末尾记录；否则新记录会追加在不完整的记录之后，
+// 下次重放在不完整的记录处停止，这些记录全部丢失。
+func (ng *NGCache) openWAL(validSize int64) error {
+	path := ng.walPath()
+	err := os.MkdirAll(filepath.Dir(path), 0755)
+	if err != nil {
+		return newError(CodeCreateDir, filepath.Dir(path), err)
+	}
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_RDWR|os.O_APPEND, 0644)
+	if err != nil {
+		return newError(CodeOpenFile, path, err)
+	}
+
+	wal := &walLog{file: file, w: bufio.NewWriter(file)}
+	info, err := file.Stat()
+	if err == nil && validSize >= 0 && info.Size() > validSize {
+		ng.logger.Warn("ngcat: 截断WAL末尾不完整的记录", "path", path, "bytes", info.Size()-validSize)
+		err = file.Truncate(validSize)
+		if err != nil {
+			err = newError(CodeWriteFile, path, err)
+		}
+	}
+	if err == nil && info.Size() == 0 {
+		err = wal.writeHeader()
+	}
+	if err != nil {
+		file.Close()
+		return err
+	}
+
+	ng.walMutex.Lock()
+	ng.wal = wal
+	ng.walMutex.Unlock()
+	return nil
+}
+
+// writeHeader 写入WAL文件头
+func (wal *walLog) writeHeader() error {
+	var header [walHeaderSize]byte
+	binary.LittleEndian.PutUint32(header[0:], WALMagic)
+	binary.LittleEndian.PutUint32(header[4:], WALVersion)
+	_, err := wal.w.Write(header[:])
+	return err
+}
+
+// append 写入一条记录
+func (wal *walLog) append(op byte, key string, value []byte) error {
+	wal.records++
+	var lenBuf [4]byte
+	wal.w.WriteByte(op)
+	binary.LittleEndian.PutUint32(lenBuf[:], uint32(len(key)))
+	wal.w.Write(lenBuf[:])
+	wal.w.WriteString(key)
+	if op == walOpSet {
+		binary.LittleEndian.PutUint32(lenBuf[:], uint32(len(value)))
+		wal.w.Write(lenBuf[:])
+		_, err := wal.w.Write(value)
+		return err
+	}
+	return nil
+}
+
+// appendWAL 在WAL模式下记录一次永久缓存的写入或删除
+func (ng *NGCache) appendWAL(op byte, key string, value []byte) {
+	ng.walMutex.Lock()
+	defer ng.walMutex.Unlock()
+	if ng.wal != nil {
+		ng.wal.append(op, key, value)
+	}
+}
+
+// flushWAL 将缓冲的WAL记录写入磁盘
+func (ng *NGCache) flushWAL() error {
+	ng.walMutex.Lock()
+	defer ng.walMutex.Unlock()
+	if ng.wal == nil {
+		return nil
+	}
+	err := ng.wal.w.Flush()
+	if err != nil {
+		return err
+	}
+	return ng.wal.file.Sync()
+}
+
+// compactWAL 将持久化数据写为新的二进制快照并清空WAL
+//
+// 压缩期间持有WAL锁，新的写入会等待压缩完成后再追加，保证不会丢失记录。
+func (ng *NGCache) compactWAL(ctx context.Context) error {
+	ng.walMutex.Lock()
+	defer ng.walMutex.Unlock()
+
+	data := ng.collectPersistData()
+	ng.applyPersistLimits(data, FormatBinary)
+	err := ng.saveToBinary(ctx, ng.persistFilePath(), data)
+	if err != nil {
+		return err
+	}
+
+	if ng.wal == nil {
+		return nil
+	}
+	ng.wal.w.Reset(ng.wal.file)
+	err = ng.wal.file.Truncate(0)
+	if err != nil {
+		return newError(CodeWriteFile, ng.wal.file.Name(), err)
+	}
+	err = ng.wal.writeHeader()
+	if err != nil {
+		return err
+	}
+	ng.wal.records = len(data.Entries)
+	return ng.wal.w.Flush()
+}
+
+// closeWAL 关闭WAL文件
+func (ng *NGCache) closeWAL() error {
+	ng.walMutex.Lock()
+	defer ng.walMutex.Unlock()
+	if ng.wal == nil {
+		return nil
+	}
+	err := ng.wal.w.Flush()
+	if cerr := ng.wal.file.Close(); err == nil {
+		err = cerr
+	}
+	ng.wal = nil
+	return err
+}
@@ -0,0 +1,240 @@
+package ngcat
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+)
+
+func walConfig(dir string) *PersistConfig {
+	return &PersistConfig{
+		Enabled:  true,
+		FilePath: dir,
+		FileName: "cache.snap",
+		Format:   FormatWAL,
+		Interval: time.Hour,
+	}
+}
+
+func TestWALReplayAfterCrash(t *testing.T) {
+	dir := t.TempDir()
+	nc := NewNGCache(1024*1024, walConfig(dir), WithClock(newFakeClock()))
+	nc.SetString("a", "1", 0)
+	nc.SetString("b", "2", 0)
+	nc.SetString("a", "3", 0)
+	nc.SetString("ttl", "x", 60)
+	nc.Delete("b")
+	// 模拟定时刷盘后进程崩溃：不调用Close，快照文件不存在
+	if err := nc.saveToPersist(); err != nil {
+		t.Fatal(err)
+	}
+	if fileExists(nc.persistFilePath()) {
+		t.Fatal("WAL mode should not write a snapshot on interval")
+	}
+
+	replayed := NewNGCache(1024*1024, walConfig(dir))
+	defer replayed.Close()
+	if v, _ := replayed.GetString("a"); v != "3" {
+		t.Fatalf("a = %q", v)
+	}
+	if _, err := replayed.GetString("b"); err != ErrKeyNotFound {
+		t.Fatalf("deleted key replayed: %v", err)
+	}
+	if _, err := replayed.GetString("ttl"); err != ErrKeyNotFound {
+		t.Fatalf("non-permanent key should not be logged: %v", err)
+	}
+}
+
+func TestWALTornTailTruncated(t *testing.T) {
+	dir := t.TempDir()
+	first := NewNGCache(1024*1024, walConfig(dir), WithClock(newFakeClock()))
+	first.SetString("a", "1", 0)
+	if err := first.saveToPersist(); err != nil {
+		t.Fatal(err)
+	}
+	// 第一次崩溃：末尾的记录只写了操作、键长度和一部分键
+	f, err := os.OpenFile(first.walPath(), os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.Write([]byte{walOpSet, 10, 0, 0, 0, 'x', 'y'})
+	f.Close()
+
+	second := NewNGCache(1024*1024, walConfig(dir), WithClock(newFakeClock()),
+		WithLogger(slog.New(slog.NewTextHandler(io.Discard, nil))))
+	second.SetString("b", "2", 0)
+	// 第二次崩溃：刷盘后不调用Close
+	if err := second.saveToPersist(); err != nil {
+		t.Fatal(err)
+	}
+
+	third := NewNGCache(1024*1024, walConfig(dir))
+	defer third.Close()
+	for key, want := range map[string]string{"a": "1", "b": "2"} {
+		if v, err := third.GetString(key); v != want {
+			t.Fatalf("%s = %q, %v, want %q", key, v, err, want)
+		}
+	}
+}
+
+func TestReadWALBytesCorruptLength(t *testing.T) {
+	// 长度为4GiB-1但文件中只剩3个字节，不应按长度分配内存
+	record := []byte{0xff, 0xff, 0xff, 0xff, 'a', 'b', 'c'}
+	var before, after runtime.MemStats
+	runtime.ReadMemStats(&before)
+	if _, err := readWALBytes(bytes.NewReader(record), int64(len(record))); err != io.ErrUnexpectedEOF {
+		t.Fatalf("err = %v, want io.ErrUnexpectedEOF", err)
+	}
+	runtime.ReadMemStats(&after)
+	if allocated := after.TotalAlloc - before.TotalAlloc; allocated > 1<<20 {
+		t.Fatalf("allocated %d bytes for a corrupt length", allocated)
+	}
+}
+
+func TestWALCompactOnClose(t *testing.T) {
+	dir := t.TempDir()
+	nc := NewNGCache(1024*1024, walConfig(dir))
+	for i := 0; i < 100; i++ {
+		nc.SetString(fmt.Sprintf("k%d", i), "v", 0)
+	}
+	walPath := nc.walPath()
+	if err := nc.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	info, err := os.Stat(walPath)
+	if err != nil || info.Size() != 8 {
+		t.Fatalf("WAL should be truncated to its header: %v, %v", info, err)
+	}
+
+	reloaded := NewNGCache(1024*1024, walConfig(dir))
+	defer reloaded.Close()
+	reloaded.SetString("after", "compaction", 0)
+	if v, _ := reloaded.GetString("k99"); v != "v" {
+		t.Fatalf("k99 = %q", v)
+	}
+}
+
+func TestCompact(t *testing.T) {
+	dir := t.TempDir()
+	nc := NewNGCache(4*1024*1024, walConfig(dir), WithLogger(slog.New(slog.NewTextHandler(io.Discard, nil))))
+	defer nc.Close()
+	value := strings.Repeat("v", 100)
+	for i := 0; i < 1000; i++ {
+		nc.SetString(fmt.Sprintf("k%d", i), value, 0)
+	}
+	for i := 0; i < 1000; i++ {
+		if i%10 != 0 {
+			nc.Delete(fmt.Sprintf("k%d", i))
+		}
+	}
+	if err := nc.Save(); err != nil {
+		t.Fatal(err)
+	}
+	before := nc.persistDiskSize()
+
+	if err := nc.Compact(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	after := nc.persistDiskSize()
+	if after >= before/5 {
+		t.Fatalf("size after compaction = %d, before = %d", after, before)
+	}
+	stats := nc.Stats()
+	if stats.Compactions != 1 || stats.CompactedBytes != before-after {
+		t.Fatalf("Compactions = %d, CompactedBytes = %d, want 1, %d", stats.Compactions, stats.CompactedBytes, before-after)
+	}
+
+	reloaded := NewNGCache(4*1024*1024, walConfig(dir))
+	defer reloaded.Close()
+	if v, _ := reloaded.GetString("k990"); v != value {
+		t.Fatalf("k990 = %q", v)
+	}
+	if _, err := reloaded.GetString("k991"); err != ErrKeyNotFound {
+		t.Fatalf("deleted key after compaction: %v", err)
+	}
+}
+
+func TestAutoCompactRatio(t *testing.T) {
+	clock := newFakeClock()
+	config := walConfig(t.TempDir())
+	config.AutoCompactRatio = 2
+	nc := NewNGCache(1024*1024, config, WithClock(clock), WithLogger(slog.New(slog.NewTextHandler(io.Discard, nil))))
+	defer nc.Close()
+
+	for i := 0; i < 10; i++ {
+		nc.SetString(fmt.Sprintf("k%d", i), "v", 0)
+	}
+	// 被删除的5个条目和5条删除记录均已失效，与5个存活条目之比为2，未超过阈值
+	for i := 0; i < 5; i++ {
+		nc.Delete(fmt.Sprintf("k%d", i))
+	}
+	nc.persistTick(clock.Now())
+	if n := nc.Stats().Compactions; n != 0 {
+		t.Fatalf("compacted at ratio 2: %d", n)
+	}
+
+	nc.SetString("k5", "v2", 0)
+	nc.persistTick(clock.Now())
+	if n := nc.Stats().Compactions; n != 1 {
+		t.Fatalf("Compactions = %d, want 1", n)
+	}
+	info, err := os.Stat(nc.walPath())
+	if err != nil || info.Size() != 8 {
+		t.Fatalf("WAL should be truncated to its header: %v, %v", info, err)
+	}
+	if ratio := nc.walDeadRatio(); ratio != 0 {
+		t.Fatalf("dead ratio after compaction = %v", ratio)
+	}
+}
+
+// benchmarkPersistWorkload 写入10000个永久缓存并完成一次持久化
+func benchmarkPersistWorkload(b *testing.B, format PersistFormat) {
+	const entries = 10000
+	keys := make([]string, entries)
+	for i := range keys {
+		keys[i] = fmt.Sprintf("key_%d", i)
+	}
+	value := make([]byte, 64)
+	dir := b.TempDir()
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		nc := NewNGCache(64*1024*1024, &PersistConfig{
+			Enabled:  true,
+			FilePath: dir,
+			FileName: fmt.Sprintf("bench_%d", i),
+			Format:   format,
+			Interval: time.Hour,
+		})
+		for _, key := range keys {
+			nc.SetBytes(key, value, 0)
+		}
+		if err := nc.saveToPersist(); err != nil {
+			b.Fatal(err)
+		}
+		b.StopTimer()
+		nc.Close()
+		b.StartTimer()
+	}
+}
+
+func BenchmarkPersistWAL10k(b *testing.B) {
+	benchmarkPersistWorkload(b, FormatWAL)
+}
+
+func BenchmarkPersistBinary10k(b *testing.B) {
+	benchmarkPersistWorkload(b, FormatBinary)
+}
+
+func BenchmarkPersistJSON10k(b *testing.B) {
+	benchmarkPersistWorkload(b, FormatJSON)
+}
@@ -0,0 +1,107 @@
+package ngcat
+
+import (
+	"context"
+	"sync"
+)
+
+// maxWarmupErrors WarmupReport中保留的错误数量上限
+const maxWarmupErrors = 10
+
+// WarmupReport 缓存预热结果
+type WarmupReport struct {
+	// Loaded 成功加载的键数量
+	Loaded int
+	// Skipped 已存在而跳过的键数量
+	Skipped int
+	// Failed 加载或写入失败的键数量
+	Failed int
+	// Errors 最先出现的若干个错误
+	Errors []error
+}
+
+// record 记录一个键的加载结果
+func (r *WarmupReport) record(err error) {
+	if err == nil {
+		r.Loaded++
+		return
+	}
+	r.Failed++
+	if len(r.Errors) < maxWarmupErrors {
+		r.Errors = append(r.Errors, err)
+	}
+}
+
+// Warmup 通过loader预热一批键
+//
+// 已存在的键会被跳过，loader最多由concurrency个协程并发调用，
+// 其返回的过期时间原样传给写入路径（0表示永久缓存）。
+// ctx取消后不再派发新的键，已在执行的loader完成后返回ctx.Err()。
+func (ng *NGCache) Warmup(ctx context.Context, keys []string, concurrency int, loader func(key string) ([]byte, int, error)) (WarmupReport, error) {
+	if concurrency <= 0 {
+		concurrency = 1
+	}
+
+	var (
+		report WarmupReport
+		mu     sync.Mutex
+		wg     sync.WaitGroup
+	)
+	jobs := make(chan string)
+
+	for i := 0; i < concurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for key := range jobs {
+				err := ng.warmupKey(key, loader)
+				mu.Lock()
+				report.record(err)
+				mu.Unlock()
+			}
+		}()
+	}
+
+dispatch:
+	for _, key := range keys {
+		if ng.contains(key) {
+			mu.Lock()
+			report.Skipped++
+			mu.Unlock()
+			continue
+		}
+		select {
+		case jobs <- key:
+		case <-ctx.Done():
+			break dispatch
+		}
+	}
+	close(jobs)
+	wg.Wait()
+
+	return report, ctx.Err()
+}
+
+// warmupKey 加载并写入单个键
+func (ng *NGCache) warmupKey(key string, loader func(key string) ([]byte, int, error)) error {
+	value, expireSeconds, err := loader(key)
+	if err != nil {
+		return newError(CodeLoadFailed, key, err)
+	}
+	err = ng.setWithPersist(key, value, expireSeconds)
+	if err != nil {
+		return newError(CodeStoreFailed, key, err)
+	}
+	return nil
+}
+
+// contains 检查键是否存在，不影响命中统计
+func (ng *NGCache) contains(key string) bool {
+	if _, err := ng.storePeek(key); err == nil {
+		return true
+	}
+	ng.persistDataMutex.RLock()
+	_, exists := ng.persistData[key]
+	ng.persistDataMutex.RUnlock()
+	return exists
+}
@@ -0,0 +1,97 @@
+package ngcat
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func warmupKeys(n int) []string {
+	keys := make([]string, n)
+	for i := range keys {
+		keys[i] = fmt.Sprintf("warm_%d", i)
+	}
+	return keys
+}
+
+func TestWarmupConcurrencyCap(t *testing.T) {
+	nc := NewNGCache(1024*1024, nil)
+	defer nc.Close()
+	nc.SetString("warm_0", "present", 0)
+
+	var inFlight, maxInFlight int32
+	report, err := nc.Warmup(context.Background(), warmupKeys(50), 4, func(key string) ([]byte, int, error) {
+		n := atomic.AddInt32(&inFlight, 1)
+		for {
+			m := atomic.LoadInt32(&maxInFlight)
+			if n <= m || atomic.CompareAndSwapInt32(&maxInFlight, m, n) {
+				break
+			}
+		}
+		time.Sleep(time.Millisecond)
+		atomic.AddInt32(&inFlight, -1)
+		return []byte(key), 0, nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if maxInFlight > 4 {
+		t.Fatalf("concurrency cap exceeded: %d", maxInFlight)
+	}
+	if report.Loaded != 49 || report.Skipped != 1 {
+		t.Fatalf("unexpected report: %+v", report)
+	}
+	if v, _ := nc.GetString("warm_0"); v != "present" {
+		t.Fatalf("existing key overwritten: %q", v)
+	}
+}
+
+func TestWarmupPartialFailure(t *testing.T) {
+	nc := NewNGCache(1024*1024, nil)
+	defer nc.Close()
+
+	loadErr := errors.New("backend down")
+	report, err := nc.Warmup(context.Background(), warmupKeys(30), 3, func(key string) ([]byte, int, error) {
+		var i int
+		fmt.Sscanf(key, "warm_%d", &i)
+		if i%2 == 1 {
+			return nil, 0, loadErr
+		}
+		return []byte(key), 60, nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report.Loaded != 15 || report.Failed != 15 {
+		t.Fatalf("unexpected report: %+v", report)
+	}
+	if len(report.Errors) != maxWarmupErrors || !errors.Is(report.Errors[0], loadErr) {
+		t.Fatalf("unexpected errors: %v", report.Errors)
+	}
+	if ttl, err := nc.cache.TTL([]byte("warm_0")); err != nil || ttl == 0 {
+		t.Fatalf("loader TTL not respected: %d, %v", ttl, err)
+	}
+}
+
+func TestWarmupCancellation(t *testing.T) {
+	nc := NewNGCache(1024*1024, nil)
+	defer nc.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	var calls int32
+	report, err := nc.Warmup(ctx, warmupKeys(1000), 2, func(key string) ([]byte, int, error) {
+		if atomic.AddInt32(&calls, 1) == 10 {
+			cancel()
+		}
+		return []byte(key), 0, nil
+	})
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected context.Canceled, got %v", err)
+	}
+	if report.Loaded >= 1000 || report.Loaded < 10 {
+		t.Fatalf("unexpected loaded count after cancel: %d", report.Loaded)
+	}
+}